@@ -3,8 +3,10 @@ package assess
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"os"
+	"path/filepath"
 	"regexp"
 	"strings"
 	"sync"
@@ -15,6 +17,7 @@ import (
 	"golang.org/x/time/rate"
 
 	"github.com/weave-labs/gollm"
+	"github.com/weave-labs/gollm/cassette"
 	"github.com/weave-labs/gollm/config"
 	"github.com/weave-labs/gollm/internal/models"
 	"github.com/weave-labs/gollm/llm"
@@ -34,6 +37,9 @@ const (
 	DefaultOpenAITokens    = 500
 )
 
+// errProviderSkipped marks why the tests of a provider cannot run.
+var errProviderSkipped = errors.New("skipping provider")
+
 // BatchTestConfig configures batch test execution
 type BatchTestConfig struct {
 	EnableBatch  bool
@@ -97,6 +103,7 @@ type TestRunner struct {
 	config       *config.Config
 	batchCfg     *BatchTestConfig
 	batchMetrics *BatchMetrics
+	cassetteDir  string
 	mu           sync.Mutex
 }
 
@@ -148,6 +155,15 @@ func (tr *TestRunner) WithConfig(cfg *config.Config) *TestRunner {
 	return tr
 }
 
+// WithCassettes records provider traffic to, or replays it from, cassette files in dir.
+// Each provider gets its own cassette named after the test and provider. The mode is
+// selected by the GOLLM_CASSETTE_MODE environment variable and defaults to replay,
+// which needs no network access or API keys.
+func (tr *TestRunner) WithCassettes(dir string) *TestRunner {
+	tr.cassetteDir = dir
+	return tr
+}
+
 // WithSystemPrompt sets the system prompt for the test case
 func (tc *TestCase) WithSystemPrompt(prompt string) *TestCase {
 	tc.SystemPrompt = prompt
@@ -310,7 +326,17 @@ func (tr *TestRunner) launchTestWorkers(
 	results chan testResult,
 ) {
 	for _, provider := range tr.providers {
-		client := tr.setupClient(provider)
+		// RunBatch may be called from a subtest of the runner's test, so the
+		// runner's test is never skipped or stopped from here.
+		client, err := tr.setupClient(tr.t, provider)
+		if errors.Is(err, errProviderSkipped) {
+			tr.t.Log(err)
+			continue
+		}
+		if err != nil {
+			tr.t.Error(err)
+			continue
+		}
 		tr.t.Logf("Initialized client for provider: %s", provider.Name)
 
 		for _, tc := range tr.cases {
@@ -464,11 +490,38 @@ func (tr *TestRunner) runBatchCase(ctx context.Context, t *testing.T, client llm
 	return response.AsText(), nil
 }
 
-func (tr *TestRunner) setupClient(provider TestProvider) llm.LLM {
+// setupClient creates the client for provider. Its cassette is saved when t
+// finishes. The error wraps errProviderSkipped when the provider has no API key
+// or no recorded cassette.
+func (tr *TestRunner) setupClient(t testing.TB, provider TestProvider) (llm.LLM, error) {
 	apiKeyEnv := strings.ToUpper(provider.Name) + "_API_KEY"
 	apiKey := os.Getenv(apiKeyEnv)
+
+	var recorder *cassette.Recorder
+	if tr.cassetteDir != "" {
+		name := strings.NewReplacer("/", "_", " ", "_").Replace(tr.t.Name()) + "_" + provider.Name + ".json"
+		path := filepath.Join(tr.cassetteDir, name)
+		rec, err := cassette.New(path, cassette.WithSecrets(apiKey))
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, fmt.Errorf("%w: cassette %s not recorded (set %s=record to record it)",
+				errProviderSkipped, path, cassette.EnvMode)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to open cassette: %w", err)
+		}
+		t.Cleanup(func() {
+			if err := rec.Save(); err != nil {
+				t.Errorf("Failed to save cassette: %v", err)
+			}
+		})
+		recorder = rec
+		if recorder.Mode() == cassette.ModeReplay {
+			apiKey = cassette.ReplayAPIKey
+		}
+	}
+
 	if apiKey == "" {
-		tr.t.Skipf("Skipping tests for %s: %s environment variable not set", provider.Name, apiKeyEnv)
+		return nil, fmt.Errorf("%w: %s environment variable not set", errProviderSkipped, apiKeyEnv)
 	}
 
 	// Create options with provider-specific settings
@@ -500,16 +553,20 @@ func (tr *TestRunner) setupClient(provider TestProvider) llm.LLM {
 		opts = append(opts, gollm.SetExtraHeaders(provider.Headers))
 	}
 
+	if recorder != nil {
+		opts = append(opts, gollm.SetHTTPTransport(recorder))
+	}
+
 	// Create LLM client
 	client, err := gollm.NewLLM(opts...)
 	if err != nil {
-		tr.t.Fatalf("Failed to create client: %v", err)
+		return nil, fmt.Errorf("failed to create client for %s: %w", provider.Name, err)
 	}
 
 	// Note: Response format for JSON schema validation is now set in runBatchCase
 	// when ExpectedSchema is present, not here in setupClient
 
-	return client
+	return client, nil
 }
 
 // buildPromptOptions creates prompt options from test case configuration
@@ -639,7 +696,13 @@ func ExpectMatches(pattern string) ValidationFunc {
 func (tr *TestRunner) Run(ctx context.Context) {
 	for _, provider := range tr.providers {
 		tr.t.Run(provider.Name, func(t *testing.T) {
-			client := tr.setupClient(provider)
+			client, err := tr.setupClient(t, provider)
+			if errors.Is(err, errProviderSkipped) {
+				t.Skip(err)
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
 
 			for _, tc := range tr.cases {
 				t.Run(tc.Name, func(t *testing.T) {
@@ -11,6 +11,7 @@ import (
 	"github.com/stretchr/testify/assert"
 
 	"github.com/weave-labs/gollm"
+	"github.com/weave-labs/gollm/cassette"
 )
 
 // TestProviderIntegration demonstrates comprehensive testing across providers
@@ -21,6 +22,7 @@ func TestProviderIntegration(t *testing.T) {
 
 	// Create test runner with multiple providers
 	test := NewTest(t).
+		WithCassettes(cassette.DefaultDir).
 		WithProviders(map[string]string{
 			"anthropic": "claude-3-5-haiku-latest",
 			"openai":    "gpt-4o-mini",
@@ -64,6 +66,7 @@ func TestJSONValidation(t *testing.T) {
 	}
 
 	test := NewTest(t).
+		WithCassettes(cassette.DefaultDir).
 		WithProviders(map[string]string{
 			"anthropic": "claude-3-5-haiku-latest",
 			"openai":    "gpt-4o-mini",
@@ -112,6 +115,7 @@ func TestProviderSpecificFeatures(t *testing.T) {
 	// Test Anthropic-specific features
 	t.Run("anthropic_features", func(t *testing.T) {
 		test := NewTest(t).
+			WithCassettes(cassette.DefaultDir).
 			WithProvider("anthropic", "claude-3-5-haiku-latest")
 
 		test.AddCase("anthropic_test", "Analyze this code for security vulnerabilities").
@@ -125,6 +129,7 @@ func TestProviderSpecificFeatures(t *testing.T) {
 	// Test OpenAI-specific features
 	t.Run("openai_features", func(t *testing.T) {
 		test := NewTest(t).
+			WithCassettes(cassette.DefaultDir).
 			WithProvider("openai", "gpt-4o-mini")
 
 		test.AddCase("openai_test", "Explain this code's complexity").
@@ -211,6 +216,7 @@ func verifyBatchMetrics(t *testing.T, metrics *BatchMetrics) {
 
 func TestBatchIntegration(t *testing.T) {
 	test := NewTest(t).
+		WithCassettes(cassette.DefaultDir).
 		WithProviders(map[string]string{
 			"anthropic": "claude-3-5-haiku-latest",
 			"openai":    "gpt-4o-mini",
@@ -233,6 +239,7 @@ func TestBatchIntegration(t *testing.T) {
 // TestBatchCrossProvider tests cross-provider consistency
 func TestBatchCrossProvider(t *testing.T) {
 	test := NewTest(t).
+		WithCassettes(cassette.DefaultDir).
 		WithProviders(map[string]string{
 			"anthropic": "claude-3-5-haiku-latest",
 			"openai":    "gpt-4o-mini",
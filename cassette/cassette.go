@@ -0,0 +1,489 @@
+// Package cassette provides an HTTP record/replay transport for exercising LLM
+// providers without network access.
+//
+// In record mode a Recorder forwards requests to the real transport and captures
+// each request/response pair, including server-sent event streams, into a JSON
+// cassette file with secrets scrubbed. In replay mode it serves the recorded
+// responses deterministically by matching the method, URL and normalized request
+// body, so any llm.LLM configured with gollm.SetHTTPTransport runs offline.
+//
+// Example usage:
+//
+//	rec := cassette.ForTest(t, "openai_basic")
+//	client, err := gollm.NewLLM(
+//	    gollm.SetProvider("openai"),
+//	    gollm.SetModel("gpt-4o-mini"),
+//	    gollm.SetAPIKey(rec.APIKey("OPENAI_API_KEY")),
+//	    gollm.SetHTTPTransport(rec),
+//	)
+package cassette
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/weave-labs/gollm/internal/logging"
+)
+
+// Mode controls whether a Recorder talks to the network.
+type Mode int
+
+const (
+	// ModeReplay serves responses from the cassette and never touches the network.
+	ModeReplay Mode = iota
+	// ModeRecord forwards requests to the real transport and records them.
+	ModeRecord
+	// ModePassthrough forwards requests without recording or replaying.
+	ModePassthrough
+)
+
+const (
+	// EnvMode is the environment variable that selects the default mode:
+	// "record", "replay" or "passthrough". Unset means replay.
+	EnvMode = "GOLLM_CASSETTE_MODE"
+
+	// ReplayAPIKey is a placeholder API key that passes configuration validation
+	// for every provider when replaying a cassette without real credentials.
+	ReplayAPIKey = "sk-ant-REDACTED"
+
+	cassetteVersion = 1
+	filePermissions = 0o600
+	dirPermissions  = 0o750
+)
+
+// ErrNoInteraction is returned in replay mode when no unused recorded
+// interaction matches a request.
+var ErrNoInteraction = errors.New("cassette: no recorded interaction matches request")
+
+// Cassette is the on-disk representation of a recording.
+type Cassette struct {
+	Interactions []*Interaction `json:"interactions"`
+	Version      int            `json:"version"`
+}
+
+// Interaction is a single recorded request/response pair.
+type Interaction struct {
+	Request  Request  `json:"request"`
+	Response Response `json:"response"`
+}
+
+// Request is the recorded, scrubbed form of an HTTP request.
+type Request struct {
+	Headers http.Header `json:"headers,omitempty"`
+	Method  string      `json:"method"`
+	URL     string      `json:"url"`
+	Body    string      `json:"body,omitempty"`
+}
+
+// Response is the recorded, scrubbed form of an HTTP response.
+// Streaming is set for server-sent event responses, whose raw event data is kept in Body.
+type Response struct {
+	Headers    http.Header `json:"headers,omitempty"`
+	Body       string      `json:"body"`
+	StatusCode int         `json:"status_code"`
+	Streaming  bool        `json:"streaming,omitempty"`
+}
+
+// Option configures a Recorder.
+type Option func(*Recorder)
+
+// WithMode overrides the mode selected from the environment.
+func WithMode(mode Mode) Option {
+	return func(r *Recorder) {
+		r.mode = mode
+	}
+}
+
+// WithTransport sets the transport used in record and passthrough modes.
+// It defaults to http.DefaultTransport.
+func WithTransport(transport http.RoundTripper) Option {
+	return func(r *Recorder) {
+		r.transport = transport
+	}
+}
+
+// WithSecrets registers literal values, such as API keys, that are replaced with
+// a placeholder wherever they appear in recorded URLs, headers or bodies.
+func WithSecrets(secrets ...string) Option {
+	return func(r *Recorder) {
+		r.secrets = append(r.secrets, secrets...)
+	}
+}
+
+// WithRedactPatterns adds header, query parameter and JSON field name patterns whose
+// values are scrubbed, on top of the built-in patterns for auth headers and API keys.
+func WithRedactPatterns(patterns ...string) Option {
+	return func(r *Recorder) {
+		r.patterns = append(r.patterns, patterns...)
+	}
+}
+
+// WithIgnoredFields excludes top-level JSON request body fields from matching,
+// for values that legitimately change between runs such as user IDs or seeds.
+func WithIgnoredFields(fields ...string) Option {
+	return func(r *Recorder) {
+		r.ignoredFields = append(r.ignoredFields, fields...)
+	}
+}
+
+// Recorder is an http.RoundTripper that records or replays interactions.
+// It is safe for concurrent use.
+type Recorder struct {
+	transport     http.RoundTripper
+	redactor      *logging.Redactor
+	cassette      *Cassette
+	path          string
+	patterns      []string
+	secrets       []string
+	ignoredFields []string
+	used          []bool
+	mode          Mode
+	mu            sync.Mutex
+	dirty         bool
+}
+
+// ModeFromEnv returns the mode selected by the GOLLM_CASSETTE_MODE environment variable.
+func ModeFromEnv() Mode {
+	switch strings.ToLower(strings.TrimSpace(os.Getenv(EnvMode))) {
+	case "record":
+		return ModeRecord
+	case "passthrough", "off":
+		return ModePassthrough
+	default:
+		return ModeReplay
+	}
+}
+
+// New creates a Recorder backed by the cassette file at path. In replay mode the
+// file must exist; in record mode any existing recording is replaced when Save is called.
+func New(path string, opts ...Option) (*Recorder, error) {
+	r := &Recorder{
+		path:      path,
+		mode:      ModeFromEnv(),
+		transport: http.DefaultTransport,
+		cassette:  &Cassette{Version: cassetteVersion},
+	}
+	for _, opt := range opts {
+		opt(r)
+	}
+	r.redactor = logging.NewRedactor(r.patterns...)
+	r.redactor.AddSecrets(r.secrets...)
+
+	if r.mode == ModeReplay {
+		if err := r.load(); err != nil {
+			return nil, err
+		}
+	}
+
+	return r, nil
+}
+
+func (r *Recorder) load() error {
+	data, err := os.ReadFile(r.path)
+	if err != nil {
+		return fmt.Errorf("cassette: failed to read %s: %w", r.path, err)
+	}
+	var c Cassette
+	if err := json.Unmarshal(data, &c); err != nil {
+		return fmt.Errorf("cassette: failed to parse %s: %w", r.path, err)
+	}
+	r.cassette = &c
+	r.used = make([]bool, len(c.Interactions))
+	return nil
+}
+
+// Mode returns the mode the recorder is operating in.
+func (r *Recorder) Mode() Mode {
+	return r.mode
+}
+
+// Path returns the cassette file path.
+func (r *Recorder) Path() string {
+	return r.path
+}
+
+// APIKey returns the value of the environment variable envVar when talking to the
+// network, and ReplayAPIKey when replaying. Real keys are registered as secrets so
+// they never reach the cassette.
+func (r *Recorder) APIKey(envVar string) string {
+	if r.mode == ModeReplay {
+		return ReplayAPIKey
+	}
+	key := os.Getenv(envVar)
+	r.mu.Lock()
+	r.redactor.AddSecrets(key)
+	r.mu.Unlock()
+	return key
+}
+
+// Interactions returns the interactions recorded or loaded so far.
+func (r *Recorder) Interactions() []*Interaction {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]*Interaction(nil), r.cassette.Interactions...)
+}
+
+// RoundTrip implements http.RoundTripper.
+func (r *Recorder) RoundTrip(req *http.Request) (*http.Response, error) {
+	switch r.mode {
+	case ModePassthrough:
+		return r.transport.RoundTrip(req)
+	case ModeRecord:
+		return r.record(req)
+	default:
+		return r.replay(req)
+	}
+}
+
+// Save writes the recorded interactions to the cassette file. It is a no-op unless
+// the recorder is in record mode and has captured at least one interaction.
+func (r *Recorder) Save() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.mode != ModeRecord || !r.dirty {
+		return nil
+	}
+
+	data, err := json.MarshalIndent(r.cassette, "", "  ")
+	if err != nil {
+		return fmt.Errorf("cassette: failed to encode: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(r.path), dirPermissions); err != nil {
+		return fmt.Errorf("cassette: failed to create directory: %w", err)
+	}
+	if err := os.WriteFile(r.path, append(data, '\n'), filePermissions); err != nil {
+		return fmt.Errorf("cassette: failed to write %s: %w", r.path, err)
+	}
+	r.dirty = false
+	return nil
+}
+
+func (r *Recorder) record(req *http.Request) (*http.Response, error) {
+	body, err := readRequestBody(req)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := r.transport.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+
+	r.mu.Lock()
+	recorded := Request{
+		Method:  req.Method,
+		URL:     r.scrubURL(req.URL),
+		Headers: r.scrubHeaders(req.Header),
+		Body:    r.scrubBody(body),
+	}
+	r.mu.Unlock()
+
+	// The response body is captured as the caller consumes it so that streams are
+	// delivered live while recording. The interaction is stored on EOF or Close.
+	resp.Body = &recordingBody{
+		ReadCloser: resp.Body,
+		onDone: func(data []byte) {
+			r.mu.Lock()
+			defer r.mu.Unlock()
+			r.cassette.Interactions = append(r.cassette.Interactions, &Interaction{
+				Request: recorded,
+				Response: Response{
+					StatusCode: resp.StatusCode,
+					Headers:    r.scrubHeaders(resp.Header),
+					Body:       r.redactor.RedactString(string(data)),
+					Streaming:  isEventStream(resp.Header),
+				},
+			})
+			r.dirty = true
+		},
+	}
+	return resp, nil
+}
+
+func (r *Recorder) replay(req *http.Request) (*http.Response, error) {
+	body, err := readRequestBody(req)
+	if err != nil {
+		return nil, err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	key := r.matchKey(req.Method, r.scrubURL(req.URL), r.scrubBody(body))
+	for i, interaction := range r.cassette.Interactions {
+		if r.used[i] {
+			continue
+		}
+		recorded := interaction.Request
+		if r.matchKey(recorded.Method, recorded.URL, recorded.Body) != key {
+			continue
+		}
+		r.used[i] = true
+		return newReplayResponse(req, interaction.Response), nil
+	}
+
+	return nil, fmt.Errorf("%w: %s %s in %s", ErrNoInteraction, req.Method, r.scrubURL(req.URL), r.path)
+}
+
+// matchKey builds the replay lookup key from the method, scrubbed URL and normalized body.
+func (r *Recorder) matchKey(method, rawURL, body string) string {
+	return method + " " + rawURL + "\n" + NormalizeBody([]byte(body), r.ignoredFields...)
+}
+
+func (r *Recorder) scrubURL(u *url.URL) string {
+	scrubbed := *u
+	query := scrubbed.Query()
+	for name := range query {
+		if r.redactor.IsSensitiveKey(name) || name == "key" {
+			query.Set(name, logging.RedactedValue)
+		}
+	}
+	scrubbed.RawQuery = query.Encode()
+	scrubbed.User = nil
+	return r.redactor.RedactString(scrubbed.String())
+}
+
+// scrubBody masks sensitive JSON fields and secret values in a request body.
+// Non-JSON bodies are scrubbed as plain text.
+func (r *Recorder) scrubBody(body []byte) string {
+	var decoded any
+	if err := json.Unmarshal(body, &decoded); err != nil {
+		return r.redactor.RedactString(string(body))
+	}
+	scrubbed, err := json.Marshal(r.scrubJSON(decoded))
+	if err != nil {
+		return r.redactor.RedactString(string(body))
+	}
+	return string(scrubbed)
+}
+
+func (r *Recorder) scrubJSON(value any) any {
+	switch v := value.(type) {
+	case map[string]any:
+		for key, item := range v {
+			if r.redactor.IsSensitiveKey(key) {
+				v[key] = logging.RedactedValue
+				continue
+			}
+			v[key] = r.scrubJSON(item)
+		}
+		return v
+	case []any:
+		for i, item := range v {
+			v[i] = r.scrubJSON(item)
+		}
+		return v
+	case string:
+		return r.redactor.RedactString(v)
+	default:
+		return v
+	}
+}
+
+func (r *Recorder) scrubHeaders(headers http.Header) http.Header {
+	if len(headers) == 0 {
+		return nil
+	}
+	redacted, ok := r.redactor.RedactValue(headers).(http.Header)
+	if !ok {
+		return nil
+	}
+	redacted.Del("Set-Cookie")
+	return redacted
+}
+
+// NormalizeBody returns a canonical form of a request body used for matching.
+// JSON bodies are re-encoded with sorted keys and without the ignored top-level
+// fields; other bodies are compared with surrounding whitespace trimmed.
+func NormalizeBody(body []byte, ignoredFields ...string) string {
+	var decoded any
+	if err := json.Unmarshal(body, &decoded); err != nil {
+		return string(bytes.TrimSpace(body))
+	}
+	if obj, ok := decoded.(map[string]any); ok {
+		for _, field := range ignoredFields {
+			delete(obj, field)
+		}
+	}
+	normalized, err := json.Marshal(decoded)
+	if err != nil {
+		return string(bytes.TrimSpace(body))
+	}
+	return string(normalized)
+}
+
+func readRequestBody(req *http.Request) ([]byte, error) {
+	if req.Body == nil || req.Body == http.NoBody {
+		return nil, nil
+	}
+	body, err := io.ReadAll(req.Body)
+	if err != nil {
+		return nil, fmt.Errorf("cassette: failed to read request body: %w", err)
+	}
+	if err := req.Body.Close(); err != nil {
+		return nil, fmt.Errorf("cassette: failed to close request body: %w", err)
+	}
+	req.Body = io.NopCloser(bytes.NewReader(body))
+	return body, nil
+}
+
+func newReplayResponse(req *http.Request, recorded Response) *http.Response {
+	headers := recorded.Headers.Clone()
+	if headers == nil {
+		headers = make(http.Header)
+	}
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", recorded.StatusCode, http.StatusText(recorded.StatusCode)),
+		StatusCode:    recorded.StatusCode,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        headers,
+		Body:          io.NopCloser(strings.NewReader(recorded.Body)),
+		ContentLength: int64(len(recorded.Body)),
+		Request:       req,
+	}
+}
+
+func isEventStream(headers http.Header) bool {
+	return strings.HasPrefix(headers.Get("Content-Type"), "text/event-stream")
+}
+
+// recordingBody tees a response body into a buffer and reports the captured bytes
+// exactly once, when the body reaches EOF or is closed.
+type recordingBody struct {
+	io.ReadCloser
+	onDone func([]byte)
+	buf    bytes.Buffer
+	once   sync.Once
+}
+
+func (b *recordingBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	b.buf.Write(p[:n])
+	if errors.Is(err, io.EOF) {
+		b.finish()
+	}
+	return n, err
+}
+
+func (b *recordingBody) Close() error {
+	b.finish()
+	return b.ReadCloser.Close()
+}
+
+func (b *recordingBody) finish() {
+	b.once.Do(func() {
+		b.onDone(b.buf.Bytes())
+	})
+}
@@ -0,0 +1,151 @@
+package cassette_test
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/weave-labs/gollm/cassette"
+	"github.com/weave-labs/gollm/config"
+	"github.com/weave-labs/gollm/internal/logging"
+	"github.com/weave-labs/gollm/llm"
+	"github.com/weave-labs/gollm/providers"
+)
+
+const testSecret = "sk-test-secret-0123456789"
+
+func newTestServer(t *testing.T) *httptest.Server {
+	t.Helper()
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		if strings.Contains(string(body), `"stream":true`) {
+			w.Header().Set("Content-Type", "text/event-stream")
+			flusher, _ := w.(http.Flusher)
+			for _, chunk := range []string{"Hello", " world"} {
+				_, _ = io.WriteString(w, "data: {\"text\":\""+chunk+"\"}\n\n")
+				flusher.Flush()
+			}
+			_, _ = io.WriteString(w, "data: [DONE]\n\n")
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = io.WriteString(w, `{"text":"pong","echo":"`+r.Header.Get("Authorization")+`"}`)
+	}))
+}
+
+func doRequest(t *testing.T, client *http.Client, url, body string) (int, string) {
+	t.Helper()
+	req, err := http.NewRequestWithContext(context.Background(), http.MethodPost, url, strings.NewReader(body))
+	require.NoError(t, err)
+	req.Header.Set("Authorization", "Bearer "+testSecret)
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := client.Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	return resp.StatusCode, string(data)
+}
+
+func TestRecordAndReplay(t *testing.T) {
+	server := newTestServer(t)
+	path := filepath.Join(t.TempDir(), "roundtrip.json")
+
+	rec, err := cassette.New(path, cassette.WithMode(cassette.ModeRecord), cassette.WithSecrets(testSecret))
+	require.NoError(t, err)
+	client := &http.Client{Transport: rec}
+
+	_, recordedJSON := doRequest(t, client, server.URL+"/v1/chat?key="+testSecret, `{"model":"m","prompt":"ping"}`)
+	_, recordedStream := doRequest(t, client, server.URL+"/v1/chat?key="+testSecret, `{"prompt":"ping","stream":true}`)
+	require.NoError(t, rec.Save())
+	server.Close()
+
+	raw, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.NotContains(t, string(raw), testSecret)
+	assert.Contains(t, string(raw), logging.RedactedValue)
+
+	interactions := rec.Interactions()
+	require.Len(t, interactions, 2)
+	assert.False(t, interactions[0].Response.Streaming)
+	assert.True(t, interactions[1].Response.Streaming)
+
+	replay, err := cassette.New(path, cassette.WithMode(cassette.ModeReplay))
+	require.NoError(t, err)
+	client = &http.Client{Transport: replay}
+
+	// Key order and whitespace in the request body do not affect matching.
+	status, stream := doRequest(t, client, server.URL+"/v1/chat?key=other", `{ "stream": true, "prompt": "ping" }`)
+	assert.Equal(t, http.StatusOK, status)
+	assert.Equal(t, recordedStream, stream)
+
+	status, body := doRequest(t, client, server.URL+"/v1/chat?key=other", `{"prompt":"ping","model":"m"}`)
+	assert.Equal(t, http.StatusOK, status)
+	assert.Equal(t, strings.ReplaceAll(recordedJSON, testSecret, logging.RedactedValue), body)
+
+	req, err := http.NewRequestWithContext(context.Background(), http.MethodPost, server.URL+"/v1/chat",
+		strings.NewReader(`{"prompt":"unrecorded"}`))
+	require.NoError(t, err)
+	_, err = replay.RoundTrip(req)
+	require.ErrorIs(t, err, cassette.ErrNoInteraction)
+}
+
+type cannedTransport struct {
+	body  string
+	calls int
+}
+
+func (c *cannedTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	c.calls++
+	return &http.Response{
+		StatusCode: http.StatusOK,
+		Header:     http.Header{"Content-Type": []string{"application/json"}},
+		Body:       io.NopCloser(strings.NewReader(c.body)),
+		Request:    req,
+	}, nil
+}
+
+func TestReplayLLMOffline(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "openai.json")
+	upstream := &cannedTransport{
+		body: `{"choices":[{"message":{"role":"assistant","content":"Paris"}}],` +
+			`"usage":{"prompt_tokens":5,"completion_tokens":1,"total_tokens":6}}`,
+	}
+
+	generate := func(rec *cassette.Recorder, apiKey string) string {
+		cfg := config.NewConfig()
+		config.ApplyOptions(cfg,
+			config.SetProvider("openai"),
+			config.SetModel("gpt-4o-mini"),
+			config.SetAPIKey(apiKey),
+			config.SetMaxRetries(0),
+			config.SetHTTPTransport(rec),
+		)
+		client, err := llm.NewLLM(cfg, logging.NewMockLogger(), providers.NewProviderRegistry())
+		require.NoError(t, err)
+
+		resp, err := client.Generate(context.Background(), llm.NewPrompt("What is the capital of France?"))
+		require.NoError(t, err)
+		return resp.AsText()
+	}
+
+	rec, err := cassette.New(path, cassette.WithMode(cassette.ModeRecord), cassette.WithTransport(upstream))
+	require.NoError(t, err)
+	assert.Equal(t, "Paris", generate(rec, testSecret))
+	require.NoError(t, rec.Save())
+
+	replay, err := cassette.New(path, cassette.WithMode(cassette.ModeReplay))
+	require.NoError(t, err)
+	assert.Equal(t, "Paris", generate(replay, cassette.ReplayAPIKey))
+	assert.Equal(t, 1, upstream.calls)
+}
@@ -0,0 +1,43 @@
+package cassette
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// DefaultDir is the directory, relative to the test's package, where ForTest
+// stores cassettes.
+const DefaultDir = "testdata/cassettes"
+
+// ForTest returns a Recorder for the cassette DefaultDir/<name>.json. In replay
+// mode the test is skipped when the cassette has not been recorded yet; in record
+// mode the cassette is saved when the test finishes.
+//
+// Run tests with GOLLM_CASSETTE_MODE=record and real API keys to refresh recordings.
+func ForTest(t testing.TB, name string, opts ...Option) *Recorder {
+	t.Helper()
+	return ForTestPath(t, filepath.Join(DefaultDir, name+".json"), opts...)
+}
+
+// ForTestPath is like ForTest but uses an explicit cassette path.
+func ForTestPath(t testing.TB, path string, opts ...Option) *Recorder {
+	t.Helper()
+
+	rec, err := New(path, opts...)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			t.Skipf("Skipping: cassette %s not recorded (set %s=record to record it)", path, EnvMode)
+		}
+		t.Fatalf("Failed to open cassette: %v", err)
+	}
+
+	t.Cleanup(func() {
+		if err := rec.Save(); err != nil {
+			t.Errorf("Failed to save cassette: %v", err)
+		}
+	})
+
+	return rec
+}
@@ -168,6 +168,12 @@ func generateResponse(
 			return "", "", fmt.Errorf("question-answer: %w", err)
 		}
 		return resp, "", nil
+	case "cot":
+		result, err := presets.SelfConsistency(ctx, llmClient, rawPrompt)
+		if err != nil {
+			return "", "", fmt.Errorf("self-consistency: %w", err)
+		}
+		return result.Answer, "", nil
 	case "summarize":
 		resp, err := presets.Summarize(ctx, llmClient, rawPrompt)
 		if err != nil {
@@ -38,6 +38,11 @@ type (
 	//   cfg = ApplyOptions(cfg, SetLogLevel(LogLevelInfo))
 	LogLevel = logging.LogLevel
 
+	// Logger is the interface gollm logs through. Applications can supply their own
+	// implementation with SetLogger, or wrap a *slog.Logger with SetSlogLogger.
+	// All output is redacted before it reaches the logger.
+	Logger = logging.Logger
+
 	// MemoryOption configures the memory settings for conversation history.
 	// It controls how much context is retained between interactions.
 	//
@@ -45,6 +50,15 @@ type (
 	//   cfg := NewConfig()
 	//   cfg = ApplyOptions(cfg, SetMemory(MemoryOption{MaxHistory: 10}))
 	MemoryOption = config.MemoryOption
+
+	// MemoryStrategy selects how conversation memory is kept within its token limit.
+	MemoryStrategy = config.MemoryStrategy
+)
+
+// Memory strategies for MemoryOption.
+const (
+	MemorySlidingWindow = config.MemorySlidingWindow // Drops the oldest messages
+	MemorySummarize     = config.MemorySummarize     // Summarizes the oldest messages
 )
 
 // Re-export core configuration functions
@@ -98,15 +112,23 @@ var (
 	SetTfsZ          = config.SetTfsZ          // Sets tail-free sampling parameter
 
 	// Runtime configuration
-	SetTimeout      = config.SetTimeout      // Sets request timeout duration
-	SetMaxRetries   = config.SetMaxRetries   // Sets maximum retry attempts
-	SetRetryDelay   = config.SetRetryDelay   // Sets delay between retries
-	SetLogLevel     = config.SetLogLevel     // Sets logging verbosity
-	SetExtraHeaders = config.SetExtraHeaders // Sets additional HTTP headers
+	SetTimeout       = config.SetTimeout       // Sets request timeout duration
+	SetMaxRetries    = config.SetMaxRetries    // Sets maximum retry attempts
+	SetRetryDelay    = config.SetRetryDelay    // Sets delay between retries
+	SetLogLevel      = config.SetLogLevel      // Sets logging verbosity
+	SetExtraHeaders  = config.SetExtraHeaders  // Sets additional HTTP headers
+	SetHTTPTransport = config.SetHTTPTransport // Sets the HTTP transport used for API requests
+
+	// Logging
+	SetLogger         = config.SetLogger         // Injects a custom Logger implementation
+	SetSlogLogger     = config.SetSlogLogger     // Injects a *slog.Logger
+	SetRedactPatterns = config.SetRedactPatterns // Adds key patterns masked in log output
 
 	// Feature toggles
-	SetEnableCaching = config.SetEnableCaching // Enables/disables response caching
-	SetMemory        = config.SetMemory        // Configures conversation memory
+	SetEnableCaching      = config.SetEnableCaching      // Enables/disables response caching
+	SetIncludeRawResponse = config.SetIncludeRawResponse // Keeps raw provider bodies in response metadata
+	SetMemory             = config.SetMemory             // Configures conversation memory
+	SetSummarizingMemory  = config.SetSummarizingMemory  // Configures memory that summarizes old messages
 
 	// Configuration creation
 	NewConfig = config.NewConfig // Creates a new Config with default values
@@ -5,6 +5,8 @@ package config
 
 import (
 	"fmt"
+	"log/slog"
+	"net/http"
 	"os"
 	"strings"
 	"time"
@@ -23,9 +25,27 @@ const (
 	defaultRetryDelay  = 2
 )
 
+// MemoryStrategy selects how conversation memory is kept within its token limit.
+type MemoryStrategy string
+
+const (
+	// MemorySlidingWindow drops the oldest messages once the limit is exceeded.
+	MemorySlidingWindow MemoryStrategy = "sliding_window"
+	// MemorySummarize replaces the oldest messages with an LLM-written summary
+	// once the limit is exceeded.
+	MemorySummarize MemoryStrategy = "summarize"
+)
+
 // MemoryOption configures conversation memory settings, controlling how much
 // context is retained between interactions with the LLM.
 type MemoryOption struct {
+	// Strategy selects how memory is compacted when it exceeds MaxTokens.
+	// The default is MemorySlidingWindow.
+	Strategy MemoryStrategy
+	// SummaryModel is the model that writes summaries for MemorySummarize,
+	// typically a cheaper model of the same provider. The default is the
+	// conversation's model.
+	SummaryModel string
 	// MaxTokens specifies the maximum number of tokens to retain in memory
 	// for context in subsequent interactions.
 	MaxTokens int
@@ -69,6 +89,9 @@ type Config struct {
 	RepeatPenalty         *float64 `env:"LLM_REPEAT_PENALTY" envDefault:"1.1"`
 	MemoryOption          *MemoryOption
 	ExtraHeaders          map[string]string
+	Logger                logging.Logger
+	HTTPTransport         http.RoundTripper
+	RedactPatterns        []string
 	MinP                  *float64          `env:"LLM_MIN_P" envDefault:"0.05"`
 	Seed                  *int              `env:"LLM_SEED"`
 	Mirostat              *int              `env:"LLM_MIROSTAT" envDefault:"0"`
@@ -91,6 +114,7 @@ type Config struct {
 	MaxCompletionTokens   int              `env:"LLM_MAX_COMPLETION_TOKENS" envDefault:"128000"`
 	Temperature           float64          `env:"LLM_TEMPERATURE" envDefault:"0.7" validate:"gte=0,lte=1"`
 	EnableCaching         bool             `env:"LLM_ENABLE_CACHING" envDefault:"false"`
+	IncludeRawResponse    bool             `env:"LLM_INCLUDE_RAW_RESPONSE" envDefault:"false"`
 }
 
 // LoadConfig creates a new Config instance, loading values from environment
@@ -179,6 +203,14 @@ func SetEnableCaching(enableCaching bool) ConfigOption {
 	}
 }
 
+// SetIncludeRawResponse keeps the unparsed provider response body in
+// Response.Metadata.RawBody, for inspecting provider-specific fields.
+func SetIncludeRawResponse(include bool) ConfigOption {
+	return func(c *Config) {
+		c.IncludeRawResponse = include
+	}
+}
+
 // SetProvider sets the LLM provider.
 func SetProvider(provider string) ConfigOption {
 	return func(c *Config) {
@@ -271,6 +303,38 @@ func SetLogLevel(level logging.LogLevel) ConfigOption {
 	}
 }
 
+// SetLogger injects an application-provided logger. Everything gollm logs is
+// passed through a redaction layer before it reaches this logger.
+func SetLogger(logger logging.Logger) ConfigOption {
+	return func(c *Config) {
+		c.Logger = logger
+	}
+}
+
+// SetSlogLogger injects a *slog.Logger. Level filtering is left to the logger's
+// handler, so LLM_LOG_LEVEL does not apply unless SetLogLevel is called later.
+func SetSlogLogger(logger *slog.Logger) ConfigOption {
+	return func(c *Config) {
+		c.Logger = logging.NewSlogLogger(logger, logging.LogLevelDebug)
+	}
+}
+
+// SetRedactPatterns adds case-insensitive key patterns whose values are masked in
+// log output, on top of the built-in patterns for auth headers and API keys.
+func SetRedactPatterns(patterns ...string) ConfigOption {
+	return func(c *Config) {
+		c.RedactPatterns = append(c.RedactPatterns, patterns...)
+	}
+}
+
+// SetHTTPTransport sets the transport used for provider API requests, for example
+// a proxy-aware transport or a cassette recorder for offline tests.
+func SetHTTPTransport(transport http.RoundTripper) ConfigOption {
+	return func(c *Config) {
+		c.HTTPTransport = transport
+	}
+}
+
 // SetMemory sets the conversation memory settings.
 func SetMemory(maxTokens int) ConfigOption {
 	return func(c *Config) {
@@ -280,6 +344,19 @@ func SetMemory(maxTokens int) ConfigOption {
 	}
 }
 
+// SetSummarizingMemory sets conversation memory that summarizes the oldest
+// messages with summaryModel once maxTokens is exceeded. An empty summaryModel
+// uses the conversation's model.
+func SetSummarizingMemory(maxTokens int, summaryModel string) ConfigOption {
+	return func(c *Config) {
+		c.MemoryOption = &MemoryOption{
+			MaxTokens:    maxTokens,
+			Strategy:     MemorySummarize,
+			SummaryModel: summaryModel,
+		}
+	}
+}
+
 // SetExtraHeaders sets additional HTTP headers.
 func SetExtraHeaders(headers map[string]string) ConfigOption {
 	return func(c *Config) {
@@ -38,6 +38,10 @@ type LLM interface {
 	// SetSystemPrompt updates the system prompt with caching configuration.
 	// The cacheType parameter determines how the prompt should be cached.
 	SetSystemPrompt(prompt string, cacheType CacheType)
+	// CountTokens returns the number of input tokens the prompt is sent as, counted
+	// with the provider's token counter. Returns ErrorTypeUnsupported when the
+	// underlying LLM cannot count tokens.
+	CountTokens(ctx context.Context, prompt *Prompt, opts ...llm.GenerateOption) (int, error)
 }
 
 // LlmImpl is the concrete implementation of the LLM interface.
@@ -79,7 +83,7 @@ func (l *LlmImpl) GetLogLevel() LogLevel {
 
 // SetOption sets an option for the LLM with the given key and value.
 func (l *LlmImpl) SetOption(key string, value any) {
-	l.logger.Debug("Setting option", "key", key, "value", value)
+	l.logger.Debug("Setting option", key, value)
 	l.LLM.SetOption(key, value)
 	l.logger.Debug("Option set successfully")
 }
@@ -112,6 +116,17 @@ func (l *LlmImpl) UpdateLogLevel(level LogLevel) {
 	}
 }
 
+// CountTokens returns the number of input tokens the prompt is sent as.
+func (l *LlmImpl) CountTokens(ctx context.Context, prompt *Prompt, opts ...llm.GenerateOption) (int, error) {
+	counter, ok := l.LLM.(interface {
+		CountTokens(ctx context.Context, prompt *llm.Prompt, opts ...llm.GenerateOption) (int, error)
+	})
+	if !ok {
+		return 0, llm.NewLLMError(llm.ErrorTypeUnsupported, "token counting is not supported", nil)
+	}
+	return counter.CountTokens(ctx, prompt, opts...)
+}
+
 // Generate Implement the base Generate method (if not already provided by embedded llm.LLM)
 func (l *LlmImpl) Generate(
 	ctx context.Context,
@@ -166,8 +181,24 @@ func setupAnthropicCaching(cfg *Config) {
 	}
 }
 
+// newLogger returns the configured logger, or a default one at cfg.LogLevel,
+// wrapped so that secrets are redacted from everything gollm logs.
+func newLogger(cfg *Config) logging.Logger {
+	var base logging.Logger = logging.NewLogger(cfg.LogLevel)
+	if cfg.Logger != nil {
+		base = cfg.Logger
+	}
+	return logging.NewRedactingLogger(base, llm.NewRedactor(cfg))
+}
+
 // NewLLM creates a new LLM instance with the provided configuration options
 func NewLLM(opts ...ConfigOption) (*LlmImpl, error) {
+	return NewLLMWithRegistry(providers.NewProviderRegistry(), opts...)
+}
+
+// NewLLMWithRegistry is like NewLLM but resolves the provider from registry, which
+// allows custom or fake providers registered with ProviderRegistry.Register to be used.
+func NewLLMWithRegistry(registry *providers.ProviderRegistry, opts ...ConfigOption) (*LlmImpl, error) {
 	cfg, err := LoadConfig()
 	if err != nil {
 		return nil, fmt.Errorf("failed to load config: %w", err)
@@ -184,20 +215,20 @@ func NewLLM(opts ...ConfigOption) (*LlmImpl, error) {
 		return nil, fmt.Errorf("invalid configuration: %w", err)
 	}
 
-	logger := logging.NewLogger(cfg.LogLevel)
+	logger := newLogger(cfg)
 	setupAnthropicCaching(cfg)
 
-	baseLLM, err := llm.NewLLM(cfg, logger, providers.NewProviderRegistry())
+	baseLLM, err := llm.NewLLM(cfg, logger, registry)
 	if err != nil {
 		logger.Error("Failed to create internal LLM", "error", err)
 		return nil, fmt.Errorf("failed to create internal LLM: %w", err)
 	}
 
-	provider, err := providers.NewProviderRegistry().
-		Get(cfg.Provider, cfg.APIKeys[cfg.Provider], cfg.Model, cfg.ExtraHeaders)
+	provider, err := registry.Get(cfg.Provider, cfg.APIKeys[cfg.Provider], cfg.Model, cfg.ExtraHeaders)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get provider: %w", err)
 	}
+	provider.SetLogger(logger)
 
 	llmInstance := &LlmImpl{
 		LLM:      baseLLM,
@@ -213,8 +244,41 @@ func NewLLM(opts ...ConfigOption) (*LlmImpl, error) {
 			logger.Error("Failed to create LLM with memory", "error", err)
 			return nil, fmt.Errorf("failed to create LLM with memory: %w", err)
 		}
+		compactor, err := newMemoryCompactor(cfg, logger, registry)
+		if err != nil {
+			return nil, err
+		}
+		llmWithMemory.SetMemoryCompactor(compactor)
 		llmInstance.LLM = llmWithMemory
 	}
 
 	return llmInstance, nil
 }
+
+// newMemoryCompactor returns the compactor for the configured memory strategy.
+// Summaries are written by an LLM of their own, with the summary model when
+// one is set, so that the summary instructions never become options of the
+// conversation's LLM.
+func newMemoryCompactor(
+	cfg *config.Config,
+	logger logging.Logger,
+	registry *providers.ProviderRegistry,
+) (llm.MemoryCompactor, error) {
+	switch cfg.MemoryOption.Strategy {
+	case "", config.MemorySlidingWindow:
+		return llm.SlidingWindowCompactor{}, nil
+	case config.MemorySummarize:
+		summaryCfg := *cfg
+		summaryCfg.MemoryOption = nil
+		if model := cfg.MemoryOption.SummaryModel; model != "" {
+			summaryCfg.Model = model
+		}
+		summarizer, err := llm.NewLLM(&summaryCfg, logger, registry)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create memory summary LLM: %w", err)
+		}
+		return llm.NewSummarizingCompactor(summarizer), nil
+	default:
+		return nil, fmt.Errorf("unknown memory strategy %q", cfg.MemoryOption.Strategy)
+	}
+}
@@ -0,0 +1,587 @@
+// Package gollmtest provides a scriptable fake provider for unit testing code that
+// depends on gollm.LLM or llm.LLM without network access or hand-written mocks.
+//
+// A Provider is registered in a providers.ProviderRegistry under ProviderName and
+// doubles as the HTTP transport of the LLM, so requests travel through the real
+// llm.LLMImpl request, retry and streaming code paths. Replies are served in the
+// order they were scripted, and every request is captured for later assertions.
+//
+// Example usage:
+//
+//	fake := gollmtest.NewProvider()
+//	fake.Reply("Paris").WithUsage(12, 1)
+//	fake.ReplyToolCall("get_weather", map[string]any{"city": "Paris"})
+//
+//	client := gollmtest.NewLLM(t, fake)
+//	resp, err := client.Generate(ctx, gollm.NewPrompt("What is the capital of France?"))
+//
+//	call := fake.LastCall()
+//	assert.Equal(t, "What is the capital of France?", call.Request.Messages[0].Content)
+package gollmtest
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/weave-labs/gollm"
+	"github.com/weave-labs/gollm/config"
+	"github.com/weave-labs/gollm/internal/logging"
+	"github.com/weave-labs/gollm/internal/models"
+	"github.com/weave-labs/gollm/providers"
+	"github.com/weave-labs/weave-go/weaveapi/llmx/v1"
+)
+
+const (
+	// ProviderName is the name the fake provider is registered under.
+	ProviderName = "gollmtest"
+	// DefaultModel is the model name used by NewLLM unless overridden.
+	DefaultModel = "gollmtest-model"
+	// RequestIDPrefix prefixes the request ID reported for each reply, followed
+	// by the reply's zero-based position in the script.
+	RequestIDPrefix = "gollmtest-req-"
+	// APIKey is a placeholder key that satisfies configuration validation.
+	APIKey = "gollmtest-fake-api-key-0123456789"
+
+	endpoint = "http://gollmtest.invalid/v1/generate"
+)
+
+// ErrScriptExhausted is returned when a request arrives after every scripted reply was used.
+var ErrScriptExhausted = errors.New("gollmtest: no scripted reply left")
+
+// Reply is a single scripted provider reply. Replies are created through the
+// Provider's Reply* methods and refined with the With* methods.
+type Reply struct {
+	response   *providers.Response
+	err        error
+	errorBody  string
+	tokens     []string
+	latency    time.Duration
+	statusCode int
+}
+
+// WithUsage attaches token usage to the reply. For streamed replies the usage is
+// reported on the final token.
+func (r *Reply) WithUsage(inputTokens, outputTokens int64) *Reply {
+	r.response.Usage = providers.NewUsage(inputTokens, 0, outputTokens, 0, 0)
+	return r
+}
+
+// WithFinishReason sets the reported finish reason, for example
+// providers.FinishReasonLength to simulate truncated output. By default replies
+// finish with providers.FinishReasonStop, or providers.FinishReasonToolCalls when
+// they carry tool calls.
+func (r *Reply) WithFinishReason(reason providers.FinishReason) *Reply {
+	r.response.FinishReason = reason
+	return r
+}
+
+// WithLatency delays the reply by d, or until the request context is done.
+func (r *Reply) WithLatency(d time.Duration) *Reply {
+	r.latency = d
+	return r
+}
+
+// WithToolCall adds a tool call to the reply. Arguments are JSON-encoded unless
+// they already are a json.RawMessage, []byte or string.
+func (r *Reply) WithToolCall(name string, arguments any) *Reply {
+	r.response.ToolCalls = append(r.response.ToolCalls, providers.ToolCall{
+		ID:   fmt.Sprintf("call_%d", len(r.response.ToolCalls)+1),
+		Type: "function",
+		Function: providers.FunctionCall{
+			Name:      name,
+			Arguments: encodeArguments(arguments),
+		},
+	})
+	return r
+}
+
+// Call is a captured request made to the fake provider.
+type Call struct {
+	// Request is the unified request built by the LLM, including the system
+	// prompt, messages and response schema.
+	Request *providers.Request
+	// Options holds the provider options in effect for the call, including
+	// "tools" and "tool_choice" when the prompt defines tools.
+	Options map[string]any
+	// Stream reports whether the call was made through GenerateStream.
+	Stream bool
+}
+
+// Tools returns the tools sent with the call, if any.
+func (c Call) Tools() []models.Tool {
+	tools, _ := c.Options["tools"].([]models.Tool)
+	return tools
+}
+
+// ToolChoice returns the tool choice sent with the call, if any.
+func (c Call) ToolChoice() map[string]any {
+	choice, _ := c.Options["tool_choice"].(map[string]any)
+	return choice
+}
+
+// Provider is a fake providers.Provider that serves scripted replies.
+// It is safe for concurrent use.
+type Provider struct {
+	logger       logging.Logger
+	options      map[string]any
+	extraHeaders map[string]string
+	unsupported  map[llmx.CapabilityType]bool
+	model        string
+	replies      []*Reply
+	calls        []Call
+	next         int
+	mu           sync.Mutex
+}
+
+// Compile-time interface checks.
+var (
+	_ providers.Provider = (*Provider)(nil)
+	_ http.RoundTripper  = (*Provider)(nil)
+)
+
+// NewProvider creates a fake provider with an empty script. All capabilities are
+// reported as supported until disabled with SetCapability.
+func NewProvider() *Provider {
+	return &Provider{
+		logger:       logging.NewLogger(logging.LogLevelWarn),
+		options:      make(map[string]any),
+		extraHeaders: make(map[string]string),
+		unsupported:  make(map[llmx.CapabilityType]bool),
+		model:        DefaultModel,
+	}
+}
+
+// Register adds the fake provider to registry under ProviderName.
+func (p *Provider) Register(registry *providers.ProviderRegistry) {
+	registry.Register(ProviderName, func(_, model string, extraHeaders map[string]string) providers.Provider {
+		p.mu.Lock()
+		defer p.mu.Unlock()
+		p.model = model
+		for k, v := range extraHeaders {
+			p.extraHeaders[k] = v
+		}
+		return p
+	})
+}
+
+// Reply scripts a text reply.
+func (p *Provider) Reply(text string) *Reply {
+	return p.add(&Reply{response: textResponse(text)})
+}
+
+// ReplyJSON scripts a reply whose text is v encoded as JSON, for structured output tests.
+func (p *Provider) ReplyJSON(v any) *Reply {
+	data, err := json.Marshal(v)
+	if err != nil {
+		panic(fmt.Sprintf("gollmtest: failed to encode reply: %v", err))
+	}
+	return p.Reply(string(data))
+}
+
+// ReplyToolCall scripts a reply containing a single tool call and no text.
+func (p *Provider) ReplyToolCall(name string, arguments any) *Reply {
+	r := p.add(&Reply{response: &providers.Response{Role: "assistant"}})
+	return r.WithToolCall(name, arguments)
+}
+
+// ReplyStream scripts a streamed reply made of the given tokens.
+func (p *Provider) ReplyStream(tokens ...string) *Reply {
+	return p.add(&Reply{response: textResponse(strings.Join(tokens, "")), tokens: tokens})
+}
+
+// ReplyStatus scripts an HTTP error reply with the given status code and body.
+func (p *Provider) ReplyStatus(statusCode int, body string) *Reply {
+	return p.add(&Reply{response: &providers.Response{}, statusCode: statusCode, errorBody: body})
+}
+
+// ReplyError scripts a transport-level failure, such as a connection error.
+func (p *Provider) ReplyError(err error) *Reply {
+	return p.add(&Reply{response: &providers.Response{}, err: err})
+}
+
+func (p *Provider) add(r *Reply) *Reply {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.replies = append(p.replies, r)
+	return r
+}
+
+// SetCapability marks a capability as supported or unsupported.
+func (p *Provider) SetCapability(capability llmx.CapabilityType, supported bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.unsupported[capability] = !supported
+}
+
+// Calls returns every captured call in order.
+func (p *Provider) Calls() []Call {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return append([]Call(nil), p.calls...)
+}
+
+// LastCall returns the most recent captured call, or the zero Call if none was made.
+func (p *Provider) LastCall() Call {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if len(p.calls) == 0 {
+		return Call{}
+	}
+	return p.calls[len(p.calls)-1]
+}
+
+// Remaining returns the number of scripted replies not yet served.
+func (p *Provider) Remaining() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return len(p.replies) - p.next
+}
+
+// Name returns ProviderName.
+func (p *Provider) Name() string {
+	return ProviderName
+}
+
+// Endpoint returns a placeholder URL that is only ever served by RoundTrip.
+func (p *Provider) Endpoint() string {
+	return endpoint
+}
+
+// Headers returns the request headers, including any extra headers.
+func (p *Provider) Headers() map[string]string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	headers := map[string]string{"Content-Type": "application/json"}
+	for k, v := range p.extraHeaders {
+		headers[k] = v
+	}
+	return headers
+}
+
+// SetExtraHeaders configures additional HTTP headers.
+func (p *Provider) SetExtraHeaders(extraHeaders map[string]string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for k, v := range extraHeaders {
+		p.extraHeaders[k] = v
+	}
+}
+
+// SetDefaultOptions records the generation defaults from cfg as options.
+func (p *Provider) SetDefaultOptions(cfg *config.Config) {
+	p.SetOption("temperature", cfg.Temperature)
+	p.SetOption("max_tokens", cfg.MaxTokens)
+	if cfg.Seed != nil {
+		p.SetOption("seed", *cfg.Seed)
+	}
+}
+
+// SetOption sets a provider option that is included in captured calls.
+func (p *Provider) SetOption(key string, value any) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.options[key] = value
+}
+
+// SetLogger configures the logger for the fake provider.
+func (p *Provider) SetLogger(logger logging.Logger) {
+	p.logger = logger
+}
+
+// HasCapability reports every capability as supported unless disabled with SetCapability.
+func (p *Provider) HasCapability(capability llmx.CapabilityType, _ string) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return !p.unsupported[capability]
+}
+
+// PrepareRequest captures the request and returns a placeholder body.
+func (p *Provider) PrepareRequest(req *providers.Request, options map[string]any) ([]byte, error) {
+	return p.capture(req, options, false), nil
+}
+
+// PrepareStreamRequest captures the streaming request and returns a placeholder body.
+func (p *Provider) PrepareStreamRequest(req *providers.Request, options map[string]any) ([]byte, error) {
+	return p.capture(req, options, true), nil
+}
+
+func (p *Provider) capture(req *providers.Request, options map[string]any, stream bool) []byte {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	merged := make(map[string]any, len(p.options)+len(options))
+	for k, v := range p.options {
+		merged[k] = v
+	}
+	for k, v := range options {
+		merged[k] = v
+	}
+	if req.Model == "" {
+		req.Model = p.model
+	}
+	p.calls = append(p.calls, Call{Request: req, Options: merged, Stream: stream})
+
+	body, _ := json.Marshal(requestBody{Call: len(p.calls) - 1, Stream: stream})
+	return body
+}
+
+// requestBody is the placeholder body sent from PrepareRequest to RoundTrip.
+type requestBody struct {
+	Call   int  `json:"call"`
+	Stream bool `json:"stream"`
+}
+
+// RoundTrip serves the next scripted reply. The fake provider is installed as the
+// LLM's HTTP transport, so no network connection is ever made.
+func (p *Provider) RoundTrip(req *http.Request) (*http.Response, error) {
+	var body requestBody
+	if req.Body != nil {
+		_ = json.NewDecoder(req.Body).Decode(&body)
+		_ = req.Body.Close()
+	}
+
+	p.mu.Lock()
+	if p.next >= len(p.replies) {
+		p.mu.Unlock()
+		return nil, ErrScriptExhausted
+	}
+	index := p.next
+	reply := p.replies[index]
+	p.next++
+	p.mu.Unlock()
+
+	if reply.latency > 0 {
+		timer := time.NewTimer(reply.latency)
+		select {
+		case <-req.Context().Done():
+			timer.Stop()
+			return nil, req.Context().Err()
+		case <-timer.C:
+		}
+	}
+
+	if reply.err != nil {
+		return nil, reply.err
+	}
+
+	if reply.statusCode != 0 && reply.statusCode != http.StatusOK {
+		return newHTTPResponse(req, index, reply.statusCode, "application/json", reply.errorBody), nil
+	}
+
+	if body.Stream {
+		return newHTTPResponse(req, index, http.StatusOK, "text/event-stream", streamBody(index, reply)), nil
+	}
+	return newHTTPResponse(req, index, http.StatusOK, "application/json", fmt.Sprintf(`{"reply":%d}`, index)), nil
+}
+
+// chunk is the wire format exchanged between RoundTrip and the parse methods.
+type chunk struct {
+	Reply int  `json:"reply"`
+	Token *int `json:"token,omitempty"`
+	Done  bool `json:"done,omitempty"`
+}
+
+func streamBody(index int, reply *Reply) string {
+	tokens := reply.tokens
+	if len(tokens) == 0 {
+		tokens = []string{reply.response.AsText()}
+	}
+
+	var b strings.Builder
+	for i := range tokens {
+		data, _ := json.Marshal(chunk{Reply: index, Token: &i})
+		b.WriteString("data: ")
+		b.Write(data)
+		b.WriteString("\n\n")
+	}
+	data, _ := json.Marshal(chunk{Reply: index, Done: true})
+	b.WriteString("data: ")
+	b.Write(data)
+	b.WriteString("\n\n")
+	return b.String()
+}
+
+// ParseResponse returns the scripted response referenced by body.
+func (p *Provider) ParseResponse(body []byte) (*providers.Response, error) {
+	var c chunk
+	if err := json.Unmarshal(body, &c); err != nil {
+		return nil, fmt.Errorf("gollmtest: unexpected response body: %w", err)
+	}
+	reply, err := p.reply(c.Reply)
+	if err != nil {
+		return nil, err
+	}
+	resp := cloneResponse(reply.response)
+	resp.FinishReason = finishReason(reply.response)
+	resp.Metadata = p.responseMetadata(c.Reply, resp.FinishReason)
+	return resp, nil
+}
+
+// ParseStreamResponse returns the scripted token referenced by chunk, and io.EOF
+// once the stream is complete. Usage is reported on the final token.
+func (p *Provider) ParseStreamResponse(data []byte) (*providers.Response, error) {
+	var c chunk
+	if err := json.Unmarshal(data, &c); err != nil {
+		return nil, fmt.Errorf("gollmtest: unexpected stream chunk: %w", err)
+	}
+	if c.Done {
+		return nil, io.EOF
+	}
+	reply, err := p.reply(c.Reply)
+	if err != nil {
+		return nil, err
+	}
+
+	tokens := reply.tokens
+	if len(tokens) == 0 {
+		tokens = []string{reply.response.AsText()}
+	}
+	if c.Token == nil || *c.Token >= len(tokens) {
+		return nil, errors.New("skip resp")
+	}
+
+	resp := &providers.Response{Role: "assistant", Content: providers.Text{Value: tokens[*c.Token]}}
+	if *c.Token == len(tokens)-1 {
+		resp.Usage = reply.response.Usage
+		resp.ToolCalls = reply.response.ToolCalls
+		resp.FinishReason = finishReason(reply.response)
+		resp.Metadata = p.responseMetadata(c.Reply, resp.FinishReason)
+	}
+	return resp, nil
+}
+
+// finishReason returns the scripted finish reason of resp, or the default one.
+func finishReason(resp *providers.Response) providers.FinishReason {
+	switch {
+	case resp.FinishReason != "":
+		return resp.FinishReason
+	case len(resp.ToolCalls) > 0:
+		return providers.FinishReasonToolCalls
+	default:
+		return providers.FinishReasonStop
+	}
+}
+
+// responseMetadata returns the metadata reported for the reply at index.
+func (p *Provider) responseMetadata(index int, reason providers.FinishReason) *providers.ResponseMetadata {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return &providers.ResponseMetadata{
+		ID:              fmt.Sprintf("gollmtest-%d", index),
+		Model:           p.model,
+		RawFinishReason: string(reason),
+	}
+}
+
+func (p *Provider) reply(index int) (*Reply, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if index < 0 || index >= len(p.replies) {
+		return nil, fmt.Errorf("gollmtest: unknown reply %d", index)
+	}
+	return p.replies[index], nil
+}
+
+// NewLLM returns a gollm.LLM backed by p. The provider is registered under
+// ProviderName, retries are disabled and logs are written to t.Log unless
+// overridden by opts.
+func NewLLM(t testing.TB, p *Provider, opts ...gollm.ConfigOption) gollm.LLM {
+	t.Helper()
+
+	registry := providers.NewProviderRegistry()
+	p.Register(registry)
+
+	base := []gollm.ConfigOption{
+		gollm.SetProvider(ProviderName),
+		gollm.SetModel(DefaultModel),
+		gollm.SetAPIKey(APIKey),
+		gollm.SetMaxRetries(0),
+		gollm.SetRetryDelay(0),
+		gollm.SetHTTPTransport(p),
+		gollm.SetLogger(&testLogger{t: t, level: logging.LogLevelDebug}),
+	}
+
+	client, err := gollm.NewLLMWithRegistry(registry, append(base, opts...)...)
+	if err != nil {
+		t.Fatalf("gollmtest: failed to create LLM: %v", err)
+	}
+	return client
+}
+
+func textResponse(text string) *providers.Response {
+	return &providers.Response{Role: "assistant", Content: providers.Text{Value: text}}
+}
+
+func cloneResponse(resp *providers.Response) *providers.Response {
+	clone := *resp
+	clone.ToolCalls = append([]providers.ToolCall(nil), resp.ToolCalls...)
+	if resp.Usage != nil {
+		usage := *resp.Usage
+		clone.Usage = &usage
+	}
+	return &clone
+}
+
+// newHTTPResponse builds the HTTP response for the reply at index. The request ID
+// header is RequestIDPrefix followed by index.
+func newHTTPResponse(req *http.Request, index, statusCode int, contentType, body string) *http.Response {
+	return &http.Response{
+		Status:     fmt.Sprintf("%d %s", statusCode, http.StatusText(statusCode)),
+		StatusCode: statusCode,
+		Proto:      "HTTP/1.1",
+		ProtoMajor: 1,
+		ProtoMinor: 1,
+		Header: http.Header{
+			"Content-Type": []string{contentType},
+			"X-Request-Id": []string{fmt.Sprintf("%s%d", RequestIDPrefix, index)},
+		},
+		Body:          io.NopCloser(strings.NewReader(body)),
+		ContentLength: int64(len(body)),
+		Request:       req,
+	}
+}
+
+func encodeArguments(arguments any) json.RawMessage {
+	switch v := arguments.(type) {
+	case json.RawMessage:
+		return v
+	case []byte:
+		return v
+	case string:
+		return json.RawMessage(v)
+	default:
+		data, err := json.Marshal(v)
+		if err != nil {
+			panic(fmt.Sprintf("gollmtest: failed to encode tool arguments: %v", err))
+		}
+		return data
+	}
+}
+
+// testLogger writes log output through testing.TB so it only shows for failing
+// tests or with -v.
+type testLogger struct {
+	t     testing.TB
+	level logging.LogLevel
+}
+
+func (l *testLogger) log(level logging.LogLevel, name, msg string, args []any) {
+	if level < l.level {
+		return
+	}
+	l.t.Helper()
+	l.t.Logf("%s %s %v", name, msg, args)
+}
+
+func (l *testLogger) Debug(msg string, args ...any)   { l.log(logging.LogLevelDebug, "DEBUG", msg, args) }
+func (l *testLogger) Info(msg string, args ...any)    { l.log(logging.LogLevelInfo, "INFO", msg, args) }
+func (l *testLogger) Warn(msg string, args ...any)    { l.log(logging.LogLevelWarn, "WARN", msg, args) }
+func (l *testLogger) Error(msg string, args ...any)   { l.log(logging.LogLevelError, "ERROR", msg, args) }
+func (l *testLogger) SetLevel(level logging.LogLevel) { l.level = level }
@@ -0,0 +1,177 @@
+package gollmtest_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/weave-labs/gollm"
+	"github.com/weave-labs/gollm/gollmtest"
+	"github.com/weave-labs/gollm/internal/models"
+	"github.com/weave-labs/gollm/llm"
+)
+
+type capital struct {
+	City string `json:"city"`
+}
+
+func TestGenerateCapturesRequest(t *testing.T) {
+	fake := gollmtest.NewProvider()
+	fake.ReplyJSON(capital{City: "Paris"}).WithUsage(12, 1)
+	fake.ReplyToolCall("get_weather", map[string]any{"city": "Paris"})
+
+	client := gollmtest.NewLLM(t, fake)
+	ctx := context.Background()
+
+	prompt := gollm.NewPrompt("What is the capital of France?",
+		gollm.WithSystemPrompt("Answer briefly.", gollm.CacheTypeEphemeral),
+		gollm.WithTools([]models.Tool{{Type: "function"}}),
+		gollm.WithToolChoice("auto"),
+	)
+	resp, err := client.Generate(ctx, prompt, llm.WithStructuredResponse[capital]())
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"city":"Paris"}`, resp.AsText())
+	require.NotNil(t, resp.Usage)
+	assert.Equal(t, int64(12), resp.Usage.InputTokens)
+
+	call := fake.LastCall()
+	require.NotNil(t, call.Request)
+	assert.Equal(t, "Answer briefly.", call.Request.SystemPrompt)
+	assert.Equal(t, "What is the capital of France?", call.Request.Messages[0].Content)
+	assert.NotNil(t, call.Request.ResponseSchema)
+	assert.Len(t, call.Tools(), 1)
+	assert.Equal(t, map[string]any{"type": "auto"}, call.ToolChoice())
+	assert.False(t, call.Stream)
+
+	resp, err = client.Generate(ctx, gollm.NewPrompt("Weather?"))
+	require.NoError(t, err)
+	require.Len(t, resp.ToolCalls, 1)
+	assert.Equal(t, "get_weather", resp.ToolCalls[0].Function.Name)
+	assert.JSONEq(t, `{"city":"Paris"}`, string(resp.ToolCalls[0].Function.Arguments))
+
+	assert.Len(t, fake.Calls(), 2)
+	assert.Zero(t, fake.Remaining())
+}
+
+func TestGenerateStream(t *testing.T) {
+	fake := gollmtest.NewProvider()
+	fake.ReplyStream("Hel", "lo", "!").WithUsage(3, 3)
+
+	client := gollmtest.NewLLM(t, fake)
+	stream, err := client.GenerateStream(context.Background(), gollm.NewPrompt("Say hello"))
+	require.NoError(t, err)
+	defer stream.Close()
+
+	var text strings.Builder
+	var outputTokens int64
+	for {
+		token, err := stream.Next(context.Background())
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		require.NoError(t, err)
+		text.WriteString(token.Text)
+		outputTokens += token.OutputTokens
+	}
+
+	assert.Equal(t, "Hello!", text.String())
+	assert.Equal(t, int64(3), outputTokens)
+	assert.True(t, fake.LastCall().Stream)
+}
+
+func TestGenerateStructuredStream(t *testing.T) {
+	fake := gollmtest.NewProvider()
+	fake.ReplyStream(`{"ci`, `ty":"Pa`, `ris"}`)
+
+	client := gollmtest.NewLLM(t, fake)
+	var cities []string
+	var final *capital
+	for snapshot, err := range gollm.GenerateStructuredStream[capital](context.Background(), client,
+		gollm.NewPrompt("What is the capital of France?")) {
+		require.NoError(t, err)
+		cities = append(cities, snapshot.Value.City)
+		if snapshot.Final {
+			final = snapshot.Value
+		}
+	}
+
+	assert.Equal(t, []string{"", "Pa", "Paris"}, cities)
+	require.NotNil(t, final)
+	assert.Equal(t, "Paris", final.City)
+	assert.NotNil(t, fake.LastCall().Request.ResponseSchema, "the schema of T is sent with the request")
+}
+
+func TestResponseMetadata(t *testing.T) {
+	fake := gollmtest.NewProvider()
+	fake.Reply("Once upon a").WithFinishReason(gollm.FinishReasonLength)
+	fake.ReplyStream("Hel", "lo")
+
+	client := gollmtest.NewLLM(t, fake, gollm.SetIncludeRawResponse(true))
+	ctx := context.Background()
+
+	resp, err := client.Generate(ctx, gollm.NewPrompt("Tell me a story"))
+	require.NoError(t, err)
+	assert.Equal(t, gollm.FinishReasonLength, resp.FinishReason)
+	require.NotNil(t, resp.Metadata)
+	assert.Equal(t, "gollmtest-0", resp.Metadata.ID)
+	assert.Equal(t, gollmtest.DefaultModel, resp.Metadata.Model)
+	assert.Equal(t, gollmtest.RequestIDPrefix+"0", resp.Metadata.RequestID)
+	assert.Positive(t, resp.Metadata.Latency)
+	assert.JSONEq(t, `{"reply":0}`, string(resp.Metadata.RawBody))
+
+	stream, err := client.GenerateStream(ctx, gollm.NewPrompt("Say hello"))
+	require.NoError(t, err)
+	resp, err = gollm.CollectStream(ctx, stream, nil)
+	require.NoError(t, err)
+	assert.Equal(t, gollm.FinishReasonStop, resp.FinishReason)
+	require.NotNil(t, resp.Metadata)
+	assert.Equal(t, gollmtest.RequestIDPrefix+"1", resp.Metadata.RequestID)
+	assert.Positive(t, resp.Metadata.Latency)
+}
+
+func TestScriptedFailures(t *testing.T) {
+	fake := gollmtest.NewProvider()
+	fake.ReplyStatus(http.StatusTooManyRequests, `{"error":"rate limited"}`)
+	fake.ReplyError(errors.New("connection reset"))
+	fake.Reply("recovered")
+	fake.Reply("too slow").WithLatency(time.Second)
+
+	client := gollmtest.NewLLM(t, fake, gollm.SetMaxRetries(2))
+	resp, err := client.Generate(context.Background(), gollm.NewPrompt("hi"))
+	require.NoError(t, err)
+	assert.Equal(t, "recovered", resp.AsText())
+	assert.Len(t, fake.Calls(), 3, "each retry is captured")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	_, err = client.Generate(ctx, gollm.NewPrompt("hi"))
+	require.Error(t, err)
+
+	_, err = client.Generate(context.Background(), gollm.NewPrompt("hi"))
+	require.Error(t, err, "script is exhausted")
+}
+
+func TestProviderOption(t *testing.T) {
+	fake := gollmtest.NewProvider()
+	fake.Reply("first")
+	fake.Reply("second")
+
+	client := gollmtest.NewLLM(t, fake)
+	client.SetOption("temperature", 0.2)
+	ctx := context.Background()
+
+	_, err := client.Generate(ctx, gollm.NewPrompt("Hi"), gollm.WithProviderOption("temperature", 0.9))
+	require.NoError(t, err)
+	assert.Equal(t, 0.9, fake.LastCall().Options["temperature"])
+
+	_, err = client.Generate(ctx, gollm.NewPrompt("Hi"))
+	require.NoError(t, err)
+	assert.Equal(t, 0.2, fake.LastCall().Options["temperature"])
+}
@@ -0,0 +1,250 @@
+// Package logging provides logging utilities for the GoLLM library.
+package logging
+
+import (
+	"fmt"
+	"log/slog"
+	"net/http"
+	"regexp"
+	"strings"
+)
+
+// RedactedValue is the placeholder written in place of masked values.
+const RedactedValue = "[REDACTED]"
+
+// DefaultRedactPatterns lists the case-insensitive key fragments whose values are
+// always masked, regardless of any patterns supplied by the caller.
+var DefaultRedactPatterns = []string{ //nolint:gochecknoglobals // read-only defaults
+	"authorization",
+	"api-key",
+	"api_key",
+	"apikey",
+	"access_token",
+	"refresh_token",
+	"auth_token",
+	"secret",
+	"password",
+	"cookie",
+}
+
+// secretValuePatterns match well-known credential shapes embedded in free text,
+// such as error bodies echoed back by a provider.
+var secretValuePatterns = []*regexp.Regexp{ //nolint:gochecknoglobals // compiled once
+	regexp.MustCompile(`(?i)(bearer\s+)[A-Za-z0-9._~+/=-]+`),
+	regexp.MustCompile(`\b(sk|pk|rk)-[A-Za-z0-9_-]{8,}`),
+	regexp.MustCompile(`\bAIza[0-9A-Za-z_-]{20,}`),
+	regexp.MustCompile(`\bgsk_[A-Za-z0-9]{16,}`),
+}
+
+// Redactor masks sensitive keys and values before they are logged.
+// Keys are matched case-insensitively by substring against the configured patterns,
+// and string values are scrubbed of known secrets and credential-like tokens.
+type Redactor struct {
+	keyPatterns []string
+	secrets     []string
+}
+
+// NewRedactor creates a Redactor that masks the default patterns plus any extra
+// key patterns supplied. Patterns are matched case-insensitively as substrings.
+func NewRedactor(patterns ...string) *Redactor {
+	r := &Redactor{}
+	for _, p := range append(append([]string{}, DefaultRedactPatterns...), patterns...) {
+		if p = strings.ToLower(strings.TrimSpace(p)); p != "" {
+			r.keyPatterns = append(r.keyPatterns, p)
+		}
+	}
+	return r
+}
+
+// AddSecrets registers literal values (such as configured API keys) that must never
+// appear in log output. Empty and very short values are ignored to avoid masking noise.
+func (r *Redactor) AddSecrets(secrets ...string) {
+	const minSecretLength = 6
+	for _, s := range secrets {
+		if len(s) >= minSecretLength {
+			r.secrets = append(r.secrets, s)
+		}
+	}
+}
+
+// IsSensitiveKey reports whether values stored under key should be masked.
+func (r *Redactor) IsSensitiveKey(key string) bool {
+	key = strings.ToLower(key)
+	for _, p := range r.keyPatterns {
+		if strings.Contains(key, p) {
+			return true
+		}
+	}
+	return false
+}
+
+// RedactString scrubs registered secrets and credential-like tokens from s.
+func (r *Redactor) RedactString(s string) string {
+	for _, secret := range r.secrets {
+		s = strings.ReplaceAll(s, secret, RedactedValue)
+	}
+	for _, re := range secretValuePatterns {
+		s = re.ReplaceAllStringFunc(s, func(match string) string {
+			if sub := re.FindStringSubmatch(match); len(sub) > 1 && strings.HasPrefix(strings.ToLower(sub[1]), "bearer") {
+				return sub[1] + RedactedValue
+			}
+			return RedactedValue
+		})
+	}
+	return s
+}
+
+// RedactValue returns a copy of v with sensitive content masked. Maps and headers
+// have their sensitive keys masked; strings, byte slices, errors and other values
+// are rendered and scrubbed.
+func (r *Redactor) RedactValue(v any) any {
+	switch val := v.(type) {
+	case nil:
+		return nil
+	case string:
+		return r.RedactString(val)
+	case []byte:
+		return r.RedactString(string(val))
+	case error:
+		return r.RedactString(val.Error())
+	case map[string]string:
+		out := make(map[string]string, len(val))
+		for k, s := range val {
+			if r.IsSensitiveKey(k) {
+				out[k] = RedactedValue
+				continue
+			}
+			out[k] = r.RedactString(s)
+		}
+		return out
+	case http.Header:
+		out := make(http.Header, len(val))
+		for k, vs := range val {
+			if r.IsSensitiveKey(k) {
+				out[k] = []string{RedactedValue}
+				continue
+			}
+			scrubbed := make([]string, len(vs))
+			for i, s := range vs {
+				scrubbed[i] = r.RedactString(s)
+			}
+			out[k] = scrubbed
+		}
+		return out
+	case map[string]any:
+		out := make(map[string]any, len(val))
+		for k, item := range val {
+			if r.IsSensitiveKey(k) {
+				out[k] = RedactedValue
+				continue
+			}
+			out[k] = r.RedactValue(item)
+		}
+		return out
+	case slog.Attr:
+		return r.redactAttr(val)
+	case bool, int, int32, int64, uint, uint32, uint64, float32, float64, LogLevel:
+		return val
+	case fmt.Stringer:
+		return r.RedactString(val.String())
+	default:
+		return r.RedactString(fmt.Sprintf("%+v", val))
+	}
+}
+
+func (r *Redactor) redactAttr(attr slog.Attr) slog.Attr {
+	if r.IsSensitiveKey(attr.Key) {
+		return slog.String(attr.Key, RedactedValue)
+	}
+	if attr.Value.Kind() == slog.KindGroup {
+		group := attr.Value.Group()
+		attrs := make([]any, len(group))
+		for i, a := range group {
+			attrs[i] = r.redactAttr(a)
+		}
+		return slog.Group(attr.Key, attrs...)
+	}
+	return slog.Any(attr.Key, r.RedactValue(attr.Value.Any()))
+}
+
+// RedactArgs masks the values of a key/value argument list as accepted by Logger.
+// A leading printf-style format string is rendered first so that its arguments
+// are scrubbed as a single message.
+func (r *Redactor) RedactArgs(args []any) []any {
+	if len(args) == 0 {
+		return args
+	}
+
+	if format, ok := args[0].(string); ok && strings.HasPrefix(format, "%") && len(args) > 1 {
+		return []any{slog.String("message", r.RedactString(fmt.Sprintf(format, args[1:]...)))}
+	}
+
+	out := make([]any, 0, len(args))
+	for i := 0; i < len(args); i++ {
+		if attr, ok := args[i].(slog.Attr); ok {
+			out = append(out, r.redactAttr(attr))
+			continue
+		}
+		key, ok := args[i].(string)
+		if !ok || i+1 >= len(args) {
+			out = append(out, r.RedactValue(args[i]))
+			continue
+		}
+		value := args[i+1]
+		i++
+		if r.IsSensitiveKey(key) {
+			out = append(out, key, RedactedValue)
+			continue
+		}
+		out = append(out, key, r.RedactValue(value))
+	}
+	return out
+}
+
+// RedactingLogger wraps a Logger and masks sensitive data in every message and argument.
+type RedactingLogger struct {
+	next     Logger
+	redactor *Redactor
+}
+
+// NewRedactingLogger wraps next so that everything it logs passes through redactor.
+// If next is already a RedactingLogger it is unwrapped first to avoid double work.
+func NewRedactingLogger(next Logger, redactor *Redactor) *RedactingLogger {
+	if existing, ok := next.(*RedactingLogger); ok {
+		next = existing.next
+	}
+	if redactor == nil {
+		redactor = NewRedactor()
+	}
+	return &RedactingLogger{next: next, redactor: redactor}
+}
+
+// Unwrap returns the underlying logger.
+func (l *RedactingLogger) Unwrap() Logger {
+	return l.next
+}
+
+// Debug logs a redacted debug message
+func (l *RedactingLogger) Debug(msg string, args ...any) {
+	l.next.Debug(l.redactor.RedactString(msg), l.redactor.RedactArgs(args)...)
+}
+
+// Info logs a redacted info message
+func (l *RedactingLogger) Info(msg string, args ...any) {
+	l.next.Info(l.redactor.RedactString(msg), l.redactor.RedactArgs(args)...)
+}
+
+// Warn logs a redacted warning message
+func (l *RedactingLogger) Warn(msg string, args ...any) {
+	l.next.Warn(l.redactor.RedactString(msg), l.redactor.RedactArgs(args)...)
+}
+
+// Error logs a redacted error message
+func (l *RedactingLogger) Error(msg string, args ...any) {
+	l.next.Error(l.redactor.RedactString(msg), l.redactor.RedactArgs(args)...)
+}
+
+// SetLevel sets the logging level on the underlying logger
+func (l *RedactingLogger) SetLevel(level LogLevel) {
+	l.next.SetLevel(level)
+}
@@ -0,0 +1,58 @@
+// Package logging provides logging utilities for the GoLLM library.
+package logging
+
+import (
+	"context"
+	"log/slog"
+	"sync/atomic"
+)
+
+// SlogLogger adapts an application-provided *slog.Logger to the Logger interface.
+// The handler's own level still applies; SetLevel adds a gollm-side threshold on top.
+type SlogLogger struct {
+	logger *slog.Logger
+	level  atomic.Int64
+}
+
+// NewSlogLogger wraps logger so it can be injected into gollm. A nil logger
+// falls back to slog.Default().
+func NewSlogLogger(logger *slog.Logger, level LogLevel) *SlogLogger {
+	if logger == nil {
+		logger = slog.Default()
+	}
+	l := &SlogLogger{logger: logger}
+	l.level.Store(int64(level))
+	return l
+}
+
+func (l *SlogLogger) log(level LogLevel, msg string, args []any) {
+	if LogLevel(l.level.Load()) > level {
+		return
+	}
+	l.logger.Log(context.Background(), toSlogLevel(level), msg, parseArgs(args)...)
+}
+
+// Debug logs a debug message
+func (l *SlogLogger) Debug(msg string, args ...any) {
+	l.log(LogLevelDebug, msg, args)
+}
+
+// Info logs an info message
+func (l *SlogLogger) Info(msg string, args ...any) {
+	l.log(LogLevelInfo, msg, args)
+}
+
+// Warn logs a warning message
+func (l *SlogLogger) Warn(msg string, args ...any) {
+	l.log(LogLevelWarn, msg, args)
+}
+
+// Error logs an error message
+func (l *SlogLogger) Error(msg string, args ...any) {
+	l.log(LogLevelError, msg, args)
+}
+
+// SetLevel sets the logging level
+func (l *SlogLogger) SetLevel(level LogLevel) {
+	l.level.Store(int64(level))
+}
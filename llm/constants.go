@@ -5,11 +5,14 @@ import "time"
 
 // Buffer and streaming constants
 const (
-	DefaultStreamBufferSize     = 4096 // Default buffer size for streaming responses
-	MaxRetryAttempts            = 63   // Maximum retry attempts for exponential backoff
-	DefaultOllamaTimeoutSeconds = 5    // Timeout for Ollama endpoint validation
-	MinAPIKeyLength             = 20   // Minimum API key length for validation
-	MaxValidationSplitParts     = 2    // Maximum parts when splitting validation rules
+	DefaultStreamBufferSize     = 4096    // Default buffer size for streaming responses
+	MaxSSELineSize              = 4 << 20 // Maximum length of a single server-sent event line
+	MaxRetryAttempts            = 63      // Maximum retry attempts for exponential backoff
+	DefaultOllamaTimeoutSeconds = 5       // Timeout for Ollama endpoint validation
+	MinAPIKeyLength             = 20      // Minimum API key length for validation
+	MaxValidationSplitParts     = 2       // Maximum parts when splitting validation rules
+	MaxLoggedErrorBodyLength    = 512     // Maximum bytes of an API error body logged above debug level
+	DefaultRepairAttempts       = 2       // Re-prompts GenerateTyped makes after an invalid structured response
 )
 
 // Timeout durations
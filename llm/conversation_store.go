@@ -0,0 +1,262 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrConversationNotFound is returned by ConversationStore.Load for unknown
+// conversation IDs.
+var ErrConversationNotFound = errors.New("conversation not found")
+
+// Conversation is the stored history of one conversation.
+type Conversation struct {
+	UpdatedAt time.Time       `json:"updated_at"`
+	ID        string          `json:"id"`
+	Messages  []MemoryMessage `json:"messages"`
+}
+
+// ConversationStore persists conversations by ID. Implementations must be safe
+// for concurrent use; SessionManager serializes the updates of each conversation.
+type ConversationStore interface {
+	// Load returns the conversation with id, or ErrConversationNotFound.
+	Load(ctx context.Context, id string) (*Conversation, error)
+	// Save replaces the stored conversation with the same ID. A conversation
+	// is either saved completely or not at all.
+	Save(ctx context.Context, conversation *Conversation) error
+	// Delete removes a conversation. Deleting an unknown ID is not an error.
+	Delete(ctx context.Context, id string) error
+	// DeleteBefore removes the conversations last updated before cutoff and
+	// returns how many were removed.
+	DeleteBefore(ctx context.Context, cutoff time.Time) (int, error)
+}
+
+// InMemoryConversationStore keeps conversations in process memory. They are
+// lost when the process exits.
+type InMemoryConversationStore struct {
+	conversations map[string]*Conversation
+	mu            sync.RWMutex
+}
+
+// NewInMemoryConversationStore creates an empty in-memory store.
+func NewInMemoryConversationStore() *InMemoryConversationStore {
+	return &InMemoryConversationStore{conversations: make(map[string]*Conversation)}
+}
+
+// Load implements ConversationStore.
+func (s *InMemoryConversationStore) Load(_ context.Context, id string) (*Conversation, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	conversation, ok := s.conversations[id]
+	if !ok {
+		return nil, fmt.Errorf("%w: %q", ErrConversationNotFound, id)
+	}
+	return conversation.clone(), nil
+}
+
+// Save implements ConversationStore.
+func (s *InMemoryConversationStore) Save(_ context.Context, conversation *Conversation) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.conversations[conversation.ID] = conversation.clone()
+	return nil
+}
+
+// Delete implements ConversationStore.
+func (s *InMemoryConversationStore) Delete(_ context.Context, id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.conversations, id)
+	return nil
+}
+
+// DeleteBefore implements ConversationStore.
+func (s *InMemoryConversationStore) DeleteBefore(_ context.Context, cutoff time.Time) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	deleted := 0
+	for id, conversation := range s.conversations {
+		if conversation.UpdatedAt.Before(cutoff) {
+			delete(s.conversations, id)
+			deleted++
+		}
+	}
+	return deleted, nil
+}
+
+// FileConversationStore keeps each conversation as a JSON file in a directory.
+// Files are replaced atomically, so a crash never leaves a partly written
+// conversation behind.
+type FileConversationStore struct {
+	dir string
+	// mu orders the renames of Save against the checks and removals of
+	// DeleteBefore, so that a conversation saved meanwhile is not removed.
+	mu sync.Mutex
+}
+
+// NewFileConversationStore creates a store in dir, creating the directory if
+// it does not exist.
+func NewFileConversationStore(dir string) (*FileConversationStore, error) {
+	if err := os.MkdirAll(dir, 0o750); err != nil {
+		return nil, fmt.Errorf("failed to create conversation directory: %w", err)
+	}
+	return &FileConversationStore{dir: dir}, nil
+}
+
+// Load implements ConversationStore.
+func (s *FileConversationStore) Load(_ context.Context, id string) (*Conversation, error) {
+	path, err := s.path(id)
+	if err != nil {
+		return nil, err
+	}
+	conversation, err := readConversation(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, fmt.Errorf("%w: %q", ErrConversationNotFound, id)
+	}
+	return conversation, err
+}
+
+// Save implements ConversationStore by writing a temporary file and renaming
+// it over the conversation's file.
+func (s *FileConversationStore) Save(_ context.Context, conversation *Conversation) error {
+	path, err := s.path(conversation.ID)
+	if err != nil {
+		return err
+	}
+	data, err := json.Marshal(conversation)
+	if err != nil {
+		return fmt.Errorf("failed to encode conversation %q: %w", conversation.ID, err)
+	}
+
+	tmp, err := os.CreateTemp(s.dir, ".conversation-*")
+	if err != nil {
+		return fmt.Errorf("failed to save conversation %q: %w", conversation.ID, err)
+	}
+	defer os.Remove(tmp.Name()) //nolint:errcheck // the file is gone after a successful rename
+
+	if _, err := tmp.Write(data); err != nil {
+		_ = tmp.Close()
+		return fmt.Errorf("failed to save conversation %q: %w", conversation.ID, err)
+	}
+	if err := tmp.Sync(); err != nil {
+		_ = tmp.Close()
+		return fmt.Errorf("failed to save conversation %q: %w", conversation.ID, err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to save conversation %q: %w", conversation.ID, err)
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("failed to save conversation %q: %w", conversation.ID, err)
+	}
+	return nil
+}
+
+// Delete implements ConversationStore.
+func (s *FileConversationStore) Delete(_ context.Context, id string) error {
+	path, err := s.path(id)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("failed to delete conversation %q: %w", id, err)
+	}
+	return nil
+}
+
+// DeleteBefore implements ConversationStore.
+func (s *FileConversationStore) DeleteBefore(ctx context.Context, cutoff time.Time) (int, error) {
+	entries, err := os.ReadDir(s.dir)
+	if err != nil {
+		return 0, fmt.Errorf("failed to list conversations: %w", err)
+	}
+
+	deleted := 0
+	for _, entry := range entries {
+		if err := ctx.Err(); err != nil {
+			return deleted, err
+		}
+		name := entry.Name()
+		if entry.IsDir() || strings.HasPrefix(name, ".") || filepath.Ext(name) != ".json" {
+			continue
+		}
+		removed, err := s.removeBefore(filepath.Join(s.dir, name), cutoff)
+		if err != nil {
+			return deleted, err
+		}
+		if removed {
+			deleted++
+		}
+	}
+	return deleted, nil
+}
+
+// removeBefore removes the conversation file at path if it was last updated
+// before cutoff. The file is read and removed without a Save in between.
+func (s *FileConversationStore) removeBefore(path string, cutoff time.Time) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	conversation, err := readConversation(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	if !conversation.UpdatedAt.Before(cutoff) {
+		return false, nil
+	}
+	if err := os.Remove(path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return false, fmt.Errorf("failed to delete conversation %q: %w", conversation.ID, err)
+	}
+	return true, nil
+}
+
+// path returns the file of a conversation. IDs are escaped, so any non-empty
+// ID maps to a file inside the store's directory.
+func (s *FileConversationStore) path(id string) (string, error) {
+	if id == "" {
+		return "", errors.New("conversation ID is empty")
+	}
+	name := url.PathEscape(id)
+	if strings.HasPrefix(name, ".") {
+		// Names starting with a dot are temporary files.
+		name = "%2E" + name[1:]
+	}
+	return filepath.Join(s.dir, name+".json"), nil
+}
+
+func readConversation(path string) (*Conversation, error) {
+	data, err := os.ReadFile(path) //nolint:gosec // path is built from an escaped ID inside the store
+	if err != nil {
+		return nil, err
+	}
+	var conversation Conversation
+	if err := json.Unmarshal(data, &conversation); err != nil {
+		return nil, fmt.Errorf("failed to decode conversation file %s: %w", path, err)
+	}
+	return &conversation, nil
+}
+
+// clone returns a copy that shares no slices with c.
+func (c *Conversation) clone() *Conversation {
+	clone := *c
+	clone.Messages = slices.Clone(c.Messages)
+	return &clone
+}
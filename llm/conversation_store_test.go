@@ -0,0 +1,111 @@
+package llm
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConversationStores(t *testing.T) {
+	fileStore, err := NewFileConversationStore(filepath.Join(t.TempDir(), "conversations"))
+	require.NoError(t, err)
+
+	stores := map[string]ConversationStore{
+		"memory": NewInMemoryConversationStore(),
+		"file":   fileStore,
+	}
+	for name, store := range stores {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			now := time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC)
+
+			_, err := store.Load(ctx, "../user/1")
+			require.ErrorIs(t, err, ErrConversationNotFound)
+
+			conversation := &Conversation{
+				ID:        "../user/1",
+				Messages:  []MemoryMessage{{Role: "user", Content: "Hi", Tokens: 5, Pinned: true}},
+				UpdatedAt: now,
+			}
+			require.NoError(t, store.Save(ctx, conversation))
+			conversation.Messages[0].Content = "changed"
+
+			loaded, err := store.Load(ctx, "../user/1")
+			require.NoError(t, err)
+			assert.Equal(t, "Hi", loaded.Messages[0].Content, "the store keeps its own copy")
+			assert.True(t, loaded.Messages[0].Pinned)
+			assert.True(t, now.Equal(loaded.UpdatedAt))
+
+			require.NoError(t, store.Save(ctx, &Conversation{ID: "recent", UpdatedAt: now.Add(time.Hour)}))
+			deleted, err := store.DeleteBefore(ctx, now.Add(time.Minute))
+			require.NoError(t, err)
+			assert.Equal(t, 1, deleted)
+			_, err = store.Load(ctx, "../user/1")
+			require.ErrorIs(t, err, ErrConversationNotFound)
+
+			require.NoError(t, store.Delete(ctx, "recent"))
+			require.NoError(t, store.Delete(ctx, "recent"), "deleting an unknown conversation is not an error")
+		})
+	}
+}
+
+func TestFileConversationStoreStaysInDirectory(t *testing.T) {
+	dir := t.TempDir()
+	store, err := NewFileConversationStore(dir)
+	require.NoError(t, err)
+
+	for _, id := range []string{"../escape", ".hidden", "a/b"} {
+		require.NoError(t, store.Save(context.Background(), &Conversation{ID: id}))
+	}
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	names := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		names = append(names, entry.Name())
+	}
+	assert.ElementsMatch(t, []string{"%2E.%2Fescape.json", "%2Ehidden.json", "a%2Fb.json"}, names)
+
+	_, err = store.Load(context.Background(), "")
+	require.Error(t, err)
+}
+
+func TestFileConversationStoreDeleteBeforeKeepsNewSaves(t *testing.T) {
+	store, err := NewFileConversationStore(t.TempDir())
+	require.NoError(t, err)
+
+	ctx := context.Background()
+	cutoff := time.Date(2025, 1, 2, 0, 0, 0, 0, time.UTC)
+	// Long histories keep DeleteBefore reading while the new saves land.
+	history := make([]MemoryMessage, 20000)
+	for i := range history {
+		history[i] = MemoryMessage{Role: "user", Content: "Hello there"}
+	}
+	ids := make([]string, 20)
+	for i := range ids {
+		ids[i] = fmt.Sprintf("session-%d", i)
+		require.NoError(t, store.Save(ctx, &Conversation{ID: ids[i], Messages: history, UpdatedAt: cutoff.Add(-time.Hour)}))
+	}
+
+	// Every session is saved again while expired ones are being deleted.
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for _, id := range ids {
+			assert.NoError(t, store.Save(ctx, &Conversation{ID: id, UpdatedAt: cutoff.Add(time.Hour)}))
+		}
+	}()
+	_, err = store.DeleteBefore(ctx, cutoff)
+	require.NoError(t, err)
+	<-done
+
+	for _, id := range ids {
+		_, err := store.Load(ctx, id)
+		require.NoError(t, err, "a conversation saved during DeleteBefore is kept")
+	}
+}
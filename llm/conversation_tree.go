@@ -0,0 +1,396 @@
+package llm
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/weave-labs/gollm/providers"
+)
+
+// ErrMessageNotFound is returned for message IDs that are not in a
+// ConversationTree.
+var ErrMessageNotFound = errors.New("message not found")
+
+// TreeMessage is a message of a ConversationTree. Messages without a parent
+// start a conversation.
+type TreeMessage struct {
+	CreatedAt time.Time `json:"created_at"`
+	ID        string    `json:"id"`
+	ParentID  string    `json:"parent_id,omitempty"`
+	PromptMessage
+}
+
+// ConversationTree keeps a conversation as a tree of messages, so that earlier
+// messages can be edited and answers regenerated without losing the
+// alternatives. Each path from a root to a message is a branch; one branch is
+// active and receives new messages.
+//
+// Example usage:
+//
+//	tree := llm.NewConversationTree()
+//	resp, err := tree.Generate(ctx, l, llm.NewPrompt("Name a color"))
+//	resp, err = tree.Regenerate(ctx, l, nil)           // try another answer
+//	err = tree.Checkout(tree.Siblings(tree.Active())[0].ID) // back to the first answer
+//	resp, err = tree.Generate(ctx, l, llm.NewPrompt("Why that one?"))
+type ConversationTree struct {
+	messages map[string]*TreeMessage
+	children map[string][]string
+	now      func() time.Time
+	order    []string
+	activeID string
+	mu       sync.RWMutex
+}
+
+// NewConversationTree creates an empty conversation tree.
+func NewConversationTree() *ConversationTree {
+	return &ConversationTree{
+		messages: make(map[string]*TreeMessage),
+		children: make(map[string][]string),
+		now:      time.Now,
+	}
+}
+
+// Add appends a message to the active branch and makes it the active message.
+func (t *ConversationTree) Add(message PromptMessage) TreeMessage {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	return t.add(t.activeID, message)
+}
+
+// AddTo adds a message as a reply to parentID, forking a new branch when the
+// parent already has replies, and makes it the active message. An empty
+// parentID starts a new conversation.
+func (t *ConversationTree) AddTo(parentID string, message PromptMessage) (TreeMessage, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if parentID != "" {
+		if _, err := t.get(parentID); err != nil {
+			return TreeMessage{}, err
+		}
+	}
+	return t.add(parentID, message), nil
+}
+
+// Edit adds a copy of the message with id with new content, as an alternative
+// to the message, and makes it the active message. The replies to the original
+// message stay on their branch; call Regenerate to answer the edited message.
+func (t *ConversationTree) Edit(id, content string) (TreeMessage, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	original, err := t.get(id)
+	if err != nil {
+		return TreeMessage{}, err
+	}
+	edited := original.PromptMessage
+	edited.Content = content
+	return t.add(original.ParentID, edited), nil
+}
+
+// Checkout makes the branch through the message with id active. The branch
+// continues with the most recent replies below the message, so checking out
+// an alternative answer restores the conversation that followed it.
+func (t *ConversationTree) Checkout(id string) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if _, err := t.get(id); err != nil {
+		return err
+	}
+	for children := t.children[id]; len(children) > 0; children = t.children[id] {
+		id = children[len(children)-1]
+	}
+	t.activeID = id
+	return nil
+}
+
+// Active returns the ID of the last message of the active branch, or an empty
+// string when the tree is empty.
+func (t *ConversationTree) Active() string {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	return t.activeID
+}
+
+// Message returns the message with id.
+func (t *ConversationTree) Message(id string) (TreeMessage, error) {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	message, err := t.get(id)
+	if err != nil {
+		return TreeMessage{}, err
+	}
+	return *message, nil
+}
+
+// Children returns the replies to the message with id, oldest first. An empty
+// id returns the messages that start a conversation.
+func (t *ConversationTree) Children(id string) []TreeMessage {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	return t.list(t.children[id])
+}
+
+// Siblings returns the message with id and its alternatives, the messages with
+// the same parent, oldest first.
+func (t *ConversationTree) Siblings(id string) []TreeMessage {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	message, err := t.get(id)
+	if err != nil {
+		return nil
+	}
+	return t.list(t.children[message.ParentID])
+}
+
+// Leaves returns the last message of every branch, oldest first.
+func (t *ConversationTree) Leaves() []TreeMessage {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	var leaves []string
+	for _, id := range t.order {
+		if len(t.children[id]) == 0 {
+			leaves = append(leaves, id)
+		}
+	}
+	return t.list(leaves)
+}
+
+// Branch returns the messages from the start of the conversation up to and
+// including the message with id, ready to send as Prompt.Messages.
+func (t *ConversationTree) Branch(id string) ([]PromptMessage, error) {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	return t.branch(id)
+}
+
+// ActiveBranch returns the messages of the active branch.
+func (t *ConversationTree) ActiveBranch() []PromptMessage {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	messages, _ := t.branch(t.activeID)
+	return messages
+}
+
+// Generate adds the prompt's input or messages to the active branch and
+// answers them with the branch as history. The other fields of the prompt,
+// such as the system prompt and tools, are sent as they are. Nothing is added
+// to the tree when generation fails.
+func (t *ConversationTree) Generate(
+	ctx context.Context,
+	l LLM,
+	prompt *Prompt,
+	opts ...GenerateOption,
+) (*providers.Response, error) {
+	t.mu.RLock()
+	parentID := t.activeID
+	history, err := t.branch(parentID)
+	t.mu.RUnlock()
+	if err != nil {
+		return nil, err
+	}
+
+	turn := turnMessages(prompt)
+	response, err := t.generate(ctx, l, prompt, append(history, turn...), opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	for _, message := range turn {
+		parentID = t.add(parentID, message).ID
+	}
+	t.add(parentID, responseMemoryMessage(response).PromptMessage())
+	return response, nil
+}
+
+// Regenerate answers the last user turn of the active branch again. When the
+// branch ends with an assistant message, the new answer is added as its
+// alternative; otherwise, for example after Edit, it answers the last
+// message. The new answer becomes the active message. The prompt may be nil;
+// its fields other than the input and messages are sent as they are.
+func (t *ConversationTree) Regenerate(
+	ctx context.Context,
+	l LLM,
+	prompt *Prompt,
+	opts ...GenerateOption,
+) (*providers.Response, error) {
+	t.mu.RLock()
+	parentID := t.activeID
+	if active, ok := t.messages[parentID]; ok && active.Role == "assistant" {
+		parentID = active.ParentID
+	}
+	history, err := t.branch(parentID)
+	t.mu.RUnlock()
+	if err != nil {
+		return nil, err
+	}
+	if len(history) == 0 {
+		return nil, NewLLMError(ErrorTypeInvalidInput, "no message to regenerate an answer to", nil)
+	}
+
+	response, err := t.generate(ctx, l, prompt, history, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.add(parentID, responseMemoryMessage(response).PromptMessage())
+	return response, nil
+}
+
+// generate sends messages as the history of prompt.
+func (t *ConversationTree) generate(
+	ctx context.Context,
+	l LLM,
+	prompt *Prompt,
+	messages []PromptMessage,
+	opts ...GenerateOption,
+) (*providers.Response, error) {
+	var withHistory Prompt
+	if prompt != nil {
+		withHistory = *prompt
+	}
+	withHistory.Input = ""
+	withHistory.Messages = messages
+
+	response, err := l.Generate(ctx, &withHistory, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate response: %w", err)
+	}
+	return response, nil
+}
+
+// MarshalJSON implements json.Marshaler, storing the messages in the order
+// they were added and the active message.
+func (t *ConversationTree) MarshalJSON() ([]byte, error) {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	return json.Marshal(conversationTreeJSON{ActiveID: t.activeID, Messages: t.list(t.order)})
+}
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (t *ConversationTree) UnmarshalJSON(data []byte) error {
+	var stored conversationTreeJSON
+	if err := json.Unmarshal(data, &stored); err != nil {
+		return fmt.Errorf("failed to decode conversation tree: %w", err)
+	}
+
+	tree := NewConversationTree()
+	for _, message := range stored.Messages {
+		if message.ID == "" {
+			return errors.New("message without an ID in conversation tree")
+		}
+		if _, ok := tree.messages[message.ID]; ok {
+			// A repeated ID would replace the earlier message and could make a parent cycle
+			return fmt.Errorf("duplicate message ID %q in conversation tree", message.ID)
+		}
+		if message.ParentID != "" {
+			if _, ok := tree.messages[message.ParentID]; !ok {
+				return fmt.Errorf("%w: parent %q of message %q", ErrMessageNotFound, message.ParentID, message.ID)
+			}
+		}
+		tree.insert(message)
+	}
+	if _, ok := tree.messages[stored.ActiveID]; stored.ActiveID != "" && !ok {
+		return fmt.Errorf("%w: active message %q", ErrMessageNotFound, stored.ActiveID)
+	}
+	tree.activeID = stored.ActiveID
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.messages, t.children, t.order, t.activeID = tree.messages, tree.children, tree.order, tree.activeID
+	if t.now == nil {
+		t.now = time.Now
+	}
+	return nil
+}
+
+// conversationTreeJSON is the stored form of a ConversationTree.
+type conversationTreeJSON struct {
+	ActiveID string        `json:"active_id,omitempty"`
+	Messages []TreeMessage `json:"messages"`
+}
+
+// add adds a reply to parentID and makes it active.
+func (t *ConversationTree) add(parentID string, message PromptMessage) TreeMessage {
+	added := TreeMessage{
+		CreatedAt:     t.now(),
+		ID:            newTreeMessageID(),
+		ParentID:      parentID,
+		PromptMessage: message,
+	}
+	t.insert(added)
+	t.activeID = added.ID
+	return added
+}
+
+// insert adds a message to the tree without changing the active message.
+func (t *ConversationTree) insert(message TreeMessage) {
+	t.messages[message.ID] = &message
+	t.children[message.ParentID] = append(t.children[message.ParentID], message.ID)
+	t.order = append(t.order, message.ID)
+}
+
+// get returns the message with id, or ErrMessageNotFound.
+func (t *ConversationTree) get(id string) (*TreeMessage, error) {
+	message, ok := t.messages[id]
+	if !ok {
+		return nil, fmt.Errorf("%w: %q", ErrMessageNotFound, id)
+	}
+	return message, nil
+}
+
+// branch returns the messages from the root up to id. An empty id returns no
+// messages.
+func (t *ConversationTree) branch(id string) ([]PromptMessage, error) {
+	var messages []PromptMessage
+	for id != "" {
+		message, err := t.get(id)
+		if err != nil {
+			return nil, err
+		}
+		messages = append(messages, message.PromptMessage)
+		id = message.ParentID
+	}
+	slices.Reverse(messages)
+	return messages, nil
+}
+
+// list returns copies of the messages with ids.
+func (t *ConversationTree) list(ids []string) []TreeMessage {
+	messages := make([]TreeMessage, 0, len(ids))
+	for _, id := range ids {
+		messages = append(messages, *t.messages[id])
+	}
+	return messages
+}
+
+// newTreeMessageID returns a random message ID.
+func newTreeMessageID() string {
+	b := make([]byte, 8)
+	_, _ = rand.Read(b)
+	return "msg_" + hex.EncodeToString(b)
+}
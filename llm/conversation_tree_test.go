@@ -0,0 +1,97 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConversationTreeRegenerate(t *testing.T) {
+	client := &scriptedLLM{replies: []string{"Blue", "Green", "Because it is calm"}}
+	tree := NewConversationTree()
+
+	_, err := tree.Generate(context.Background(), client, &Prompt{SystemPrompt: "Be brief", Input: "Name a color"})
+	require.NoError(t, err)
+	first := tree.Active()
+
+	resp, err := tree.Regenerate(context.Background(), client, &Prompt{SystemPrompt: "Be brief"})
+	require.NoError(t, err)
+	assert.Equal(t, "Green", resp.AsText())
+	require.Len(t, client.prompts, 2)
+	assert.Equal(t, "Be brief", client.prompts[1].SystemPrompt)
+	assert.Equal(t, []PromptMessage{{Role: "user", Content: "Name a color"}}, client.prompts[1].Messages,
+		"the answer being regenerated is not sent")
+
+	siblings := tree.Siblings(tree.Active())
+	require.Len(t, siblings, 2)
+	assert.Equal(t, first, siblings[0].ID)
+
+	require.NoError(t, tree.Checkout(first))
+	_, err = tree.Generate(context.Background(), client, NewPrompt("Why?"))
+	require.NoError(t, err)
+	assert.Equal(t, []PromptMessage{
+		{Role: "user", Content: "Name a color"},
+		{Role: "assistant", Content: "Blue"},
+		{Role: "user", Content: "Why?"},
+		{Role: "assistant", Content: "Because it is calm"},
+	}, tree.ActiveBranch())
+	assert.Len(t, tree.Leaves(), 2)
+}
+
+func TestConversationTreeEdit(t *testing.T) {
+	client := &scriptedLLM{replies: []string{"Paris", "Rome"}}
+	tree := NewConversationTree()
+
+	_, err := tree.Generate(context.Background(), client, NewPrompt("Capital of France?"))
+	require.NoError(t, err)
+	answer := tree.Active()
+	question := tree.Children("")[0].ID
+
+	edited, err := tree.Edit(question, "Capital of Italy?")
+	require.NoError(t, err)
+	assert.Empty(t, edited.ParentID)
+	_, err = tree.Regenerate(context.Background(), client, nil)
+	require.NoError(t, err)
+
+	assert.Equal(t, []PromptMessage{
+		{Role: "user", Content: "Capital of Italy?"},
+		{Role: "assistant", Content: "Rome"},
+	}, tree.ActiveBranch())
+
+	original, err := tree.Branch(answer)
+	require.NoError(t, err)
+	assert.Equal(t, "Paris", original[1].Content, "the original branch is kept")
+
+	require.NoError(t, tree.Checkout(question))
+	assert.Equal(t, answer, tree.Active(), "checkout follows the branch to its latest reply")
+}
+
+func TestConversationTreeJSON(t *testing.T) {
+	tree := NewConversationTree()
+	root := tree.Add(PromptMessage{Role: "user", Content: "Hi"})
+	tree.Add(PromptMessage{Role: "assistant", Content: "Hello"})
+	alternative, err := tree.AddTo(root.ID, PromptMessage{Role: "assistant", Content: "Hey"})
+	require.NoError(t, err)
+
+	data, err := json.Marshal(tree)
+	require.NoError(t, err)
+
+	restored := NewConversationTree()
+	require.NoError(t, json.Unmarshal(data, restored))
+	assert.Equal(t, alternative.ID, restored.Active())
+	assert.Len(t, restored.Children(root.ID), 2)
+	assert.Equal(t, tree.ActiveBranch(), restored.ActiveBranch())
+
+	_, err = tree.AddTo("msg_unknown", PromptMessage{Role: "user", Content: "Lost"})
+	require.ErrorIs(t, err, ErrMessageNotFound)
+
+	cyclic := `{"active_id":"b","messages":[` +
+		`{"id":"a","role":"user","content":"Hi"},` +
+		`{"id":"b","parent_id":"a","role":"assistant","content":"Hello"},` +
+		`{"id":"a","parent_id":"b","role":"user","content":"Again"}]}`
+	err = json.Unmarshal([]byte(cyclic), NewConversationTree())
+	require.EqualError(t, err, `duplicate message ID "a" in conversation tree`)
+}
@@ -1,7 +1,11 @@
 package llm
 
 import (
+	"encoding/json"
+	"errors"
+	"fmt"
 	"reflect"
+	"time"
 
 	"github.com/google/jsonschema-go/jsonschema"
 )
@@ -33,12 +37,46 @@ func WithStructuredResponse[T any]() GenerateOption {
 }
 
 // WithStructuredResponseJSON configures Generate to produce output conforming to the provided JSON schema.
+// The response is validated against the schema, and an invalid response is retried with the
+// validation errors.
 func WithStructuredResponseJSON(json []byte) GenerateOption {
 	return func(cfg *GenerateConfig) {
 		cfg.StructuredResponseJSON = json
 	}
 }
 
+// WithRepairAttempts sets how many times GenerateTyped re-prompts the model with
+// validation errors after an invalid structured response. Zero disables repair.
+func WithRepairAttempts(n int) GenerateOption {
+	return func(cfg *GenerateConfig) {
+		cfg.RepairAttempts = max(n, 0)
+	}
+}
+
+// deferStructuredDecode leaves decoding and validation of a structured response
+// to the caller, so that a malformed response is returned instead of retried.
+func deferStructuredDecode() GenerateOption {
+	return func(cfg *GenerateConfig) {
+		cfg.deferDecode = true
+	}
+}
+
+// WithPromptRenderer sets how the prompt's fields are turned into the system
+// prompt and messages of the request, overriding the LLM's renderer.
+func WithPromptRenderer(renderer PromptRenderer) GenerateOption {
+	return func(cfg *GenerateConfig) {
+		cfg.renderer = renderer
+	}
+}
+
+// WithContextOverflow sets what happens when a request does not fit the
+// model's context window. The default is ContextOverflowError.
+func WithContextOverflow(policy ContextOverflowPolicy) GenerateOption {
+	return func(cfg *GenerateConfig) {
+		cfg.ContextOverflow = policy
+	}
+}
+
 // WithStreamBufferSize sets the size of the token buffer for streaming responses.
 func WithStreamBufferSize(size int) GenerateOption {
 	return func(cfg *GenerateConfig) {
@@ -53,11 +91,72 @@ func WithRetryStrategy(strategy RetryStrategy) GenerateOption {
 	}
 }
 
+// WithFirstTokenTimeout fails a stream with ErrFirstTokenTimeout when no token
+// arrives within d of the request being sent.
+func WithFirstTokenTimeout(d time.Duration) GenerateOption {
+	return func(cfg *GenerateConfig) {
+		cfg.FirstTokenTimeout = d
+	}
+}
+
+// WithIdleTimeout fails a stream with ErrStreamIdleTimeout when no event arrives
+// within d of the previous one.
+func WithIdleTimeout(d time.Duration) GenerateOption {
+	return func(cfg *GenerateConfig) {
+		cfg.IdleTimeout = d
+	}
+}
+
+// WithStreamReconnect re-issues a stream request after a transient read failure or
+// timeout, as permitted by the retry strategy. Text already delivered is skipped in
+// the new stream; if the new stream does not reproduce it, Next returns ErrStreamDiverged.
+// Resuming is therefore most reliable with deterministic settings such as a fixed seed.
+func WithStreamReconnect() GenerateOption {
+	return func(cfg *GenerateConfig) {
+		cfg.StreamReconnect = true
+	}
+}
+
+// WithProviderOption sets a provider option, such as "temperature" or "seed",
+// for a single request, overriding the value set with SetOption.
+func WithProviderOption(key string, value any) GenerateOption {
+	return func(cfg *GenerateConfig) {
+		if cfg.providerOptions == nil {
+			cfg.providerOptions = make(map[string]any)
+		}
+		cfg.providerOptions[key] = value
+	}
+}
+
 // GenerateConfig holds configuration options for text generation.
 type GenerateConfig struct {
 	RetryStrategy            RetryStrategy
 	StructuredResponseSchema *jsonschema.Schema
 	StructuredResponseJSON   []byte
 	StreamBufferSize         int
+	RepairAttempts           int
+	FirstTokenTimeout        time.Duration
+	IdleTimeout              time.Duration
+	StreamReconnect          bool
+	ContextOverflow          ContextOverflowPolicy
 	structuredResponseType   any
+	providerOptions          map[string]any
+	renderer                 PromptRenderer
+	deferDecode              bool
+}
+
+// errInvalidResponseSchema is returned when WithStructuredResponseJSON is not a valid schema.
+var errInvalidResponseSchema = errors.New("invalid response JSON schema")
+
+// responseSchema returns the schema the response must match: the one set by
+// WithStructuredResponse, or the parsed WithStructuredResponseJSON schema.
+func (cfg *GenerateConfig) responseSchema() (*jsonschema.Schema, error) {
+	if cfg.StructuredResponseSchema != nil || cfg.StructuredResponseJSON == nil {
+		return cfg.StructuredResponseSchema, nil
+	}
+	var schema jsonschema.Schema
+	if err := json.Unmarshal(cfg.StructuredResponseJSON, &schema); err != nil {
+		return nil, fmt.Errorf("%w: %w", errInvalidResponseSchema, err)
+	}
+	return &schema, nil
 }
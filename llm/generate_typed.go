@@ -0,0 +1,207 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/go-playground/validator/v10"
+
+	"github.com/weave-labs/gollm/providers"
+)
+
+// StructuredAttempt records one response that failed to decode or validate.
+type StructuredAttempt struct {
+	// Response is the raw provider response of the attempt.
+	Response *providers.Response
+	// Err is the decoding or validation failure.
+	Err error
+	// Attempt is the 1-based attempt number.
+	Attempt int
+}
+
+// StructuredOutputError is returned by GenerateTyped when no attempt, including
+// every repair attempt, produced a valid value. It lists each failed attempt.
+type StructuredOutputError struct {
+	Attempts []StructuredAttempt
+}
+
+// Error implements the error interface.
+func (e *StructuredOutputError) Error() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "structured output failed validation after %d attempt(s)", len(e.Attempts))
+	for _, attempt := range e.Attempts {
+		fmt.Fprintf(&b, "; attempt %d: %v", attempt.Attempt, attempt.Err)
+	}
+	return b.String()
+}
+
+// Unwrap returns the error of every failed attempt.
+func (e *StructuredOutputError) Unwrap() []error {
+	errs := make([]error, len(e.Attempts))
+	for i, attempt := range e.Attempts {
+		errs[i] = attempt.Err
+	}
+	return errs
+}
+
+// GenerateTyped generates a structured response and decodes it into a T.
+//
+// The response is decoded as JSON, validated against the JSON schema of T and,
+// for struct types, against its `validate` tags. When any of these steps fails,
+// the model is shown its previous output together with the exact validation
+// errors and asked for a corrected response, up to the number of repair attempts
+// set with WithRepairAttempts (DefaultRepairAttempts by default). If every attempt
+// fails, the error is a *StructuredOutputError listing each of them. Errors from
+// the underlying Generate call are returned as is.
+//
+// Example:
+//
+//	type Recipe struct {
+//	    Title string   `json:"title" validate:"required"`
+//	    Steps []string `json:"steps" validate:"min=1"`
+//	}
+//
+//	recipe, resp, err := GenerateTyped[Recipe](ctx, client, NewPrompt("A pancake recipe"))
+func GenerateTyped[T any](
+	ctx context.Context,
+	l LLM,
+	prompt *Prompt,
+	opts ...GenerateOption,
+) (*T, *providers.Response, error) {
+	cfg := &GenerateConfig{RepairAttempts: DefaultRepairAttempts}
+	for _, opt := range opts {
+		opt(cfg)
+	}
+
+	opts = append(opts[:len(opts):len(opts)], WithStructuredResponse[T](), deferStructuredDecode())
+
+	var failed []StructuredAttempt
+	current := prompt
+	for attempt := 1; attempt <= cfg.RepairAttempts+1; attempt++ {
+		resp, err := l.Generate(ctx, current, opts...)
+		if err != nil {
+			return nil, resp, err
+		}
+
+		value, err := decodeStructured[T]([]byte(extractJSON(resp.AsText())))
+		if err == nil {
+			return value, resp, nil
+		}
+
+		failed = append(failed, StructuredAttempt{Attempt: attempt, Response: resp, Err: err})
+		current = repairPrompt(prompt, resp.AsText(), err)
+	}
+
+	last := failed[len(failed)-1].Response
+	return nil, last, &StructuredOutputError{Attempts: failed}
+}
+
+// repairPrompt returns a copy of prompt that shows the model its invalid output
+// and the validation errors, and asks for a corrected response.
+func repairPrompt(prompt *Prompt, output string, err error) *Prompt {
+	repaired := *prompt
+	repaired.Messages = append([]PromptMessage(nil), prompt.Messages...)
+	if len(repaired.Messages) == 0 {
+		repaired.Messages = append(repaired.Messages, PromptMessage{Role: "user", Content: prompt.Input})
+	}
+
+	var b strings.Builder
+	b.WriteString("Your previous response was not valid:\n")
+	for _, problem := range validationProblems(err) {
+		b.WriteString("- ")
+		b.WriteString(problem)
+		b.WriteString("\n")
+	}
+	b.WriteString("Respond again with only the corrected JSON value that satisfies the required schema.")
+
+	repaired.Input = b.String()
+	repaired.Messages = append(repaired.Messages,
+		PromptMessage{Role: "assistant", Content: output},
+		PromptMessage{Role: "user", Content: repaired.Input},
+	)
+	return &repaired
+}
+
+// validationProblems splits a decoding or validation error into the individual
+// problems reported to the model.
+func validationProblems(err error) []string {
+	var fieldErrs validator.ValidationErrors
+	if errors.As(err, &fieldErrs) {
+		problems := make([]string, len(fieldErrs))
+		for i, fieldErr := range fieldErrs {
+			problems[i] = fieldErr.Error()
+		}
+		return problems
+	}
+
+	var schemaErr *SchemaValidationError
+	if errors.As(err, &schemaErr) {
+		problems := make([]string, len(schemaErr.Violations))
+		for i, violation := range schemaErr.Violations {
+			problems[i] = violation.String()
+		}
+		return problems
+	}
+
+	var llmErr *LLMError
+	if errors.As(err, &llmErr) && llmErr.Err != nil {
+		err = llmErr.Err
+	}
+	var problems []string
+	for _, line := range strings.Split(err.Error(), "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			problems = append(problems, line)
+		}
+	}
+	return problems
+}
+
+// validateTags validates value against its `validate` struct tags. Values that
+// are not structs have no tags and always pass.
+func validateTags(value any) error {
+	rv := reflect.ValueOf(value)
+	for rv.Kind() == reflect.Ptr {
+		if rv.IsNil() {
+			return nil
+		}
+		rv = rv.Elem()
+	}
+	if rv.Kind() != reflect.Struct {
+		return nil
+	}
+	if err := getValidator().Struct(value); err != nil {
+		return NewLLMError(ErrorTypeResponse, "structured response failed validation", err)
+	}
+	return nil
+}
+
+// extractJSON returns the JSON value in a response. Models without native
+// structured output sometimes wrap it in a Markdown code fence or surround it
+// with prose. When no valid JSON value is found, the trimmed text, or the contents
+// of its code fence, is returned so that the decoding error describes it.
+func extractJSON(text string) string {
+	text = strings.TrimSpace(text)
+	if json.Valid([]byte(text)) {
+		return text
+	}
+
+	if _, fenced, ok := strings.Cut(text, "```"); ok {
+		// Drop the info string, such as "json", and the closing fence.
+		if i := strings.IndexByte(fenced, '\n'); i >= 0 {
+			fenced = fenced[i+1:]
+		}
+		fenced, _, _ = strings.Cut(fenced, "```")
+		text = strings.TrimSpace(fenced)
+	}
+
+	start := strings.IndexAny(text, "{[")
+	end := strings.LastIndexAny(text, "}]")
+	if start < 0 || end < start || !json.Valid([]byte(text[start:end+1])) {
+		return text
+	}
+	return text[start : end+1]
+}
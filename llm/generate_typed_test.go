@@ -0,0 +1,118 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/weave-labs/gollm/providers"
+)
+
+// scriptedLLM answers Generate calls with canned texts and records the prompts.
+type scriptedLLM struct {
+	LLM
+	err     error
+	replies []string
+	prompts []*Prompt
+	configs []*GenerateConfig
+}
+
+func (s *scriptedLLM) Generate(_ context.Context, prompt *Prompt, opts ...GenerateOption) (*providers.Response, error) {
+	cfg := &GenerateConfig{}
+	for _, opt := range opts {
+		opt(cfg)
+	}
+	s.prompts = append(s.prompts, prompt)
+	s.configs = append(s.configs, cfg)
+	if s.err != nil {
+		return nil, s.err
+	}
+	reply := s.replies[0]
+	s.replies = s.replies[1:]
+	return &providers.Response{Content: providers.Text{Value: reply}}, nil
+}
+
+type typedContact struct {
+	Name  string `json:"name" validate:"required"`
+	Email string `json:"email" validate:"required,email"`
+	Age   int    `json:"age" validate:"min=0,max=150"`
+}
+
+func TestGenerateTyped(t *testing.T) {
+	client := &scriptedLLM{replies: []string{"```json\n{\"name\":\"Ada\",\"email\":\"ada@example.com\",\"age\":36}\n```"}}
+
+	contact, resp, err := GenerateTyped[typedContact](context.Background(), client, NewPrompt("Extract the contact"))
+	require.NoError(t, err)
+	assert.Equal(t, typedContact{Name: "Ada", Email: "ada@example.com", Age: 36}, *contact)
+	assert.NotNil(t, resp)
+
+	require.Len(t, client.configs, 1)
+	assert.NotNil(t, client.configs[0].StructuredResponseSchema, "the schema of T is requested")
+	assert.True(t, client.configs[0].deferDecode, "decoding is left to GenerateTyped")
+}
+
+func TestGenerateTypedRepairs(t *testing.T) {
+	client := &scriptedLLM{replies: []string{
+		`{"name":"Ada","email":"ada@example"`,
+		`{"name":"Ada","email":"not-an-email","age":36}`,
+		`{"name":"Ada","email":"ada@example.com","age":36}`,
+	}}
+
+	contact, _, err := GenerateTyped[typedContact](context.Background(), client, NewPrompt("Extract the contact"))
+	require.NoError(t, err)
+	assert.Equal(t, "ada@example.com", contact.Email)
+	require.Len(t, client.prompts, 3)
+
+	repair := client.prompts[2]
+	require.Len(t, repair.Messages, 3, "the original request, the invalid output and the repair request")
+	assert.Equal(t, "Extract the contact", repair.Messages[0].Content)
+	assert.Equal(t, `{"name":"Ada","email":"not-an-email","age":36}`, repair.Messages[1].Content)
+	assert.Equal(t, "assistant", repair.Messages[1].Role)
+	assert.Contains(t, repair.Messages[2].Content, "'Email' failed on the 'email' tag")
+}
+
+func TestGenerateTypedFailures(t *testing.T) {
+	t.Run("repair attempts exhausted", func(t *testing.T) {
+		client := &scriptedLLM{replies: []string{`not json`, `{"name":"","email":"ada@example.com","age":36}`}}
+
+		contact, resp, err := GenerateTyped[typedContact](context.Background(), client,
+			NewPrompt("Extract the contact"), WithRepairAttempts(1))
+		assert.Nil(t, contact)
+		assert.NotNil(t, resp, "the last response is returned for inspection")
+
+		var outputErr *StructuredOutputError
+		require.ErrorAs(t, err, &outputErr)
+		require.Len(t, outputErr.Attempts, 2)
+		assert.Equal(t, 1, outputErr.Attempts[0].Attempt)
+		assert.Equal(t, "not json", outputErr.Attempts[0].Response.AsText())
+		assert.Contains(t, outputErr.Attempts[1].Err.Error(), "'Name' failed on the 'required' tag")
+		assert.Contains(t, err.Error(), "after 2 attempt(s)")
+	})
+
+	t.Run("generation error", func(t *testing.T) {
+		generateErr := errors.New("rate limited")
+		client := &scriptedLLM{err: generateErr}
+
+		_, _, err := GenerateTyped[typedContact](context.Background(), client, NewPrompt("Extract the contact"))
+		require.ErrorIs(t, err, generateErr)
+		assert.Len(t, client.prompts, 1, "generation errors are not repaired")
+	})
+}
+
+func TestExtractJSON(t *testing.T) {
+	tests := map[string]string{
+		`{"a":1}`:                              `{"a":1}`,
+		"```json\n{\"a\":1}\n```":              `{"a":1}`,
+		"Here it is:\n```\n[1,2]\n```\nEnjoy.": `[1,2]`,
+		`Sure! {"a":{"b":[1]}} Let me know.`:   `{"a":{"b":[1]}}`,
+		"```json\n{\"a\":\n```":                `{"a":`,
+		"no json here":                         "no json here",
+		`  {"a": "}"}  `:                       `{"a": "}"}`,
+	}
+	for input, want := range tests {
+		assert.Equal(t, want, extractJSON(input), input)
+	}
+}
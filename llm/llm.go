@@ -7,8 +7,10 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
+	"maps"
 	"net/http"
 	"sync"
 	"time"
@@ -63,6 +65,10 @@ type LLMImpl struct {
 	RetryDelay           time.Duration
 	optionsMutex         sync.RWMutex
 	structuredOutputType any
+	renderer             PromptRenderer
+	tokenCounter         providers.TokenCounter
+	localCounterOnce     sync.Once
+	localCounter         providers.TokenCounter
 }
 
 // NewLLM creates a new LLM instance with the specified configuration.
@@ -82,9 +88,12 @@ func NewLLM(cfg *config.Config, logger logging.Logger, registry *providers.Provi
 
 	provider.SetDefaultOptions(cfg)
 
+	logger = logging.NewRedactingLogger(logger, NewRedactor(cfg))
+	provider.SetLogger(logger)
+
 	llmClient := &LLMImpl{
 		Provider:   provider,
-		client:     &http.Client{Timeout: cfg.Timeout},
+		client:     &http.Client{Timeout: cfg.Timeout, Transport: cfg.HTTPTransport},
 		logger:     logger,
 		config:     cfg,
 		MaxRetries: cfg.MaxRetries,
@@ -101,6 +110,7 @@ func (l *LLMImpl) SetOption(key string, value any) {
 	defer l.optionsMutex.Unlock()
 
 	l.Options[key] = value
+	// The option key is the log key, so the redactor masks values of sensitive options.
 	l.logger.Debug("Option set", key, value)
 }
 
@@ -160,28 +170,48 @@ func (l *LLMImpl) GenerateStream(ctx context.Context, prompt *Prompt, opts ...Ge
 		options[k] = v
 	}
 	l.optionsMutex.RUnlock()
+	maps.Copy(options, generateConfig.providerOptions)
 	options["stream"] = true
 
-	builder := providers.NewRequestBuilder().WithPrompt(prompt.Input)
-
-	if prompt.SystemPrompt != "" {
-		builder.WithSystemPrompt(prompt.SystemPrompt)
-	}
+	rendered := l.promptRenderer(generateConfig).Render(prompt)
+	builder := providers.NewRequestBuilder().
+		WithSystemPrompt(rendered.SystemPrompt).
+		WithMessages(ToMessages(rendered.Messages))
 
 	if generateConfig.StructuredResponseJSON != nil {
 		builder.WithResponseJSONSchema(generateConfig.StructuredResponseJSON)
 	}
 
 	if generateConfig.StructuredResponseSchema != nil {
-		builder.WithResponseSchema(generateConfig.StructuredResponseSchema)
+		builder.WithResponseSchema(l.transformResponseSchema(generateConfig.StructuredResponseSchema))
 	}
 
-	providerReq := builder.Build()
+	providerReq, err := l.preflight(ctx, builder.Build(), generateConfig)
+	if err != nil {
+		return nil, err
+	}
 	body, err := l.Provider.PrepareStreamRequest(providerReq, options)
+	if errors.Is(err, providers.ErrSchemaTooDeep) {
+		return nil, NewLLMError(ErrorTypeUnsupported, "response schema is not supported by the model", err)
+	}
 	if err != nil {
 		return nil, NewLLMError(ErrorTypeRequest, "failed to prepare stream request", err)
 	}
 
+	open := func(ctx context.Context) (io.ReadCloser, error) {
+		return l.openStream(ctx, body)
+	}
+
+	respBody, err := open(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return newProviderStream(ctx, respBody, open, l.Provider, generateConfig), nil
+}
+
+// openStream sends a prepared streaming request and returns the response body.
+func (l *LLMImpl) openStream(ctx context.Context, body []byte) (io.ReadCloser, error) {
 	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, l.Provider.Endpoint(), bytes.NewReader(body))
 	if err != nil {
 		return nil, NewLLMError(ErrorTypeRequest, "failed to create stream request", err)
@@ -203,23 +233,38 @@ func (l *LLMImpl) GenerateStream(ctx context.Context, prompt *Prompt, opts ...Ge
 		return nil, NewLLMError(ErrorTypeAPI, fmt.Sprintf("API error: status code %d", resp.StatusCode), nil)
 	}
 
-	return newProviderStream(resp.Body, l.Provider, generateConfig), nil
+	return &streamBody{ReadCloser: resp.Body, requestID: requestID(resp.Header)}, nil
 }
 
-// generateWithRetries handles standard generation with retry logic
+// generateWithRetries handles standard generation with retry logic. A
+// structured response that does not match its schema is retried with a repair
+// prompt listing the violations.
 func (l *LLMImpl) generateWithRetries(
 	ctx context.Context,
 	prompt *Prompt,
 	genCfg *GenerateConfig,
 ) (*providers.Response, error) {
+	current := prompt
+	var lastErr error
 	for attempt := 0; attempt <= l.MaxRetries; attempt++ {
-		result, err := l.attemptGenerate(ctx, prompt, genCfg)
+		result, err := l.attemptGenerate(ctx, current, genCfg)
 		if err == nil {
 			return result, nil
 		}
+		if errors.Is(err, providers.ErrSchemaTooDeep) || errors.Is(err, errInvalidResponseSchema) ||
+			errors.Is(err, ErrContextWindowExceeded) {
+			return nil, err
+		}
+		lastErr = err
 
 		l.logger.Warn("Generation attempt failed", "error", err, "attempt", attempt+1)
 
+		var schemaErr *SchemaValidationError
+		if errors.As(err, &schemaErr) && result != nil {
+			current = repairPrompt(prompt, result.AsText(), err)
+			continue
+		}
+
 		if attempt < l.MaxRetries {
 			if err := l.wait(ctx); err != nil {
 				return nil, err
@@ -227,7 +272,7 @@ func (l *LLMImpl) generateWithRetries(
 		}
 	}
 
-	return nil, fmt.Errorf("failed to generate after %d attempts", l.MaxRetries+1)
+	return nil, fmt.Errorf("failed to generate after %d attempts: %w", l.MaxRetries+1, lastErr)
 }
 
 // wait implements a cancellable delay between retry attempts.
@@ -250,7 +295,18 @@ func (l *LLMImpl) attemptGenerate(
 ) (*providers.Response, error) {
 	response := &providers.Response{}
 
-	reqBody, err := l.prepareRequestBody(prompt, genCfg.StructuredResponseSchema)
+	schema, err := genCfg.responseSchema()
+	if err != nil {
+		return response, NewLLMError(ErrorTypeInvalidInput, "failed to prepare request", err)
+	}
+
+	reqBody, req, sent, err := l.prepareRequestBody(ctx, prompt, schema, genCfg)
+	if errors.Is(err, ErrContextWindowExceeded) {
+		return response, err
+	}
+	if errors.Is(err, providers.ErrSchemaTooDeep) {
+		return response, NewLLMError(ErrorTypeUnsupported, "response schema is not supported by the model", err)
+	}
 	if err != nil {
 		return response, NewLLMError(ErrorTypeRequest, "failed to prepare request", err)
 	}
@@ -259,15 +315,28 @@ func (l *LLMImpl) attemptGenerate(
 	if err != nil {
 		return response, err
 	}
+	l.calibrateTokenCounter(req, response.Usage)
 
-	if genCfg.StructuredResponseSchema != nil {
+	if schema != nil {
 		textContent, ok := response.Content.(providers.Text)
 		if !ok {
 			return nil, NewLLMError(ErrorTypeResponse, "response content is not text", nil)
 		}
 
-		if err := json.Unmarshal([]byte(textContent.Value), genCfg.structuredResponseType); err != nil {
-			return nil, fmt.Errorf("failed to unmarshal structured response: %w", err)
+		// Models without native structured output may surround the JSON with
+		// prose or a code fence, so the response is reduced to the JSON value.
+		response.Content = providers.Text{Value: extractJSON(textContent.Value)}
+		if genCfg.deferDecode {
+			return response, nil
+		}
+
+		if err := ValidateJSON(sent, []byte(response.AsText())); err != nil {
+			return response, NewLLMError(ErrorTypeResponse, "structured response does not match schema", err)
+		}
+		if genCfg.structuredResponseType != nil {
+			if err := json.Unmarshal([]byte(response.AsText()), genCfg.structuredResponseType); err != nil {
+				return response, fmt.Errorf("failed to unmarshal structured response: %w", err)
+			}
 		}
 	}
 
@@ -295,30 +364,69 @@ func (l *LLMImpl) prepareOptions(prompt *Prompt) map[string]any {
 	return options
 }
 
-// prepareRequestBody prepares the request body using the new provider architecture
-func (l *LLMImpl) prepareRequestBody(prompt *Prompt, schema *jsonschema.Schema) ([]byte, error) {
+// prepareRequestBody prepares the request body using the new provider architecture,
+// after checking that the request fits the model's context window. It also returns
+// the request and the response schema as sent to the provider.
+func (l *LLMImpl) prepareRequestBody(
+	ctx context.Context,
+	prompt *Prompt,
+	schema *jsonschema.Schema,
+	genCfg *GenerateConfig,
+) ([]byte, *providers.Request, *jsonschema.Schema, error) {
 	options := l.prepareOptions(prompt)
+	maps.Copy(options, genCfg.providerOptions)
 
+	rendered := l.promptRenderer(genCfg).Render(prompt)
 	builder := providers.NewRequestBuilder()
-	builder.WithSystemPrompt(prompt.SystemPrompt).
-		WithMessages(ToMessages(prompt.Messages))
+	builder.WithSystemPrompt(rendered.SystemPrompt).
+		WithMessages(ToMessages(rendered.Messages))
 
+	var sent *jsonschema.Schema
 	if schema != nil {
-		builder.WithResponseSchema(schema)
+		sent = l.transformResponseSchema(schema)
+		builder.WithResponseSchema(sent)
 	}
 
-	if prompt.SystemPrompt != "" {
-		builder.WithSystemPrompt(prompt.SystemPrompt)
+	req, err := l.preflight(ctx, builder.Build(), genCfg)
+	if err != nil {
+		return nil, nil, nil, err
 	}
 
-	req := builder.Build()
-
 	reqBody, err := l.Provider.PrepareRequest(req, options)
 	if err != nil {
-		return nil, fmt.Errorf("provider PrepareRequest failed: %w", err)
+		return nil, nil, nil, fmt.Errorf("provider PrepareRequest failed: %w", err)
 	}
 
-	return reqBody, nil
+	return reqBody, req, sent, nil
+}
+
+// SetPromptRenderer sets the renderer used for prompts generated without
+// WithPromptRenderer. A nil renderer restores DefaultPromptRenderer.
+func (l *LLMImpl) SetPromptRenderer(renderer PromptRenderer) {
+	l.renderer = renderer
+}
+
+// promptRenderer returns the renderer for a generation.
+func (l *LLMImpl) promptRenderer(cfg *GenerateConfig) PromptRenderer {
+	switch {
+	case cfg.renderer != nil:
+		return cfg.renderer
+	case l.renderer != nil:
+		return l.renderer
+	default:
+		return DefaultPromptRenderer{}
+	}
+}
+
+// transformResponseSchema rewrites schema into the form the provider accepts
+// and logs every lossy change.
+func (l *LLMImpl) transformResponseSchema(schema *jsonschema.Schema) *jsonschema.Schema {
+	transformed, warnings := providers.TransformResponseSchema(l.Provider, l.config.Model, schema)
+	for _, warning := range warnings {
+		l.logger.Warn("Response schema changed for provider", "provider", l.Provider.Name(),
+			"path", warning.Path, "warning", warning.Message)
+	}
+	return transformed
 }
 
 // executeRequest sends the HTTP request and processes the response
@@ -332,6 +440,7 @@ func (l *LLMImpl) executeRequest(ctx context.Context, reqBody []byte) (*provider
 		req.Header.Set(k, v)
 	}
 
+	start := time.Now()
 	resp, err := l.client.Do(req)
 	if err != nil {
 		return nil, NewLLMError(ErrorTypeRequest, "failed to send request", err)
@@ -349,7 +458,9 @@ func (l *LLMImpl) executeRequest(ctx context.Context, reqBody []byte) (*provider
 	}
 
 	if resp.StatusCode != http.StatusOK {
-		l.logger.Error("API error", "provider", l.Provider.Name(), "status", resp.StatusCode, "body", string(body))
+		l.logger.Error("API error", "provider", l.Provider.Name(), "status", resp.StatusCode,
+			"body", truncateForLog(body, MaxLoggedErrorBodyLength))
+		l.logger.Debug("API error body", "provider", l.Provider.Name(), "body", string(body))
 		return nil, NewLLMError(ErrorTypeAPI, fmt.Sprintf("API error: status code %d", resp.StatusCode), nil)
 	}
 
@@ -358,5 +469,27 @@ func (l *LLMImpl) executeRequest(ctx context.Context, reqBody []byte) (*provider
 		return nil, NewLLMError(ErrorTypeResponse, "failed to parse response", err)
 	}
 
+	if response.Metadata == nil {
+		response.Metadata = &providers.ResponseMetadata{}
+	}
+	response.Metadata.RequestID = requestID(resp.Header)
+	response.Metadata.Latency = time.Since(start)
+	if l.config.IncludeRawResponse {
+		response.Metadata.RawBody = body
+	}
+
 	return response, nil
 }
+
+// requestIDHeaders lists the headers providers use to report their request ID.
+var requestIDHeaders = []string{"X-Request-Id", "Request-Id", "X-Goog-Request-Id", "Cf-Ray"}
+
+// requestID returns the provider request ID from response headers, if any.
+func requestID(header http.Header) string {
+	for _, name := range requestIDHeaders {
+		if id := header.Get(name); id != "" {
+			return id
+		}
+	}
+	return ""
+}
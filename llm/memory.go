@@ -4,108 +4,175 @@ package llm
 import (
 	"context"
 	"fmt"
+	"slices"
 	"sync"
 
-	"github.com/pkoukk/tiktoken-go"
-
 	"github.com/weave-labs/gollm/internal/logging"
 	"github.com/weave-labs/gollm/providers"
 )
 
-// Memory manages conversation history with token-based truncation.
+// Memory manages conversation history with token-based compaction.
 // It provides thread-safe operations for adding, retrieving, and managing messages
 // while ensuring the total token count stays within specified limits.
 type Memory struct {
 	logger      logging.Logger
-	encoding    *tiktoken.Tiktoken
+	counter     providers.TokenCounter
+	compactor   MemoryCompactor
 	messages    []MemoryMessage
+	rules       providers.MessageRules
 	totalTokens int
 	maxTokens   int
-	mutex       sync.Mutex
+	// generation changes whenever messages are replaced rather than
+	// appended, so that a compaction computed meanwhile can be discarded.
+	generation int
+	mutex      sync.Mutex
+	compacting bool
 }
 
 // NewMemory creates a new Memory instance with the specified token limit and model.
-// It initializes the token encoder based on the model and sets up logging.
+// Tokens are counted with the tiktoken encoding of the model, or estimated when
+// the encoding is not available.
 func NewMemory(maxTokens int, model string, logger logging.Logger) (*Memory, error) {
-	encoding, err := tiktoken.EncodingForModel(model)
-	if err != nil {
-		logger.Warn("Failed to get encoding for model, defaulting to gpt-4o", "model", model, "error", err)
-		encoding, err = tiktoken.EncodingForModel("gpt-4o")
-		if err != nil {
-			return nil, fmt.Errorf("failed to get default encoding: %w", err)
-		}
-	}
+	return NewMemoryWithCounter(maxTokens, providers.NewTiktokenCounter(model), logger), nil
+}
 
+// NewMemoryWithCounter creates a new Memory instance that counts the tokens of
+// each message with counter.
+func NewMemoryWithCounter(maxTokens int, counter providers.TokenCounter, logger logging.Logger) *Memory {
 	return &Memory{
 		messages:  []MemoryMessage{},
 		maxTokens: maxTokens,
-		encoding:  encoding,
+		counter:   counter,
 		logger:    logger,
-	}, nil
+	}
+}
+
+// countTokens returns the tokens of a message, including its tool calls and
+// the message's formatting overhead.
+func (m *Memory) countTokens(message MemoryMessage) int {
+	prompt := message.PromptMessage()
+	req := &providers.Request{Messages: []providers.Message{prompt.ToMessage()}}
+	count, err := m.counter.CountTokens(context.Background(), req)
+	if err != nil {
+		m.logger.Warn("Failed to count message tokens, estimating", "error", err)
+		count, _ = providers.NewEstimatingTokenCounter(0).CountTokens(context.Background(), req)
+	}
+	return count
 }
 
 // Add appends a new message to the conversation history.
-// It automatically truncates older messages if the token limit is exceeded.
+// It automatically compacts the history if the token limit is exceeded.
 // This operation is thread-safe.
 func (m *Memory) Add(role, content string) {
-	m.mutex.Lock()
-	defer m.mutex.Unlock()
-
-	tokens := m.encoding.Encode(content, nil, nil)
-	message := MemoryMessage{Role: role, Content: content, Tokens: len(tokens)}
-	m.messages = append(m.messages, message)
-	m.totalTokens += len(tokens)
-
-	// Truncate if needed
-	m.truncateIfNeeded()
-
-	m.logger.Debug("Added message", "role", role, "tokens", len(tokens), "total_tokens", m.totalTokens)
+	m.add(context.Background(), MemoryMessage{Role: role, Content: content})
 }
 
 // AddStructured adds a pre-constructed message to the conversation history.
 // This allows adding messages with custom metadata like cache control.
-// It automatically truncates older messages if the token limit is exceeded.
+// It automatically compacts the history if the token limit is exceeded.
 // This operation is thread-safe.
 func (m *Memory) AddStructured(message MemoryMessage) {
+	m.add(context.Background(), message)
+}
+
+// AddMessage adds a prompt message, including its tool calls, to the
+// conversation history.
+// It automatically compacts the history if the token limit is exceeded.
+// This operation is thread-safe.
+func (m *Memory) AddMessage(message PromptMessage) {
+	m.add(context.Background(), NewMemoryMessage(message))
+}
+
+// SetMessageRules sets the provider's rules on message order, which compaction
+// and GetPromptMessages follow.
+func (m *Memory) SetMessageRules(rules providers.MessageRules) {
 	m.mutex.Lock()
 	defer m.mutex.Unlock()
 
+	m.rules = rules
+}
+
+// SetCompactor sets how the history is compacted when it exceeds the token
+// limit. A nil compactor restores SlidingWindowCompactor.
+func (m *Memory) SetCompactor(compactor MemoryCompactor) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	m.compactor = compactor
+}
+
+// add appends a message and compacts the history, with ctx bounding any
+// request the compactor makes. The compactor runs without holding the lock, so
+// that a summary request does not block readers; messages added meanwhile are
+// kept after its result.
+func (m *Memory) add(ctx context.Context, message MemoryMessage) {
+	m.mutex.Lock()
+
 	// If tokens aren't already calculated, calculate them
-	if message.Tokens == 0 && message.Content != "" {
-		tokens := m.encoding.Encode(message.Content, nil, nil)
-		message.Tokens = len(tokens)
+	if message.Tokens == 0 && !message.empty() {
+		message.Tokens = m.countTokens(message)
 	}
 
 	m.messages = append(m.messages, message)
 	m.totalTokens += message.Tokens
 
-	// Truncate if needed
-	m.truncateIfNeeded()
-
-	m.logger.Debug("Added structured message",
+	m.logger.Debug("Added message",
 		"role", message.Role,
 		"tokens", message.Tokens,
 		"cache_control", message.CacheControl,
 		"total_tokens", m.totalTokens)
+
+	if m.totalTokens <= m.maxTokens || m.compacting {
+		m.mutex.Unlock()
+		return
+	}
+
+	compactor := m.compactor
+	if compactor == nil {
+		compactor = SlidingWindowCompactor{}
+	}
+	snapshot := slices.Clone(m.messages)
+	generation := m.generation
+	maxTokens := m.maxTokens
+	m.compacting = true
+	m.mutex.Unlock()
+
+	compacted, err := compactor.Compact(ctx, snapshot, maxTokens)
+
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.compacting = false
+
+	switch {
+	case generation != m.generation:
+		m.logger.Debug("Memory changed while compacting, discarding the result")
+		compacted = m.messages
+	case err != nil:
+		m.logger.Warn("Failed to compact memory, dropping the oldest messages", "error", err)
+		compacted = m.messages
+	default:
+		compacted = append(compacted, m.messages[len(snapshot):]...)
+	}
+	m.applyCompaction(compacted)
 }
 
-// truncateIfNeeded truncates messages if the total token count exceeds the maxTokens.
-// This is called automatically by Add when necessary.
-func (m *Memory) truncateIfNeeded() {
-	for m.totalTokens > m.maxTokens && len(m.messages) > 1 {
-		removed := m.messages[0]
-		m.messages = m.messages[1:]
-		m.totalTokens -= removed.Tokens
-		m.logger.Debug(
-			"Removed message from memory",
-			"role",
-			removed.Role,
-			"tokens",
-			removed.Tokens,
-			"total_tokens",
-			m.totalTokens,
-		)
+// applyCompaction replaces the messages with compacted ones, dropping the
+// oldest messages if they still exceed maxTokens.
+func (m *Memory) applyCompaction(messages []MemoryMessage) {
+	for i := range messages {
+		if messages[i].Tokens == 0 && !messages[i].empty() {
+			messages[i].Tokens = m.countTokens(messages[i])
+		}
 	}
+	messages = dropOldestMessages(messages, m.maxTokens, m.rules)
+
+	m.logger.Debug("Compacted memory",
+		"messages_before", len(m.messages),
+		"messages", len(messages),
+		"total_tokens", memoryTokens(messages))
+	m.messages = messages
+	m.totalTokens = memoryTokens(messages)
+	m.generation++
 }
 
 // GetPrompt returns the full conversation history as a formatted string.
@@ -141,6 +208,24 @@ func (m *Memory) GetMessages() []MemoryMessage {
 	return messages
 }
 
+// GetPromptMessages returns the conversation history as prompt messages ready
+// to send. When the provider requires alternating roles, consecutive messages
+// of the same role are merged.
+// This operation is thread-safe.
+func (m *Memory) GetPromptMessages() []PromptMessage {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	messages := make([]PromptMessage, 0, len(m.messages))
+	for _, msg := range m.messages {
+		messages = append(messages, msg.PromptMessage())
+	}
+	if m.rules.Alternate {
+		messages = mergeConsecutiveRoles(messages)
+	}
+	return messages
+}
+
 // Clear removes all messages from memory and resets the token count.
 // This operation is thread-safe.
 func (m *Memory) Clear() {
@@ -149,9 +234,35 @@ func (m *Memory) Clear() {
 
 	m.messages = []MemoryMessage{}
 	m.totalTokens = 0
+	m.generation++
 	m.logger.Debug("Cleared memory")
 }
 
+// memoryCheckpoint is the state of memory at one point of the conversation.
+type memoryCheckpoint struct {
+	messages    []MemoryMessage
+	totalTokens int
+}
+
+// checkpoint returns the current state of memory, for restore.
+func (m *Memory) checkpoint() memoryCheckpoint {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	return memoryCheckpoint{messages: slices.Clone(m.messages), totalTokens: m.totalTokens}
+}
+
+// restore resets memory to a checkpoint, undoing the messages added since.
+func (m *Memory) restore(checkpoint memoryCheckpoint) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	m.messages = checkpoint.messages
+	m.totalTokens = checkpoint.totalTokens
+	m.generation++
+	m.logger.Debug("Restored memory", "messages", len(m.messages), "total_tokens", m.totalTokens)
+}
+
 // LLMWithMemory wraps an LLM instance with conversation memory capabilities.
 // It maintains a conversation history, automatically adding user prompts and
 // assistant responses to create context for future interactions.
@@ -183,18 +294,27 @@ func (l *LLMWithMemory) SetOption(key string, value any) {
 	l.LLM.SetOption(key, value)
 }
 
-// GenerateStream initiates a streaming response from the LLM.
+// GenerateStream initiates a streaming response from the LLM with the
+// conversation history. The prompt is added to memory when the stream starts,
+// and the streamed assistant response when the stream reaches its end. If the
+// stream fails or is closed before its end, the prompt is removed again.
 func (l *LLMWithMemory) GenerateStream(
 	ctx context.Context,
 	prompt *Prompt,
 	opts ...GenerateOption,
 ) (TokenStream, error) {
-	stream, err := l.LLM.GenerateStream(ctx, prompt, opts...)
+	checkpoint := l.memory.checkpoint()
+	for _, message := range turnMessages(prompt) {
+		l.memory.add(ctx, NewMemoryMessage(message))
+	}
+
+	stream, err := l.LLM.GenerateStream(ctx, l.historyPrompt(prompt), opts...)
 	if err != nil {
+		l.memory.restore(checkpoint)
 		return nil, fmt.Errorf("failed to start stream: %w", err)
 	}
 
-	return stream, nil
+	return &memoryStream{stream: stream, memory: l.memory, checkpoint: checkpoint}, nil
 }
 
 // NewLLMWithMemory creates a new LLM instance with memory.
@@ -206,6 +326,11 @@ func NewLLMWithMemory(llm LLM, maxTokens int, model string) (*LLMWithMemory, err
 	if err != nil {
 		return nil, err
 	}
+	if impl, ok := llm.(*LLMImpl); ok {
+		// Count like the pre-flight check, with the provider's calibrated counter.
+		memory.counter = impl.localTokenCounter()
+		memory.rules = providers.GetMessageRules(impl.Provider, impl.config.Model)
+	}
 
 	return &LLMWithMemory{
 		LLM:                   llm,
@@ -221,62 +346,40 @@ func (l *LLMWithMemory) Generate(
 	prompt *Prompt,
 	opts ...GenerateOption,
 ) (*providers.Response, error) {
-	l.memory.Add("user", prompt.Input)
-
-	var response *providers.Response
-	var err error
-
-	if l.useStructuredMessages {
-		// Get structured messages from memory
-		messages := l.memory.GetMessages()
-
-		// Make a copy of the original prompt with empty input
-		// (since content will be in structured messages)
-		emptyPrompt := &Prompt{
-			SystemPrompt: prompt.SystemPrompt,
-			Tools:        prompt.Tools,
-			ToolChoice:   prompt.ToolChoice,
-			Input:        "", // Empty as content is in messages
-		}
-
-		// Add structured messages to the options
-		withMessages := func(_ *GenerateConfig) {
-			// We're simply passing this function to keep the original options
-			// The structured messages will be added in the WithOption call
-		}
-
-		// Set structured messages option
-		l.LLM.SetOption("structured_messages", messages)
-
-		// Generate with structured messages
-		response, err = l.LLM.Generate(ctx, emptyPrompt, append(opts, withMessages)...)
-
-		// Remove the structured messages option after use
-		l.LLM.SetOption("structured_messages", nil)
-	} else {
-		// Fallback to traditional flattened prompt approach
-		fullPrompt := l.memory.GetPrompt()
-
-		// Create a new Prompt with the full memory context
-		memoryPrompt := &Prompt{
-			SystemPrompt: prompt.SystemPrompt,
-			Tools:        prompt.Tools,
-			ToolChoice:   prompt.ToolChoice,
-			Input:        fullPrompt,
-		}
-
-		response, err = l.LLM.Generate(ctx, memoryPrompt, opts...)
+	for _, message := range turnMessages(prompt) {
+		l.memory.add(ctx, NewMemoryMessage(message))
 	}
 
+	response, err := l.LLM.Generate(ctx, l.historyPrompt(prompt), opts...)
 	if err != nil {
 		return nil, fmt.Errorf("failed to generate response: %w", err)
 	}
 
-	// Add assistant response to memory
-	l.memory.Add("assistant", response.AsText())
+	// Add assistant response, with any tool calls, to memory
+	l.memory.add(ctx, responseMemoryMessage(response))
 	return response, nil
 }
 
+// historyPrompt returns prompt with the conversation history in place of its
+// input, as structured messages or flattened into a single text.
+func (l *LLMWithMemory) historyPrompt(prompt *Prompt) *Prompt {
+	if l.useStructuredMessages {
+		// Send the history as messages, keeping tool calls and cache types
+		withHistory := *prompt
+		withHistory.Input = ""
+		withHistory.Messages = l.memory.GetPromptMessages()
+		return &withHistory
+	}
+
+	// Fallback to traditional flattened prompt approach
+	return &Prompt{
+		SystemPrompt: prompt.SystemPrompt,
+		Tools:        prompt.Tools,
+		ToolChoice:   prompt.ToolChoice,
+		Input:        l.memory.GetPrompt(),
+	}
+}
+
 // SetUseStructuredMessages configures whether to use structured messages.
 // When enabled, messages are passed to the provider as structured objects.
 // When disabled, messages are flattened into a single text prompt.
@@ -287,6 +390,18 @@ func (l *LLMWithMemory) SetUseStructuredMessages(use bool) {
 	l.useStructuredMessages = use
 }
 
+// AddMessage adds a prompt message, including its tool calls, to memory. Use
+// it to record tool results before the next Generate call.
+func (l *LLMWithMemory) AddMessage(message PromptMessage) {
+	l.memory.AddMessage(message)
+}
+
+// SetMemoryCompactor sets how the conversation history is compacted when it
+// exceeds the token limit, for example with a SummarizingCompactor.
+func (l *LLMWithMemory) SetMemoryCompactor(compactor MemoryCompactor) {
+	l.memory.SetCompactor(compactor)
+}
+
 // ClearMemory removes all messages from the conversation history.
 func (l *LLMWithMemory) ClearMemory() {
 	l.memory.Clear()
@@ -323,12 +438,93 @@ func (l *LLMWithMemory) AddStructuredMessage(role, content, cacheControl string)
 }
 
 // MemoryMessage represents a single message in the conversation history.
-// It includes the role of the speaker, the content of the message,
-// and the number of tokens in the message for efficient memory management.
+// It keeps the full structure of a PromptMessage, including tool calls, and
+// the number of tokens in the message for efficient memory management.
+// Pinned messages are never dropped when memory is compacted.
 type MemoryMessage struct {
-	Metadata     map[string]any
-	Role         string
-	Content      string
-	CacheControl string
-	Tokens       int
+	Metadata     map[string]any `json:"metadata,omitempty"`
+	Role         string         `json:"role"`
+	Content      string         `json:"content"`
+	CacheControl string         `json:"cache_control,omitempty"`
+	Name         string         `json:"name,omitempty"`
+	ToolCallID   string         `json:"tool_call_id,omitempty"`
+	ToolCalls    []ToolCall     `json:"tool_calls,omitempty"`
+	Tokens       int            `json:"tokens,omitempty"`
+	Pinned       bool           `json:"pinned,omitempty"`
+}
+
+// NewMemoryMessage creates a memory message from a prompt message.
+func NewMemoryMessage(message PromptMessage) MemoryMessage {
+	return MemoryMessage{
+		Role:         message.Role,
+		Content:      message.Content,
+		CacheControl: string(message.CacheType),
+		Name:         message.Name,
+		ToolCallID:   message.ToolCallID,
+		ToolCalls:    message.ToolCalls,
+	}
+}
+
+// PromptMessage returns the message as a prompt message.
+func (m MemoryMessage) PromptMessage() PromptMessage {
+	return PromptMessage{
+		Role:       m.Role,
+		Content:    m.Content,
+		CacheType:  CacheType(m.CacheControl),
+		Name:       m.Name,
+		ToolCallID: m.ToolCallID,
+		ToolCalls:  m.ToolCalls,
+	}
+}
+
+// isToolResult reports whether the message answers a tool call.
+func (m MemoryMessage) isToolResult() bool {
+	return m.Role == "tool" || m.ToolCallID != ""
+}
+
+func (m MemoryMessage) empty() bool {
+	return m.Content == "" && len(m.ToolCalls) == 0
+}
+
+// turnMessages returns the messages a prompt adds to a conversation: its
+// Messages, or its Input as a user message.
+func turnMessages(prompt *Prompt) []PromptMessage {
+	if len(prompt.Messages) > 0 {
+		return prompt.Messages
+	}
+	return []PromptMessage{{Role: "user", Content: prompt.Input}}
+}
+
+// responseMemoryMessage returns the assistant message of a response.
+func responseMemoryMessage(response *providers.Response) MemoryMessage {
+	message := MemoryMessage{Role: "assistant", Content: response.AsText()}
+	for _, call := range response.ToolCalls {
+		toolCall := ToolCall{ID: call.ID, Type: call.Type}
+		toolCall.Function.Name = call.Function.Name
+		toolCall.Function.Arguments = call.Function.Arguments
+		message.ToolCalls = append(message.ToolCalls, toolCall)
+	}
+	return message
+}
+
+// mergeConsecutiveRoles merges consecutive text messages of the same role, so
+// that user and assistant turns alternate. Tool calls and results are kept as
+// they are.
+func mergeConsecutiveRoles(messages []PromptMessage) []PromptMessage {
+	merged := make([]PromptMessage, 0, len(messages))
+	for _, msg := range messages {
+		if n := len(merged); n > 0 && mergeable(merged[n-1]) && mergeable(msg) && merged[n-1].Role == msg.Role {
+			merged[n-1].Content += "\n\n" + msg.Content
+			if msg.CacheType != "" {
+				merged[n-1].CacheType = msg.CacheType
+			}
+			continue
+		}
+		merged = append(merged, msg)
+	}
+	return merged
+}
+
+func mergeable(msg PromptMessage) bool {
+	return len(msg.ToolCalls) == 0 && msg.ToolCallID == "" && msg.Role != "tool"
 }
@@ -0,0 +1,204 @@
+package llm
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/weave-labs/gollm/providers"
+)
+
+const (
+	// memorySummaryKey marks the summary message in MemoryMessage.Metadata.
+	memorySummaryKey = "memory_summary"
+
+	// memorySummaryPrefix introduces the summary to the model.
+	memorySummaryPrefix = "Summary of the earlier conversation:\n"
+
+	// summaryTargetRatio is the share of the token limit that messages are
+	// summarized down to, leaving room for the conversation to grow before
+	// the next summary.
+	summaryTargetRatio = 0.5
+
+	summaryInstructions = "You summarize conversations for an assistant that will continue them. " +
+		"Merge the existing summary and the new messages into a single summary. Keep names, numbers, " +
+		"decisions, commitments, open questions and the user's stated goals and preferences. " +
+		"Leave out greetings and small talk. Reply with the summary only."
+)
+
+// MemoryCompactor reduces conversation memory that exceeds its token limit.
+// Memory calls Compact without holding its lock and with a copy of its
+// messages. Messages added while Compact runs are kept after its result, and
+// the result is discarded if memory is cleared meanwhile.
+type MemoryCompactor interface {
+	// Compact returns messages reduced towards maxTokens without modifying
+	// messages. New messages may leave Tokens zero to have Memory count them.
+	// If the result still exceeds maxTokens, Memory drops its oldest messages.
+	Compact(ctx context.Context, messages []MemoryMessage, maxTokens int) ([]MemoryMessage, error)
+}
+
+// SlidingWindowCompactor drops the oldest messages until memory fits its limit.
+// System and pinned messages and the latest turn are never dropped, and tool
+// calls are dropped together with their results. It is the default compactor
+// of Memory.
+type SlidingWindowCompactor struct {
+	// Rules are the provider's rules on message order. Memory applies its
+	// own rules after any compactor, so they are only needed when the
+	// compactor is used on its own.
+	Rules providers.MessageRules
+}
+
+// Compact implements MemoryCompactor.
+func (c SlidingWindowCompactor) Compact(_ context.Context, messages []MemoryMessage, maxTokens int) ([]MemoryMessage, error) {
+	return dropOldestMessages(messages, maxTokens, c.Rules), nil
+}
+
+// SummarizingCompactor replaces the oldest messages with a summary written by
+// an LLM, so that long conversations keep the facts a sliding window would
+// drop. The summary is stored as a pinned user message at the start of memory
+// and is merged into the next summary when memory fills up again.
+type SummarizingCompactor struct {
+	summarizer LLM
+}
+
+// NewSummarizingCompactor creates a compactor that writes summaries with
+// summarizer, which may use a cheaper model than the conversation. The
+// summarizer should not be the conversation's LLM: Generate keeps the system
+// prompt of the summary request as an option of the LLM it is sent with.
+func NewSummarizingCompactor(summarizer LLM) *SummarizingCompactor {
+	return &SummarizingCompactor{summarizer: summarizer}
+}
+
+// Compact implements MemoryCompactor. It summarizes the oldest unpinned
+// messages until the rest take at most half of maxTokens. Tool calls are
+// summarized together with their results.
+func (s *SummarizingCompactor) Compact(
+	ctx context.Context,
+	messages []MemoryMessage,
+	maxTokens int,
+) ([]MemoryMessage, error) {
+	target := int(float64(maxTokens) * summaryTargetRatio)
+	total := memoryTokens(messages)
+
+	var previous string
+	var span, kept []MemoryMessage
+	groups := messageGroups(messages)
+	for i, group := range groups {
+		switch {
+		case len(group) == 1 && IsMemorySummary(group[0]):
+			previous = strings.TrimPrefix(group[0].Content, memorySummaryPrefix)
+			total -= group[0].Tokens
+		case total > target && !keepGroup(group) && i < len(groups)-1:
+			span = append(span, group...)
+			total -= memoryTokens(group)
+		default:
+			kept = append(kept, group...)
+		}
+	}
+	if len(span) == 0 {
+		return messages, nil
+	}
+
+	response, err := s.summarizer.Generate(ctx, &Prompt{
+		SystemPrompt: summaryInstructions,
+		Input:        summaryInput(previous, span),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to summarize memory: %w", err)
+	}
+
+	summary := MemoryMessage{
+		Role:     "user",
+		Content:  memorySummaryPrefix + strings.TrimSpace(response.AsText()),
+		Pinned:   true,
+		Metadata: map[string]any{memorySummaryKey: true},
+	}
+	return append([]MemoryMessage{summary}, kept...), nil
+}
+
+// IsMemorySummary reports whether msg is a summary written by a
+// SummarizingCompactor.
+func IsMemorySummary(msg MemoryMessage) bool {
+	summary, _ := msg.Metadata[memorySummaryKey].(bool)
+	return summary
+}
+
+// summaryInput formats the previous summary and the messages to summarize.
+func summaryInput(previous string, span []MemoryMessage) string {
+	var b strings.Builder
+	if previous != "" {
+		b.WriteString("Existing summary:\n")
+		b.WriteString(previous)
+		b.WriteString("\n\n")
+	}
+	b.WriteString("New messages:\n")
+	for _, msg := range span {
+		if msg.Content != "" {
+			fmt.Fprintf(&b, "%s: %s\n", msg.Role, msg.Content)
+		}
+		for _, call := range msg.ToolCalls {
+			fmt.Fprintf(&b, "%s called %s(%s)\n", msg.Role, call.Function.Name, call.Function.Arguments)
+		}
+	}
+	return b.String()
+}
+
+// dropOldestMessages drops the oldest messages until messages fit maxTokens.
+// System and pinned messages and the latest turn are never dropped, and an
+// assistant message with tool calls is dropped together with their results.
+// When rules require the conversation to start with a user message, leading
+// assistant turns and tool results are dropped as well.
+func dropOldestMessages(messages []MemoryMessage, maxTokens int, rules providers.MessageRules) []MemoryMessage {
+	total := memoryTokens(messages)
+	groups := messageGroups(messages)
+	kept := make([]MemoryMessage, 0, len(messages))
+	leading := true
+	for i, group := range groups {
+		droppable := !keepGroup(group) && i < len(groups)-1
+		if droppable && (total > maxTokens || (rules.FirstUser && leading && group[0].Role != "user")) {
+			total -= memoryTokens(group)
+			continue
+		}
+		if group[0].Role != "system" {
+			leading = false
+		}
+		kept = append(kept, group...)
+	}
+	return kept
+}
+
+// messageGroups splits messages into the groups that are kept or dropped
+// together: an assistant message with tool calls and the tool results that
+// follow it, or a single message.
+func messageGroups(messages []MemoryMessage) [][]MemoryMessage {
+	var groups [][]MemoryMessage
+	start := 0
+	for i := 1; i <= len(messages); i++ {
+		if i < len(messages) && messages[i].isToolResult() && len(messages[start].ToolCalls) > 0 {
+			continue
+		}
+		groups = append(groups, messages[start:i])
+		start = i
+	}
+	return groups
+}
+
+// keepGroup reports whether a group holds a system or pinned message, which
+// compaction never drops.
+func keepGroup(group []MemoryMessage) bool {
+	for _, msg := range group {
+		if msg.Pinned || msg.Role == "system" {
+			return true
+		}
+	}
+	return false
+}
+
+// memoryTokens returns the total tokens of messages.
+func memoryTokens(messages []MemoryMessage) int {
+	total := 0
+	for _, msg := range messages {
+		total += msg.Tokens
+	}
+	return total
+}
@@ -0,0 +1,57 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"io"
+	"sync"
+)
+
+// memoryStream wraps the stream of an LLMWithMemory. It records the streamed
+// assistant response, with any tool calls, when the stream reaches its end,
+// and restores memory to its checkpoint if the stream fails or is closed
+// before then.
+type memoryStream struct {
+	stream     TokenStream
+	memory     *Memory
+	checkpoint memoryCheckpoint
+	acc        StreamAccumulator
+	mutex      sync.Mutex
+	done       bool
+}
+
+// Next implements TokenStream.
+func (s *memoryStream) Next(ctx context.Context) (*StreamToken, error) {
+	token, err := s.stream.Next(ctx)
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if s.done {
+		return token, err
+	}
+	switch {
+	case errors.Is(err, io.EOF):
+		s.done = true
+		s.memory.add(ctx, responseMemoryMessage(s.acc.Response()))
+	case err != nil:
+		s.done = true
+		s.memory.restore(s.checkpoint)
+	default:
+		s.acc.Add(token)
+	}
+	return token, err
+}
+
+// Close implements TokenStream. Closing the stream before its end removes the
+// prompt from memory.
+func (s *memoryStream) Close() error {
+	s.mutex.Lock()
+	if !s.done {
+		s.done = true
+		s.memory.restore(s.checkpoint)
+	}
+	s.mutex.Unlock()
+
+	return s.stream.Close()
+}
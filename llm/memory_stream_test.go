@@ -0,0 +1,91 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/weave-labs/gollm/internal/logging"
+	"github.com/weave-labs/gollm/providers"
+)
+
+// streamingLLM answers GenerateStream calls with a fixed stream and records the prompts.
+type streamingLLM struct {
+	LLM
+	stream  TokenStream
+	prompts []*Prompt
+}
+
+func (s *streamingLLM) GenerateStream(_ context.Context, prompt *Prompt, _ ...GenerateOption) (TokenStream, error) {
+	s.prompts = append(s.prompts, prompt)
+	return s.stream, nil
+}
+
+func newStreamingMemoryLLM(stream TokenStream) (*LLMWithMemory, *streamingLLM) {
+	base := &streamingLLM{stream: stream}
+	memory := NewMemoryWithCounter(1000, providers.NewEstimatingTokenCounter(0), logging.NewLogger(logging.LogLevelError))
+	memory.Add("user", "Hi")
+	memory.Add("assistant", "Hello!")
+	return &LLMWithMemory{LLM: base, memory: memory, useStructuredMessages: true}, base
+}
+
+func TestLLMWithMemoryGenerateStreamRecordsResponse(t *testing.T) {
+	l, base := newStreamingMemoryLLM(&sliceStream{tokens: []*StreamToken{
+		{Text: "Let me "},
+		{Text: "check."},
+		{ToolCalls: []providers.ToolCall{{
+			ID: "call_1", Type: "function",
+			Function: providers.FunctionCall{Name: "lookup", Arguments: []byte(`{"q":"go"}`)},
+		}}},
+	}})
+
+	stream, err := l.GenerateStream(context.Background(), NewPrompt("Search for go"))
+	require.NoError(t, err)
+	require.Len(t, base.prompts, 1)
+	assert.Len(t, base.prompts[0].Messages, 3, "the history is sent with the prompt")
+	assert.Len(t, l.GetMemory(), 3, "the prompt is recorded when the stream starts")
+
+	resp, err := CollectStream(context.Background(), stream, nil)
+	require.NoError(t, err)
+	assert.Equal(t, "Let me check.", resp.AsText())
+
+	messages := l.GetMemory()
+	require.Len(t, messages, 4)
+	assert.Equal(t, "assistant", messages[3].Role)
+	assert.Equal(t, "Let me check.", messages[3].Content)
+	require.Len(t, messages[3].ToolCalls, 1)
+	assert.Equal(t, "lookup", messages[3].ToolCalls[0].Function.Name)
+}
+
+func TestLLMWithMemoryGenerateStreamRollsBack(t *testing.T) {
+	t.Run("stream error", func(t *testing.T) {
+		l, _ := newStreamingMemoryLLM(&sliceStream{
+			tokens: []*StreamToken{{Text: "Partial"}},
+			err:    errors.New("connection reset"),
+		})
+
+		stream, err := l.GenerateStream(context.Background(), NewPrompt("Tell me more"))
+		require.NoError(t, err)
+		_, err = CollectStream(context.Background(), stream, nil)
+		require.Error(t, err)
+
+		assert.Len(t, l.GetMemory(), 2, "the prompt is removed")
+	})
+
+	t.Run("closed early", func(t *testing.T) {
+		inner := &sliceStream{tokens: []*StreamToken{{Text: "One"}, {Text: "Two"}}}
+		l, _ := newStreamingMemoryLLM(inner)
+
+		stream, err := l.GenerateStream(context.Background(), NewPrompt("Count"))
+		require.NoError(t, err)
+		_, err = stream.Next(context.Background())
+		require.NoError(t, err)
+		require.NoError(t, stream.Close())
+
+		assert.True(t, inner.closed)
+		assert.Len(t, l.GetMemory(), 2, "the prompt is removed")
+	})
+}
@@ -2,6 +2,7 @@ package llm
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"testing"
 
@@ -12,6 +13,7 @@ import (
 
 	"github.com/weave-labs/gollm/config"
 	"github.com/weave-labs/gollm/internal/logging"
+	"github.com/weave-labs/gollm/providers"
 )
 
 // MockProvider implements a simple mock provider for testing
@@ -286,3 +288,167 @@ func TestCachingBenefit(t *testing.T) {
 	t.Logf("Speedup: %.2fx", float64(firstRunDuration)/float64(secondRunDuration))
 }
 */
+
+func TestSlidingWindowKeepsPinnedMessages(t *testing.T) {
+	memory := NewMemoryWithCounter(30, providers.NewEstimatingTokenCounter(0), logging.NewLogger(logging.LogLevelError))
+	memory.AddStructured(MemoryMessage{Role: "user", Content: "Pinned", Tokens: 10, Pinned: true})
+	memory.AddStructured(MemoryMessage{Role: "user", Content: "First", Tokens: 10})
+	memory.AddStructured(MemoryMessage{Role: "assistant", Content: "Second", Tokens: 10})
+	memory.AddStructured(MemoryMessage{Role: "user", Content: "Third", Tokens: 10})
+
+	messages := memory.GetMessages()
+	require.Len(t, messages, 3)
+	assert.Equal(t, "Pinned", messages[0].Content)
+	assert.Equal(t, "Second", messages[1].Content)
+	assert.Equal(t, "Third", messages[2].Content)
+}
+
+func TestSummarizingCompactor(t *testing.T) {
+	summarizer := &scriptedLLM{replies: []string{"The user is Ada.", "The user is Ada and likes tea."}}
+	memory := NewMemoryWithCounter(80, providers.NewEstimatingTokenCounter(0), logging.NewLogger(logging.LogLevelError))
+	memory.SetCompactor(NewSummarizingCompactor(summarizer))
+
+	for _, content := range []string{"I am Ada", "Hello Ada", "What is my name?", "Ada"} {
+		memory.AddStructured(MemoryMessage{Role: "user", Content: content, Tokens: 20})
+	}
+	require.Empty(t, summarizer.prompts, "memory within its limit is not summarized")
+
+	memory.AddStructured(MemoryMessage{Role: "user", Content: "I like tea", Tokens: 20})
+	require.Len(t, summarizer.prompts, 1)
+	assert.Equal(t, "New messages:\nuser: I am Ada\nuser: Hello Ada\nuser: What is my name?\n", summarizer.prompts[0].Input)
+
+	messages := memory.GetMessages()
+	require.Len(t, messages, 3)
+	assert.True(t, IsMemorySummary(messages[0]))
+	assert.True(t, messages[0].Pinned)
+	assert.Equal(t, "Summary of the earlier conversation:\nThe user is Ada.", messages[0].Content)
+	assert.Positive(t, messages[0].Tokens, "the summary is counted")
+	assert.Equal(t, "I like tea", messages[2].Content)
+
+	memory.AddStructured(MemoryMessage{Role: "user", Content: "Noted", Tokens: 20})
+	require.Len(t, summarizer.prompts, 2)
+	assert.Contains(t, summarizer.prompts[1].Input, "Existing summary:\nThe user is Ada.\n\nNew messages:\nuser: Ada\n")
+
+	messages = memory.GetMessages()
+	assert.Equal(t, "Summary of the earlier conversation:\nThe user is Ada and likes tea.", messages[0].Content)
+	assert.Equal(t, "Noted", messages[len(messages)-1].Content)
+}
+
+func TestSummarizingCompactorFailureDropsMessages(t *testing.T) {
+	summarizer := &scriptedLLM{err: errors.New("unavailable")}
+	memory := NewMemoryWithCounter(20, providers.NewEstimatingTokenCounter(0), logging.NewLogger(logging.LogLevelError))
+	memory.SetCompactor(NewSummarizingCompactor(summarizer))
+
+	for _, content := range []string{"One", "Two", "Three"} {
+		memory.AddStructured(MemoryMessage{Role: "user", Content: content, Tokens: 10})
+	}
+
+	messages := memory.GetMessages()
+	require.Len(t, messages, 2)
+	assert.Equal(t, "Two", messages[0].Content)
+}
+
+// blockingCompactor keeps the last message and waits for release before
+// returning.
+type blockingCompactor struct {
+	started chan struct{}
+	release chan struct{}
+}
+
+func (c blockingCompactor) Compact(_ context.Context, messages []MemoryMessage, _ int) ([]MemoryMessage, error) {
+	close(c.started)
+	<-c.release
+	return messages[len(messages)-1:], nil
+}
+
+func TestCompactionRunsOutsideTheLock(t *testing.T) {
+	compactor := blockingCompactor{started: make(chan struct{}), release: make(chan struct{})}
+	memory := NewMemoryWithCounter(20, providers.NewEstimatingTokenCounter(0), logging.NewLogger(logging.LogLevelError))
+	memory.SetCompactor(compactor)
+	memory.AddStructured(MemoryMessage{Role: "user", Content: "One", Tokens: 10})
+	memory.AddStructured(MemoryMessage{Role: "assistant", Content: "Two", Tokens: 10})
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		memory.AddStructured(MemoryMessage{Role: "user", Content: "Three", Tokens: 10})
+	}()
+	<-compactor.started
+
+	assert.Len(t, memory.GetMessages(), 3, "readers are not blocked by the compactor")
+	memory.AddStructured(MemoryMessage{Role: "assistant", Content: "Four", Tokens: 5})
+
+	close(compactor.release)
+	<-done
+
+	messages := memory.GetMessages()
+	require.Len(t, messages, 2)
+	assert.Equal(t, "Three", messages[0].Content)
+	assert.Equal(t, "Four", messages[1].Content, "messages added while compacting are kept")
+}
+
+func TestCompactionIsDiscardedAfterClear(t *testing.T) {
+	compactor := blockingCompactor{started: make(chan struct{}), release: make(chan struct{})}
+	memory := NewMemoryWithCounter(10, providers.NewEstimatingTokenCounter(0), logging.NewLogger(logging.LogLevelError))
+	memory.SetCompactor(compactor)
+	memory.AddStructured(MemoryMessage{Role: "user", Content: "One", Tokens: 10})
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		memory.AddStructured(MemoryMessage{Role: "assistant", Content: "Two", Tokens: 10})
+	}()
+	<-compactor.started
+	memory.Clear()
+	close(compactor.release)
+	<-done
+
+	assert.Empty(t, memory.GetMessages())
+}
+
+func TestSlidingWindowKeepsToolCallPairs(t *testing.T) {
+	call := ToolCall{ID: "call_1", Type: "function"}
+	call.Function.Name = "get_weather"
+	call.Function.Arguments = json.RawMessage(`{"city":"Paris"}`)
+
+	memory := NewMemoryWithCounter(40, providers.NewEstimatingTokenCounter(0), logging.NewLogger(logging.LogLevelError))
+	memory.AddStructured(MemoryMessage{Role: "system", Content: "Be brief", Tokens: 5})
+	memory.AddStructured(MemoryMessage{Role: "user", Content: "Weather in Paris?", Tokens: 10})
+	memory.AddStructured(MemoryMessage{Role: "assistant", ToolCalls: []ToolCall{call}, Tokens: 10})
+	memory.AddStructured(MemoryMessage{Role: "tool", ToolCallID: "call_1", Content: "Sunny", Tokens: 10})
+	memory.AddStructured(MemoryMessage{Role: "assistant", Content: "It is sunny.", Tokens: 10})
+	memory.AddStructured(MemoryMessage{Role: "user", Content: "Thanks", Tokens: 10})
+
+	messages := memory.GetMessages()
+	require.Len(t, messages, 3, "the tool call is dropped together with its result")
+	assert.Equal(t, "system", messages[0].Role)
+	assert.Equal(t, "It is sunny.", messages[1].Content)
+	assert.Equal(t, "Thanks", messages[2].Content)
+}
+
+func TestSlidingWindowStartsWithUserWhenRequired(t *testing.T) {
+	memory := NewMemoryWithCounter(25, providers.NewEstimatingTokenCounter(0), logging.NewLogger(logging.LogLevelError))
+	memory.SetMessageRules(providers.MessageRules{FirstUser: true, Alternate: true})
+	memory.AddStructured(MemoryMessage{Role: "user", Content: "One", Tokens: 10})
+	memory.AddStructured(MemoryMessage{Role: "assistant", Content: "Two", Tokens: 10})
+	memory.AddStructured(MemoryMessage{Role: "user", Content: "Three", Tokens: 10})
+
+	messages := memory.GetMessages()
+	require.Len(t, messages, 1, "the leading assistant message is dropped")
+	assert.Equal(t, "Three", messages[0].Content)
+}
+
+func TestGetPromptMessagesMergesRolesWhenAlternating(t *testing.T) {
+	memory := NewMemoryWithCounter(1000, providers.NewEstimatingTokenCounter(0), logging.NewLogger(logging.LogLevelError))
+	memory.AddMessage(PromptMessage{Role: "user", Content: "Hello"})
+	memory.AddMessage(PromptMessage{Role: "user", Content: "Are you there?", CacheType: CacheTypeEphemeral})
+	memory.AddMessage(PromptMessage{Role: "assistant", Content: "Yes"})
+
+	assert.Len(t, memory.GetPromptMessages(), 3)
+
+	memory.SetMessageRules(providers.MessageRules{Alternate: true})
+	messages := memory.GetPromptMessages()
+	require.Len(t, messages, 2)
+	assert.Equal(t, "Hello\n\nAre you there?", messages[0].Content)
+	assert.Equal(t, CacheTypeEphemeral, messages[0].CacheType)
+}
@@ -0,0 +1,338 @@
+package llm
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// ErrInvalidPartialJSON is returned when streamed output cannot be the prefix of a JSON document.
+var ErrInvalidPartialJSON = errors.New("invalid partial JSON")
+
+// partialFrame tracks one open object or array while scanning partial JSON.
+type partialFrame struct {
+	key         string
+	index       int
+	kind        byte
+	expectKey   bool
+	expectColon bool
+	expectComma bool
+}
+
+// PartialJSONParser incrementally scans a JSON document that arrives in fragments,
+// such as a structured response being streamed token by token. At any point it can
+// produce a syntactically complete snapshot of the document so far by dropping
+// incomplete keys and literals and closing open strings, arrays and objects.
+//
+// Text before the first '{' or '[' (for example a Markdown code fence) and text
+// after the root value is complete are ignored. The zero value is ready to use.
+type PartialJSONParser struct {
+	err          error
+	buf          []byte
+	stack        []partialFrame
+	completed    []string
+	safeClosers  string
+	pos          int
+	safeEnd      int
+	rootStart    int
+	rootEnd      int
+	stringStart  int
+	literalStart int
+	started      bool
+	done         bool
+	inString     bool
+	escaped      bool
+	stringIsKey  bool
+	inLiteral    bool
+}
+
+// Write appends a fragment of the document and scans it.
+// It returns ErrInvalidPartialJSON once the input can no longer be valid JSON.
+func (p *PartialJSONParser) Write(fragment []byte) (int, error) {
+	if p.err != nil {
+		return 0, p.err
+	}
+	p.buf = append(p.buf, fragment...)
+	for p.pos < len(p.buf) && !p.done {
+		if err := p.scan(p.buf[p.pos]); err != nil {
+			p.err = fmt.Errorf("%w at offset %d: %w", ErrInvalidPartialJSON, p.pos, err)
+			return len(fragment), p.err
+		}
+		p.pos++
+	}
+	return len(fragment), nil
+}
+
+// WriteString is like Write but takes a string.
+func (p *PartialJSONParser) WriteString(fragment string) (int, error) {
+	return p.Write([]byte(fragment))
+}
+
+// Done reports whether the root value has been closed.
+func (p *PartialJSONParser) Done() bool {
+	return p.done
+}
+
+// Raw returns the input received so far, including any text around the root value.
+func (p *PartialJSONParser) Raw() []byte {
+	return p.buf
+}
+
+// Completed returns the JSON pointers (RFC 6901) of values that were completed
+// since the previous call, in the order they were closed. Nested values are
+// reported before their parents; the root value itself is not reported.
+func (p *PartialJSONParser) Completed() []string {
+	completed := p.completed
+	p.completed = nil
+	return completed
+}
+
+// Snapshot returns a valid JSON document holding everything parsed so far, or nil
+// if no root value has started. A string value that is still being streamed is
+// included with its text so far; keys, numbers and literals appear once complete.
+func (p *PartialJSONParser) Snapshot() []byte {
+	if !p.started {
+		return nil
+	}
+	if p.done {
+		return p.buf[p.rootStart:p.rootEnd]
+	}
+
+	if p.inString && !p.stringIsKey {
+		text := trimIncompleteString(p.buf[p.stringStart+1:], p.escaped)
+		out := make([]byte, 0, len(p.buf)-p.rootStart+len(p.stack)+1)
+		out = append(out, p.buf[p.rootStart:p.stringStart+1]...)
+		out = append(out, text...)
+		out = append(out, '"')
+		return append(out, closers(p.stack)...)
+	}
+
+	out := make([]byte, 0, p.safeEnd-p.rootStart+len(p.safeClosers))
+	out = append(out, p.buf[p.rootStart:p.safeEnd]...)
+	return append(out, p.safeClosers...)
+}
+
+// scan advances the parser state by one byte.
+func (p *PartialJSONParser) scan(c byte) error {
+	if !p.started {
+		if c == '{' || c == '[' {
+			p.started = true
+			p.rootStart = p.pos
+			p.push(c)
+		}
+		return nil
+	}
+
+	if p.inString {
+		p.scanString(c)
+		return nil
+	}
+
+	if p.inLiteral {
+		if isLiteralByte(c) {
+			return nil
+		}
+		if err := p.endLiteral(); err != nil {
+			return err
+		}
+	}
+
+	switch c {
+	case ' ', '\t', '\n', '\r':
+		return nil
+	case '{', '[':
+		if err := p.beginValue(); err != nil {
+			return err
+		}
+		p.push(c)
+	case '}', ']':
+		return p.pop(c)
+	case ',':
+		top := &p.stack[len(p.stack)-1]
+		if !top.expectComma {
+			return errors.New("unexpected ','")
+		}
+		top.expectComma = false
+		if top.kind == '{' {
+			top.expectKey = true
+		} else {
+			top.index++
+		}
+	case ':':
+		top := &p.stack[len(p.stack)-1]
+		if !top.expectColon {
+			return errors.New("unexpected ':'")
+		}
+		top.expectColon = false
+	case '"':
+		top := p.stack[len(p.stack)-1]
+		p.stringIsKey = top.kind == '{' && top.expectKey
+		if !p.stringIsKey {
+			if err := p.beginValue(); err != nil {
+				return err
+			}
+		}
+		p.inString = true
+		p.stringStart = p.pos
+	default:
+		if c != '-' && (c < '0' || c > '9') && c != 't' && c != 'f' && c != 'n' {
+			return fmt.Errorf("unexpected character %q", c)
+		}
+		if err := p.beginValue(); err != nil {
+			return err
+		}
+		p.inLiteral = true
+		p.literalStart = p.pos
+	}
+	return nil
+}
+
+// scanString consumes one byte inside a string.
+func (p *PartialJSONParser) scanString(c byte) {
+	switch {
+	case p.escaped:
+		p.escaped = false
+	case c == '\\':
+		p.escaped = true
+	case c == '"':
+		p.inString = false
+		if !p.stringIsKey {
+			p.endValue(p.pos + 1)
+			return
+		}
+		top := &p.stack[len(p.stack)-1]
+		if err := json.Unmarshal(p.buf[p.stringStart:p.pos+1], &top.key); err != nil {
+			top.key = string(p.buf[p.stringStart+1 : p.pos])
+		}
+		top.expectKey = false
+		top.expectColon = true
+	}
+}
+
+// beginValue checks that a value may start at the current position.
+func (p *PartialJSONParser) beginValue() error {
+	top := p.stack[len(p.stack)-1]
+	switch {
+	case top.kind == '{' && top.expectKey:
+		return errors.New("expected object key")
+	case top.expectColon:
+		return errors.New("expected ':'")
+	case top.expectComma:
+		return errors.New("expected ','")
+	}
+	return nil
+}
+
+// endLiteral completes the number or literal that ends at the current position.
+func (p *PartialJSONParser) endLiteral() error {
+	p.inLiteral = false
+	if !json.Valid(p.buf[p.literalStart:p.pos]) {
+		return fmt.Errorf("invalid literal %q", p.buf[p.literalStart:p.pos])
+	}
+	p.endValue(p.pos)
+	return nil
+}
+
+func (p *PartialJSONParser) push(kind byte) {
+	p.stack = append(p.stack, partialFrame{kind: kind, expectKey: kind == '{'})
+	p.markSafe(p.pos + 1)
+}
+
+func (p *PartialJSONParser) pop(c byte) error {
+	top := p.stack[len(p.stack)-1]
+	if (c == '}') != (top.kind == '{') || top.expectColon || (top.kind == '{' && !top.expectKey && !top.expectComma) {
+		return fmt.Errorf("unexpected %q", c)
+	}
+	p.stack = p.stack[:len(p.stack)-1]
+	if len(p.stack) == 0 {
+		p.done = true
+		p.rootEnd = p.pos + 1
+		p.markSafe(p.pos + 1)
+		return nil
+	}
+	p.endValue(p.pos + 1)
+	return nil
+}
+
+// endValue records the value that was just completed and makes end a safe cut point.
+func (p *PartialJSONParser) endValue(end int) {
+	p.stack[len(p.stack)-1].expectComma = true
+	p.completed = append(p.completed, p.pointer())
+	p.markSafe(end)
+}
+
+// markSafe records end as the last position at which the document can be cut and
+// closed to form valid JSON.
+func (p *PartialJSONParser) markSafe(end int) {
+	p.safeEnd = end
+	p.safeClosers = closers(p.stack)
+}
+
+// pointer returns the JSON pointer of the value at the current position.
+func (p *PartialJSONParser) pointer() string {
+	var b strings.Builder
+	for _, frame := range p.stack {
+		b.WriteByte('/')
+		if frame.kind == '[' {
+			b.WriteString(strconv.Itoa(frame.index))
+			continue
+		}
+		b.WriteString(strings.NewReplacer("~", "~0", "/", "~1").Replace(frame.key))
+	}
+	return b.String()
+}
+
+// closers returns the brackets that close every open frame, innermost first.
+func closers(stack []partialFrame) string {
+	b := make([]byte, len(stack))
+	for i, frame := range stack {
+		if frame.kind == '{' {
+			b[len(stack)-1-i] = '}'
+		} else {
+			b[len(stack)-1-i] = ']'
+		}
+	}
+	return string(b)
+}
+
+// isLiteralByte reports whether c can continue a number or a true/false/null literal.
+func isLiteralByte(c byte) bool {
+	return (c >= '0' && c <= '9') || (c >= 'a' && c <= 'z') || c == '.' || c == '+' || c == '-' || c == 'E'
+}
+
+// trimIncompleteString drops a trailing partial escape sequence or UTF-8 rune from
+// the body of an unterminated string so that it can be closed safely. escaped
+// reports whether the final byte is an unpaired backslash.
+func trimIncompleteString(text []byte, escaped bool) []byte {
+	if escaped {
+		return text[:len(text)-1]
+	}
+	// A \u escape needs four hex digits.
+	for n := 2; n <= 5 && n <= len(text); n++ {
+		i := len(text) - n
+		if text[i] == '\\' && text[i+1] == 'u' && !precededByBackslash(text, i) {
+			return text[:i]
+		}
+	}
+	for i := 1; i <= utf8.UTFMax && i <= len(text); i++ {
+		if utf8.RuneStart(text[len(text)-i]) {
+			if !utf8.FullRune(text[len(text)-i:]) {
+				text = text[:len(text)-i]
+			}
+			break
+		}
+	}
+	return text
+}
+
+// precededByBackslash reports whether the backslash at i is itself escaped.
+func precededByBackslash(text []byte, i int) bool {
+	n := 0
+	for j := i - 1; j >= 0 && text[j] == '\\'; j-- {
+		n++
+	}
+	return n%2 == 1
+}
@@ -0,0 +1,77 @@
+package llm
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPartialJSONParserSnapshots(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  string
+	}{
+		{name: "not started", input: "```json\n", want: ""},
+		{name: "open object", input: `{`, want: `{}`},
+		{name: "partial key", input: `{"tit`, want: `{}`},
+		{name: "key without value", input: `{"title":`, want: `{}`},
+		{name: "partial string value", input: `{"title":"Pan`, want: `{"title":"Pan"}`},
+		{name: "trailing comma", input: `{"title":"Pancakes",`, want: `{"title":"Pancakes"}`},
+		{name: "partial number", input: `{"a":"x","servings":1`, want: `{"a":"x"}`},
+		{name: "partial literal", input: `{"vegan":tr`, want: `{}`},
+		{name: "completed literal", input: `{"vegan":true,"n":2,`, want: `{"vegan":true,"n":2}`},
+		{name: "nested containers", input: `{"steps":[{"text":"Mix`, want: `{"steps":[{"text":"Mix"}]}`},
+		{name: "partial escape", input: `{"a":"line\`, want: `{"a":"line"}`},
+		{name: "partial unicode escape", input: `{"a":"caf\u00`, want: `{"a":"caf"}`},
+		{name: "escaped backslash", input: `{"a":"c:\\`, want: `{"a":"c:\\"}`},
+		{name: "partial rune", input: "{\"a\":\"caf\xc3", want: `{"a":"caf"}`},
+		{name: "code fence", input: "```json\n{\"a\":1}\n```", want: `{"a":1}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var p PartialJSONParser
+			_, err := p.WriteString(tt.input)
+			require.NoError(t, err)
+
+			got := p.Snapshot()
+			assert.Equal(t, tt.want, string(got))
+			if got != nil {
+				assert.True(t, json.Valid(got), "snapshot must be valid JSON: %s", got)
+			}
+		})
+	}
+}
+
+func TestPartialJSONParserByteByByte(t *testing.T) {
+	doc := `{"title":"Crêpes \"fines\"","tags":["a","b"],"meta":{"n":12,"ok":false},"x/y":null}`
+
+	var p PartialJSONParser
+	var completed []string
+	for i := range len(doc) {
+		_, err := p.Write([]byte{doc[i]})
+		require.NoError(t, err)
+		completed = append(completed, p.Completed()...)
+		if snapshot := p.Snapshot(); snapshot != nil {
+			require.True(t, json.Valid(snapshot), "invalid snapshot after %q: %s", doc[:i+1], snapshot)
+		}
+	}
+
+	assert.True(t, p.Done())
+	assert.JSONEq(t, doc, string(p.Snapshot()))
+	assert.Equal(t, []string{"/title", "/tags/0", "/tags/1", "/tags", "/meta/n", "/meta/ok", "/meta", "/x~1y"}, completed)
+}
+
+func TestPartialJSONParserErrors(t *testing.T) {
+	for _, input := range []string{`{"a" 1}`, `{"a":1 2}`, `{"a":1]`, `{"a":}`, `{"a":tru}`, `[1,@]`, `{1:2}`} {
+		var p PartialJSONParser
+		_, err := p.WriteString(input)
+		require.ErrorIs(t, err, ErrInvalidPartialJSON, input)
+
+		_, err = p.WriteString("}")
+		require.ErrorIs(t, err, ErrInvalidPartialJSON, "errors are sticky")
+	}
+}
@@ -35,9 +35,11 @@ type PromptMessage struct {
 
 func (pm *PromptMessage) ToMessage() providers.Message {
 	msg := providers.Message{
-		Role:    pm.Role,
-		Content: pm.Content,
-		Name:    pm.Name,
+		Role:       pm.Role,
+		Content:    pm.Content,
+		Name:       pm.Name,
+		ToolCallID: pm.ToolCallID,
+		CacheType:  providers.CacheType(pm.CacheType),
 	}
 	if len(pm.ToolCalls) > 0 {
 		var toolCalls []providers.ToolCall
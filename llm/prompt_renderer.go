@@ -0,0 +1,192 @@
+package llm
+
+import (
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// PromptPlacement selects where a rendered part of a Prompt is sent.
+type PromptPlacement string
+
+const (
+	// PlacementSystem appends the part to the system prompt.
+	PlacementSystem PromptPlacement = "system"
+	// PlacementUser adds the part to the last user message: context and
+	// directives before its content, everything else after it.
+	PlacementUser PromptPlacement = "user"
+	// PlacementFewShot sends each example that has an input and an output as a
+	// user message followed by an assistant message, ahead of the conversation.
+	// It only applies to examples.
+	PlacementFewShot PromptPlacement = "few_shot"
+)
+
+// RenderedPrompt is a Prompt reduced to what every provider accepts.
+type RenderedPrompt struct {
+	SystemPrompt string
+	Messages     []PromptMessage
+}
+
+// PromptRenderer turns every field of a Prompt into a system prompt and messages.
+// Generate and GenerateStream render each prompt before preparing the request.
+type PromptRenderer interface {
+	Render(prompt *Prompt) RenderedPrompt
+}
+
+// PromptRendererFunc adapts a function to the PromptRenderer interface.
+type PromptRendererFunc func(prompt *Prompt) RenderedPrompt
+
+// Render calls f(prompt).
+func (f PromptRendererFunc) Render(prompt *Prompt) RenderedPrompt {
+	return f(prompt)
+}
+
+// DefaultPromptRenderer renders Context, Directives, Output, Examples and
+// MaxLength in the layout of Prompt.String. Its zero value places instructions
+// in the user message and examples as few-shot pairs.
+type DefaultPromptRenderer struct {
+	// Instructions is where context, directives, the output format and the
+	// length limit go: PlacementUser (the default) or PlacementSystem.
+	Instructions PromptPlacement
+	// Examples is where examples go: PlacementFewShot (the default),
+	// PlacementUser or PlacementSystem. With PlacementFewShot, examples without
+	// an input and an output are listed with the instructions.
+	Examples PromptPlacement
+}
+
+// Render implements PromptRenderer. The prompt is not modified.
+func (r DefaultPromptRenderer) Render(prompt *Prompt) RenderedPrompt {
+	instructions := r.Instructions
+	if instructions != PlacementSystem {
+		instructions = PlacementUser
+	}
+	examples := r.Examples
+	if examples != PlacementSystem && examples != PlacementUser {
+		examples = PlacementFewShot
+	}
+
+	messages := slices.Clone(prompt.Messages)
+	if len(messages) == 0 && prompt.Input != "" {
+		messages = []PromptMessage{{Role: "user", Content: prompt.Input}}
+	}
+
+	var fewShot []PromptMessage
+	var listed []string
+	for _, example := range prompt.Examples {
+		if examples == PlacementFewShot {
+			if input, output, ok := splitExample(example); ok {
+				fewShot = append(fewShot,
+					PromptMessage{Role: "user", Content: input},
+					PromptMessage{Role: "assistant", Content: output},
+				)
+				continue
+			}
+		}
+		listed = append(listed, example)
+	}
+	if examples == PlacementFewShot {
+		examples = instructions
+	}
+
+	sections := map[PromptPlacement]*promptSections{
+		PlacementSystem: {},
+		PlacementUser:   {},
+	}
+	if prompt.Context != "" {
+		sections[instructions].before = append(sections[instructions].before, "Context: "+prompt.Context)
+	}
+	if len(prompt.Directives) > 0 {
+		sections[instructions].before = append(sections[instructions].before,
+			"Directives:\n"+bulletList(prompt.Directives))
+	}
+	if prompt.Output != "" {
+		sections[instructions].after = append(sections[instructions].after,
+			"Expected Output Format:\n"+prompt.Output)
+	}
+	if len(listed) > 0 {
+		sections[examples].after = append(sections[examples].after, "Examples:\n"+bulletList(listed))
+	}
+	if prompt.MaxLength > 0 {
+		sections[instructions].after = append(sections[instructions].after,
+			fmt.Sprintf("Please limit your response to approximately %d words.", prompt.MaxLength))
+	}
+
+	system := sections[PlacementSystem].around(prompt.SystemPrompt)
+	if user := sections[PlacementUser]; !user.empty() {
+		last := -1
+		for i := len(messages) - 1; i >= 0 && last < 0; i-- {
+			if messages[i].Role == "user" {
+				last = i
+			}
+		}
+		if last < 0 {
+			messages = append(messages, PromptMessage{Role: "user", Content: user.around("")})
+		} else {
+			messages[last].Content = user.around(messages[last].Content)
+		}
+	}
+
+	return RenderedPrompt{SystemPrompt: system, Messages: append(fewShot, messages...)}
+}
+
+// promptSections are the rendered parts placed before and after a text.
+type promptSections struct {
+	before []string
+	after  []string
+}
+
+func (s *promptSections) empty() bool {
+	return len(s.before) == 0 && len(s.after) == 0
+}
+
+// around joins the sections placed before text, text and those placed after it.
+func (s *promptSections) around(text string) string {
+	parts := slices.Concat(s.before, []string{text}, s.after)
+	return strings.Join(slices.DeleteFunc(parts, func(part string) bool { return part == "" }), "\n\n")
+}
+
+func bulletList(items []string) string {
+	var b strings.Builder
+	for i, item := range items {
+		if i > 0 {
+			b.WriteString("\n")
+		}
+		b.WriteString("- ")
+		b.WriteString(item)
+	}
+	return b.String()
+}
+
+// splitExample returns the input and output of an example written as a JSON
+// object with "input" and "output" fields, or as text with an "Input:" line
+// followed by an "Output:" line.
+func splitExample(example string) (input, output string, ok bool) {
+	var pair struct {
+		Input  string `json:"input"`
+		Output string `json:"output"`
+	}
+	if err := json.Unmarshal([]byte(example), &pair); err == nil {
+		return pair.Input, pair.Output, pair.Input != "" && pair.Output != ""
+	}
+
+	rest, found := cutLabel(strings.TrimSpace(example), "input:")
+	if !found {
+		return "", "", false
+	}
+	index := strings.Index(strings.ToLower(rest), "\noutput:")
+	if index < 0 {
+		return "", "", false
+	}
+	input = strings.TrimSpace(rest[:index])
+	output = strings.TrimSpace(rest[index+len("\noutput:"):])
+	return input, output, input != "" && output != ""
+}
+
+// cutLabel removes a case-insensitive label from the start of s.
+func cutLabel(s, label string) (string, bool) {
+	if len(s) < len(label) || !strings.EqualFold(s[:len(label)], label) {
+		return s, false
+	}
+	return s[len(label):], true
+}
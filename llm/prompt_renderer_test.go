@@ -0,0 +1,109 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDefaultPromptRenderer(t *testing.T) {
+	prompt := NewPrompt("Summarize the report",
+		WithSystemPrompt("You are an analyst.", ""),
+		WithContext("Q3 revenue grew 12%."),
+		WithDirectives("Be brief", "Use numbers"),
+		WithOutput("Summary:"),
+		WithExamples(`{"input":"Sales fell 3%","output":"Sales declined."}`, "Keep a neutral tone"),
+		WithMaxLength(50),
+	)
+
+	t.Run("defaults", func(t *testing.T) {
+		rendered := DefaultPromptRenderer{}.Render(prompt)
+		assert.Equal(t, "You are an analyst.", rendered.SystemPrompt)
+		assert.Equal(t, []PromptMessage{
+			{Role: "user", Content: "Sales fell 3%"},
+			{Role: "assistant", Content: "Sales declined."},
+			{Role: "user", Content: "Context: Q3 revenue grew 12%.\n\n" +
+				"Directives:\n- Be brief\n- Use numbers\n\n" +
+				"Summarize the report\n\n" +
+				"Expected Output Format:\nSummary:\n\n" +
+				"Examples:\n- Keep a neutral tone\n\n" +
+				"Please limit your response to approximately 50 words."},
+		}, rendered.Messages)
+	})
+
+	t.Run("system placement", func(t *testing.T) {
+		rendered := DefaultPromptRenderer{Instructions: PlacementSystem, Examples: PlacementSystem}.Render(prompt)
+		assert.Equal(t, "Context: Q3 revenue grew 12%.\n\n"+
+			"Directives:\n- Be brief\n- Use numbers\n\n"+
+			"You are an analyst.\n\n"+
+			"Expected Output Format:\nSummary:\n\n"+
+			"Examples:\n"+`- {"input":"Sales fell 3%","output":"Sales declined."}`+"\n- Keep a neutral tone\n\n"+
+			"Please limit your response to approximately 50 words.", rendered.SystemPrompt)
+		assert.Equal(t, []PromptMessage{{Role: "user", Content: "Summarize the report"}}, rendered.Messages)
+	})
+
+	assert.Equal(t, "Summarize the report", prompt.Messages[0].Content, "the prompt is not modified")
+}
+
+func TestDefaultPromptRendererInput(t *testing.T) {
+	rendered := DefaultPromptRenderer{}.Render(&Prompt{Input: "Hello", Directives: []string{"Answer in French"}})
+	assert.Equal(t, []PromptMessage{{Role: "user", Content: "Directives:\n- Answer in French\n\nHello"}},
+		rendered.Messages, "a prompt without messages sends its input")
+}
+
+func TestSplitExample(t *testing.T) {
+	tests := map[string]struct {
+		example, input, output string
+		ok                     bool
+	}{
+		"json":        {`{"input":"2+2","output":"4"}`, "2+2", "4", true},
+		"labels":      {"Input: 2+2\nOutput: 4", "2+2", "4", true},
+		"lower case":  {"input: 2+2\noutput: 4", "2+2", "4", true},
+		"no output":   {"Input: 2+2", "", "", false},
+		"plain text":  {"Challenge: Decoherence, Solution: Error correction", "", "", false},
+		"json string": {`"just text"`, "", "", false},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			input, output, ok := splitExample(tt.example)
+			assert.Equal(t, tt.ok, ok)
+			assert.Equal(t, tt.input, input)
+			assert.Equal(t, tt.output, output)
+		})
+	}
+}
+
+func TestGenerateRendersPrompt(t *testing.T) {
+	var requests []map[string]any
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]any
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+		requests = append(requests, body)
+		assert.NoError(t, json.NewEncoder(w).Encode(map[string]any{"model": "llama3", "response": "ok", "done": true}))
+	}))
+	defer server.Close()
+
+	client := newOllamaTestLLM(t, server)
+	prompt := NewPrompt("Summarize the report", WithDirectives("Be brief"))
+
+	_, err := client.Generate(context.Background(), prompt)
+	require.NoError(t, err)
+	assert.Contains(t, requests[0]["prompt"], "Directives:\n- Be brief")
+
+	_, err = client.Generate(context.Background(), prompt,
+		WithPromptRenderer(DefaultPromptRenderer{Instructions: PlacementSystem}))
+	require.NoError(t, err)
+	assert.Contains(t, requests[1]["prompt"], "System: Directives:\n- Be brief", "Ollama inlines the system prompt")
+
+	client.SetPromptRenderer(PromptRendererFunc(func(p *Prompt) RenderedPrompt {
+		return RenderedPrompt{Messages: []PromptMessage{{Role: "user", Content: "custom " + p.Input}}}
+	}))
+	_, err = client.Generate(context.Background(), prompt)
+	require.NoError(t, err)
+	assert.Contains(t, requests[2]["prompt"], "custom Summarize the report")
+}
@@ -25,10 +25,16 @@ import (
 //	    "text": "Hello, world!",
 //	})
 type PromptTemplate struct {
-	Name        string         // Unique identifier for the template
-	Description string         // Human-readable description of the template's purpose
-	Template    string         // Go template string for generating prompts
-	Options     []PromptOption // Configuration options for generated prompts
+	Name        string             // Unique identifier for the template
+	Description string             // Human-readable description of the template's purpose
+	Template    string             // Go template string for generating prompts
+	Options     []PromptOption     // Configuration options for generated prompts
+	Version     string             // Version of the template, set by its front matter
+	Variables   []TemplateVariable // Declared variables, checked before execution
+	Model       *TemplateModel     // Model configuration the template is written for
+
+	// partials are the named templates available to Template, by name.
+	partials map[string]string
 }
 
 // PromptTemplateOption is a function type that modifies a PromptTemplate.
@@ -101,15 +107,17 @@ func WithPromptOptions(options ...PromptOption) PromptTemplateOption {
 }
 
 // Execute generates a Prompt from the PromptTemplate with the given data.
-// It applies the template's options to the generated prompt and validates
-// the result.
+// It checks the data against the declared Variables, filling in defaults,
+// and applies the template's options to the generated prompt. Referencing a
+// variable that is not in data is an error.
 //
 // Parameters:
 //   - data: Map of key-value pairs to substitute in the template
 //
 // Returns:
 //   - Generated and configured Prompt instance
-//   - Error if template parsing, execution, or validation fails
+//   - Error if a variable is missing or has the wrong type, or if template
+//     parsing or execution fails
 //
 // Example:
 //
@@ -121,10 +129,21 @@ func WithPromptOptions(options ...PromptOption) PromptTemplateOption {
 //	    log.Fatal(err)
 //	}
 func (pt *PromptTemplate) Execute(data map[string]any) (*Prompt, error) {
-	tmpl, err := template.New(pt.Name).Parse(pt.Template)
+	data, err := pt.bindVariables(data)
+	if err != nil {
+		return nil, err
+	}
+
+	tmpl, err := pt.parse()
 	if err != nil {
 		return nil, fmt.Errorf("failed to parse template: %w", err)
 	}
+	return pt.execute(tmpl, data)
+}
+
+// execute renders a parsed template with data into a Prompt with the
+// template's options applied.
+func (pt *PromptTemplate) execute(tmpl *template.Template, data any) (*Prompt, error) {
 
 	var buf bytes.Buffer
 	if err := tmpl.Execute(&buf, data); err != nil {
@@ -136,3 +155,28 @@ func (pt *PromptTemplate) Execute(data map[string]any) (*Prompt, error) {
 
 	return prompt, nil
 }
+
+// parse parses the template and its partials. Partials can be executed with
+// the template action or with the include function, which returns the output
+// as a string for use in pipelines.
+func (pt *PromptTemplate) parse() (*template.Template, error) {
+	tmpl := template.New(pt.Name).Option("missingkey=error")
+	tmpl.Funcs(template.FuncMap{
+		"include": func(name string, data any) (string, error) {
+			var buf bytes.Buffer
+			if err := tmpl.ExecuteTemplate(&buf, name, data); err != nil {
+				return "", err
+			}
+			return buf.String(), nil
+		},
+	})
+	if _, err := tmpl.Parse(pt.Template); err != nil {
+		return nil, err
+	}
+	for name, partial := range pt.partials {
+		if _, err := tmpl.New(name).Parse(partial); err != nil {
+			return nil, err
+		}
+	}
+	return tmpl, nil
+}
@@ -0,0 +1,30 @@
+package llm
+
+import (
+	"github.com/weave-labs/gollm/config"
+	"github.com/weave-labs/gollm/internal/logging"
+)
+
+// NewRedactor builds a log redactor for cfg. It masks the built-in sensitive keys,
+// any cfg.RedactPatterns, every configured API key, and the values of sensitive
+// extra headers wherever they appear in log output.
+func NewRedactor(cfg *config.Config) *logging.Redactor {
+	redactor := logging.NewRedactor(cfg.RedactPatterns...)
+	for _, apiKey := range cfg.APIKeys {
+		redactor.AddSecrets(apiKey)
+	}
+	for name, value := range cfg.ExtraHeaders {
+		if redactor.IsSensitiveKey(name) {
+			redactor.AddSecrets(value)
+		}
+	}
+	return redactor
+}
+
+// truncateForLog shortens body to at most limit bytes for inclusion in log output.
+func truncateForLog(body []byte, limit int) string {
+	if len(body) <= limit {
+		return string(body)
+	}
+	return string(body[:limit]) + "...(truncated)"
+}
@@ -0,0 +1,793 @@
+package llm
+
+import (
+	"encoding/json"
+	"fmt"
+	"maps"
+	"math"
+	"net"
+	"net/mail"
+	"net/url"
+	"reflect"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/google/jsonschema-go/jsonschema"
+)
+
+// SchemaViolation is one way a JSON value fails to match a schema.
+type SchemaViolation struct {
+	// Path is the JSON pointer of the offending value; empty for the root.
+	Path string
+	// Keyword is the schema keyword that failed, such as "required" or "pattern".
+	Keyword string
+	// Message describes the failure.
+	Message string
+}
+
+// String returns the violation prefixed with its path.
+func (v SchemaViolation) String() string {
+	if v.Path == "" {
+		return "/: " + v.Message
+	}
+	return v.Path + ": " + v.Message
+}
+
+// SchemaValidationError is returned when a value does not match a schema. It
+// lists every violation found, one per line of Error.
+type SchemaValidationError struct {
+	Violations []SchemaViolation
+}
+
+// Error implements the error interface.
+func (e *SchemaValidationError) Error() string {
+	lines := make([]string, len(e.Violations))
+	for i, violation := range e.Violations {
+		lines[i] = violation.String()
+	}
+	return strings.Join(lines, "\n")
+}
+
+// ValidateValue validates value, as decoded by encoding/json into an any, against
+// schema under JSON Schema draft 2020-12. It returns a *SchemaValidationError
+// listing every violation, or nil when value matches.
+//
+// References to the schema's $defs, definitions, anchors and JSON pointers are
+// resolved; remote references are reported as violations. The common formats
+// date-time, date, time, email, uri, uuid, ipv4 and ipv6 are asserted.
+func ValidateValue(schema *jsonschema.Schema, value any) error {
+	if schema == nil {
+		return nil
+	}
+	v := &schemaValidator{root: schema, active: make(map[activeRef]bool)}
+	violations, _ := v.validate(schema, value, "")
+	if len(violations) == 0 {
+		return nil
+	}
+	return &SchemaValidationError{Violations: violations}
+}
+
+// ValidateJSON decodes data and validates it with ValidateValue.
+func ValidateJSON(schema *jsonschema.Schema, data []byte) error {
+	var value any
+	if err := json.Unmarshal(data, &value); err != nil {
+		return fmt.Errorf("failed to parse response JSON: %w", err)
+	}
+	return ValidateValue(schema, value)
+}
+
+// schemaValidator validates instances against one root schema.
+type schemaValidator struct {
+	root *jsonschema.Schema
+	// active holds the references being followed for each instance location,
+	// which stops references that loop without descending into the instance.
+	active map[activeRef]bool
+}
+
+type activeRef struct {
+	schema *jsonschema.Schema
+	path   string
+}
+
+// evaluated records the properties and items a schema evaluated successfully,
+// which unevaluatedProperties and unevaluatedItems exclude.
+type evaluated struct {
+	properties map[string]bool
+	items      map[int]bool
+	allItems   bool
+}
+
+func (e *evaluated) merge(other evaluated) {
+	for name := range other.properties {
+		e.addProperty(name)
+	}
+	for i := range other.items {
+		e.addItem(i)
+	}
+	e.allItems = e.allItems || other.allItems
+}
+
+func (e *evaluated) addProperty(name string) {
+	if e.properties == nil {
+		e.properties = make(map[string]bool)
+	}
+	e.properties[name] = true
+}
+
+func (e *evaluated) addItem(i int) {
+	if e.items == nil {
+		e.items = make(map[int]bool)
+	}
+	e.items[i] = true
+}
+
+// validate returns the violations of instance at path against s and what s
+// evaluated.
+func (v *schemaValidator) validate(
+	s *jsonschema.Schema,
+	instance any,
+	path string,
+) ([]SchemaViolation, evaluated) {
+	var violations []SchemaViolation
+	var eval evaluated
+	fail := func(keyword, format string, args ...any) {
+		violations = append(violations, SchemaViolation{Path: path, Keyword: keyword, Message: fmt.Sprintf(format, args...)})
+	}
+
+	if isFalse(s) {
+		fail("false", "no value is allowed here")
+		return violations, eval
+	}
+
+	v.validateRefs(s, instance, path, &violations, &eval)
+	v.validateType(s, instance, fail)
+	validateNumber(s, instance, fail)
+	validateString(s, instance, fail)
+	v.validateApplicators(s, instance, path, &violations, &eval)
+
+	switch value := instance.(type) {
+	case []any:
+		v.validateArray(s, value, path, fail, &violations, &eval)
+	case map[string]any:
+		v.validateObject(s, value, path, fail, &violations, &eval)
+	}
+
+	if len(violations) > 0 {
+		return violations, evaluated{}
+	}
+	return nil, eval
+}
+
+func (v *schemaValidator) validateRefs(
+	s *jsonschema.Schema,
+	instance any,
+	path string,
+	violations *[]SchemaViolation,
+	eval *evaluated,
+) {
+	for _, r := range [...]struct{ keyword, ref string }{{"$ref", s.Ref}, {"$dynamicRef", s.DynamicRef}} {
+		keyword, ref := r.keyword, r.ref
+		if ref == "" {
+			continue
+		}
+		target := v.resolve(ref)
+		if target == nil {
+			*violations = append(*violations, SchemaViolation{path, keyword,
+				fmt.Sprintf("cannot resolve reference %q", ref)})
+			continue
+		}
+
+		key := activeRef{target, path}
+		if v.active[key] {
+			continue
+		}
+		v.active[key] = true
+		refViolations, refEval := v.validate(target, instance, path)
+		delete(v.active, key)
+
+		*violations = append(*violations, refViolations...)
+		eval.merge(refEval)
+	}
+}
+
+func (v *schemaValidator) validateType(s *jsonschema.Schema, instance any, fail func(string, string, ...any)) {
+	got := jsonTypeOf(instance)
+	if s.Type != "" && !typeMatches(s.Type, got) {
+		fail("type", "expected %s, got %s", s.Type, got)
+	}
+	if s.Types != nil && !slices.ContainsFunc(s.Types, func(want string) bool { return typeMatches(want, got) }) {
+		fail("type", "expected one of %s, got %s", strings.Join(s.Types, ", "), got)
+	}
+
+	if s.Enum != nil && !slices.ContainsFunc(s.Enum, func(allowed any) bool { return jsonEqual(allowed, instance) }) {
+		fail("enum", "%s is not one of %s", compactJSON(instance), compactJSON(s.Enum))
+	}
+	if s.Const != nil && !jsonEqual(*s.Const, instance) {
+		fail("const", "%s is not %s", compactJSON(instance), compactJSON(*s.Const))
+	}
+}
+
+func validateNumber(s *jsonschema.Schema, instance any, fail func(string, string, ...any)) {
+	n, ok := toFloat(instance)
+	if !ok {
+		return
+	}
+	if s.MultipleOf != nil && *s.MultipleOf > 0 {
+		if q := n / *s.MultipleOf; math.Abs(q-math.Round(q)) > 1e-9 {
+			fail("multipleOf", "%v is not a multiple of %v", n, *s.MultipleOf)
+		}
+	}
+	if s.Minimum != nil && n < *s.Minimum {
+		fail("minimum", "%v is less than the minimum %v", n, *s.Minimum)
+	}
+	if s.Maximum != nil && n > *s.Maximum {
+		fail("maximum", "%v is greater than the maximum %v", n, *s.Maximum)
+	}
+	if s.ExclusiveMinimum != nil && n <= *s.ExclusiveMinimum {
+		fail("exclusiveMinimum", "%v must be greater than %v", n, *s.ExclusiveMinimum)
+	}
+	if s.ExclusiveMaximum != nil && n >= *s.ExclusiveMaximum {
+		fail("exclusiveMaximum", "%v must be less than %v", n, *s.ExclusiveMaximum)
+	}
+}
+
+func validateString(s *jsonschema.Schema, instance any, fail func(string, string, ...any)) {
+	str, ok := instance.(string)
+	if !ok {
+		return
+	}
+	length := utf8.RuneCountInString(str)
+	if s.MinLength != nil && length < *s.MinLength {
+		fail("minLength", "%q is shorter than %d characters", str, *s.MinLength)
+	}
+	if s.MaxLength != nil && length > *s.MaxLength {
+		fail("maxLength", "%q is longer than %d characters", str, *s.MaxLength)
+	}
+	if s.Pattern != "" {
+		re, err := compilePattern(s.Pattern)
+		switch {
+		case err != nil:
+			fail("pattern", "invalid pattern %q: %v", s.Pattern, err)
+		case !re.MatchString(str):
+			fail("pattern", "%q does not match the pattern %q", str, s.Pattern)
+		}
+	}
+	if check, ok := formatCheckers[s.Format]; ok && !check(str) {
+		fail("format", "%q is not a valid %s", str, s.Format)
+	}
+}
+
+func (v *schemaValidator) validateApplicators(
+	s *jsonschema.Schema,
+	instance any,
+	path string,
+	violations *[]SchemaViolation,
+	eval *evaluated,
+) {
+	fail := func(keyword, format string, args ...any) {
+		*violations = append(*violations, SchemaViolation{path, keyword, fmt.Sprintf(format, args...)})
+	}
+
+	for _, sub := range s.AllOf {
+		subViolations, subEval := v.validate(sub, instance, path)
+		*violations = append(*violations, subViolations...)
+		eval.merge(subEval)
+	}
+
+	if s.AnyOf != nil {
+		matched := false
+		var failures [][]SchemaViolation
+		for _, sub := range s.AnyOf {
+			subViolations, subEval := v.validate(sub, instance, path)
+			if len(subViolations) == 0 {
+				matched = true
+				eval.merge(subEval)
+			} else {
+				failures = append(failures, subViolations)
+			}
+		}
+		if !matched {
+			*violations = append(*violations, closestAlternative(s.AnyOf, instance, failures, path, "anyOf")...)
+		}
+	}
+
+	if s.OneOf != nil {
+		var matches []int
+		var failures [][]SchemaViolation
+		for i, sub := range s.OneOf {
+			subViolations, subEval := v.validate(sub, instance, path)
+			if len(subViolations) == 0 {
+				matches = append(matches, i)
+				eval.merge(subEval)
+			} else {
+				failures = append(failures, subViolations)
+			}
+		}
+		switch {
+		case len(matches) == 0:
+			*violations = append(*violations, closestAlternative(s.OneOf, instance, failures, path, "oneOf")...)
+		case len(matches) > 1:
+			fail("oneOf", "value matches alternatives %v, but must match exactly one", matches)
+		}
+	}
+
+	if s.Not != nil {
+		if notViolations, _ := v.validate(s.Not, instance, path); len(notViolations) == 0 {
+			fail("not", "value must not match %s", compactJSON(s.Not))
+		}
+	}
+
+	if s.If != nil {
+		ifViolations, ifEval := v.validate(s.If, instance, path)
+		branch := s.Else
+		if len(ifViolations) == 0 {
+			eval.merge(ifEval)
+			branch = s.Then
+		}
+		if branch != nil {
+			branchViolations, branchEval := v.validate(branch, instance, path)
+			*violations = append(*violations, branchViolations...)
+			eval.merge(branchEval)
+		}
+	}
+}
+
+// closestAlternative reports why no alternative matched. When the type of only
+// one alternative accepts the value, as for a nullable object, its violations
+// are more useful than a summary and are returned instead.
+func closestAlternative(
+	alternatives []*jsonschema.Schema,
+	instance any,
+	failures [][]SchemaViolation,
+	path, keyword string,
+) []SchemaViolation {
+	candidate := -1
+	for i, failure := range failures {
+		if slices.ContainsFunc(failure, func(v SchemaViolation) bool { return v.Path == path && v.Keyword == "type" }) {
+			continue
+		}
+		if candidate >= 0 {
+			candidate = -1
+			break
+		}
+		candidate = i
+	}
+	if candidate >= 0 {
+		return failures[candidate]
+	}
+	return []SchemaViolation{{path, keyword, fmt.Sprintf("%s does not match any of the %d alternatives",
+		jsonTypeOf(instance), len(alternatives))}}
+}
+
+func (v *schemaValidator) validateArray(
+	s *jsonschema.Schema,
+	items []any,
+	path string,
+	fail func(string, string, ...any),
+	violations *[]SchemaViolation,
+	eval *evaluated,
+) {
+	for i, item := range items {
+		var itemSchema *jsonschema.Schema
+		switch {
+		case i < len(s.PrefixItems):
+			itemSchema = s.PrefixItems[i]
+		case s.Items != nil:
+			itemSchema = s.Items
+		default:
+			continue
+		}
+		itemViolations, _ := v.validate(itemSchema, item, pointerIndex(path, i))
+		*violations = append(*violations, itemViolations...)
+		eval.addItem(i)
+	}
+
+	if s.MinItems != nil && len(items) < *s.MinItems {
+		fail("minItems", "array has %d items, fewer than %d", len(items), *s.MinItems)
+	}
+	if s.MaxItems != nil && len(items) > *s.MaxItems {
+		fail("maxItems", "array has %d items, more than %d", len(items), *s.MaxItems)
+	}
+	if s.UniqueItems {
+		for i := range items {
+			for j := i + 1; j < len(items); j++ {
+				if jsonEqual(items[i], items[j]) {
+					fail("uniqueItems", "items %d and %d are equal", i, j)
+				}
+			}
+		}
+	}
+
+	if s.Contains != nil {
+		contained := 0
+		for i, item := range items {
+			if itemViolations, _ := v.validate(s.Contains, item, pointerIndex(path, i)); len(itemViolations) == 0 {
+				contained++
+				eval.addItem(i)
+			}
+		}
+		minContains := 1
+		if s.MinContains != nil {
+			minContains = *s.MinContains
+		}
+		if contained < minContains {
+			fail("contains", "array has %d items matching %s, fewer than %d", contained, compactJSON(s.Contains),
+				minContains)
+		}
+		if s.MaxContains != nil && contained > *s.MaxContains {
+			fail("maxContains", "array has %d items matching %s, more than %d", contained, compactJSON(s.Contains),
+				*s.MaxContains)
+		}
+	}
+
+	if s.UnevaluatedItems != nil && !eval.allItems {
+		for i, item := range items {
+			if eval.items[i] {
+				continue
+			}
+			if isFalse(s.UnevaluatedItems) {
+				fail("unevaluatedItems", "unexpected item at index %d", i)
+				continue
+			}
+			itemViolations, _ := v.validate(s.UnevaluatedItems, item, pointerIndex(path, i))
+			*violations = append(*violations, itemViolations...)
+		}
+		eval.allItems = true
+	}
+}
+
+func (v *schemaValidator) validateObject(
+	s *jsonschema.Schema,
+	object map[string]any,
+	path string,
+	fail func(string, string, ...any),
+	violations *[]SchemaViolation,
+	eval *evaluated,
+) {
+	names := slices.Sorted(maps.Keys(object))
+
+	for _, name := range s.Required {
+		if _, ok := object[name]; !ok {
+			fail("required", "missing required property %q", name)
+		}
+	}
+	for _, name := range slices.Sorted(maps.Keys(s.DependentRequired)) {
+		if _, ok := object[name]; !ok {
+			continue
+		}
+		for _, dependency := range s.DependentRequired[name] {
+			if _, ok := object[dependency]; !ok {
+				fail("dependentRequired", "property %q requires property %q", name, dependency)
+			}
+		}
+	}
+	if s.MinProperties != nil && len(object) < *s.MinProperties {
+		fail("minProperties", "object has %d properties, fewer than %d", len(object), *s.MinProperties)
+	}
+	if s.MaxProperties != nil && len(object) > *s.MaxProperties {
+		fail("maxProperties", "object has %d properties, more than %d", len(object), *s.MaxProperties)
+	}
+
+	local := make(map[string]bool)
+	for _, name := range names {
+		memberPath := pointerMember(path, name)
+		if property, ok := s.Properties[name]; ok {
+			propertyViolations, _ := v.validate(property, object[name], memberPath)
+			*violations = append(*violations, propertyViolations...)
+			local[name] = true
+		}
+		for _, pattern := range slices.Sorted(maps.Keys(s.PatternProperties)) {
+			re, err := compilePattern(pattern)
+			if err != nil {
+				fail("patternProperties", "invalid pattern %q: %v", pattern, err)
+				continue
+			}
+			if re.MatchString(name) {
+				patternViolations, _ := v.validate(s.PatternProperties[pattern], object[name], memberPath)
+				*violations = append(*violations, patternViolations...)
+				local[name] = true
+			}
+		}
+		if s.PropertyNames != nil {
+			if nameViolations, _ := v.validate(s.PropertyNames, name, memberPath); len(nameViolations) > 0 {
+				fail("propertyNames", "property name %q is not allowed", name)
+			}
+		}
+	}
+
+	if s.AdditionalProperties != nil {
+		for _, name := range names {
+			if local[name] {
+				continue
+			}
+			local[name] = true
+			if isFalse(s.AdditionalProperties) {
+				fail("additionalProperties", "unexpected property %q", name)
+				continue
+			}
+			additionalViolations, _ := v.validate(s.AdditionalProperties, object[name], pointerMember(path, name))
+			*violations = append(*violations, additionalViolations...)
+		}
+	}
+
+	for _, name := range slices.Sorted(maps.Keys(s.DependentSchemas)) {
+		if _, ok := object[name]; !ok {
+			continue
+		}
+		dependentViolations, dependentEval := v.validate(s.DependentSchemas[name], object, path)
+		*violations = append(*violations, dependentViolations...)
+		eval.merge(dependentEval)
+	}
+
+	for name := range local {
+		eval.addProperty(name)
+	}
+
+	if s.UnevaluatedProperties != nil {
+		for _, name := range names {
+			if eval.properties[name] {
+				continue
+			}
+			if isFalse(s.UnevaluatedProperties) {
+				fail("unevaluatedProperties", "unexpected property %q", name)
+				continue
+			}
+			unevaluatedViolations, _ := v.validate(s.UnevaluatedProperties, object[name], pointerMember(path, name))
+			*violations = append(*violations, unevaluatedViolations...)
+		}
+		for _, name := range names {
+			eval.addProperty(name)
+		}
+	}
+}
+
+// resolve returns the subschema of the root that ref points to: "#", a JSON
+// pointer such as "#/$defs/item", or an anchor such as "#item".
+func (v *schemaValidator) resolve(ref string) *jsonschema.Schema {
+	fragment, ok := strings.CutPrefix(ref, "#")
+	if !ok {
+		if v.root.ID == "" || !strings.HasPrefix(ref, v.root.ID) {
+			return nil
+		}
+		fragment = strings.TrimPrefix(strings.TrimPrefix(ref, v.root.ID), "#")
+	}
+	if fragment == "" {
+		return v.root
+	}
+	if !strings.HasPrefix(fragment, "/") {
+		return findAnchor(v.root, fragment, make(map[*jsonschema.Schema]bool))
+	}
+
+	current := v.root
+	tokens := strings.Split(fragment[1:], "/")
+	for i := 0; i < len(tokens) && current != nil; i++ {
+		keyword := unescapePointer(tokens[i])
+		field, ok := schemaField(current, keyword)
+		if !ok {
+			return nil
+		}
+		switch child := field.Interface().(type) {
+		case *jsonschema.Schema:
+			current = child
+		case []*jsonschema.Schema:
+			if i++; i >= len(tokens) {
+				return nil
+			}
+			index, err := strconv.Atoi(tokens[i])
+			if err != nil || index < 0 || index >= len(child) {
+				return nil
+			}
+			current = child[index]
+		case map[string]*jsonschema.Schema:
+			if i++; i >= len(tokens) {
+				return nil
+			}
+			current = child[unescapePointer(tokens[i])]
+		default:
+			return nil
+		}
+	}
+	return current
+}
+
+// schemaField returns the field of s holding keyword.
+func schemaField(s *jsonschema.Schema, keyword string) (reflect.Value, bool) {
+	v := reflect.ValueOf(s).Elem()
+	for i := range v.NumField() {
+		if name, _, _ := strings.Cut(v.Type().Field(i).Tag.Get("json"), ","); name == keyword {
+			return v.Field(i), true
+		}
+	}
+	return reflect.Value{}, false
+}
+
+// findAnchor returns the subschema of s declaring the anchor or dynamic anchor.
+func findAnchor(s *jsonschema.Schema, anchor string, seen map[*jsonschema.Schema]bool) *jsonschema.Schema {
+	if s == nil || seen[s] {
+		return nil
+	}
+	seen[s] = true
+	if s.Anchor == anchor || s.DynamicAnchor == anchor {
+		return s
+	}
+
+	v := reflect.ValueOf(s).Elem()
+	for i := range v.NumField() {
+		var found *jsonschema.Schema
+		switch child := v.Field(i).Interface().(type) {
+		case *jsonschema.Schema:
+			found = findAnchor(child, anchor, seen)
+		case []*jsonschema.Schema:
+			for _, c := range child {
+				if found = findAnchor(c, anchor, seen); found != nil {
+					break
+				}
+			}
+		case map[string]*jsonschema.Schema:
+			for _, name := range slices.Sorted(maps.Keys(child)) {
+				if found = findAnchor(child[name], anchor, seen); found != nil {
+					break
+				}
+			}
+		}
+		if found != nil {
+			return found
+		}
+	}
+	return nil
+}
+
+// isFalse reports whether s is the schema false.
+func isFalse(s *jsonschema.Schema) bool {
+	if s == nil || s.Not == nil || !reflect.ValueOf(*s.Not).IsZero() {
+		return false
+	}
+	rest := *s
+	rest.Not = nil
+	return reflect.ValueOf(rest).IsZero()
+}
+
+// jsonTypeOf returns the JSON Schema type of a decoded JSON value. Whole numbers
+// are reported as integer.
+func jsonTypeOf(instance any) string {
+	switch value := instance.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return typeBoolean
+	case string:
+		return typeString
+	case []any:
+		return typeArray
+	case map[string]any:
+		return typeObject
+	default:
+		if n, ok := toFloat(value); ok {
+			if n == math.Trunc(n) && !math.IsInf(n, 0) {
+				return typeInteger
+			}
+			return typeNumber
+		}
+		return fmt.Sprintf("%T", instance)
+	}
+}
+
+func typeMatches(want, got string) bool {
+	return want == got || (want == typeNumber && got == typeInteger)
+}
+
+func toFloat(instance any) (float64, bool) {
+	switch n := instance.(type) {
+	case float64:
+		return n, true
+	case float32:
+		return float64(n), true
+	case int:
+		return float64(n), true
+	case int64:
+		return float64(n), true
+	case json.Number:
+		f, err := n.Float64()
+		return f, err == nil
+	default:
+		return 0, false
+	}
+}
+
+// jsonEqual reports whether two decoded JSON values are equal, comparing
+// numbers by value.
+func jsonEqual(a, b any) bool {
+	if x, ok := toFloat(a); ok {
+		y, ok := toFloat(b)
+		return ok && x == y
+	}
+	switch x := a.(type) {
+	case []any:
+		y, ok := b.([]any)
+		return ok && slices.EqualFunc(x, y, jsonEqual)
+	case map[string]any:
+		y, ok := b.(map[string]any)
+		return ok && maps.EqualFunc(x, y, jsonEqual)
+	default:
+		return reflect.DeepEqual(a, b)
+	}
+}
+
+func compactJSON(value any) string {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Sprint(value)
+	}
+	return string(data)
+}
+
+func pointerMember(path, name string) string {
+	return path + "/" + strings.NewReplacer("~", "~0", "/", "~1").Replace(name)
+}
+
+func pointerIndex(path string, i int) string {
+	return path + "/" + strconv.Itoa(i)
+}
+
+func unescapePointer(token string) string {
+	token, _ = url.PathUnescape(token)
+	return strings.NewReplacer("~1", "/", "~0", "~").Replace(token)
+}
+
+// patternCache holds compiled pattern and patternProperties expressions.
+var patternCache sync.Map
+
+func compilePattern(pattern string) (*regexp.Regexp, error) {
+	if re, ok := patternCache.Load(pattern); ok {
+		return re.(*regexp.Regexp), nil
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, err
+	}
+	patternCache.Store(pattern, re)
+	return re, nil
+}
+
+var uuidPattern = regexp.MustCompile(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`)
+
+// formatCheckers assert the formats models are most often asked for.
+var formatCheckers = map[string]func(string) bool{
+	"date-time": func(s string) bool {
+		_, err := time.Parse(time.RFC3339, s)
+		return err == nil
+	},
+	"date": func(s string) bool {
+		_, err := time.Parse(time.DateOnly, s)
+		return err == nil
+	},
+	"time": func(s string) bool {
+		_, err := time.Parse("15:04:05Z07:00", s)
+		if err != nil {
+			_, err = time.Parse("15:04:05.999999999Z07:00", s)
+		}
+		return err == nil
+	},
+	"email": func(s string) bool {
+		address, err := mail.ParseAddress(s)
+		return err == nil && address.Address == s
+	},
+	"uri": func(s string) bool {
+		u, err := url.Parse(s)
+		return err == nil && u.Scheme != ""
+	},
+	"uuid": uuidPattern.MatchString,
+	"ipv4": func(s string) bool {
+		ip := net.ParseIP(s)
+		return ip != nil && ip.To4() != nil && !strings.Contains(s, ":")
+	},
+	"ipv6": func(s string) bool {
+		ip := net.ParseIP(s)
+		return ip != nil && strings.Contains(s, ":")
+	},
+}
@@ -0,0 +1,220 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/google/jsonschema-go/jsonschema"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/weave-labs/gollm/config"
+	"github.com/weave-labs/gollm/internal/logging"
+	"github.com/weave-labs/gollm/providers"
+)
+
+const productSchema = `{
+	"type": "object",
+	"required": ["sku", "price", "tags"],
+	"additionalProperties": false,
+	"properties": {
+		"sku": {"type": "string", "pattern": "^[A-Z]{3}-[0-9]+$"},
+		"price": {"type": "number", "exclusiveMinimum": 0},
+		"size": {"enum": ["S", "M", "L"]},
+		"tags": {"type": "array", "items": {"type": "string"}, "minItems": 1, "uniqueItems": true},
+		"variant": {"$ref": "#/$defs/variant"},
+		"contact": {"oneOf": [{"type": "string", "format": "email"}, {"type": "string", "format": "uri"}]}
+	},
+	"$defs": {
+		"variant": {
+			"type": "object",
+			"required": ["color"],
+			"properties": {"color": {"type": "string", "minLength": 3}}
+		}
+	}
+}`
+
+func parseSchema(t *testing.T, data string) *jsonschema.Schema {
+	t.Helper()
+	var schema jsonschema.Schema
+	require.NoError(t, json.Unmarshal([]byte(data), &schema))
+	return &schema
+}
+
+func TestValidateValue(t *testing.T) {
+	schema := parseSchema(t, productSchema)
+
+	tests := []struct {
+		name     string
+		instance string
+		// violations are the expected violations as "path: message".
+		violations []string
+	}{
+		{
+			name:     "valid",
+			instance: `{"sku":"ABC-1","price":9.5,"tags":["new"],"variant":{"color":"red"},"contact":"a@example.com"}`,
+		},
+		{
+			name:     "every violation is reported",
+			instance: `{"sku":"abc","price":0,"size":"XL","tags":[],"extra":true}`,
+			violations: []string{
+				`/sku: "abc" does not match the pattern "^[A-Z]{3}-[0-9]+$"`,
+				`/price: 0 must be greater than 0`,
+				`/size: "XL" is not one of ["S","M","L"]`,
+				`/tags: array has 0 items, fewer than 1`,
+				`/: unexpected property "extra"`,
+			},
+		},
+		{
+			name:     "references and nested paths",
+			instance: `{"sku":"ABC-1","price":1,"tags":["a","a",3],"variant":{"color":"re"}}`,
+			violations: []string{
+				`/tags/2: expected string, got integer`,
+				`/tags: items 0 and 1 are equal`,
+				`/variant/color: "re" is shorter than 3 characters`,
+			},
+		},
+		{
+			name:     "missing properties and alternatives",
+			instance: `{"contact":"nobody","variant":{}}`,
+			violations: []string{
+				`/: missing required property "sku"`,
+				`/: missing required property "price"`,
+				`/: missing required property "tags"`,
+				`/contact: string does not match any of the 2 alternatives`,
+				`/variant: missing required property "color"`,
+			},
+		},
+		{
+			name:       "wrong root type",
+			instance:   `[1]`,
+			violations: []string{`/: expected object, got array`},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var instance any
+			require.NoError(t, json.Unmarshal([]byte(tt.instance), &instance))
+
+			err := ValidateValue(schema, instance)
+			if tt.violations == nil {
+				require.NoError(t, err)
+				return
+			}
+			var validationErr *SchemaValidationError
+			require.ErrorAs(t, err, &validationErr)
+			got := make([]string, len(validationErr.Violations))
+			for i, violation := range validationErr.Violations {
+				got[i] = violation.String()
+			}
+			assert.ElementsMatch(t, tt.violations, got)
+		})
+	}
+}
+
+func TestValidateValueApplicators(t *testing.T) {
+	schema := parseSchema(t, `{
+		"type": "object",
+		"properties": {"kind": {"type": "string"}},
+		"if": {"properties": {"kind": {"const": "card"}}},
+		"then": {"required": ["number"], "properties": {"number": {"type": "string"}}},
+		"unevaluatedProperties": false
+	}`)
+
+	require.NoError(t, ValidateValue(schema, map[string]any{"kind": "card", "number": "4242"}))
+
+	err := ValidateValue(schema, map[string]any{"kind": "card"})
+	require.ErrorContains(t, err, `/: missing required property "number"`)
+
+	err = ValidateValue(schema, map[string]any{"kind": "cash", "number": "4242"})
+	require.ErrorContains(t, err, `/: unexpected property "number"`, "then did not apply, so number is unevaluated")
+
+	recursive := parseSchema(t, `{
+		"$defs": {"node": {"type": "object", "properties": {"children": {"type": "array", "items": {"$ref": "#/$defs/node"}}}}},
+		"$ref": "#/$defs/node"
+	}`)
+	var tree any
+	require.NoError(t, json.Unmarshal([]byte(`{"children":[{"children":[{"children":"leaf"}]}]}`), &tree))
+	require.EqualError(t, ValidateValue(recursive, tree), "/children/0/children/0/children: expected array, got string")
+}
+
+func TestValidateAgainstSchema(t *testing.T) {
+	require.NoError(t, ValidateAgainstSchema(`{"text":"Hello"}`, `{"type":"object","properties":{"text":{"type":"string"}}}`))
+
+	err := ValidateAgainstSchema(`{"text":1}`, map[string]any{
+		"type":       "object",
+		"properties": map[string]any{"text": map[string]any{"type": "string"}},
+	})
+	var validationErr *SchemaValidationError
+	require.ErrorAs(t, err, &validationErr)
+	assert.Equal(t, "/text", validationErr.Violations[0].Path)
+	assert.Equal(t, "type", validationErr.Violations[0].Keyword)
+}
+
+// newOllamaTestLLM returns an LLM whose provider sends requests to server.
+func newOllamaTestLLM(t *testing.T, server *httptest.Server) *LLMImpl {
+	t.Helper()
+	cfg := &config.Config{Model: "llama3", OllamaEndpoint: server.URL, MaxRetries: 2}
+	provider := providers.NewOllamaProvider("", cfg.Model, nil)
+	provider.SetDefaultOptions(cfg)
+	logger := logging.NewLogger(logging.LogLevelError)
+	provider.SetLogger(logger)
+	return &LLMImpl{
+		Provider:   provider,
+		client:     server.Client(),
+		logger:     logger,
+		config:     cfg,
+		MaxRetries: cfg.MaxRetries,
+		Options:    make(map[string]any),
+	}
+}
+
+func TestGenerateValidatesStructuredResponseJSON(t *testing.T) {
+	replies := []string{`{"sku":"abc","price":1,"tags":["a"]}`, `{"sku":"ABC-1","price":1,"tags":["a"]}`}
+	var requests []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			Prompt string `json:"prompt"`
+			System string `json:"system"`
+		}
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+		requests = append(requests, body.System+"\n"+body.Prompt)
+
+		reply := replies[0]
+		replies = replies[1:]
+		assert.NoError(t, json.NewEncoder(w).Encode(map[string]any{"model": "llama3", "response": reply, "done": true}))
+	}))
+	defer server.Close()
+
+	client := newOllamaTestLLM(t, server)
+	resp, err := client.Generate(context.Background(), NewPrompt("Describe the product"),
+		WithStructuredResponseJSON([]byte(productSchema)))
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"sku":"ABC-1","price":1,"tags":["a"]}`, resp.AsText())
+
+	require.Len(t, requests, 2)
+	assert.Contains(t, requests[0], `"pattern": "^[A-Z]{3}-[0-9]+$"`, "the schema is sent to the provider")
+	assert.Contains(t, requests[1], `/sku: "abc" does not match the pattern`, "the retry repairs the violations")
+}
+
+func TestGenerateReportsSchemaViolations(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		assert.NoError(t, json.NewEncoder(w).Encode(map[string]any{"model": "llama3", "response": `{}`, "done": true}))
+	}))
+	defer server.Close()
+
+	_, err := newOllamaTestLLM(t, server).Generate(context.Background(), NewPrompt("Describe the product"),
+		WithStructuredResponseJSON([]byte(productSchema)))
+	var validationErr *SchemaValidationError
+	require.ErrorAs(t, err, &validationErr)
+	assert.True(t, strings.HasPrefix(err.Error(), "failed to generate after 3 attempts"))
+
+	_, err = newOllamaTestLLM(t, server).Generate(context.Background(), NewPrompt("Describe the product"),
+		WithStructuredResponseJSON([]byte(`{"type": 1}`)))
+	require.ErrorIs(t, err, errInvalidResponseSchema)
+}
@@ -0,0 +1,235 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/weave-labs/gollm/internal/logging"
+	"github.com/weave-labs/gollm/providers"
+)
+
+// SessionManager serves many conversations from one LLM, keeping the history
+// of each in a ConversationStore keyed by session ID. Turns of the same session
+// run one at a time; different sessions run concurrently.
+//
+// Example:
+//
+//	store, err := NewFileConversationStore("conversations")
+//	if err != nil {
+//	    log.Fatal(err)
+//	}
+//	sessions := NewSessionManager(client, store, 4000, WithSessionTTL(24*time.Hour))
+//	go sessions.RunEviction(ctx, time.Hour)
+//
+//	response, err := sessions.Generate(ctx, userID, NewPrompt("What did I ask before?"))
+type SessionManager struct {
+	llm       LLM
+	store     ConversationStore
+	logger    logging.Logger
+	counter   providers.TokenCounter
+	compactor MemoryCompactor
+	rules     providers.MessageRules
+	now       func() time.Time
+	locks     map[string]*sessionLock
+	maxTokens int
+	ttl       time.Duration
+	mu        sync.Mutex
+}
+
+// sessionLock serializes the turns of one session. It is removed from the
+// manager once no turn holds or waits for it.
+type sessionLock struct {
+	mu   sync.Mutex
+	refs int
+}
+
+// SessionOption configures a SessionManager.
+type SessionOption func(*SessionManager)
+
+// WithSessionTTL expires sessions that have not been updated for ttl. Expired
+// sessions start over with an empty history and are deleted from the store by
+// EvictExpired.
+func WithSessionTTL(ttl time.Duration) SessionOption {
+	return func(m *SessionManager) {
+		m.ttl = ttl
+	}
+}
+
+// WithSessionCompactor sets how session histories are compacted when they
+// exceed the token limit. The default is SlidingWindowCompactor.
+func WithSessionCompactor(compactor MemoryCompactor) SessionOption {
+	return func(m *SessionManager) {
+		m.compactor = compactor
+	}
+}
+
+// WithSessionTokenCounter sets the counter for the tokens of session messages.
+// The default is the local token counter of the LLM's provider.
+func WithSessionTokenCounter(counter providers.TokenCounter) SessionOption {
+	return func(m *SessionManager) {
+		m.counter = counter
+	}
+}
+
+// NewSessionManager creates a SessionManager that generates with l, stores
+// histories in store and keeps each history within maxTokens.
+func NewSessionManager(l LLM, store ConversationStore, maxTokens int, opts ...SessionOption) *SessionManager {
+	m := &SessionManager{
+		llm:       l,
+		store:     store,
+		logger:    l.GetLogger(),
+		maxTokens: maxTokens,
+		now:       time.Now,
+		locks:     make(map[string]*sessionLock),
+	}
+	for _, opt := range opts {
+		opt(m)
+	}
+	impl, isImpl := l.(*LLMImpl)
+	if isImpl {
+		m.rules = providers.GetMessageRules(impl.Provider, impl.config.Model)
+	}
+	if m.counter == nil {
+		if isImpl {
+			m.counter = impl.localTokenCounter()
+		} else {
+			m.counter = providers.NewEstimatingTokenCounter(0)
+		}
+	}
+	return m
+}
+
+// Generate runs one turn of a session: it loads the session's history, sends
+// it with prompt, and saves the history with the prompt and the response
+// appended. The prompt's Input, or its Messages if it has any, are recorded as
+// the user's turn. Nothing is saved when generation fails.
+func (m *SessionManager) Generate(
+	ctx context.Context,
+	sessionID string,
+	prompt *Prompt,
+	opts ...GenerateOption,
+) (*providers.Response, error) {
+	unlock := m.lock(sessionID)
+	defer unlock()
+
+	conversation, err := m.load(ctx, sessionID)
+	if err != nil {
+		return nil, err
+	}
+
+	memory := NewMemoryWithCounter(m.maxTokens, m.counter, m.logger)
+	memory.compactor = m.compactor
+	memory.rules = m.rules
+	memory.messages = conversation.Messages
+	memory.totalTokens = memoryTokens(conversation.Messages)
+
+	for _, message := range turnMessages(prompt) {
+		memory.add(ctx, NewMemoryMessage(message))
+	}
+
+	withHistory := *prompt
+	withHistory.Input = ""
+	withHistory.Messages = memory.GetPromptMessages()
+	response, err := m.llm.Generate(ctx, &withHistory, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate response: %w", err)
+	}
+	memory.add(ctx, responseMemoryMessage(response))
+
+	conversation.Messages = memory.GetMessages()
+	conversation.UpdatedAt = m.now()
+	if err := m.store.Save(ctx, conversation); err != nil {
+		return response, fmt.Errorf("failed to save session %q: %w", sessionID, err)
+	}
+	return response, nil
+}
+
+// History returns the messages of a session, or an empty history for an
+// unknown or expired session.
+func (m *SessionManager) History(ctx context.Context, sessionID string) ([]MemoryMessage, error) {
+	conversation, err := m.load(ctx, sessionID)
+	if err != nil {
+		return nil, err
+	}
+	return conversation.Messages, nil
+}
+
+// Delete removes a session's history.
+func (m *SessionManager) Delete(ctx context.Context, sessionID string) error {
+	unlock := m.lock(sessionID)
+	defer unlock()
+
+	return m.store.Delete(ctx, sessionID)
+}
+
+// EvictExpired deletes the sessions that have not been updated within the TTL
+// from the store and returns how many were deleted. Without a TTL it does
+// nothing.
+func (m *SessionManager) EvictExpired(ctx context.Context) (int, error) {
+	if m.ttl <= 0 {
+		return 0, nil
+	}
+	evicted, err := m.store.DeleteBefore(ctx, m.now().Add(-m.ttl))
+	if evicted > 0 {
+		m.logger.Debug("Evicted expired sessions", "count", evicted)
+	}
+	return evicted, err
+}
+
+// RunEviction calls EvictExpired every interval until ctx is done.
+func (m *SessionManager) RunEviction(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if _, err := m.EvictExpired(ctx); err != nil && ctx.Err() == nil {
+				m.logger.Warn("Failed to evict expired sessions", "error", err)
+			}
+		}
+	}
+}
+
+// load returns the stored conversation of a session, or a new one if the
+// session is unknown or expired.
+func (m *SessionManager) load(ctx context.Context, sessionID string) (*Conversation, error) {
+	conversation, err := m.store.Load(ctx, sessionID)
+	switch {
+	case errors.Is(err, ErrConversationNotFound):
+		return &Conversation{ID: sessionID}, nil
+	case err != nil:
+		return nil, fmt.Errorf("failed to load session %q: %w", sessionID, err)
+	case m.ttl > 0 && conversation.UpdatedAt.Before(m.now().Add(-m.ttl)):
+		return &Conversation{ID: sessionID}, nil
+	}
+	return conversation, nil
+}
+
+// lock acquires the lock of a session and returns its release function.
+func (m *SessionManager) lock(sessionID string) func() {
+	m.mu.Lock()
+	lock, ok := m.locks[sessionID]
+	if !ok {
+		lock = &sessionLock{}
+		m.locks[sessionID] = lock
+	}
+	lock.refs++
+	m.mu.Unlock()
+
+	lock.mu.Lock()
+	return func() {
+		lock.mu.Unlock()
+
+		m.mu.Lock()
+		defer m.mu.Unlock()
+		if lock.refs--; lock.refs == 0 {
+			delete(m.locks, sessionID)
+		}
+	}
+}
@@ -0,0 +1,116 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/weave-labs/gollm/internal/logging"
+	"github.com/weave-labs/gollm/providers"
+)
+
+// echoLLM answers with the number of messages it was sent.
+type echoLLM struct {
+	LLM
+	err   error
+	mu    sync.Mutex
+	calls []*Prompt
+}
+
+func (e *echoLLM) Generate(_ context.Context, prompt *Prompt, _ ...GenerateOption) (*providers.Response, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.calls = append(e.calls, prompt)
+	if e.err != nil {
+		return nil, e.err
+	}
+	return &providers.Response{Content: providers.Text{Value: fmt.Sprintf("%d messages", len(prompt.Messages))}}, nil
+}
+
+func (e *echoLLM) GetLogger() logging.Logger { return logging.NewLogger(logging.LogLevelError) }
+
+func TestSessionManager(t *testing.T) {
+	ctx := context.Background()
+	client := &echoLLM{}
+	store := NewInMemoryConversationStore()
+	sessions := NewSessionManager(client, store, 1000)
+
+	_, err := sessions.Generate(ctx, "alice", NewPrompt("Hi, I am Alice", WithSystemPrompt("Be brief", CacheTypeEphemeral)))
+	require.NoError(t, err)
+	_, err = sessions.Generate(ctx, "bob", NewPrompt("Hi, I am Bob"))
+	require.NoError(t, err)
+	response, err := sessions.Generate(ctx, "alice", NewPrompt("Who am I?"))
+	require.NoError(t, err)
+	assert.Equal(t, "3 messages", response.AsText(), "the history of the session is sent")
+
+	last := client.calls[2]
+	assert.Equal(t, "Hi, I am Alice", last.Messages[0].Content)
+	assert.Equal(t, "assistant", last.Messages[1].Role)
+	assert.Equal(t, "Who am I?", last.Messages[2].Content)
+
+	history, err := sessions.History(ctx, "alice")
+	require.NoError(t, err)
+	require.Len(t, history, 4)
+	assert.Equal(t, "3 messages", history[3].Content)
+
+	client.err = errors.New("unavailable")
+	_, err = sessions.Generate(ctx, "bob", NewPrompt("Still there?"))
+	require.Error(t, err)
+	history, err = sessions.History(ctx, "bob")
+	require.NoError(t, err)
+	assert.Len(t, history, 2, "failed turns are not saved")
+
+	require.NoError(t, sessions.Delete(ctx, "bob"))
+	history, err = sessions.History(ctx, "bob")
+	require.NoError(t, err)
+	assert.Empty(t, history)
+}
+
+func TestSessionManagerConcurrentTurns(t *testing.T) {
+	client := &echoLLM{}
+	sessions := NewSessionManager(client, NewInMemoryConversationStore(), 100000)
+
+	var wg sync.WaitGroup
+	for range 20 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := sessions.Generate(context.Background(), "shared", NewPrompt("Hello"))
+			assert.NoError(t, err)
+		}()
+	}
+	wg.Wait()
+
+	history, err := sessions.History(context.Background(), "shared")
+	require.NoError(t, err)
+	assert.Len(t, history, 40, "no turn is lost")
+	assert.Empty(t, sessions.locks, "idle session locks are released")
+}
+
+func TestSessionManagerTTL(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	store := NewInMemoryConversationStore()
+	sessions := NewSessionManager(&echoLLM{}, store, 1000, WithSessionTTL(time.Hour))
+	sessions.now = func() time.Time { return now }
+
+	_, err := sessions.Generate(ctx, "alice", NewPrompt("Hi"))
+	require.NoError(t, err)
+
+	now = now.Add(2 * time.Hour)
+	history, err := sessions.History(ctx, "alice")
+	require.NoError(t, err)
+	assert.Empty(t, history, "expired sessions start over")
+
+	evicted, err := sessions.EvictExpired(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 1, evicted)
+	_, err = store.Load(ctx, "alice")
+	require.ErrorIs(t, err, ErrConversationNotFound)
+}
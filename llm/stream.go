@@ -7,19 +7,26 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"sync"
 	"time"
 
 	"github.com/weave-labs/gollm/providers"
 )
 
 // StreamToken represents a single token from the streaming response.
+// Usage, ToolCalls, FinishReason and ResponseMetadata are only set on tokens whose
+// provider event carried them, typically the first or last ones of a stream.
 type StreamToken struct {
-	Metadata     map[string]any
-	Text         string
-	Type         string
-	Index        int
-	InputTokens  int64
-	OutputTokens int64
+	Metadata         map[string]any
+	Usage            *providers.Usage
+	ResponseMetadata *providers.ResponseMetadata
+	Text             string
+	Type             string
+	FinishReason     providers.FinishReason
+	ToolCalls        []providers.ToolCall
+	Index            int
+	InputTokens      int64
+	OutputTokens     int64
 }
 
 // TokenStream represents a stream of tokens from the LLM.
@@ -45,9 +52,13 @@ type Event struct {
 	Data []byte
 }
 
+// NewSSEDecoder creates a decoder that accepts lines up to MaxSSELineSize bytes,
+// so large events such as tool-call arguments are not rejected by the scanner.
 func NewSSEDecoder(reader io.Reader) *SSEDecoder {
+	scanner := bufio.NewScanner(reader)
+	scanner.Buffer(make([]byte, 0, bufio.MaxScanTokenSize), MaxSSELineSize)
 	return &SSEDecoder{
-		reader: bufio.NewScanner(reader),
+		reader: scanner,
 	}
 }
 
@@ -90,6 +101,9 @@ func (d *SSEDecoder) Next() bool {
 		}
 	}
 
+	if err := d.reader.Err(); err != nil {
+		d.err = fmt.Errorf("failed to read event stream: %w", err)
+	}
 	return false
 }
 
@@ -97,73 +111,260 @@ func (d *SSEDecoder) Event() Event {
 	return d.current
 }
 
+// Err returns the first read error encountered by the decoder, if any.
+// A clean end of stream is not an error.
 func (d *SSEDecoder) Err() error {
 	return d.err
 }
 
-// providerStream implements TokenStream for a specific provider
+// Stream errors returned by TokenStream.Next.
+var (
+	// ErrFirstTokenTimeout is returned when no token arrives within the first-token timeout.
+	ErrFirstTokenTimeout = errors.New("timed out waiting for first stream token")
+	// ErrStreamIdleTimeout is returned when the gap between tokens exceeds the idle timeout.
+	ErrStreamIdleTimeout = errors.New("stream idle timeout exceeded")
+	// ErrStreamDiverged is returned when a resumed stream does not reproduce the
+	// text that was already delivered, so it cannot be continued seamlessly.
+	ErrStreamDiverged = errors.New("resumed stream diverged from delivered output")
+	// ErrStreamClosed is returned by Next after Close has been called.
+	ErrStreamClosed = errors.New("stream closed")
+)
+
+// streamOpener issues the streaming request and returns the response body.
+type streamOpener func(ctx context.Context) (io.ReadCloser, error)
+
+// streamBody is a stream response body annotated with the provider request ID.
+type streamBody struct {
+	io.ReadCloser
+	requestID string
+}
+
+// sseResult carries one decoded event, or the terminal read error, from the
+// reader goroutine to Next.
+type sseResult struct {
+	err   error
+	event Event
+}
+
+// providerStream implements TokenStream for a specific provider.
+//
+// The response body is read by a background goroutine so that Next can honor
+// context cancellation and timeouts while a read is blocked. Closing the stream,
+// or cancelling the context passed to Next, closes the body.
 type providerStream struct {
 	provider      providers.Provider
 	retryStrategy RetryStrategy
-	decoder       *SSEDecoder
+	ctx           context.Context //nolint:containedctx // request lifetime used to re-issue the stream
+	open          streamOpener
 	config        *GenerateConfig
+	body          io.ReadCloser
+	events        chan sseResult
+	stop          chan struct{}
+	startedAt     time.Time
+	delivered     bytes.Buffer
+	requestID     string
 	buffer        []byte
+	terminal      error
 	currentIndex  int
+	replayed      int
+	mu            sync.Mutex
+	closeOnce     sync.Once
+	started       bool
 }
 
-func newProviderStream(reader io.ReadCloser, provider providers.Provider, cfg *GenerateConfig) *providerStream {
-	return &providerStream{
-		decoder:       NewSSEDecoder(reader),
+func newProviderStream(
+	ctx context.Context,
+	body io.ReadCloser,
+	open streamOpener,
+	provider providers.Provider,
+	cfg *GenerateConfig,
+) *providerStream {
+	s := &providerStream{
+		ctx:           ctx,
+		open:          open,
 		provider:      provider,
 		config:        cfg,
 		buffer:        make([]byte, 0, DefaultStreamBufferSize),
 		currentIndex:  0,
 		retryStrategy: cfg.RetryStrategy,
+		stop:          make(chan struct{}),
+		startedAt:     time.Now(),
 	}
+	s.attach(body)
+	return s
+}
+
+// attach starts reading events from body, replacing any previous body.
+func (s *providerStream) attach(body io.ReadCloser) {
+	events := make(chan sseResult)
+	s.mu.Lock()
+	s.body = body
+	s.events = events
+	if sb, ok := body.(*streamBody); ok {
+		s.requestID = sb.requestID
+	}
+	s.mu.Unlock()
+
+	go func() {
+		decoder := NewSSEDecoder(body)
+		for decoder.Next() {
+			select {
+			case events <- sseResult{event: decoder.Event()}:
+			case <-s.stop:
+				return
+			}
+		}
+		err := decoder.Err()
+		if err == nil {
+			err = io.EOF
+		}
+		select {
+		case events <- sseResult{err: err}:
+		case <-s.stop:
+		}
+	}()
 }
 
 func (s *providerStream) Next(ctx context.Context) (*StreamToken, error) {
 	for {
+		s.mu.Lock()
+		terminal, events := s.terminal, s.events
+		s.mu.Unlock()
+		if terminal != nil {
+			return nil, terminal
+		}
+
+		timeout, timeoutErr := s.config.IdleTimeout, ErrStreamIdleTimeout
+		if !s.started && s.config.FirstTokenTimeout > 0 {
+			// The first token is due by a fixed deadline, so keepalives and
+			// skipped events do not extend it.
+			timeout = time.Until(s.startedAt.Add(s.config.FirstTokenTimeout))
+			timeoutErr = ErrFirstTokenTimeout
+			if timeout <= 0 {
+				return nil, s.finish(ErrFirstTokenTimeout)
+			}
+		}
+		var timer *time.Timer
+		var expired <-chan time.Time
+		if timeout > 0 {
+			timer = time.NewTimer(timeout)
+			expired = timer.C
+		}
+
+		var result sseResult
+		var err error
 		select {
 		case <-ctx.Done():
-			return nil, fmt.Errorf("context canceled: %w", ctx.Err())
-		default:
-			token, shouldContinue, err := s.processNextEvent()
+			err = fmt.Errorf("context canceled: %w", ctx.Err())
+		case <-expired:
+			err = timeoutErr
+		case result = <-events:
+			err = result.err
+		}
+		if timer != nil {
+			timer.Stop()
+		}
+
+		if err == nil {
+			token, shouldContinue, err := s.processEvent(result.event)
 			if err != nil {
-				return nil, err
+				return nil, s.finish(err)
 			}
 			if shouldContinue {
 				continue
 			}
 			return token, nil
 		}
+
+		if errors.Is(err, io.EOF) {
+			return nil, s.finish(io.EOF)
+		}
+		if ctx.Err() == nil && s.reconnect(ctx, err) {
+			continue
+		}
+		return nil, s.finish(err)
 	}
 }
 
-// processNextEvent handles the next event from the decoder
-func (s *providerStream) processNextEvent() (*StreamToken, bool, error) {
-	if !s.decoder.Next() {
-		return s.handleDecoderEnd()
+// finish records err as the terminal result of the stream and releases its resources.
+func (s *providerStream) finish(err error) error {
+	s.mu.Lock()
+	if s.terminal == nil {
+		s.terminal = err
+	}
+	err = s.terminal
+	s.mu.Unlock()
+	_ = s.Close()
+	return err
+}
+
+// reconnect re-issues the request after a transient failure when stream
+// reconnection is enabled. Text that was already delivered is replayed from the
+// new stream and skipped, so the caller sees a seamless continuation.
+func (s *providerStream) reconnect(ctx context.Context, cause error) bool {
+	if !s.config.StreamReconnect || s.open == nil || s.retryStrategy == nil || !s.retryStrategy.ShouldRetry(cause) {
+		return false
+	}
+
+	s.closeBody()
+
+	select {
+	case <-ctx.Done():
+		return false
+	case <-s.ctx.Done():
+		return false
+	case <-time.After(s.retryStrategy.NextDelay()):
+	}
+
+	body, err := s.open(s.ctx)
+	if err != nil {
+		return false
 	}
 
-	event := s.decoder.Event()
+	s.mu.Lock()
+	terminal := s.terminal
+	s.mu.Unlock()
+	if terminal != nil {
+		_ = body.Close()
+		return false
+	}
+
+	s.replayed = 0
+	s.attach(body)
+	return true
+}
+
+// processEvent parses an event and turns it into a token, skipping text that a
+// resumed stream replays.
+func (s *providerStream) processEvent(event Event) (*StreamToken, bool, error) {
 	if len(event.Data) == 0 {
 		return nil, true, nil // continue
 	}
 
-	return s.processEventData(event)
-}
+	token, shouldContinue, err := s.processEventData(event)
+	if err != nil || shouldContinue {
+		return token, shouldContinue, err
+	}
 
-// handleDecoderEnd handles the case when decoder has no more events
-func (s *providerStream) handleDecoderEnd() (*StreamToken, bool, error) {
-	if err := s.decoder.Err(); err != nil {
-		if s.retryStrategy.ShouldRetry(err) {
-			time.Sleep(s.retryStrategy.NextDelay())
-			return nil, true, nil // continue
+	if pending := s.delivered.Len() - s.replayed; pending > 0 {
+		text := token.Text
+		n := min(len(text), pending)
+		if !bytes.Equal([]byte(text[:n]), s.delivered.Bytes()[s.replayed:s.replayed+n]) {
+			return nil, false, ErrStreamDiverged
+		}
+		s.replayed += n
+		token.Text = text[n:]
+		if token.Text == "" && !hasUsage(token.Usage) && len(token.ToolCalls) == 0 &&
+			token.FinishReason == "" && token.ResponseMetadata == nil {
+			return nil, true, nil // continue - still replaying delivered text
 		}
-		return nil, false, err
 	}
-	return nil, false, io.EOF
+
+	s.started = true
+	s.delivered.WriteString(token.Text)
+	s.replayed = s.delivered.Len()
+	s.currentIndex++
+	return token, false, nil
 }
 
 // processEventData processes the event data and creates a stream token
@@ -199,13 +400,67 @@ func (s *providerStream) createStreamToken(event Event, resp *providers.Response
 	}
 
 	if resp.Usage != nil {
+		streamToken.Usage = resp.Usage
 		streamToken.InputTokens = resp.Usage.InputTokens
 		streamToken.OutputTokens = resp.Usage.OutputTokens
 	}
 
+	streamToken.ToolCalls = resp.ToolCalls
+	streamToken.FinishReason = resp.FinishReason
+	streamToken.ResponseMetadata = resp.Metadata
+
+	// The request ID and latency are reported with the finish reason
+	if resp.FinishReason != "" {
+		var metadata providers.ResponseMetadata
+		if resp.Metadata != nil {
+			metadata = *resp.Metadata
+		}
+		s.mu.Lock()
+		metadata.RequestID = s.requestID
+		s.mu.Unlock()
+		metadata.Latency = time.Since(s.startedAt)
+		streamToken.ResponseMetadata = &metadata
+	}
+
 	return streamToken
 }
 
+// hasUsage reports whether usage carries any non-zero counts.
+func hasUsage(usage *providers.Usage) bool {
+	return usage != nil && *usage != (providers.Usage{})
+}
+
+// closeBody closes the current response body, unblocking the reader goroutine.
+func (s *providerStream) closeBody() {
+	s.mu.Lock()
+	body := s.body
+	s.body = nil
+	s.mu.Unlock()
+	if body != nil {
+		_ = body.Close()
+	}
+}
+
+// Close stops the reader goroutine and closes the response body.
+// It is safe to call Close more than once.
 func (s *providerStream) Close() error {
+	var err error
+	s.closeOnce.Do(func() {
+		s.mu.Lock()
+		if s.terminal == nil {
+			s.terminal = ErrStreamClosed
+		}
+		body := s.body
+		s.body = nil
+		s.mu.Unlock()
+
+		close(s.stop)
+		if body != nil {
+			err = body.Close()
+		}
+	})
+	if err != nil {
+		return fmt.Errorf("failed to close stream body: %w", err)
+	}
 	return nil
 }
@@ -0,0 +1,238 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"iter"
+	"strings"
+
+	"github.com/weave-labs/gollm/providers"
+)
+
+// StreamAccumulator assembles streamed tokens into a complete response.
+// Text is concatenated, tool-call fragments are merged, usage counts keep their
+// largest reported value, response metadata is merged and the last reported
+// finish reason is kept. The zero value is ready to use.
+type StreamAccumulator struct {
+	usage        *providers.Usage
+	metadata     *providers.ResponseMetadata
+	finishReason providers.FinishReason
+	text         strings.Builder
+	toolCalls    []providers.ToolCall
+	tokens       int
+}
+
+// Add records a token.
+func (a *StreamAccumulator) Add(token *StreamToken) {
+	if token == nil {
+		return
+	}
+	a.tokens++
+	a.text.WriteString(token.Text)
+
+	for _, fragment := range token.ToolCalls {
+		a.addToolCall(fragment)
+	}
+
+	if token.FinishReason != "" {
+		a.finishReason = token.FinishReason
+	}
+
+	if token.ResponseMetadata != nil {
+		a.addMetadata(token.ResponseMetadata)
+	}
+
+	usage := token.Usage
+	if usage == nil && (token.InputTokens != 0 || token.OutputTokens != 0) {
+		usage = &providers.Usage{InputTokens: token.InputTokens, OutputTokens: token.OutputTokens}
+	}
+	if hasUsage(usage) {
+		a.addUsage(usage)
+	}
+}
+
+// addUsage merges usage reported by a token. Providers report cumulative counts,
+// possibly split across events (Anthropic reports input tokens on message_start
+// and output tokens on message_delta), so each field keeps its largest value.
+func (a *StreamAccumulator) addUsage(usage *providers.Usage) {
+	if a.usage == nil {
+		a.usage = &providers.Usage{}
+	}
+	a.usage.InputTokens = max(a.usage.InputTokens, usage.InputTokens)
+	a.usage.CachedInputTokens = max(a.usage.CachedInputTokens, usage.CachedInputTokens)
+	a.usage.OutputTokens = max(a.usage.OutputTokens, usage.OutputTokens)
+	a.usage.CachedOutputTokens = max(a.usage.CachedOutputTokens, usage.CachedOutputTokens)
+	a.usage.ReasoningTokens = max(a.usage.ReasoningTokens, usage.ReasoningTokens)
+	a.usage.TotalTokens = max(a.usage.TotalTokens, usage.TotalTokens,
+		providers.NewUsage(a.usage.InputTokens, a.usage.CachedInputTokens,
+			a.usage.OutputTokens, a.usage.CachedOutputTokens, 0).TotalTokens)
+}
+
+// addToolCall merges a streamed tool-call fragment. A fragment with a new ID starts
+// a new call; a fragment with a known ID or without an ID extends the matching or
+// most recent call, appending its argument text.
+func (a *StreamAccumulator) addToolCall(fragment providers.ToolCall) {
+	target := -1
+	if fragment.ID != "" {
+		for i := range a.toolCalls {
+			if a.toolCalls[i].ID == fragment.ID {
+				target = i
+				break
+			}
+		}
+	} else if len(a.toolCalls) > 0 {
+		target = len(a.toolCalls) - 1
+	}
+
+	if target < 0 {
+		fragment.Function.Arguments = append([]byte(nil), fragment.Function.Arguments...)
+		a.toolCalls = append(a.toolCalls, fragment)
+		return
+	}
+
+	call := &a.toolCalls[target]
+	if call.Type == "" {
+		call.Type = fragment.Type
+	}
+	if call.Function.Name == "" {
+		call.Function.Name = fragment.Function.Name
+	}
+	call.Function.Arguments = append(call.Function.Arguments, fragment.Function.Arguments...)
+}
+
+// addMetadata merges metadata reported by a token. Fields reported later win,
+// so the values of the final events take precedence.
+func (a *StreamAccumulator) addMetadata(metadata *providers.ResponseMetadata) {
+	if a.metadata == nil {
+		a.metadata = &providers.ResponseMetadata{}
+	}
+	if metadata.ID != "" {
+		a.metadata.ID = metadata.ID
+	}
+	if metadata.Model != "" {
+		a.metadata.Model = metadata.Model
+	}
+	if metadata.SystemFingerprint != "" {
+		a.metadata.SystemFingerprint = metadata.SystemFingerprint
+	}
+	if metadata.RequestID != "" {
+		a.metadata.RequestID = metadata.RequestID
+	}
+	if metadata.RawFinishReason != "" {
+		a.metadata.RawFinishReason = metadata.RawFinishReason
+	}
+	if metadata.Latency != 0 {
+		a.metadata.Latency = metadata.Latency
+	}
+}
+
+// Text returns the text accumulated so far.
+func (a *StreamAccumulator) Text() string {
+	return a.text.String()
+}
+
+// Tokens returns the number of tokens added.
+func (a *StreamAccumulator) Tokens() int {
+	return a.tokens
+}
+
+// Response returns the assembled response. Usage is nil when no token reported usage.
+func (a *StreamAccumulator) Response() *providers.Response {
+	resp := &providers.Response{
+		Role:         "assistant",
+		Content:      providers.Text{Value: a.text.String()},
+		FinishReason: a.finishReason,
+	}
+	if a.usage != nil {
+		usage := *a.usage
+		resp.Usage = &usage
+	}
+	if a.metadata != nil {
+		metadata := *a.metadata
+		resp.Metadata = &metadata
+	}
+	if len(a.toolCalls) > 0 {
+		resp.ToolCalls = append([]providers.ToolCall(nil), a.toolCalls...)
+	}
+	return resp
+}
+
+// CollectStream consumes stream until it ends and returns the assembled response.
+// If onToken is not nil it is called for every token; returning an error stops
+// consumption. The stream is closed before CollectStream returns. On failure the
+// partial response collected so far is returned along with the error.
+func CollectStream(
+	ctx context.Context,
+	stream TokenStream,
+	onToken func(*StreamToken) error,
+) (resp *providers.Response, err error) {
+	var acc StreamAccumulator
+	defer func() {
+		if closeErr := stream.Close(); closeErr != nil && err == nil && !errors.Is(closeErr, ErrStreamClosed) {
+			err = fmt.Errorf("failed to close stream: %w", closeErr)
+		}
+	}()
+
+	for {
+		token, err := stream.Next(ctx)
+		if errors.Is(err, io.EOF) {
+			return acc.Response(), nil
+		}
+		if err != nil {
+			return acc.Response(), fmt.Errorf("stream failed: %w", err)
+		}
+
+		acc.Add(token)
+		if onToken != nil {
+			if err := onToken(token); err != nil {
+				return acc.Response(), err
+			}
+		}
+	}
+}
+
+// CollectStreamTo is like CollectStream but writes the text of each token to w as it arrives.
+func CollectStreamTo(ctx context.Context, stream TokenStream, w io.Writer) (*providers.Response, error) {
+	return CollectStream(ctx, stream, func(token *StreamToken) error {
+		if token.Text == "" {
+			return nil
+		}
+		if _, err := io.WriteString(w, token.Text); err != nil {
+			return fmt.Errorf("failed to write token: %w", err)
+		}
+		return nil
+	})
+}
+
+// StreamTokens adapts stream for use with range-over-func loops. The sequence ends
+// at the end of the stream; a failure is yielded once as a nil token with the error.
+// The stream is closed when iteration finishes or the loop exits early.
+//
+// Example usage:
+//
+//	for token, err := range llm.StreamTokens(ctx, stream) {
+//	    if err != nil {
+//	        return err
+//	    }
+//	    fmt.Print(token.Text)
+//	}
+func StreamTokens(ctx context.Context, stream TokenStream) iter.Seq2[*StreamToken, error] {
+	return func(yield func(*StreamToken, error) bool) {
+		defer func() { _ = stream.Close() }()
+		for {
+			token, err := stream.Next(ctx)
+			if errors.Is(err, io.EOF) {
+				return
+			}
+			if err != nil {
+				yield(nil, err)
+				return
+			}
+			if !yield(token, nil) {
+				return
+			}
+		}
+	}
+}
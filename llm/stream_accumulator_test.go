@@ -0,0 +1,188 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/weave-labs/gollm/providers"
+)
+
+// sliceStream is a TokenStream over a fixed list of tokens, optionally ending with an error.
+type sliceStream struct {
+	err    error
+	tokens []*StreamToken
+	closed bool
+}
+
+func (s *sliceStream) Next(context.Context) (*StreamToken, error) {
+	if len(s.tokens) == 0 {
+		if s.err != nil {
+			return nil, s.err
+		}
+		return nil, io.EOF
+	}
+	token := s.tokens[0]
+	s.tokens = s.tokens[1:]
+	return token, nil
+}
+
+func (s *sliceStream) Close() error {
+	s.closed = true
+	return nil
+}
+
+func TestCollectStream(t *testing.T) {
+	stream := &sliceStream{tokens: []*StreamToken{
+		{Text: "Hello", Usage: &providers.Usage{InputTokens: 10}},
+		{Text: ", world", OutputTokens: 2},
+		{ToolCalls: []providers.ToolCall{{
+			ID: "call_1", Type: "function",
+			Function: providers.FunctionCall{Name: "lookup", Arguments: []byte(`{"q":`)},
+		}}},
+		{ToolCalls: []providers.ToolCall{{Function: providers.FunctionCall{Arguments: []byte(`"go"}`)}}}},
+		{FinishReason: "tool_calls", Usage: &providers.Usage{OutputTokens: 5, TotalTokens: 15}},
+	}}
+
+	var forwarded []string
+	resp, err := CollectStream(context.Background(), stream, func(token *StreamToken) error {
+		forwarded = append(forwarded, token.Text)
+		return nil
+	})
+	require.NoError(t, err)
+	assert.True(t, stream.closed)
+	assert.Len(t, forwarded, 5)
+
+	assert.Equal(t, "Hello, world", resp.AsText())
+	assert.Equal(t, providers.FinishReasonToolCalls, resp.FinishReason)
+	require.NotNil(t, resp.Usage)
+	assert.Equal(t, int64(10), resp.Usage.InputTokens)
+	assert.Equal(t, int64(5), resp.Usage.OutputTokens)
+	assert.Equal(t, int64(15), resp.Usage.TotalTokens)
+	require.Len(t, resp.ToolCalls, 1)
+	assert.Equal(t, "lookup", resp.ToolCalls[0].Function.Name)
+	assert.JSONEq(t, `{"q":"go"}`, string(resp.ToolCalls[0].Function.Arguments))
+}
+
+// accumulateEvents parses stream events with p and accumulates the tokens, skipping
+// events the parser does not turn into tokens.
+func accumulateEvents(t *testing.T, p providers.Provider, events ...string) *providers.Response {
+	t.Helper()
+
+	var acc StreamAccumulator
+	for _, event := range events {
+		resp, err := p.ParseStreamResponse([]byte(event))
+		if err != nil {
+			continue
+		}
+		acc.Add(&StreamToken{
+			Text:         resp.AsText(),
+			Usage:        resp.Usage,
+			ToolCalls:    resp.ToolCalls,
+			FinishReason: resp.FinishReason,
+		})
+	}
+	return acc.Response()
+}
+
+func TestStreamAccumulatorAnthropicUsage(t *testing.T) {
+	// Anthropic reports cumulative usage on message_start and message_delta.
+	resp := accumulateEvents(t, providers.NewAnthropicProvider("key", "claude-3-5-haiku-latest", nil),
+		`{"type":"message_start","message":{"id":"msg_1","model":"claude-3-5-haiku-latest",`+
+			`"usage":{"input_tokens":25,"output_tokens":1}}}`,
+		`{"type":"content_block_delta","index":0,"delta":{"type":"text_delta","text":"Hi"}}`,
+		`{"type":"message_delta","delta":{"stop_reason":"end_turn"},"usage":{"output_tokens":12}}`,
+	)
+
+	require.NotNil(t, resp.Usage)
+	assert.Equal(t, int64(25), resp.Usage.InputTokens)
+	assert.Equal(t, int64(12), resp.Usage.OutputTokens)
+	assert.Equal(t, int64(37), resp.Usage.TotalTokens)
+	assert.Equal(t, "Hi", resp.AsText())
+}
+
+func TestStreamAccumulatorAnthropicToolCalls(t *testing.T) {
+	resp := accumulateEvents(t, providers.NewAnthropicProvider("key", "claude-3-5-haiku-latest", nil),
+		`{"type":"content_block_start","index":0,"content_block":{"type":"text","text":""}}`,
+		`{"type":"content_block_delta","index":0,"delta":{"type":"text_delta","text":"Checking."}}`,
+		`{"type":"content_block_start","index":1,"content_block":{"type":"tool_use",`+
+			`"id":"toolu_1","name":"get_weather","input":{}}}`,
+		`{"type":"content_block_delta","index":1,"delta":{"type":"input_json_delta","partial_json":""}}`,
+		`{"type":"content_block_delta","index":1,"delta":{"type":"input_json_delta","partial_json":"{\"city\": "}}`,
+		`{"type":"content_block_delta","index":1,"delta":{"type":"input_json_delta","partial_json":"\"Tokyo\"}"}}`,
+		`{"type":"content_block_stop","index":1}`,
+		`{"type":"message_delta","delta":{"stop_reason":"tool_use"},"usage":{"output_tokens":40}}`,
+	)
+
+	assert.Equal(t, "Checking.", resp.AsText())
+	assert.Equal(t, providers.FinishReasonToolCalls, resp.FinishReason)
+	require.Len(t, resp.ToolCalls, 1)
+	assert.Equal(t, "toolu_1", resp.ToolCalls[0].ID)
+	assert.Equal(t, "get_weather", resp.ToolCalls[0].Function.Name)
+	assert.JSONEq(t, `{"city":"Tokyo"}`, string(resp.ToolCalls[0].Function.Arguments))
+}
+
+func TestStreamAccumulatorOpenAIToolCalls(t *testing.T) {
+	resp := accumulateEvents(t, providers.NewOpenAIProvider("key", "gpt-4o", nil),
+		`{"id":"c1","choices":[{"delta":{"role":"assistant","content":null,"tool_calls":[{"index":0,`+
+			`"id":"call_1","type":"function","function":{"name":"get_weather","arguments":""}}]}}]}`,
+		`{"id":"c1","choices":[{"delta":{"tool_calls":[{"index":0,"function":{"arguments":"{\"city\":"}}]}}]}`,
+		`{"id":"c1","choices":[{"delta":{"tool_calls":[{"index":0,"function":{"arguments":"\"Tokyo\"}"}}]}}]}`,
+		`{"id":"c1","choices":[{"delta":{"tool_calls":[{"index":1,`+
+			`"id":"call_2","type":"function","function":{"name":"get_time","arguments":"{}"}}]}}]}`,
+		`{"id":"c1","choices":[{"delta":{},"finish_reason":"tool_calls"}]}`,
+		"[DONE]",
+	)
+
+	assert.Equal(t, providers.FinishReasonToolCalls, resp.FinishReason)
+	require.Len(t, resp.ToolCalls, 2)
+	assert.Equal(t, "call_1", resp.ToolCalls[0].ID)
+	assert.Equal(t, "get_weather", resp.ToolCalls[0].Function.Name)
+	assert.JSONEq(t, `{"city":"Tokyo"}`, string(resp.ToolCalls[0].Function.Arguments))
+	assert.Equal(t, "call_2", resp.ToolCalls[1].ID)
+	assert.Equal(t, "get_time", resp.ToolCalls[1].Function.Name)
+	assert.JSONEq(t, `{}`, string(resp.ToolCalls[1].Function.Arguments))
+}
+
+func TestCollectStreamToWriterAndErrors(t *testing.T) {
+	streamErr := errors.New("connection reset")
+	stream := &sliceStream{
+		tokens: []*StreamToken{{Text: "partial "}, {Text: "answer"}},
+		err:    streamErr,
+	}
+
+	var out strings.Builder
+	resp, err := CollectStreamTo(context.Background(), stream, &out)
+	require.ErrorIs(t, err, streamErr)
+	assert.Equal(t, "partial answer", out.String())
+	assert.Equal(t, "partial answer", resp.AsText(), "partial response is returned on failure")
+	assert.Nil(t, resp.Usage)
+	assert.True(t, stream.closed)
+}
+
+func TestStreamTokens(t *testing.T) {
+	stream := &sliceStream{tokens: []*StreamToken{{Text: "a"}, {Text: "b"}, {Text: "c"}}}
+
+	var texts []string
+	for token, err := range StreamTokens(context.Background(), stream) {
+		require.NoError(t, err)
+		texts = append(texts, token.Text)
+		if token.Text == "b" {
+			break
+		}
+	}
+	assert.Equal(t, []string{"a", "b"}, texts)
+	assert.True(t, stream.closed, "stream is closed when the loop exits early")
+
+	streamErr := errors.New("boom")
+	var gotErr error
+	for _, err := range StreamTokens(context.Background(), &sliceStream{err: streamErr}) {
+		gotErr = err
+	}
+	require.ErrorIs(t, gotErr, streamErr)
+}
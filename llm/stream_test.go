@@ -0,0 +1,254 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/weave-labs/gollm/providers"
+)
+
+// sseTextProvider parses "data: <text>" events as plain text tokens and "[DONE]" as the end of stream.
+type sseTextProvider struct {
+	providers.Provider
+}
+
+func (sseTextProvider) ParseStreamResponse(chunk []byte) (*providers.Response, error) {
+	text := strings.TrimSuffix(string(chunk), "\n")
+	if text == "[DONE]" {
+		return nil, io.EOF
+	}
+	return &providers.Response{Content: providers.Text{Value: text}}, nil
+}
+
+// trackedBody is a response body that records Close and can fail after its content.
+type trackedBody struct {
+	io.Reader
+	closed atomic.Bool
+}
+
+func (b *trackedBody) Close() error {
+	b.closed.Store(true)
+	if c, ok := b.Reader.(io.Closer); ok {
+		return c.Close()
+	}
+	return nil
+}
+
+type failingReader struct {
+	r   io.Reader
+	err error
+}
+
+func (f *failingReader) Read(p []byte) (int, error) {
+	n, err := f.r.Read(p)
+	if errors.Is(err, io.EOF) {
+		return n, f.err
+	}
+	return n, err
+}
+
+func sse(events ...string) string {
+	var b strings.Builder
+	for _, e := range events {
+		b.WriteString("data: " + e + "\n\n")
+	}
+	return b.String()
+}
+
+func newTestStream(body io.ReadCloser, open streamOpener, cfg *GenerateConfig) *providerStream {
+	if cfg.RetryStrategy == nil {
+		cfg.RetryStrategy = &DefaultRetryStrategy{MaxRetries: 2}
+	}
+	return newProviderStream(context.Background(), body, open, sseTextProvider{}, cfg)
+}
+
+func collect(t *testing.T, stream TokenStream) (string, error) {
+	t.Helper()
+	var text strings.Builder
+	for {
+		token, err := stream.Next(context.Background())
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				return text.String(), nil
+			}
+			return text.String(), err
+		}
+		text.WriteString(token.Text)
+	}
+}
+
+func TestProviderStreamClosesBody(t *testing.T) {
+	body := &trackedBody{Reader: strings.NewReader(sse("Hello", " world", "[DONE]"))}
+	stream := newTestStream(body, nil, &GenerateConfig{})
+
+	text, err := collect(t, stream)
+	require.NoError(t, err)
+	assert.Equal(t, "Hello world", text)
+	assert.True(t, body.closed.Load(), "body is closed at end of stream")
+
+	_, err = stream.Next(context.Background())
+	require.ErrorIs(t, err, io.EOF)
+	require.NoError(t, stream.Close())
+}
+
+func TestProviderStreamTokenIndex(t *testing.T) {
+	body := &trackedBody{Reader: strings.NewReader(sse("a", "b", "[DONE]"))}
+	stream := newTestStream(body, nil, &GenerateConfig{})
+
+	first, err := stream.Next(context.Background())
+	require.NoError(t, err)
+	second, err := stream.Next(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 0, first.Index)
+	assert.Equal(t, 1, second.Index)
+}
+
+func TestProviderStreamContextCancellation(t *testing.T) {
+	pr, pw := io.Pipe()
+	defer pw.Close()
+	body := &trackedBody{Reader: pr}
+	stream := newTestStream(body, nil, &GenerateConfig{})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	_, err := stream.Next(ctx)
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.True(t, body.closed.Load())
+
+	_, err = stream.Next(context.Background())
+	require.ErrorIs(t, err, context.DeadlineExceeded, "stream stays terminated")
+}
+
+func TestProviderStreamTimeouts(t *testing.T) {
+	t.Run("first token", func(t *testing.T) {
+		pr, pw := io.Pipe()
+		defer pw.Close()
+		stream := newTestStream(&trackedBody{Reader: pr}, nil,
+			&GenerateConfig{FirstTokenTimeout: 20 * time.Millisecond})
+
+		_, err := stream.Next(context.Background())
+		require.ErrorIs(t, err, ErrFirstTokenTimeout)
+	})
+
+	t.Run("first token after keepalives", func(t *testing.T) {
+		pr, pw := io.Pipe()
+		defer pw.Close()
+		stream := newTestStream(&trackedBody{Reader: pr}, nil,
+			&GenerateConfig{FirstTokenTimeout: 30 * time.Millisecond})
+
+		done := make(chan struct{})
+		defer close(done)
+		go func() {
+			ticker := time.NewTicker(5 * time.Millisecond)
+			defer ticker.Stop()
+			for {
+				select {
+				case <-done:
+					return
+				case <-ticker.C:
+					if _, err := io.WriteString(pw, "event: ping\n\n"); err != nil {
+						return
+					}
+				}
+			}
+		}()
+
+		start := time.Now()
+		_, err := stream.Next(context.Background())
+		require.ErrorIs(t, err, ErrFirstTokenTimeout, "keepalives do not extend the first-token timeout")
+		assert.Less(t, time.Since(start), 200*time.Millisecond)
+	})
+
+	t.Run("idle", func(t *testing.T) {
+		pr, pw := io.Pipe()
+		defer pw.Close()
+		stream := newTestStream(&trackedBody{Reader: pr}, nil,
+			&GenerateConfig{FirstTokenTimeout: time.Second, IdleTimeout: 20 * time.Millisecond})
+
+		go func() { _, _ = io.WriteString(pw, sse("Hello")) }()
+
+		token, err := stream.Next(context.Background())
+		require.NoError(t, err)
+		assert.Equal(t, "Hello", token.Text)
+
+		_, err = stream.Next(context.Background())
+		require.ErrorIs(t, err, ErrStreamIdleTimeout)
+	})
+}
+
+func TestSSEDecoderLongLinesAndErrors(t *testing.T) {
+	long := strings.Repeat("x", 200*1024)
+	decoder := NewSSEDecoder(strings.NewReader(sse(long)))
+	require.True(t, decoder.Next())
+	assert.Len(t, decoder.Event().Data, len(long)+1)
+	require.False(t, decoder.Next())
+	require.NoError(t, decoder.Err())
+
+	readErr := errors.New("connection reset")
+	decoder = NewSSEDecoder(&failingReader{r: strings.NewReader(sse("a")), err: readErr})
+	require.True(t, decoder.Next())
+	require.False(t, decoder.Next())
+	require.ErrorIs(t, decoder.Err(), readErr)
+}
+
+func TestProviderStreamSurfacesReadErrors(t *testing.T) {
+	readErr := errors.New("connection reset")
+	body := &trackedBody{Reader: &failingReader{r: strings.NewReader(sse("Hel")), err: readErr}}
+	stream := newTestStream(body, nil, &GenerateConfig{})
+
+	text, err := collect(t, stream)
+	require.ErrorIs(t, err, readErr)
+	assert.Equal(t, "Hel", text)
+}
+
+func TestProviderStreamReconnect(t *testing.T) {
+	readErr := errors.New("connection reset")
+	first := &trackedBody{Reader: &failingReader{r: strings.NewReader(sse("Hel")), err: readErr}}
+
+	t.Run("resumes after delivered text", func(t *testing.T) {
+		var opens int
+		open := func(context.Context) (io.ReadCloser, error) {
+			opens++
+			return &trackedBody{Reader: strings.NewReader(sse("He", "llo", "!", "[DONE]"))}, nil
+		}
+		stream := newTestStream(first, open, &GenerateConfig{StreamReconnect: true})
+
+		text, err := collect(t, stream)
+		require.NoError(t, err)
+		assert.Equal(t, "Hello!", text)
+		assert.Equal(t, 1, opens)
+		assert.True(t, first.closed.Load())
+	})
+
+	t.Run("diverged", func(t *testing.T) {
+		body := &trackedBody{Reader: &failingReader{r: strings.NewReader(sse("Hel")), err: readErr}}
+		open := func(context.Context) (io.ReadCloser, error) {
+			return &trackedBody{Reader: strings.NewReader(sse("Goodbye", "[DONE]"))}, nil
+		}
+		stream := newTestStream(body, open, &GenerateConfig{StreamReconnect: true})
+
+		_, err := collect(t, stream)
+		require.ErrorIs(t, err, ErrStreamDiverged)
+	})
+
+	t.Run("disabled", func(t *testing.T) {
+		body := &trackedBody{Reader: &failingReader{r: strings.NewReader(sse("Hel")), err: readErr}}
+		open := func(context.Context) (io.ReadCloser, error) {
+			t.Fatal("request must not be re-issued")
+			return nil, nil
+		}
+		stream := newTestStream(body, open, &GenerateConfig{})
+
+		_, err := collect(t, stream)
+		require.ErrorIs(t, err, readErr)
+	})
+}
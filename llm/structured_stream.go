@@ -0,0 +1,160 @@
+package llm
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"iter"
+
+	"github.com/google/jsonschema-go/jsonschema"
+
+	"github.com/weave-labs/gollm/providers"
+)
+
+// ErrIncompleteStructuredResponse is returned when a structured stream ends before
+// the JSON object is complete.
+var ErrIncompleteStructuredResponse = errors.New("stream ended before the structured response was complete")
+
+// StructuredSnapshot is one update of a structured response that is being streamed.
+type StructuredSnapshot[T any] struct {
+	// Value holds every field parsed so far. Fields that have not arrived yet keep
+	// their zero values, and a string that is still streaming holds its text so far.
+	Value *T
+	// Token is the token that produced this update. It is nil on the final snapshot.
+	Token *StreamToken
+	// Completed lists the JSON pointers (for example "/title" or "/items/0") of the
+	// values completed by this update. Nested values are listed before their parents.
+	Completed []string
+	// Final is set on the last snapshot, whose Value is the complete response after
+	// it has been validated against the schema and `validate` tags of T.
+	Final bool
+}
+
+// GenerateStructuredStream streams a structured response of type T, adding
+// WithStructuredResponse[T] to opts. See StreamStructured for how the stream is consumed.
+func GenerateStructuredStream[T any](
+	ctx context.Context,
+	l LLM,
+	prompt *Prompt,
+	opts ...GenerateOption,
+) iter.Seq2[*StructuredSnapshot[T], error] {
+	return func(yield func(*StructuredSnapshot[T], error) bool) {
+		opts = append(opts[:len(opts):len(opts)], WithStructuredResponse[T]())
+		stream, err := l.GenerateStream(ctx, prompt, opts...)
+		if err != nil {
+			yield(nil, err)
+			return
+		}
+		for snapshot, err := range StreamStructured[T](ctx, stream) {
+			if !yield(snapshot, err) {
+				return
+			}
+		}
+	}
+}
+
+// StreamStructured parses a stream of JSON fragments tolerantly as they arrive and
+// yields a progressively populated T whenever the parsed value changes or a field
+// is completed. When the stream ends, the complete object is validated against the
+// JSON schema of T and yielded as the final snapshot.
+//
+// A failure, including a validation failure, is yielded once as a nil snapshot with
+// the error. The stream is closed when iteration finishes or the loop exits early.
+//
+// Example usage:
+//
+//	for snapshot, err := range llm.StreamStructured[Recipe](ctx, stream) {
+//	    if err != nil {
+//	        return err
+//	    }
+//	    render(snapshot.Value)
+//	}
+func StreamStructured[T any](ctx context.Context, stream TokenStream) iter.Seq2[*StructuredSnapshot[T], error] {
+	return func(yield func(*StructuredSnapshot[T], error) bool) {
+		defer func() { _ = stream.Close() }()
+
+		var parser PartialJSONParser
+		var last []byte
+		var finalCompleted []string
+		for {
+			token, err := stream.Next(ctx)
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			if err != nil {
+				yield(nil, err)
+				return
+			}
+			if token.Text == "" || parser.Done() {
+				continue
+			}
+
+			if _, err := parser.WriteString(token.Text); err != nil {
+				yield(nil, fmt.Errorf("failed to parse structured response: %w", err))
+				return
+			}
+
+			completed := parser.Completed()
+			if parser.Done() {
+				finalCompleted = completed
+				continue
+			}
+			current := parser.Snapshot()
+			if current == nil || (bytes.Equal(current, last) && len(completed) == 0) {
+				continue
+			}
+			last = current
+
+			value := new(T)
+			if err := json.Unmarshal(current, value); err != nil {
+				// The partial value does not fit T yet, for example a string
+				// that will turn out to be part of a larger value. Wait for more.
+				continue
+			}
+			if !yield(&StructuredSnapshot[T]{Value: value, Token: token, Completed: completed}, nil) {
+				return
+			}
+		}
+
+		if !parser.Done() {
+			yield(nil, ErrIncompleteStructuredResponse)
+			return
+		}
+		value, err := decodeStructured[T](parser.Snapshot())
+		if err != nil {
+			yield(nil, err)
+			return
+		}
+		yield(&StructuredSnapshot[T]{Value: value, Completed: finalCompleted, Final: true}, nil)
+	}
+}
+
+// decodeStructured validates data against the JSON schema of T, decodes it and
+// validates the result against its `validate` struct tags.
+func decodeStructured[T any](data []byte) (*T, error) {
+	schema, err := jsonschema.For[T](&jsonschema.ForOptions{IgnoreInvalidTypes: true})
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate schema: %w", err)
+	}
+	var instance any
+	if err := json.Unmarshal(data, &instance); err != nil {
+		return nil, NewLLMError(ErrorTypeResponse, "invalid structured response", err)
+	}
+	// Providers in strict mode send null for optional fields they leave out.
+	providers.StripOptionalNulls(schema, instance)
+	if err := ValidateValue(schema, instance); err != nil {
+		return nil, NewLLMError(ErrorTypeResponse, "structured response does not match schema", err)
+	}
+
+	value := new(T)
+	if err := json.Unmarshal(data, value); err != nil {
+		return nil, NewLLMError(ErrorTypeResponse, "failed to unmarshal structured response", err)
+	}
+	if err := validateTags(value); err != nil {
+		return nil, err
+	}
+	return value, nil
+}
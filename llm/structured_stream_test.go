@@ -0,0 +1,90 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type streamedRecipe struct {
+	Title    string   `json:"title"`
+	Steps    []string `json:"steps"`
+	Servings int      `json:"servings"`
+}
+
+func textStream(fragments ...string) *sliceStream {
+	tokens := make([]*StreamToken, len(fragments))
+	for i, fragment := range fragments {
+		tokens[i] = &StreamToken{Text: fragment, Index: i}
+	}
+	return &sliceStream{tokens: tokens}
+}
+
+func TestStreamStructured(t *testing.T) {
+	stream := textStream(`{"title":"Pan`, `cakes","ste`, `ps":["Mix","Fry`, `"],"servings":`, `4}`)
+
+	var snapshots []*StructuredSnapshot[streamedRecipe]
+	for snapshot, err := range StreamStructured[streamedRecipe](context.Background(), stream) {
+		require.NoError(t, err)
+		snapshots = append(snapshots, snapshot)
+	}
+
+	require.Len(t, snapshots, 5)
+	assert.True(t, stream.closed)
+
+	assert.Equal(t, "Pan", snapshots[0].Value.Title)
+	assert.Empty(t, snapshots[0].Completed)
+
+	assert.Equal(t, "Pancakes", snapshots[1].Value.Title)
+	assert.Equal(t, []string{"/title"}, snapshots[1].Completed)
+
+	assert.Equal(t, []string{"Mix", "Fry"}, snapshots[2].Value.Steps)
+	assert.Equal(t, []string{"/steps/0"}, snapshots[2].Completed)
+
+	assert.Equal(t, []string{"/steps/1", "/steps"}, snapshots[3].Completed)
+	assert.Zero(t, snapshots[3].Value.Servings)
+
+	final := snapshots[4]
+	assert.True(t, final.Final)
+	assert.Nil(t, final.Token)
+	assert.Equal(t, []string{"/servings"}, final.Completed)
+	assert.Equal(t, streamedRecipe{Title: "Pancakes", Steps: []string{"Mix", "Fry"}, Servings: 4}, *final.Value)
+}
+
+func TestStreamStructuredFailures(t *testing.T) {
+	collectErr := func(stream TokenStream) error {
+		for _, err := range StreamStructured[streamedRecipe](context.Background(), stream) {
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	t.Run("incomplete", func(t *testing.T) {
+		err := collectErr(textStream(`{"title":"Pancakes"`))
+		require.ErrorIs(t, err, ErrIncompleteStructuredResponse)
+	})
+
+	t.Run("schema violation", func(t *testing.T) {
+		err := collectErr(textStream(`{"title":"Pancakes","steps":[],"servings":"four"}`))
+		var llmErr *LLMError
+		require.ErrorAs(t, err, &llmErr)
+		assert.Equal(t, ErrorTypeResponse, llmErr.Type)
+	})
+
+	t.Run("malformed", func(t *testing.T) {
+		err := collectErr(textStream(`{"title" "Pancakes"}`))
+		require.ErrorIs(t, err, ErrInvalidPartialJSON)
+	})
+
+	t.Run("stream error", func(t *testing.T) {
+		streamErr := errors.New("connection reset")
+		stream := textStream(`{"title":`)
+		stream.err = streamErr
+		require.ErrorIs(t, collectErr(stream), streamErr)
+	})
+}
@@ -0,0 +1,451 @@
+package llm
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"maps"
+	"os"
+	"path"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/weave-labs/gollm/config"
+)
+
+// ErrTemplateNotFound is returned when a TemplateLibrary has no template with
+// the requested name or version.
+var ErrTemplateNotFound = errors.New("template not found")
+
+// templateExtensions are the file extensions loaded by LoadTemplates.
+var templateExtensions = []string{".md", ".tmpl", ".yaml", ".yml"}
+
+// partialsDir is the directory name whose files are loaded as partials.
+const partialsDir = "partials"
+
+// TemplateVariable declares a variable used by a PromptTemplate.
+//
+// In front matter, variables are a map from name to either a type or a full
+// declaration:
+//
+//	variables:
+//	  text: string
+//	  words:
+//	    type: int
+//	    default: 100
+//	    description: Length of the summary
+type TemplateVariable struct {
+	Name        string `yaml:"-"`
+	Type        string `yaml:"type"`        // string, int, number, bool, array, object or any
+	Required    bool   `yaml:"required"`    // Execute fails when a required variable is missing
+	Default     any    `yaml:"default"`     // Value used when the variable is missing
+	Description string `yaml:"description"` // Human-readable description of the variable
+}
+
+// UnmarshalYAML accepts a bare type as shorthand for a required variable.
+func (v *TemplateVariable) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind == yaml.ScalarNode {
+		v.Type = node.Value
+		v.Required = true
+		return nil
+	}
+	type plain TemplateVariable
+	return node.Decode((*plain)(v))
+}
+
+// TemplateModel is the model configuration a template is written for.
+type TemplateModel struct {
+	Provider    string   `yaml:"provider"`
+	Model       string   `yaml:"model"`
+	Temperature *float64 `yaml:"temperature"`
+	TopP        *float64 `yaml:"top_p"`
+	MaxTokens   int      `yaml:"max_tokens"`
+}
+
+// ConfigOptions returns the configuration options for the model, to be passed
+// to NewLLM.
+func (m *TemplateModel) ConfigOptions() []config.ConfigOption {
+	if m == nil {
+		return nil
+	}
+	var opts []config.ConfigOption
+	if m.Provider != "" {
+		opts = append(opts, config.SetProvider(m.Provider))
+	}
+	if m.Model != "" {
+		opts = append(opts, config.SetModel(m.Model))
+	}
+	if m.Temperature != nil {
+		opts = append(opts, config.SetTemperature(*m.Temperature))
+	}
+	if m.TopP != nil {
+		opts = append(opts, config.SetTopP(*m.TopP))
+	}
+	if m.MaxTokens > 0 {
+		opts = append(opts, config.SetMaxTokens(m.MaxTokens))
+	}
+	return opts
+}
+
+// templateFile is the front matter of a template file. In YAML files the
+// template itself is the template field.
+type templateFile struct {
+	Name         string                      `yaml:"name"`
+	Version      string                      `yaml:"version"`
+	Description  string                      `yaml:"description"`
+	Variables    map[string]TemplateVariable `yaml:"variables"`
+	SystemPrompt string                      `yaml:"system_prompt"`
+	Context      string                      `yaml:"context"`
+	Directives   []string                    `yaml:"directives"`
+	Output       string                      `yaml:"output"`
+	Examples     []string                    `yaml:"examples"`
+	MaxLength    int                         `yaml:"max_length"`
+	Model        *TemplateModel              `yaml:"model"`
+	Template     string                      `yaml:"template"`
+}
+
+// ParsePromptTemplate parses a template file. Markdown and text templates may
+// start with YAML front matter between "---" lines; YAML templates put the
+// template in a template field next to the front matter fields:
+//
+//	---
+//	name: summarize
+//	version: 1.2.0
+//	variables:
+//	  text: string
+//	system_prompt: You are a concise summarizer.
+//	directives:
+//	  - Keep the original meaning
+//	model:
+//	  provider: openai
+//	  model: gpt-4o-mini
+//	---
+//	Summarize the following text:
+//
+//	{{.text}}
+//
+// The front matter options are applied to every prompt generated from the
+// template. A template without a name is named after its file by
+// TemplateLibrary.
+func ParsePromptTemplate(data []byte) (*PromptTemplate, error) {
+	return parsePromptTemplate(data, false)
+}
+
+func parsePromptTemplate(data []byte, isYAML bool) (*PromptTemplate, error) {
+	var file templateFile
+	body := string(data)
+	if isYAML {
+		if err := yaml.Unmarshal(data, &file); err != nil {
+			return nil, fmt.Errorf("failed to parse template: %w", err)
+		}
+		body = file.Template
+	} else if frontMatter, rest, ok := cutFrontMatter(body); ok {
+		if err := yaml.Unmarshal([]byte(frontMatter), &file); err != nil {
+			return nil, fmt.Errorf("failed to parse front matter: %w", err)
+		}
+		body = rest
+	}
+
+	pt := NewPromptTemplate(file.Name, file.Description, body)
+	pt.Version = file.Version
+	pt.Model = file.Model
+	for _, name := range slices.Sorted(maps.Keys(file.Variables)) {
+		variable := file.Variables[name]
+		variable.Name = name
+		if !isTemplateVariableType(variable.Type) {
+			return nil, fmt.Errorf("variable %q has unknown type %q", name, variable.Type)
+		}
+		pt.Variables = append(pt.Variables, variable)
+	}
+
+	if file.SystemPrompt != "" {
+		pt.Options = append(pt.Options, WithSystemPrompt(file.SystemPrompt, ""))
+	}
+	if file.Context != "" {
+		pt.Options = append(pt.Options, WithContext(file.Context))
+	}
+	if len(file.Directives) > 0 {
+		pt.Options = append(pt.Options, WithDirectives(file.Directives...))
+	}
+	if file.Output != "" {
+		pt.Options = append(pt.Options, WithOutput(file.Output))
+	}
+	if len(file.Examples) > 0 {
+		pt.Options = append(pt.Options, WithExamples(file.Examples...))
+	}
+	if file.MaxLength > 0 {
+		pt.Options = append(pt.Options, WithMaxLength(file.MaxLength))
+	}
+	return pt, nil
+}
+
+// cutFrontMatter splits a document into its front matter and the rest.
+func cutFrontMatter(doc string) (frontMatter, rest string, ok bool) {
+	doc = strings.TrimPrefix(doc, "\ufeff")
+	first, after, found := strings.Cut(doc, "\n")
+	if !found || strings.TrimSpace(first) != "---" {
+		return "", doc, false
+	}
+	for offset := 0; offset < len(after); {
+		line, next, _ := strings.Cut(after[offset:], "\n")
+		if strings.TrimSpace(line) == "---" {
+			return after[:offset], next, true
+		}
+		offset += len(line) + 1
+	}
+	return "", doc, false
+}
+
+// TemplateLibrary is a set of prompt templates loaded from files, with every
+// version of each template.
+type TemplateLibrary struct {
+	templates map[string][]*PromptTemplate // by name, sorted by ascending version
+}
+
+// LoadTemplateDir loads the templates in dir and its subdirectories.
+func LoadTemplateDir(dir string) (*TemplateLibrary, error) {
+	return LoadTemplates(os.DirFS(dir))
+}
+
+// LoadTemplates loads every .md, .tmpl, .yaml and .yml file in fsys as a
+// template, which makes it usable with an embed.FS.
+//
+// Files in a directory named "partials" are partials instead: they are
+// available to every template under their path relative to that directory,
+// without the extension, through the template action or the include function:
+//
+//	{{template "tone" .}}
+//	{{include "format/json" . | printf "%q"}}
+//
+// Templates without a name in their front matter are named after their path
+// without the extension. Several files may declare the same name with
+// different versions.
+func LoadTemplates(fsys fs.FS) (*TemplateLibrary, error) {
+	lib := &TemplateLibrary{templates: make(map[string][]*PromptTemplate)}
+	partials := make(map[string]string)
+	var templates []*PromptTemplate
+
+	err := fs.WalkDir(fsys, ".", func(file string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		ext := path.Ext(file)
+		if entry.IsDir() || !slices.Contains(templateExtensions, ext) {
+			return nil
+		}
+		data, err := fs.ReadFile(fsys, file)
+		if err != nil {
+			return err
+		}
+
+		if name, ok := partialName(file); ok {
+			if _, body, found := cutFrontMatter(string(data)); found {
+				data = []byte(body)
+			}
+			partials[name] = string(data)
+			return nil
+		}
+
+		pt, err := parsePromptTemplate(data, ext == ".yaml" || ext == ".yml")
+		if err != nil {
+			return fmt.Errorf("%s: %w", file, err)
+		}
+		if pt.Name == "" {
+			pt.Name = strings.TrimSuffix(file, ext)
+		}
+		templates = append(templates, pt)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to load templates: %w", err)
+	}
+
+	for _, pt := range templates {
+		pt.partials = partials
+		versions := lib.templates[pt.Name]
+		index, found := slices.BinarySearchFunc(versions, pt.Version, func(t *PromptTemplate, version string) int {
+			return compareVersions(t.Version, version)
+		})
+		if found {
+			return nil, fmt.Errorf("failed to load templates: duplicate template %q version %q", pt.Name, pt.Version)
+		}
+		lib.templates[pt.Name] = slices.Insert(versions, index, pt)
+	}
+	return lib, nil
+}
+
+// partialName returns the name of the partial at file, if it is one.
+func partialName(file string) (string, bool) {
+	dir := file
+	for dir != "." && dir != "/" {
+		dir = path.Dir(dir)
+		if path.Base(dir) == partialsDir {
+			rel := strings.TrimPrefix(file, dir+"/")
+			return strings.TrimSuffix(rel, path.Ext(rel)), true
+		}
+	}
+	return "", false
+}
+
+// Get returns the latest version of the named template.
+func (l *TemplateLibrary) Get(name string) (*PromptTemplate, error) {
+	versions := l.templates[name]
+	if len(versions) == 0 {
+		return nil, fmt.Errorf("%w: %q", ErrTemplateNotFound, name)
+	}
+	return versions[len(versions)-1], nil
+}
+
+// GetVersion returns the given version of the named template.
+func (l *TemplateLibrary) GetVersion(name, version string) (*PromptTemplate, error) {
+	for _, pt := range l.templates[name] {
+		if compareVersions(pt.Version, version) == 0 {
+			return pt, nil
+		}
+	}
+	return nil, fmt.Errorf("%w: %q version %q", ErrTemplateNotFound, name, version)
+}
+
+// Names returns the names of the templates in the library, sorted.
+func (l *TemplateLibrary) Names() []string {
+	return slices.Sorted(maps.Keys(l.templates))
+}
+
+// Versions returns the versions of the named template, oldest first.
+func (l *TemplateLibrary) Versions(name string) []string {
+	versions := make([]string, 0, len(l.templates[name]))
+	for _, pt := range l.templates[name] {
+		versions = append(versions, pt.Version)
+	}
+	return versions
+}
+
+// Execute executes the latest version of the named template with data.
+func (l *TemplateLibrary) Execute(name string, data map[string]any) (*Prompt, error) {
+	pt, err := l.Get(name)
+	if err != nil {
+		return nil, err
+	}
+	return pt.Execute(data)
+}
+
+// compareVersions compares dot-separated versions such as "1.10.0" and "v1.9",
+// numerically where both parts are numbers. Missing parts count as zero.
+func compareVersions(a, b string) int {
+	aParts := strings.Split(strings.TrimPrefix(a, "v"), ".")
+	bParts := strings.Split(strings.TrimPrefix(b, "v"), ".")
+	for i := range max(len(aParts), len(bParts)) {
+		aPart, bPart := "0", "0"
+		if i < len(aParts) && aParts[i] != "" {
+			aPart = aParts[i]
+		}
+		if i < len(bParts) && bParts[i] != "" {
+			bPart = bParts[i]
+		}
+		aNum, aErr := strconv.Atoi(aPart)
+		bNum, bErr := strconv.Atoi(bPart)
+		switch {
+		case aErr == nil && bErr == nil && aNum != bNum:
+			if aNum < bNum {
+				return -1
+			}
+			return 1
+		case (aErr != nil || bErr != nil) && aPart != bPart:
+			return strings.Compare(aPart, bPart)
+		}
+	}
+	return 0
+}
+
+// bindVariables returns data with the defaults of missing variables filled in,
+// after checking that required variables are present and have their declared
+// types. Undeclared variables are passed through.
+func (pt *PromptTemplate) bindVariables(data map[string]any) (map[string]any, error) {
+	if len(pt.Variables) == 0 {
+		return data, nil
+	}
+	bound := make(map[string]any, len(data)+len(pt.Variables))
+	for key, value := range data {
+		bound[key] = value
+	}
+
+	var problems []string
+	for _, variable := range pt.Variables {
+		value, ok := bound[variable.Name]
+		switch {
+		case !ok && variable.Default != nil:
+			bound[variable.Name] = variable.Default
+		case !ok && variable.Required:
+			problems = append(problems, fmt.Sprintf("missing required variable %q", variable.Name))
+		case !ok:
+			bound[variable.Name] = zeroTemplateValue(variable.Type)
+		case !matchesTemplateVariableType(variable.Type, value):
+			problems = append(problems, fmt.Sprintf("variable %q must be %s, got %T", variable.Name, variable.Type, value))
+		}
+	}
+	if len(problems) > 0 {
+		return nil, fmt.Errorf("template %q: %s", pt.Name, strings.Join(problems, "; "))
+	}
+	return bound, nil
+}
+
+func isTemplateVariableType(typ string) bool {
+	switch typ {
+	case "", "any", "string", "int", "integer", "number", "float", "bool", "boolean", "array", "list", "object", "map":
+		return true
+	}
+	return false
+}
+
+func matchesTemplateVariableType(typ string, value any) bool {
+	if value == nil {
+		return typ == "" || typ == "any"
+	}
+	v := reflect.ValueOf(value)
+	switch typ {
+	case "string":
+		return v.Kind() == reflect.String
+	case "int", "integer":
+		switch {
+		case v.CanInt(), v.CanUint():
+			return true
+		case v.CanFloat():
+			return v.Float() == float64(int64(v.Float()))
+		}
+		return false
+	case "number", "float":
+		return v.CanInt() || v.CanUint() || v.CanFloat()
+	case "bool", "boolean":
+		return v.Kind() == reflect.Bool
+	case "array", "list":
+		return v.Kind() == reflect.Slice || v.Kind() == reflect.Array
+	case "object", "map":
+		return v.Kind() == reflect.Map || v.Kind() == reflect.Struct ||
+			(v.Kind() == reflect.Pointer && v.Elem().Kind() == reflect.Struct)
+	}
+	return true
+}
+
+// zeroTemplateValue is the value of an optional variable without a default, so
+// that templates can test it with if.
+func zeroTemplateValue(typ string) any {
+	switch typ {
+	case "string":
+		return ""
+	case "int", "integer":
+		return 0
+	case "number", "float":
+		return 0.0
+	case "bool", "boolean":
+		return false
+	case "array", "list":
+		return []any{}
+	case "object", "map":
+		return map[string]any{}
+	}
+	return nil
+}
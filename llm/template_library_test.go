@@ -0,0 +1,123 @@
+package llm
+
+import (
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/weave-labs/gollm/config"
+)
+
+var templateFS = fstest.MapFS{
+	"summarize.md": {Data: []byte(`---
+name: summarize
+version: 1.10.0
+description: Summarizes text
+variables:
+  text: string
+  words:
+    type: int
+    default: 50
+  audience:
+    type: string
+system_prompt: You are a concise summarizer.
+directives:
+  - Keep the original meaning
+output: A single paragraph
+model:
+  provider: openai
+  model: gpt-4o-mini
+  temperature: 0.2
+---
+{{template "tone" .}}
+Summarize in {{.words}} words{{if .audience}} for {{.audience}}{{end}}:
+{{.text}}`)},
+	"old/summarize.md": {Data: []byte("---\nname: summarize\nversion: 1.9.0\n---\nSummarize: {{.text}}")},
+	"translate.yaml": {Data: []byte(`version: "1"
+variables:
+  language: string
+template: |
+  Translate to {{.language}}: {{include "quote" .text}}`)},
+	"partials/tone.md":  {Data: []byte("Use a neutral tone.")},
+	"partials/quote.md": {Data: []byte(`"{{.}}"`)},
+	"notes.txt":         {Data: []byte("not a template")},
+}
+
+func TestLoadTemplates(t *testing.T) {
+	lib, err := LoadTemplates(templateFS)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"summarize", "translate"}, lib.Names())
+	assert.Equal(t, []string{"1.9.0", "1.10.0"}, lib.Versions("summarize"))
+
+	summarize, err := lib.Get("summarize")
+	require.NoError(t, err)
+	assert.Equal(t, "1.10.0", summarize.Version)
+	assert.Equal(t, "Summarizes text", summarize.Description)
+	assert.Len(t, summarize.Model.ConfigOptions(), 3)
+
+	cfg := &config.Config{}
+	for _, opt := range summarize.Model.ConfigOptions() {
+		opt(cfg)
+	}
+	assert.Equal(t, "gpt-4o-mini", cfg.Model)
+
+	prompt, err := lib.Execute("summarize", map[string]any{"text": "Long text"})
+	require.NoError(t, err)
+	assert.Equal(t, "Use a neutral tone.\nSummarize in 50 words:\nLong text", prompt.Input)
+	assert.Equal(t, "You are a concise summarizer.", prompt.SystemPrompt)
+	assert.Equal(t, []string{"Keep the original meaning"}, prompt.Directives)
+	assert.Equal(t, "A single paragraph", prompt.Output)
+
+	old, err := lib.GetVersion("summarize", "v1.9")
+	require.NoError(t, err)
+	prompt, err = old.Execute(map[string]any{"text": "Long text"})
+	require.NoError(t, err)
+	assert.Equal(t, "Summarize: Long text", prompt.Input)
+
+	prompt, err = lib.Execute("translate", map[string]any{"language": "French", "text": "Hello"})
+	require.NoError(t, err)
+	assert.Equal(t, "Translate to French: \"Hello\"", prompt.Input)
+
+	_, err = lib.Get("missing")
+	require.ErrorIs(t, err, ErrTemplateNotFound)
+	_, err = lib.GetVersion("summarize", "2.0.0")
+	require.ErrorIs(t, err, ErrTemplateNotFound)
+}
+
+func TestTemplateVariables(t *testing.T) {
+	lib, err := LoadTemplates(templateFS)
+	require.NoError(t, err)
+
+	_, err = lib.Execute("summarize", map[string]any{})
+	require.EqualError(t, err, `template "summarize": missing required variable "text"`)
+
+	_, err = lib.Execute("summarize", map[string]any{"text": "Long text", "words": "fifty"})
+	require.EqualError(t, err, `template "summarize": variable "words" must be int, got string`)
+
+	pt := NewPromptTemplate("greeting", "", "Hello {{.name}}")
+	_, err = pt.Execute(map[string]any{})
+	require.ErrorContains(t, err, `map has no entry for key "name"`, "missing variables are not rendered as <no value>")
+}
+
+func TestLoadTemplatesErrors(t *testing.T) {
+	_, err := LoadTemplates(fstest.MapFS{
+		"a.md": {Data: []byte("---\nname: a\nversion: 1.0\n---\nA")},
+		"b.md": {Data: []byte("---\nname: a\nversion: 1.0.0\n---\nB")},
+	})
+	require.ErrorContains(t, err, `duplicate template "a" version "1.0.0"`)
+
+	_, err = LoadTemplates(fstest.MapFS{
+		"a.md": {Data: []byte("---\nvariables:\n  n: integr\n---\nA")},
+	})
+	require.ErrorContains(t, err, `a.md: variable "n" has unknown type "integr"`)
+}
+
+func TestCompareVersions(t *testing.T) {
+	assert.Equal(t, -1, compareVersions("1.9.0", "1.10.0"))
+	assert.Equal(t, 0, compareVersions("v2", "2.0.0"))
+	assert.Equal(t, 1, compareVersions("1.0.1", "1.0"))
+	assert.Equal(t, -1, compareVersions("1.0.alpha", "1.0.beta"))
+	assert.Equal(t, 0, compareVersions("", "0"))
+}
@@ -0,0 +1,265 @@
+package llm
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/weave-labs/gollm/providers"
+)
+
+// ErrContextWindowExceeded is returned when a request does not fit the model's
+// context window after reserving room for the response.
+var ErrContextWindowExceeded = errors.New("request exceeds the model's context window")
+
+// ContextOverflowPolicy selects what happens when a request does not fit the
+// model's context window.
+type ContextOverflowPolicy int
+
+const (
+	// ContextOverflowError fails the request with ErrContextWindowExceeded
+	// before it is sent.
+	ContextOverflowError ContextOverflowPolicy = iota
+	// ContextOverflowTruncate drops the oldest messages until the request fits.
+	// The system prompt and the last message are always kept.
+	ContextOverflowTruncate
+	// ContextOverflowIgnore sends the request without checking its size.
+	ContextOverflowIgnore
+)
+
+// exactCountThreshold is the share of the input budget above which the local
+// estimate is confirmed with the exact counter, which may be an API call.
+const exactCountThreshold = 0.5
+
+// SetTokenCounter sets the counter used to check requests against the model's
+// context window. A nil counter restores the provider's default: its count
+// tokens API where there is one, tiktoken for OpenAI, and a calibrated estimate
+// otherwise.
+func (l *LLMImpl) SetTokenCounter(counter providers.TokenCounter) {
+	l.tokenCounter = counter
+}
+
+// CountTokens returns the number of input tokens of the request prompt renders to.
+func (l *LLMImpl) CountTokens(ctx context.Context, prompt *Prompt, opts ...GenerateOption) (int, error) {
+	genCfg := &GenerateConfig{}
+	for _, opt := range opts {
+		opt(genCfg)
+	}
+	rendered := l.promptRenderer(genCfg).Render(prompt)
+	req := providers.NewRequestBuilder().
+		WithSystemPrompt(rendered.SystemPrompt).
+		WithMessages(ToMessages(rendered.Messages)).
+		Build()
+	return l.exactTokenCounter().CountTokens(ctx, req)
+}
+
+// localTokenCounter returns the provider's local counter, which never makes
+// requests.
+func (l *LLMImpl) localTokenCounter() providers.TokenCounter {
+	l.localCounterOnce.Do(func() {
+		l.localCounter = providers.DefaultTokenCounter(l.Provider.Name(), l.config.Model)
+	})
+	return l.localCounter
+}
+
+// exactTokenCounter returns the most accurate counter available: the one set
+// with SetTokenCounter, the provider's count tokens API or the local counter.
+func (l *LLMImpl) exactTokenCounter() providers.TokenCounter {
+	if l.tokenCounter != nil {
+		return l.tokenCounter
+	}
+	if counting, ok := l.Provider.(providers.TokenCountingProvider); ok {
+		return &remoteTokenCounter{llm: l, provider: counting, fallback: l.localTokenCounter()}
+	}
+	return l.localTokenCounter()
+}
+
+// calibrateTokenCounter improves the local estimate from the input tokens the
+// provider reported for req.
+func (l *LLMImpl) calibrateTokenCounter(req *providers.Request, usage *providers.Usage) {
+	if req == nil || usage == nil || usage.InputTokens <= 0 {
+		return
+	}
+	if calibrator, ok := l.localTokenCounter().(providers.TokenCountCalibrator); ok {
+		calibrator.Calibrate(req, int(usage.InputTokens))
+	}
+}
+
+// inputBudget returns how many input tokens a request may have: the context
+// window less the max_tokens the request is sent with, which providers count
+// against the window. The result is not positive when max_tokens fills it.
+func (l *LLMImpl) inputBudget(limits providers.ModelLimits, genCfg *GenerateConfig) int {
+	reserved := l.config.MaxTokens
+	if maxTokens, ok := l.maxTokensOption(genCfg); ok {
+		reserved = maxTokens
+	}
+	return limits.ContextWindow - max(reserved, 0)
+}
+
+// maxTokensOption returns the max_tokens or max_completion_tokens option set
+// for the request or with SetOption.
+func (l *LLMImpl) maxTokensOption(genCfg *GenerateConfig) (int, bool) {
+	l.optionsMutex.RLock()
+	defer l.optionsMutex.RUnlock()
+	for _, options := range []map[string]any{genCfg.providerOptions, l.Options} {
+		for _, key := range []string{"max_completion_tokens", "max_tokens"} {
+			switch v := options[key].(type) {
+			case int:
+				return v, true
+			case int64:
+				return int(v), true
+			case float64:
+				return int(v), true
+			}
+		}
+	}
+	return 0, false
+}
+
+// preflight checks that req fits the model's context window and applies the
+// overflow policy when it does not. Models without registered limits are not
+// checked. The request is first estimated locally, and only counted with the
+// provider's count tokens API when the estimate comes close to the budget.
+func (l *LLMImpl) preflight(
+	ctx context.Context,
+	req *providers.Request,
+	genCfg *GenerateConfig,
+) (*providers.Request, error) {
+	if genCfg.ContextOverflow == ContextOverflowIgnore {
+		return req, nil
+	}
+	limits, ok := providers.GetCapabilityRegistry().GetLimits(l.Provider.Name(), l.config.Model)
+	if !ok || limits.ContextWindow == 0 {
+		return req, nil
+	}
+	budget := l.inputBudget(limits, genCfg)
+	if budget <= 0 {
+		return nil, NewLLMError(ErrorTypeInvalidInput,
+			fmt.Sprintf("max_tokens of %d leaves no room for input in the %d token context window of %s",
+				limits.ContextWindow-budget, limits.ContextWindow, l.config.Model),
+			ErrContextWindowExceeded)
+	}
+
+	estimate, err := l.localTokenCounter().CountTokens(ctx, req)
+	if err != nil {
+		l.logger.Warn("Failed to estimate tokens, sending the request unchecked", "error", err)
+		return req, nil
+	}
+	_, remote := l.exactTokenCounter().(*remoteTokenCounter)
+	if remote && float64(estimate) <= exactCountThreshold*float64(budget) {
+		return req, nil
+	}
+	count := l.countTokens(ctx, req, estimate)
+	if count <= budget {
+		return req, nil
+	}
+
+	if genCfg.ContextOverflow == ContextOverflowTruncate {
+		truncated := truncateRequest(ctx, l.localTokenCounter(), req, count-budget, float64(count)/float64(estimate))
+		if len(truncated.Messages) < len(req.Messages) {
+			l.logger.Warn("Dropped messages that do not fit the context window",
+				"model", l.config.Model, "dropped", len(req.Messages)-len(truncated.Messages))
+			req = truncated
+			if count = l.countTokens(ctx, req, 0); count <= budget {
+				return req, nil
+			}
+		}
+	}
+
+	return nil, NewLLMError(ErrorTypeInvalidInput,
+		fmt.Sprintf("request has %d input tokens, but %s allows %d", count, l.config.Model, budget),
+		ErrContextWindowExceeded)
+}
+
+// countTokens counts req exactly, falling back to estimate when that fails.
+// An estimate of zero is recomputed.
+func (l *LLMImpl) countTokens(ctx context.Context, req *providers.Request, estimate int) int {
+	count, err := l.exactTokenCounter().CountTokens(ctx, req)
+	if err == nil {
+		return count
+	}
+	l.logger.Warn("Failed to count tokens, using an estimate", "error", err)
+	if estimate == 0 {
+		estimate, _ = l.localTokenCounter().CountTokens(ctx, req)
+	}
+	return estimate
+}
+
+// truncateRequest returns a copy of req without its oldest messages, dropping
+// at least excess tokens as counted by counter and scaled by scale. The last
+// message is kept, and the remaining conversation starts with a user message.
+func truncateRequest(
+	ctx context.Context,
+	counter providers.TokenCounter,
+	req *providers.Request,
+	excess int,
+	scale float64,
+) *providers.Request {
+	messages := req.Messages
+	dropped := 0.0
+	for len(messages) > 1 && (dropped < float64(excess) || messages[0].Role != "user") {
+		size, _ := counter.CountTokens(ctx, &providers.Request{Messages: messages[:1]})
+		dropped += float64(size) * scale
+		messages = messages[1:]
+	}
+
+	truncated := *req
+	truncated.Messages = messages
+	return &truncated
+}
+
+// remoteTokenCounter counts tokens with the provider's count tokens API.
+type remoteTokenCounter struct {
+	llm      *LLMImpl
+	provider providers.TokenCountingProvider
+	fallback providers.TokenCounter
+}
+
+// CountTokens implements providers.TokenCounter. When the API call fails, the
+// count comes from the fallback counter.
+func (r *remoteTokenCounter) CountTokens(ctx context.Context, req *providers.Request) (int, error) {
+	count, err := r.count(ctx, req)
+	if err != nil {
+		r.llm.logger.Debug("Token count request failed, using the local counter", "error", err)
+		return r.fallback.CountTokens(ctx, req)
+	}
+	return count, nil
+}
+
+func (r *remoteTokenCounter) count(ctx context.Context, req *providers.Request) (int, error) {
+	options := r.llm.prepareOptions(&Prompt{})
+	endpoint, body, err := r.provider.PrepareTokenCountRequest(req, options)
+	if err != nil {
+		return 0, err
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	for k, v := range r.llm.Provider.Headers() {
+		httpReq.Header.Set(k, v)
+	}
+
+	resp, err := r.llm.client.Do(httpReq)
+	if err != nil {
+		return 0, err
+	}
+	defer func() {
+		if err := resp.Body.Close(); err != nil {
+			r.llm.logger.Error("Failed to close response body", "error", err)
+		}
+	}()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return 0, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return 0, fmt.Errorf("token count API error: status code %d", resp.StatusCode)
+	}
+	return r.provider.ParseTokenCountResponse(respBody)
+}
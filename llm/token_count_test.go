@@ -0,0 +1,164 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/weave-labs/gollm/config"
+	"github.com/weave-labs/gollm/internal/logging"
+	"github.com/weave-labs/gollm/providers"
+)
+
+// fixedTokenCounter counts every character of the messages as a token.
+type fixedTokenCounter struct{}
+
+func (fixedTokenCounter) CountTokens(_ context.Context, req *providers.Request) (int, error) {
+	count := len(req.SystemPrompt)
+	for _, msg := range req.Messages {
+		count += len(msg.Content)
+	}
+	return count, nil
+}
+
+func TestPreflight(t *testing.T) {
+	providers.GetCapabilityRegistry().RegisterLimits(providers.ProviderOllama, "preflight-test",
+		providers.ModelLimits{ContextWindow: 100, MaxOutputTokens: 20})
+
+	var prompts []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			Prompt string `json:"prompt"`
+		}
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+		prompts = append(prompts, body.Prompt)
+		assert.NoError(t, json.NewEncoder(w).Encode(map[string]any{"model": "llama3", "response": "ok", "done": true}))
+	}))
+	defer server.Close()
+
+	client := newOllamaTestLLM(t, server)
+	client.config.Model = "preflight-test"
+	client.config.MaxTokens = 20
+	client.SetTokenCounter(fixedTokenCounter{})
+
+	_, err := client.Generate(context.Background(), NewPrompt(strings.Repeat("a", 80)))
+	require.NoError(t, err, "80 tokens fit the 80 token budget")
+
+	long := NewPrompt("", WithMessages([]PromptMessage{
+		{Role: "user", Content: strings.Repeat("u", 40)},
+		{Role: "assistant", Content: strings.Repeat("b", 40)},
+		{Role: "user", Content: "question"},
+	}))
+
+	_, err = client.Generate(context.Background(), long)
+	require.ErrorIs(t, err, ErrContextWindowExceeded)
+	var llmErr *LLMError
+	require.ErrorAs(t, err, &llmErr)
+	assert.Equal(t, ErrorTypeInvalidInput, llmErr.Type)
+	assert.Contains(t, err.Error(), "request has 88 input tokens, but preflight-test allows 80")
+	assert.Len(t, prompts, 1, "the oversized request is not sent or retried")
+
+	_, err = client.Generate(context.Background(), long, WithContextOverflow(ContextOverflowTruncate))
+	require.NoError(t, err)
+	require.Len(t, prompts, 2)
+	assert.NotContains(t, prompts[1], "uuuu", "the oldest messages are dropped")
+	assert.NotContains(t, prompts[1], "bbbb", "the conversation starts with a user message")
+	assert.Contains(t, prompts[1], "question")
+
+	_, err = client.Generate(context.Background(), long, WithContextOverflow(ContextOverflowIgnore))
+	require.NoError(t, err)
+	assert.Len(t, prompts, 3)
+}
+
+// roundTripFunc serves HTTP requests with a function.
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+func TestPreflightCountsWithProviderAPI(t *testing.T) {
+	var paths []string
+	transport := roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		paths = append(paths, req.URL.Path)
+		body := `{"input_tokens": 199500}`
+		if !strings.HasSuffix(req.URL.Path, "/count_tokens") {
+			body = `{"content":[{"type":"text","text":"ok"}],"stop_reason":"end_turn","usage":{"input_tokens":10,"output_tokens":1}}`
+		}
+		return &http.Response{StatusCode: http.StatusOK, Body: io.NopCloser(strings.NewReader(body)), Header: http.Header{}}, nil
+	})
+
+	cfg := &config.Config{Model: "claude-3-5-haiku-20241022", MaxTokens: 1000}
+	provider := providers.NewAnthropicProvider("fake-key", cfg.Model, nil)
+	provider.SetDefaultOptions(cfg)
+	logger := logging.NewLogger(logging.LogLevelError)
+	client := &LLMImpl{
+		Provider: provider,
+		client:   &http.Client{Transport: transport},
+		logger:   logger,
+		config:   cfg,
+		Options:  make(map[string]any),
+	}
+
+	_, err := client.Generate(context.Background(), NewPrompt("Hello"))
+	require.NoError(t, err)
+	assert.Equal(t, []string{"/v1/messages"}, paths, "small requests are only estimated")
+
+	count, err := client.CountTokens(context.Background(), NewPrompt("Hello"))
+	require.NoError(t, err)
+	assert.Equal(t, 199500, count)
+
+	_, err = client.Generate(context.Background(), NewPrompt(strings.Repeat("word ", 120000)))
+	require.ErrorIs(t, err, ErrContextWindowExceeded)
+	assert.Equal(t, []string{"/v1/messages", "/v1/messages/count_tokens", "/v1/messages/count_tokens"}, paths)
+}
+
+func TestPreflightWithDefaultConfig(t *testing.T) {
+	tests := []struct {
+		provider providers.Provider
+		model    string
+	}{
+		{providers.NewOpenAIProvider("test-key", "gpt-4", nil), "gpt-4"},
+		{providers.NewGroqProvider("test-key", "llama3-70b-8192", nil), "llama3-70b-8192"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.model, func(t *testing.T) {
+			cfg, err := config.LoadConfig()
+			require.NoError(t, err)
+			cfg.Model = tt.model
+			client := &LLMImpl{
+				Provider: tt.provider,
+				logger:   logging.NewLogger(logging.LogLevelError),
+				config:   cfg,
+				Options:  make(map[string]any),
+			}
+
+			limits, ok := providers.GetCapabilityRegistry().GetLimits(tt.provider.Name(), tt.model)
+			require.True(t, ok)
+			assert.Equal(t, 8192-16000, client.inputBudget(limits, &GenerateConfig{}),
+				"the default max_tokens is reserved as sent")
+
+			req := providers.NewRequestBuilder().
+				WithMessages([]providers.Message{{Role: "user", Content: "Hello"}}).
+				Build()
+			_, err = client.preflight(context.Background(), req, &GenerateConfig{})
+			require.ErrorIs(t, err, ErrContextWindowExceeded, "a max_tokens that fills the window is rejected")
+			assert.ErrorContains(t, err, "max_tokens of 16000 leaves no room for input")
+
+			client.SetOption("max_tokens", 1000)
+			assert.Equal(t, 7192, client.inputBudget(limits, &GenerateConfig{}))
+			_, err = client.preflight(context.Background(), req, &GenerateConfig{})
+			require.NoError(t, err)
+			assert.Equal(t, 4192, client.inputBudget(limits, &GenerateConfig{
+				providerOptions: map[string]any{"max_tokens": 4000},
+			}), "a max_tokens set for the request takes precedence")
+		})
+	}
+}
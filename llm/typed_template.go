@@ -0,0 +1,297 @@
+package llm
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"slices"
+	"strings"
+	"text/template"
+	"text/template/parse"
+)
+
+// TypedTemplate is a PromptTemplate rendered from an In struct whose response
+// is decoded into an Out. The fields the template references are checked
+// against In when the TypedTemplate is created, so a misspelled field is a
+// construction error instead of a broken prompt.
+//
+// Example:
+//
+//	type Ticket struct {
+//	    Subject string
+//	    Body    string
+//	}
+//
+//	type Triage struct {
+//	    Priority string `json:"priority" validate:"oneof=low medium high"`
+//	    Team     string `json:"team"`
+//	}
+//
+//	triage, err := NewTypedTemplate[Ticket, Triage](
+//	    "triage",
+//	    "Routes support tickets",
+//	    "Triage this ticket.\nSubject: {{.Subject}}\n\n{{.Body}}",
+//	)
+//	if err != nil {
+//	    log.Fatal(err)
+//	}
+//	result, err := triage.Run(ctx, client, Ticket{Subject: "Login fails", Body: "..."})
+type TypedTemplate[In, Out any] struct {
+	template *PromptTemplate
+	parsed   *template.Template
+}
+
+// NewTypedTemplate creates a TypedTemplate with the same parameters as
+// NewPromptTemplate. It returns an error if the template does not parse or
+// references fields that In does not have.
+func NewTypedTemplate[In, Out any](
+	name, description, tmpl string,
+	opts ...PromptTemplateOption,
+) (*TypedTemplate[In, Out], error) {
+	return BindTypedTemplate[In, Out](NewPromptTemplate(name, description, tmpl, opts...))
+}
+
+// BindTypedTemplate creates a TypedTemplate from an existing PromptTemplate,
+// such as one loaded from a TemplateLibrary. Its declared variables must be
+// fields of In.
+func BindTypedTemplate[In, Out any](pt *PromptTemplate) (*TypedTemplate[In, Out], error) {
+	parsed, err := pt.parse()
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse template: %w", err)
+	}
+
+	in := reflect.TypeFor[In]()
+	checker := &templateChecker{
+		template:    parsed,
+		visited:     make(map[string]bool),
+		addressable: in.Kind() == reflect.Pointer,
+	}
+	for _, variable := range pt.Variables {
+		checker.fieldType(in, []string{variable.Name})
+	}
+	checker.walk(parsed.Tree.Root, in, in)
+	if len(checker.problems) > 0 {
+		return nil, fmt.Errorf("template %q: %s", pt.Name, strings.Join(checker.problems, "; "))
+	}
+
+	return &TypedTemplate[In, Out]{template: pt, parsed: parsed}, nil
+}
+
+// Template returns the underlying PromptTemplate.
+func (tt *TypedTemplate[In, Out]) Template() *PromptTemplate {
+	return tt.template
+}
+
+// Execute renders the template with in as its data.
+func (tt *TypedTemplate[In, Out]) Execute(in In) (*Prompt, error) {
+	return tt.template.execute(tt.parsed, in)
+}
+
+// Run renders the template with in and generates a structured response with
+// GenerateTyped, returning the decoded Out.
+func (tt *TypedTemplate[In, Out]) Run(ctx context.Context, l LLM, in In, opts ...GenerateOption) (*Out, error) {
+	prompt, err := tt.Execute(in)
+	if err != nil {
+		return nil, err
+	}
+	out, _, err := GenerateTyped[Out](ctx, l, prompt, opts...)
+	return out, err
+}
+
+// templateChecker checks the fields referenced by a parsed template against
+// the type of its data. Types that cannot be determined statically, such as
+// interfaces or the results of functions, are not checked.
+type templateChecker struct {
+	template *template.Template
+	visited  map[string]bool // partials already checked, by name and data type
+	problems []string
+	// addressable is whether the data is reached through a pointer, which
+	// lets templates call the pointer methods of its values.
+	addressable bool
+}
+
+// walk checks node with dot as the type of "." and root as the type of "$".
+// A nil type is not checked.
+func (c *templateChecker) walk(node parse.Node, dot, root reflect.Type) {
+	switch node := node.(type) {
+	case *parse.ListNode:
+		if node == nil {
+			return
+		}
+		for _, child := range node.Nodes {
+			c.walk(child, dot, root)
+		}
+	case *parse.ActionNode:
+		c.pipeType(node.Pipe, dot, root)
+	case *parse.IfNode:
+		c.pipeType(node.Pipe, dot, root)
+		c.walk(node.List, dot, root)
+		c.walk(node.ElseList, dot, root)
+	case *parse.WithNode:
+		c.walk(node.List, c.pipeType(node.Pipe, dot, root), root)
+		c.walk(node.ElseList, dot, root)
+	case *parse.RangeNode:
+		c.walk(node.List, rangeElem(c.pipeType(node.Pipe, dot, root)), root)
+		c.walk(node.ElseList, dot, root)
+	case *parse.TemplateNode:
+		var data reflect.Type
+		if node.Pipe != nil {
+			data = c.pipeType(node.Pipe, dot, root)
+		}
+		c.partial(node.Name, data)
+	}
+}
+
+// pipeType checks a pipeline and returns the type it evaluates to, if known.
+func (c *templateChecker) pipeType(pipe *parse.PipeNode, dot, root reflect.Type) reflect.Type {
+	if pipe == nil {
+		return nil
+	}
+	var typ reflect.Type
+	for _, cmd := range pipe.Cmds {
+		typ = c.commandType(cmd, dot, root)
+	}
+	if len(pipe.Cmds) != 1 {
+		return nil
+	}
+	return typ
+}
+
+// commandType checks the arguments of a command and returns its type, if known.
+func (c *templateChecker) commandType(cmd *parse.CommandNode, dot, root reflect.Type) reflect.Type {
+	types := make([]reflect.Type, len(cmd.Args))
+	for i, arg := range cmd.Args {
+		types[i] = c.argType(arg, dot, root)
+	}
+	if ident, ok := cmd.Args[0].(*parse.IdentifierNode); ok && ident.Ident == "include" && len(cmd.Args) == 3 {
+		if name, ok := cmd.Args[1].(*parse.StringNode); ok {
+			c.partial(name.Text, types[2])
+		}
+	}
+	if len(cmd.Args) != 1 {
+		return nil
+	}
+	return types[0]
+}
+
+// argType checks an argument and returns its type, if known.
+func (c *templateChecker) argType(arg parse.Node, dot, root reflect.Type) reflect.Type {
+	switch arg := arg.(type) {
+	case *parse.DotNode:
+		return dot
+	case *parse.FieldNode:
+		return c.fieldType(dot, arg.Ident)
+	case *parse.VariableNode:
+		if arg.Ident[0] == "$" {
+			return c.fieldType(root, arg.Ident[1:])
+		}
+	case *parse.ChainNode:
+		if pipe, ok := arg.Node.(*parse.PipeNode); ok {
+			return c.fieldType(c.pipeType(pipe, dot, root), arg.Field)
+		}
+		c.argType(arg.Node, dot, root)
+	case *parse.PipeNode:
+		return c.pipeType(arg, dot, root)
+	}
+	return nil
+}
+
+// fieldType resolves a chain of field or method names on typ like text/template
+// does, recording a problem for names that do not exist.
+func (c *templateChecker) fieldType(typ reflect.Type, idents []string) reflect.Type {
+	addressable := c.addressable
+	for _, ident := range idents {
+		if typ == nil {
+			return nil
+		}
+		if method, ok := methodByName(typ, ident, addressable); ok {
+			typ, addressable = nil, false
+			if method.Type.NumOut() > 0 {
+				typ = method.Type.Out(0)
+			}
+			continue
+		}
+		for typ.Kind() == reflect.Pointer {
+			typ, addressable = typ.Elem(), true
+		}
+		switch typ.Kind() {
+		case reflect.Struct:
+			if _, ok := reflect.PointerTo(typ).MethodByName(ident); ok {
+				c.report(fmt.Sprintf("method %q has a pointer receiver, but %s is not a pointer", ident, typ))
+				return nil
+			}
+			field, ok := typ.FieldByName(ident)
+			if !ok || !field.IsExported() {
+				c.report(fmt.Sprintf("%s has no field %q", typ, ident))
+				return nil
+			}
+			typ = field.Type
+		case reflect.Map:
+			if typ.Key().Kind() != reflect.String {
+				return nil
+			}
+			typ, addressable = typ.Elem(), false
+		default:
+			if typ.Kind() != reflect.Interface {
+				c.report(fmt.Sprintf("can't evaluate field %q in %s", ident, typ))
+			}
+			return nil
+		}
+	}
+	return typ
+}
+
+// report records a problem once.
+func (c *templateChecker) report(problem string) {
+	if !slices.Contains(c.problems, problem) {
+		c.problems = append(c.problems, problem)
+	}
+}
+
+// methodByName finds a method of typ, or of a pointer to typ if the value is
+// addressable: like Go, text/template only calls pointer methods on values it
+// can take the address of.
+func methodByName(typ reflect.Type, name string, addressable bool) (reflect.Method, bool) {
+	if method, ok := typ.MethodByName(name); ok {
+		return method, true
+	}
+	if !addressable || typ.Kind() == reflect.Pointer || typ.Kind() == reflect.Interface {
+		return reflect.Method{}, false
+	}
+	return reflect.PointerTo(typ).MethodByName(name)
+}
+
+// partial checks the named partial with data as its dot and root.
+func (c *templateChecker) partial(name string, data reflect.Type) {
+	key := fmt.Sprintf("%s %v", name, data)
+	tmpl := c.template.Lookup(name)
+	if c.visited[key] || tmpl == nil || tmpl.Tree == nil {
+		return
+	}
+	c.visited[key] = true
+	c.walk(tmpl.Tree.Root, data, data)
+}
+
+// rangeElem returns the type of the elements range iterates over in typ.
+func rangeElem(typ reflect.Type) reflect.Type {
+	if typ == nil {
+		return nil
+	}
+	for typ.Kind() == reflect.Pointer {
+		typ = typ.Elem()
+	}
+	switch typ.Kind() {
+	case reflect.Slice:
+		// Slice elements are addressable, so they are ranged over as pointers.
+		if elem := typ.Elem(); elem.Kind() != reflect.Pointer && elem.Kind() != reflect.Interface {
+			return reflect.PointerTo(elem)
+		}
+		return typ.Elem()
+	case reflect.Array, reflect.Map, reflect.Chan:
+		return typ.Elem()
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return typ
+	}
+	return nil
+}
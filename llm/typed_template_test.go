@@ -0,0 +1,124 @@
+package llm
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type ticket struct {
+	Subject  string
+	Body     string
+	Customer *customer
+	Labels   []label
+}
+
+type customer struct {
+	Name string
+	Tier string
+}
+
+func (c customer) Greeting() string { return "Dear " + c.Name }
+
+type label struct{ Name string }
+
+func (l *label) Tag() string { return "#" + l.Name }
+
+type triage struct {
+	Priority string `json:"priority" validate:"required,oneof=low medium high"`
+	Team     string `json:"team"`
+}
+
+func TestNewTypedTemplate(t *testing.T) {
+	tmpl, err := NewTypedTemplate[ticket, triage]("triage", "",
+		"{{.Customer.Greeting}} ({{$.Customer.Tier}})\n{{.Subject}}\n{{range .Labels}}#{{.Name}} {{end}}\n{{with .Body}}{{.}}{{end}}",
+		WithPromptOptions(WithDirectives("Pick a team")),
+	)
+	require.NoError(t, err)
+
+	in := ticket{
+		Subject:  "Login fails",
+		Body:     "Since Monday",
+		Customer: &customer{Name: "Ada", Tier: "gold"},
+		Labels:   []label{{Name: "auth"}, {Name: "urgent"}},
+	}
+	prompt, err := tmpl.Execute(in)
+	require.NoError(t, err)
+	assert.Equal(t, "Dear Ada (gold)\nLogin fails\n#auth #urgent \nSince Monday", prompt.Input)
+	assert.Equal(t, []string{"Pick a team"}, prompt.Directives)
+
+	client := &scriptedLLM{replies: []string{`{"priority":"urgent"}`, `{"priority":"high","team":"identity"}`}}
+	result, err := tmpl.Run(context.Background(), client, in)
+	require.NoError(t, err)
+	assert.Equal(t, &triage{Priority: "high", Team: "identity"}, result)
+	assert.Equal(t, prompt.Input, client.prompts[0].Input)
+	assert.NotNil(t, client.configs[0].StructuredResponseSchema, "the response is requested as structured output")
+}
+
+func TestNewTypedTemplateChecksFields(t *testing.T) {
+	tests := map[string]struct {
+		template string
+		problem  string
+	}{
+		"top level":      {"{{.Subjct}}", `llm.ticket has no field "Subjct"`},
+		"nested":         {"{{.Customer.Nme}}", `llm.customer has no field "Nme"`},
+		"range element":  {"{{range .Labels}}{{.Title}}{{end}}", `llm.label has no field "Title"`},
+		"root variable":  {"{{range .Labels}}{{$.Sender}}{{end}}", `llm.ticket has no field "Sender"`},
+		"field of value": {"{{.Subject.Length}}", `can't evaluate field "Length" in string`},
+		"condition":      {"{{if .Urgent}}!{{end}}", `llm.ticket has no field "Urgent"`},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			_, err := NewTypedTemplate[ticket, triage]("triage", "", tt.template)
+			require.EqualError(t, err, `template "triage": `+tt.problem)
+		})
+	}
+
+	_, err := NewTypedTemplate[map[string]any, triage]("free", "", "{{.anything.goes}}")
+	require.NoError(t, err, "maps are not checked")
+}
+
+func TestNewTypedTemplatePointerMethods(t *testing.T) {
+	_, err := NewTypedTemplate[label, triage]("tag", "", "{{.Tag}}")
+	require.EqualError(t, err, `template "tag": method "Tag" has a pointer receiver, but llm.label is not a pointer`)
+
+	tmpl, err := NewTypedTemplate[*label, triage]("tag", "", "{{.Tag}}")
+	require.NoError(t, err)
+	prompt, err := tmpl.Execute(&label{Name: "auth"})
+	require.NoError(t, err)
+	assert.Equal(t, "#auth", prompt.Input)
+
+	ranged, err := NewTypedTemplate[ticket, triage]("tags", "", "{{range .Labels}}{{.Tag}} {{end}}")
+	require.NoError(t, err, "slice elements are addressable")
+	prompt, err = ranged.Execute(ticket{Labels: []label{{Name: "auth"}, {Name: "urgent"}}})
+	require.NoError(t, err)
+	assert.Equal(t, "#auth #urgent ", prompt.Input)
+}
+
+func TestBindTypedTemplate(t *testing.T) {
+	lib, err := LoadTemplates(templateFS)
+	require.NoError(t, err)
+	pt, err := lib.Get("summarize")
+	require.NoError(t, err)
+
+	type summaryInput struct {
+		Text string
+	}
+	_, err = BindTypedTemplate[summaryInput, triage](pt)
+	require.ErrorContains(t, err, `has no field "text"`)
+
+	type lowerInput struct {
+		text     string
+		words    int
+		audience string
+	}
+	_, err = BindTypedTemplate[lowerInput, triage](pt)
+	require.ErrorContains(t, err, `has no field "text"`, "unexported fields cannot be rendered")
+
+	translate, err := lib.Get("translate")
+	require.NoError(t, err)
+	_, err = BindTypedTemplate[map[string]string, triage](translate)
+	require.NoError(t, err)
+}
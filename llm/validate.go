@@ -6,7 +6,6 @@ package llm
 import (
 	"context"
 	"encoding/json"
-	"errors"
 	"fmt"
 	"net/http"
 	"reflect"
@@ -16,6 +15,7 @@ import (
 	"sync"
 
 	"github.com/go-playground/validator/v10"
+	"github.com/google/jsonschema-go/jsonschema"
 )
 
 const (
@@ -399,15 +399,10 @@ func applyExcludesRule(schema map[string]any, value string) {
 	}
 }
 
-// ValidateAgainstSchema validates a JSON response against a JSON schema.
-// It ensures the response matches the expected structure and constraints.
-//
-// Parameters:
-//   - response: The JSON response string to validate
-//   - schema: The schema to validate against
-//
-// Returns:
-//   - error: nil if validation passes, otherwise returns validation errors
+// ValidateAgainstSchema validates a JSON response against a JSON schema under
+// draft 2020-12. The schema may be a *jsonschema.Schema, JSON text as a string or
+// []byte, or any value that marshals to a schema. A mismatch is reported as a
+// *SchemaValidationError whose violations carry JSON-pointer paths.
 //
 // Example:
 //
@@ -427,184 +422,31 @@ func ValidateAgainstSchema(response string, schema any) error {
 		return fmt.Errorf("failed to parse response JSON: %w", err)
 	}
 
-	var schemaMap map[string]any
+	var parsed *jsonschema.Schema
 	switch s := schema.(type) {
+	case *jsonschema.Schema:
+		parsed = s
 	case string:
-		if err := json.Unmarshal([]byte(s), &schemaMap); err != nil {
+		if err := json.Unmarshal([]byte(s), &parsed); err != nil {
 			return fmt.Errorf("failed to parse schema JSON string: %w", err)
 		}
 	case []byte:
-		if err := json.Unmarshal(s, &schemaMap); err != nil {
+		if err := json.Unmarshal(s, &parsed); err != nil {
 			return fmt.Errorf("failed to parse schema JSON bytes: %w", err)
 		}
-	case map[string]any:
-		schemaMap = s
 	default:
-		// Try to marshal and unmarshal to ensure we have a proper object
 		schemaBytes, err := json.Marshal(schema)
 		if err != nil {
 			return fmt.Errorf("failed to marshal schema: %w", err)
 		}
-		if err := json.Unmarshal(schemaBytes, &schemaMap); err != nil {
+		if err := json.Unmarshal(schemaBytes, &parsed); err != nil {
 			return fmt.Errorf("failed to parse schema JSON: %w", err)
 		}
 	}
 
-	if err := validateJSONAgainstSchema(responseData, schemaMap); err != nil {
+	if err := ValidateValue(parsed, responseData); err != nil {
 		return fmt.Errorf("response does not match schema: %w", err)
 	}
 
 	return nil
 }
-
-// validateJSONAgainstSchema performs the actual JSON schema validation.
-// It recursively validates complex data structures against their schema.
-//
-// Parameters:
-//   - data: The data to validate
-//   - schema: The schema to validate against
-//
-// Returns:
-//   - error: nil if validation passes, otherwise returns validation errors
-func validateJSONAgainstSchema(data any, schema map[string]any) error {
-	schemaType, ok := schema["type"].(string)
-	if !ok {
-		return errors.New("schema missing 'type' field")
-	}
-
-	switch schemaType {
-	case typeObject:
-		return validateObject(data, schema)
-	case typeArray:
-		return validateArray(data, schema)
-	case typeString, typeNumber, typeInteger, typeBoolean:
-		return validatePrimitive(data, schemaType)
-	default:
-		return fmt.Errorf("unsupported schema type: %s", schemaType)
-	}
-}
-
-// validateObject validates an object against its schema.
-// It checks object properties and their types according to the schema.
-//
-// Parameters:
-//   - data: The object to validate
-//   - schema: The schema to validate against
-//
-// Returns:
-//   - error: nil if validation passes, otherwise returns validation errors
-func validateObject(data any, schema map[string]any) error {
-	dataMap, ok := data.(map[string]any)
-	if !ok {
-		return fmt.Errorf("expected object, got %T", data)
-	}
-
-	properties, ok := schema["properties"].(map[string]any)
-	if !ok {
-		return errors.New("invalid 'properties' in schema")
-	}
-
-	for key, propSchema := range properties {
-		if err := validateObjectProperty(key, propSchema, dataMap, schema); err != nil {
-			return err
-		}
-	}
-
-	return nil
-}
-
-// validateObjectProperty validates a single property of an object
-func validateObjectProperty(key string, propSchema any, dataMap map[string]any, schema map[string]any) error {
-	propData, exists := dataMap[key]
-	if !exists {
-		return checkRequiredField(key, schema)
-	}
-
-	propSchemaMap, ok := propSchema.(map[string]any)
-	if !ok {
-		return nil // Skip if not a valid schema map
-	}
-
-	if err := validateJSONAgainstSchema(propData, propSchemaMap); err != nil {
-		return fmt.Errorf("invalid field '%s': %w", key, err)
-	}
-
-	return nil
-}
-
-// checkRequiredField checks if a field is required and missing
-func checkRequiredField(key string, schema map[string]any) error {
-	required, ok := schema["required"].([]any)
-	if !ok {
-		return nil // No required fields specified
-	}
-
-	for _, req := range required {
-		reqStr, ok := req.(string)
-		if ok && reqStr == key {
-			return fmt.Errorf("missing required field: %s", key)
-		}
-	}
-
-	return nil // Field is not required
-}
-
-// validateArray validates an array against its schema.
-// It checks array items and their types according to the schema.
-//
-// Parameters:
-//   - data: The array to validate
-//   - schema: The schema to validate against
-//
-// Returns:
-//   - error: nil if validation passes, otherwise returns validation errors
-func validateArray(data any, schema map[string]any) error {
-	dataSlice, ok := data.([]any)
-	if !ok {
-		return fmt.Errorf("expected array, got %T", data)
-	}
-
-	items, ok := schema["items"].(map[string]any)
-	if !ok {
-		return errors.New("invalid 'items' in schema")
-	}
-
-	for i, item := range dataSlice {
-		if err := validateJSONAgainstSchema(item, items); err != nil {
-			return fmt.Errorf("invalid item at index %d: %w", i, err)
-		}
-	}
-
-	return nil
-}
-
-// validatePrimitive validates a primitive value against its expected type.
-// It ensures the value matches the type specified in the schema.
-//
-// Parameters:
-//   - data: The value to validate
-//   - expectedType: The expected JSON Schema type
-//
-// Returns:
-//   - error: nil if validation passes, otherwise returns validation errors
-func validatePrimitive(data any, expectedType string) error {
-	switch expectedType {
-	case "string":
-		if _, ok := data.(string); !ok {
-			return fmt.Errorf("expected string, got %T", data)
-		}
-	case "number":
-		if _, ok := data.(float64); !ok {
-			return fmt.Errorf("expected number, got %T", data)
-		}
-	case "integer":
-		if _, ok := data.(float64); !ok {
-			return fmt.Errorf("expected integer, got %T", data)
-		}
-	case "boolean":
-		if _, ok := data.(bool); !ok {
-			return fmt.Errorf("expected boolean, got %T", data)
-		}
-	}
-	return nil
-}
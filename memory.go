@@ -0,0 +1,51 @@
+// Package gollm provides conversation memory and sessions for Language Learning Models.
+// This file contains type definitions and re-exports for storing conversation history.
+package gollm
+
+import (
+	"github.com/weave-labs/gollm/llm"
+)
+
+// Re-export memory and session types from the llm package
+type (
+	// MemoryMessage is a single message of a conversation history.
+	MemoryMessage = llm.MemoryMessage
+
+	// MemoryCompactor reduces conversation memory that exceeds its token limit.
+	MemoryCompactor = llm.MemoryCompactor
+
+	// Conversation is the stored history of one conversation.
+	Conversation = llm.Conversation
+
+	// ConversationStore persists conversations by ID.
+	ConversationStore = llm.ConversationStore
+
+	// SessionManager serves many conversations from one LLM, keyed by session ID.
+	SessionManager = llm.SessionManager
+
+	// SessionOption configures a SessionManager.
+	SessionOption = llm.SessionOption
+
+	// ConversationTree keeps a conversation as a tree of edited and regenerated branches.
+	ConversationTree = llm.ConversationTree
+
+	// TreeMessage is a message of a ConversationTree.
+	TreeMessage = llm.TreeMessage
+)
+
+// Re-export memory and session constructors, options and errors from the llm package
+var (
+	NewMemoryMessage             = llm.NewMemoryMessage             // Stores a prompt message, with its tool calls
+	NewSummarizingCompactor      = llm.NewSummarizingCompactor      // Summarizes the oldest messages with an LLM
+	NewInMemoryConversationStore = llm.NewInMemoryConversationStore // Keeps conversations in process memory
+	NewFileConversationStore     = llm.NewFileConversationStore     // Keeps conversations as JSON files
+	NewSessionManager            = llm.NewSessionManager            // Serves conversations keyed by session ID
+	NewConversationTree          = llm.NewConversationTree          // Creates an empty conversation tree
+
+	WithSessionTTL          = llm.WithSessionTTL          // Expires sessions that are not updated
+	WithSessionCompactor    = llm.WithSessionCompactor    // Sets how session histories are compacted
+	WithSessionTokenCounter = llm.WithSessionTokenCounter // Sets how session messages are counted
+
+	ErrConversationNotFound = llm.ErrConversationNotFound
+	ErrMessageNotFound      = llm.ErrMessageNotFound
+)
@@ -0,0 +1,42 @@
+package gollm_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/weave-labs/gollm"
+	"github.com/weave-labs/gollm/gollmtest"
+)
+
+func TestSummarizingMemoryKeepsConversationOptions(t *testing.T) {
+	fake := gollmtest.NewProvider()
+	for range 8 {
+		fake.Reply("Noted.")
+	}
+	client := gollmtest.NewLLM(t, fake, gollm.SetSummarizingMemory(120, ""))
+	ctx := context.Background()
+
+	for i := range 4 {
+		_, err := client.Generate(ctx, gollm.NewPrompt(strings.Repeat("Remember this fact. ", 10)+string(rune('A'+i))))
+		require.NoError(t, err)
+	}
+
+	summarized := false
+	for _, call := range fake.Calls() {
+		if strings.HasPrefix(call.Request.SystemPrompt, "You summarize conversations") {
+			summarized = true
+			continue
+		}
+		if summarized {
+			assert.Empty(t, call.Request.SystemPrompt)
+			systemPrompt, _ := call.Options["system_prompt"].(string)
+			assert.NotContains(t, systemPrompt, "You summarize conversations",
+				"the summary instructions do not leak into the conversation")
+		}
+	}
+	require.True(t, summarized, "memory was summarized")
+}
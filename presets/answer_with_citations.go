@@ -0,0 +1,107 @@
+// Package presets provides utilities for enhancing Language Learning Model interactions
+// with specific reasoning patterns and question-answering capabilities.
+package presets
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/weave-labs/gollm"
+	"github.com/weave-labs/gollm/providers"
+	"github.com/weave-labs/gollm/rag"
+)
+
+// CitedAnswer is an answer based on retrieved sources.
+type CitedAnswer struct {
+	// Response is the response of the final generation attempt.
+	Response *providers.Response
+	// Answer is the text of the answer.
+	Answer string
+	// Citations are the retrieved chunks the answer cites, in the order the
+	// model cited them.
+	Citations []rag.ScoredChunk
+	// Sources are all chunks retrieved for the question, most relevant first.
+	Sources []rag.ScoredChunk
+}
+
+// citedAnswerResponse is the structured response requested from the model.
+type citedAnswerResponse struct {
+	Answer    string   `json:"answer" validate:"required" jsonschema:"the answer to the question"`
+	Citations []string `json:"citations" jsonschema:"IDs of the sources the answer is based on, without brackets"`
+}
+
+// AnswerWithCitations answers a question from the chunks retriever finds for
+// it, citing the chunks the answer is based on. The model is instructed to
+// answer only from the sources and to say so when they do not contain the
+// answer. Citations of IDs that were not retrieved are dropped.
+//
+// Parameters:
+//   - ctx: Context for cancellation and timeouts
+//   - l: LLM instance to use for generation
+//   - retriever: Retriever that finds the sources of the answer
+//   - question: The question to be answered
+//   - opts: Optional prompt configuration options
+//
+// Returns:
+//   - *CitedAnswer: The answer with its citations and all retrieved sources
+//   - error: Any error encountered during retrieval or generation
+//
+// Example usage:
+//
+//	answer, err := AnswerWithCitations(ctx, llm, retriever,
+//	    "How many vacation days do employees get?",
+//	    gollm.WithMaxLength(100),
+//	)
+//	for _, chunk := range answer.Citations {
+//	    fmt.Printf("[%s] %s\n", chunk.ID, chunk.SourceID)
+//	}
+func AnswerWithCitations(
+	ctx context.Context,
+	l gollm.LLM,
+	retriever *rag.Retriever,
+	question string,
+	opts ...gollm.PromptOption,
+) (*CitedAnswer, error) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	if l == nil {
+		return nil, errors.New("LLM instance cannot be nil")
+	}
+	if retriever == nil {
+		return nil, errors.New("retriever cannot be nil")
+	}
+
+	prompt := gollm.NewPrompt(question,
+		gollm.WithDirectives(
+			"Answer the question using only the provided sources",
+			"Cite the ID of every source the answer is based on",
+			"If the sources do not contain the answer, say that you do not know and cite no sources",
+		),
+	)
+	prompt.Apply(opts...)
+
+	sources, err := retriever.Augment(ctx, prompt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve sources: %w", err)
+	}
+
+	result, response, err := gollm.GenerateTyped[citedAnswerResponse](ctx, l, prompt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate answer: %w", err)
+	}
+
+	byID := make(map[string]rag.ScoredChunk, len(sources))
+	for _, chunk := range sources {
+		byID[chunk.ID] = chunk
+	}
+	answer := &CitedAnswer{Response: response, Answer: result.Answer, Sources: sources}
+	for _, id := range result.Citations {
+		if chunk, ok := byID[id]; ok {
+			answer.Citations = append(answer.Citations, chunk)
+			delete(byID, id)
+		}
+	}
+	return answer, nil
+}
@@ -0,0 +1,70 @@
+package presets
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/weave-labs/gollm/gollmtest"
+	"github.com/weave-labs/gollm/rag"
+)
+
+// newHandbookRetriever returns a retriever over three handbook chunks whose
+// embeddings count the keywords "vacation", "days" and "salary".
+func newHandbookRetriever(t *testing.T, opts ...rag.RetrieverOption) *rag.Retriever {
+	t.Helper()
+
+	keywords := []string{"vacation", "days", "salary"}
+	embedder := rag.EmbedderFunc(func(_ context.Context, texts []string) ([][]float32, error) {
+		vectors := make([][]float32, len(texts))
+		for i, text := range texts {
+			vectors[i] = make([]float32, len(keywords))
+			for j, keyword := range keywords {
+				vectors[i][j] = float32(strings.Count(strings.ToLower(text), keyword))
+			}
+		}
+		return vectors, nil
+	})
+
+	retriever := rag.NewRetriever(embedder, rag.NewInMemoryStore(), opts...)
+	require.NoError(t, retriever.Index(context.Background(), []rag.Chunk{
+		{ID: "handbook#1", SourceID: "handbook.md", Text: "Employees get 25 vacation days."},
+		{ID: "handbook#2", SourceID: "handbook.md", Text: "Unused vacation carries over."},
+		{ID: "handbook#3", SourceID: "handbook.md", Text: "Salary is paid monthly."},
+	}))
+	return retriever
+}
+
+func TestAnswerWithCitations(t *testing.T) {
+	for _, placement := range []rag.Placement{rag.PlaceInContext, rag.PlaceInMessages} {
+		fake := gollmtest.NewProvider()
+		fake.ReplyJSON(map[string]any{
+			"answer":    "25 days, and unused days carry over.",
+			"citations": []string{"handbook#2", "handbook#9", "handbook#1", "handbook#2"},
+		})
+
+		retriever := newHandbookRetriever(t, rag.WithTopK(2), rag.WithPlacement(placement))
+		answer, err := AnswerWithCitations(context.Background(), gollmtest.NewLLM(t, fake), retriever,
+			"How many vacation days do employees get?")
+		require.NoError(t, err)
+
+		assert.Equal(t, "25 days, and unused days carry over.", answer.Answer)
+		require.Len(t, answer.Sources, 2)
+		assert.Equal(t, "handbook#1", answer.Sources[0].ID, "sources are ordered by relevance")
+		assert.Equal(t, "handbook#2", answer.Sources[1].ID)
+		require.Len(t, answer.Citations, 2, "unknown and repeated citations are dropped")
+		assert.Equal(t, "handbook#2", answer.Citations[0].ID, "citations keep the model's order")
+		assert.Equal(t, "handbook#1", answer.Citations[1].ID)
+
+		var request strings.Builder
+		for _, message := range fake.LastCall().Request.Messages {
+			request.WriteString(message.Content)
+		}
+		assert.Contains(t, request.String(), "Answer the question using only the provided sources")
+		assert.Contains(t, request.String(), "[handbook#1] (source: handbook.md)")
+		assert.NotContains(t, request.String(), "handbook#3")
+	}
+}
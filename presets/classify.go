@@ -0,0 +1,316 @@
+package presets
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/google/jsonschema-go/jsonschema"
+
+	"github.com/weave-labs/gollm"
+	"github.com/weave-labs/gollm/providers"
+)
+
+// DefaultClassifyConcurrency is the number of texts ClassifyBatch classifies
+// at the same time unless WithClassifyConcurrency is given.
+const DefaultClassifyConcurrency = 4
+
+// Label is a class a text can be assigned to.
+type Label[L ~string] struct {
+	// Name is the value returned when the label is chosen.
+	Name L
+	// Description explains when the label applies.
+	Description string
+	// Examples are texts that belong to the label.
+	Examples []string
+}
+
+// LabelScore is a chosen label with the model's confidence in it.
+type LabelScore[L ~string] struct {
+	// Label is the chosen label.
+	Label L `json:"label"`
+	// Confidence is the model's confidence between 0 and 1.
+	Confidence float64 `json:"confidence"`
+}
+
+// Classification is the result of classifying a text.
+type Classification[L ~string] struct {
+	// Response is the response of the model.
+	Response *providers.Response
+	// Label is the most confident label. It is empty when a multi-label
+	// classification chose no label.
+	Label L
+	// Rationale is the model's explanation of its choice.
+	Rationale string
+	// Labels are the chosen labels, most confident first. Single-label
+	// classifications choose exactly one.
+	Labels []LabelScore[L]
+	// Confidence is the confidence of Label.
+	Confidence float64
+}
+
+// ClassifyOption configures Classify and ClassifyBatch.
+type ClassifyOption func(*classifyConfig)
+
+type classifyConfig struct {
+	promptOptions []gollm.PromptOption
+	concurrency   int
+	multiLabel    bool
+}
+
+// WithMultiLabel lets the model choose every label that applies, including
+// none, instead of exactly one.
+func WithMultiLabel() ClassifyOption {
+	return func(c *classifyConfig) {
+		c.multiLabel = true
+	}
+}
+
+// WithClassifyConcurrency limits how many texts ClassifyBatch classifies at the
+// same time.
+func WithClassifyConcurrency(n int) ClassifyOption {
+	return func(c *classifyConfig) {
+		c.concurrency = n
+	}
+}
+
+// WithClassifyPromptOptions sets prompt options, such as gollm.WithDirectives
+// or gollm.WithContext, for every classification prompt.
+func WithClassifyPromptOptions(opts ...gollm.PromptOption) ClassifyOption {
+	return func(c *classifyConfig) {
+		c.promptOptions = append(c.promptOptions, opts...)
+	}
+}
+
+// classifyResponse is the structured response requested from the model.
+type classifyResponse struct {
+	Labels []struct {
+		Label      string  `json:"label" jsonschema:"one of the labels"`
+		Confidence float64 `json:"confidence" jsonschema:"confidence that the label applies, between 0 and 1"`
+	} `json:"labels" jsonschema:"the labels that apply to the text"`
+	Rationale string `json:"rationale" jsonschema:"a brief explanation of the choice"`
+}
+
+// Classify assigns a text to one of the given labels, or with WithMultiLabel
+// to every label that applies. The model is given the description and examples
+// of each label and must answer with a structured response whose labels are
+// constrained to the label names.
+//
+// Parameters:
+//   - ctx: Context for cancellation and timeouts
+//   - l: LLM instance to use for classification
+//   - text: The text to be classified
+//   - labels: The labels to choose from
+//   - opts: Options for multi-label classification and the prompt
+//
+// Returns:
+//   - *Classification[L]: The chosen labels with their confidence and the rationale
+//   - error: Any error encountered during classification
+//
+// Example usage:
+//
+//	type Intent string
+//
+//	labels := []Label[Intent]{
+//	    {Name: "billing", Description: "Invoices, payments and refunds"},
+//	    {Name: "support", Description: "Problems using the product",
+//	        Examples: []string{"The app crashes when I log in"}},
+//	}
+//	result, err := Classify(ctx, llm, "I was charged twice this month", labels)
+//	fmt.Println(result.Label, result.Confidence)
+func Classify[L ~string](
+	ctx context.Context,
+	l gollm.LLM,
+	text string,
+	labels []Label[L],
+	opts ...ClassifyOption,
+) (*Classification[L], error) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	if l == nil {
+		return nil, errors.New("LLM instance cannot be nil")
+	}
+	cfg := newClassifyConfig(opts)
+	schema, err := classifySchema(labels, cfg.multiLabel)
+	if err != nil {
+		return nil, err
+	}
+	return classify(ctx, l, text, labels, schema, cfg)
+}
+
+// ClassifyBatch classifies texts like Classify, with at most the configured
+// number of concurrent calls. The result of each text is at its index; when
+// some texts fail, their results are nil and the error joins their errors.
+//
+// Example usage:
+//
+//	results, err := ClassifyBatch(ctx, llm, tickets, labels,
+//	    WithMultiLabel(),
+//	    WithClassifyConcurrency(8),
+//	)
+func ClassifyBatch[L ~string](
+	ctx context.Context,
+	l gollm.LLM,
+	texts []string,
+	labels []Label[L],
+	opts ...ClassifyOption,
+) ([]*Classification[L], error) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	if l == nil {
+		return nil, errors.New("LLM instance cannot be nil")
+	}
+	cfg := newClassifyConfig(opts)
+	schema, err := classifySchema(labels, cfg.multiLabel)
+	if err != nil {
+		return nil, err
+	}
+
+	results := make([]*Classification[L], len(texts))
+	errs := runConcurrently(ctx, len(texts), cfg.concurrency, func(i int) error {
+		var err error
+		results[i], err = classify(ctx, l, texts[i], labels, schema, cfg)
+		return err
+	})
+	for i, err := range errs {
+		if err != nil {
+			errs[i] = fmt.Errorf("text %d: %w", i, err)
+		}
+	}
+
+	return results, errors.Join(errs...)
+}
+
+// newClassifyConfig applies opts over the defaults.
+func newClassifyConfig(opts []ClassifyOption) classifyConfig {
+	cfg := classifyConfig{concurrency: DefaultClassifyConcurrency}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	cfg.concurrency = max(cfg.concurrency, 1)
+	return cfg
+}
+
+// classifySchema returns the response schema with the label names as the enum
+// of each label.
+func classifySchema[L ~string](labels []Label[L], multiLabel bool) ([]byte, error) {
+	if len(labels) == 0 {
+		return nil, errors.New("labels cannot be empty")
+	}
+	names := make([]any, len(labels))
+	for i, label := range labels {
+		if label.Name == "" {
+			return nil, fmt.Errorf("label %d has no name", i)
+		}
+		if slices.Contains(names[:i], any(string(label.Name))) {
+			return nil, fmt.Errorf("duplicate label %q", label.Name)
+		}
+		names[i] = string(label.Name)
+	}
+
+	schema, err := jsonschema.For[classifyResponse](&jsonschema.ForOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate classification schema: %w", err)
+	}
+	labelsSchema := schema.Properties["labels"]
+	labelsSchema.Items.Properties["label"].Enum = names
+	zero, one := 0.0, 1.0
+	labelsSchema.Items.Properties["confidence"].Minimum = &zero
+	labelsSchema.Items.Properties["confidence"].Maximum = &one
+	if !multiLabel {
+		single := 1
+		labelsSchema.MinItems = &single
+		labelsSchema.MaxItems = &single
+	}
+
+	data, err := json.Marshal(schema)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode classification schema: %w", err)
+	}
+	return data, nil
+}
+
+// classify classifies one text with a prepared schema.
+func classify[L ~string](
+	ctx context.Context,
+	l gollm.LLM,
+	text string,
+	labels []Label[L],
+	schema []byte,
+	cfg classifyConfig,
+) (*Classification[L], error) {
+	prompt := gollm.NewPrompt(classifyInput(text, labels, cfg.multiLabel),
+		gollm.WithDirectives(
+			"Choose labels only from the list of labels",
+			"Base the choice on the label descriptions and examples",
+			"Give a confidence between 0 and 1 for each chosen label",
+			"Explain the choice briefly in the rationale",
+		),
+	)
+	prompt.Apply(cfg.promptOptions...)
+
+	response, err := l.Generate(ctx, prompt, gollm.WithStructuredResponseJSON(schema))
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate classification: %w", err)
+	}
+	var result classifyResponse
+	if err := json.Unmarshal([]byte(response.AsText()), &result); err != nil {
+		return nil, fmt.Errorf("failed to decode classification: %w", err)
+	}
+
+	classification := &Classification[L]{Response: response, Rationale: result.Rationale}
+	for _, chosen := range result.Labels {
+		label := L(chosen.Label)
+		if slices.ContainsFunc(classification.Labels, func(s LabelScore[L]) bool { return s.Label == label }) {
+			continue
+		}
+		classification.Labels = append(classification.Labels, LabelScore[L]{
+			Label:      label,
+			Confidence: min(max(chosen.Confidence, 0), 1),
+		})
+	}
+	slices.SortStableFunc(classification.Labels, func(a, b LabelScore[L]) int {
+		switch {
+		case a.Confidence > b.Confidence:
+			return -1
+		case a.Confidence < b.Confidence:
+			return 1
+		}
+		return 0
+	})
+	if len(classification.Labels) > 0 {
+		classification.Label = classification.Labels[0].Label
+		classification.Confidence = classification.Labels[0].Confidence
+	}
+	return classification, nil
+}
+
+// classifyInput describes the labels and the text to classify.
+func classifyInput[L ~string](text string, labels []Label[L], multiLabel bool) string {
+	var b strings.Builder
+	if multiLabel {
+		b.WriteString("Classify the text below with every label that applies to it, or no label if none applies.\n\n")
+	} else {
+		b.WriteString("Classify the text below with the single label that fits it best.\n\n")
+	}
+
+	b.WriteString("Labels:\n")
+	for _, label := range labels {
+		fmt.Fprintf(&b, "- %s", label.Name)
+		if label.Description != "" {
+			fmt.Fprintf(&b, ": %s", label.Description)
+		}
+		b.WriteString("\n")
+		for _, example := range label.Examples {
+			fmt.Fprintf(&b, "  Example: %q\n", example)
+		}
+	}
+
+	fmt.Fprintf(&b, "\nText:\n%s", text)
+	return b.String()
+}
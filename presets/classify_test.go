@@ -0,0 +1,169 @@
+package presets
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/weave-labs/gollm/gollmtest"
+)
+
+type intent string
+
+var intentLabels = []Label[intent]{
+	{Name: "billing", Description: "Invoices, payments and refunds"},
+	{Name: "support", Description: "Problems using the product", Examples: []string{"The app crashes"}},
+	{Name: "sales", Description: "Pricing and plans"},
+}
+
+// labelsSchema decodes the schema of the labels property from a classification schema.
+func labelsSchema(t *testing.T, schema []byte) map[string]any {
+	t.Helper()
+
+	var decoded struct {
+		Properties map[string]map[string]any `json:"properties"`
+	}
+	require.NoError(t, json.Unmarshal(schema, &decoded))
+	return decoded.Properties["labels"]
+}
+
+func TestClassifySchema(t *testing.T) {
+	schema, err := classifySchema(intentLabels, false)
+	require.NoError(t, err)
+
+	labels := labelsSchema(t, schema)
+	assert.InDelta(t, 1, labels["minItems"], 0, "single-label classifications choose exactly one label")
+	assert.InDelta(t, 1, labels["maxItems"], 0)
+	items, ok := labels["items"].(map[string]any)
+	require.True(t, ok)
+	properties, ok := items["properties"].(map[string]any)
+	require.True(t, ok)
+	label, ok := properties["label"].(map[string]any)
+	require.True(t, ok)
+	assert.Equal(t, []any{"billing", "support", "sales"}, label["enum"])
+	confidence, ok := properties["confidence"].(map[string]any)
+	require.True(t, ok)
+	assert.InDelta(t, 0, confidence["minimum"], 0)
+	assert.InDelta(t, 1, confidence["maximum"], 0)
+
+	schema, err = classifySchema(intentLabels, true)
+	require.NoError(t, err)
+	labels = labelsSchema(t, schema)
+	assert.NotContains(t, labels, "minItems", "multi-label classifications may choose no label")
+	assert.NotContains(t, labels, "maxItems")
+}
+
+func TestClassifySchemaErrors(t *testing.T) {
+	_, err := classifySchema[intent](nil, false)
+	require.EqualError(t, err, "labels cannot be empty")
+
+	_, err = classifySchema([]Label[intent]{{Name: "billing"}, {Name: ""}}, false)
+	require.EqualError(t, err, "label 1 has no name")
+
+	_, err = classifySchema([]Label[intent]{{Name: "billing"}, {Name: "sales"}, {Name: "billing"}}, false)
+	require.EqualError(t, err, `duplicate label "billing"`)
+
+	fake := gollmtest.NewProvider()
+	_, err = Classify(context.Background(), gollmtest.NewLLM(t, fake), "text", []Label[intent]{{Name: ""}})
+	require.Error(t, err)
+	assert.Empty(t, fake.Calls(), "invalid labels fail before calling the model")
+}
+
+func TestClassify(t *testing.T) {
+	fake := gollmtest.NewProvider()
+	fake.ReplyJSON(map[string]any{
+		"labels":    []map[string]any{{"label": "billing", "confidence": 0.8}},
+		"rationale": "The customer was charged twice.",
+	})
+
+	result, err := Classify(context.Background(), gollmtest.NewLLM(t, fake), "I was charged twice", intentLabels)
+	require.NoError(t, err)
+
+	assert.Equal(t, intent("billing"), result.Label)
+	assert.InDelta(t, 0.8, result.Confidence, 1e-9)
+	assert.Equal(t, "The customer was charged twice.", result.Rationale)
+	assert.Equal(t, []LabelScore[intent]{{Label: "billing", Confidence: 0.8}}, result.Labels)
+
+	prompt := fake.LastCall().Request.Messages[0].Content
+	assert.Contains(t, prompt, "- support: Problems using the product")
+	assert.Contains(t, prompt, `Example: "The app crashes"`)
+	assert.Contains(t, prompt, "I was charged twice")
+}
+
+func TestClassifyMultiLabelDedupAndOrder(t *testing.T) {
+	fake := gollmtest.NewProvider()
+	fake.ReplyJSON(map[string]any{
+		"labels": []map[string]any{
+			{"label": "sales", "confidence": 0.4},
+			{"label": "billing", "confidence": 0.9},
+			{"label": "sales", "confidence": 0.7},
+			{"label": "support", "confidence": 0.4},
+		},
+		"rationale": "Pricing and a refund.",
+	})
+
+	result, err := Classify(context.Background(), gollmtest.NewLLM(t, fake), "Can I get a refund and a cheaper plan?",
+		intentLabels, WithMultiLabel())
+	require.NoError(t, err)
+
+	assert.Equal(t, []LabelScore[intent]{
+		{Label: "billing", Confidence: 0.9},
+		{Label: "sales", Confidence: 0.4},
+		{Label: "support", Confidence: 0.4},
+	}, result.Labels, "repeated labels keep their first score; ties keep the model's order")
+	assert.Equal(t, intent("billing"), result.Label)
+	assert.InDelta(t, 0.9, result.Confidence, 1e-9)
+}
+
+func TestClassifyMultiLabelNone(t *testing.T) {
+	fake := gollmtest.NewProvider()
+	fake.ReplyJSON(map[string]any{"labels": []any{}, "rationale": "Unrelated."})
+
+	result, err := Classify(context.Background(), gollmtest.NewLLM(t, fake), "Hello", intentLabels, WithMultiLabel())
+	require.NoError(t, err)
+	assert.Empty(t, result.Label)
+	assert.Empty(t, result.Labels)
+	assert.Zero(t, result.Confidence)
+}
+
+func TestClassifyBatchPartialFailure(t *testing.T) {
+	fake := gollmtest.NewProvider()
+	fake.ReplyJSON(map[string]any{
+		"labels":    []map[string]any{{"label": "billing", "confidence": 0.9}},
+		"rationale": "A payment.",
+	})
+	fake.ReplyError(errors.New("connection reset"))
+	fake.ReplyJSON(map[string]any{
+		"labels":    []map[string]any{{"label": "support", "confidence": 0.6}},
+		"rationale": "A problem.",
+	})
+
+	texts := []string{"first text", "second text", "third text"}
+	results, err := ClassifyBatch(context.Background(), gollmtest.NewLLM(t, fake), texts, intentLabels,
+		WithClassifyConcurrency(1))
+	require.Error(t, err)
+	require.Len(t, results, len(texts))
+
+	// With one call at a time, the calls are served in script order.
+	textOfCall := func(call int) int {
+		content := fake.Calls()[call].Request.Messages[0].Content
+		return slices.IndexFunc(texts, func(text string) bool { return strings.Contains(content, text) })
+	}
+	failed := textOfCall(1)
+	require.NotEqual(t, -1, failed)
+	assert.Nil(t, results[failed], "a failed text has no result")
+	assert.ErrorContains(t, err, fmt.Sprintf("text %d: ", failed))
+	assert.ErrorContains(t, err, "connection reset")
+
+	require.NotNil(t, results[textOfCall(0)])
+	assert.Equal(t, intent("billing"), results[textOfCall(0)].Label)
+	require.NotNil(t, results[textOfCall(2)])
+	assert.Equal(t, intent("support"), results[textOfCall(2)].Label)
+}
@@ -0,0 +1,32 @@
+package presets
+
+import (
+	"context"
+	"sync"
+)
+
+// runConcurrently calls fn for every index in [0, n) with at most concurrency
+// calls running at the same time, and returns the error of each call at its
+// index. Calls that have not started when ctx is done are skipped and get
+// ctx.Err() as their error.
+func runConcurrently(ctx context.Context, n, concurrency int, fn func(i int) error) []error {
+	errs := make([]error, n)
+	workerPool := make(chan struct{}, max(concurrency, 1))
+	var wg sync.WaitGroup
+	for i := range n {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			select {
+			case workerPool <- struct{}{}:
+				defer func() { <-workerPool }()
+			case <-ctx.Done():
+				errs[i] = ctx.Err()
+				return
+			}
+			errs[i] = fn(i)
+		}()
+	}
+	wg.Wait()
+	return errs
+}
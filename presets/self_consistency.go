@@ -0,0 +1,260 @@
+package presets
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/weave-labs/gollm"
+	"github.com/weave-labs/gollm/providers"
+)
+
+// Defaults of SelfConsistency.
+const (
+	DefaultSelfConsistencySamples     = 5
+	DefaultSelfConsistencyTemperature = 0.7
+)
+
+// ReasoningPath is one sampled chain of thought and the answer it reached.
+type ReasoningPath struct {
+	// Response is the response of the sample.
+	Response *providers.Response
+	// Reasoning is the step-by-step reasoning of the sample.
+	Reasoning string
+	// Answer is the final answer of the sample.
+	Answer string
+}
+
+// AnswerVotes is an answer with the number of samples that reached it.
+type AnswerVotes struct {
+	// Answer is the answer as given by the first sample that reached it.
+	Answer string
+	// Paths are the indexes in SelfConsistencyResult.Paths of the samples
+	// that reached the answer.
+	Paths []int
+	// Votes is the number of samples that reached the answer.
+	Votes int
+}
+
+// SelfConsistencyResult is the result of SelfConsistency.
+type SelfConsistencyResult struct {
+	// Answer is the answer reached by most samples.
+	Answer string
+	// Votes are the distinct answers, most votes first. Ties are ordered by
+	// the first sample that reached them.
+	Votes []AnswerVotes
+	// Paths are the reasoning paths of the samples that succeeded.
+	Paths []ReasoningPath
+	// Errors are the errors of the samples that failed.
+	Errors []error
+	// Usage is the token usage of all samples, where reported.
+	Usage providers.Usage
+	// Agreement is the share of successful samples that reached Answer.
+	Agreement float64
+}
+
+// SelfConsistencyOption configures SelfConsistency.
+type SelfConsistencyOption func(*selfConsistencyConfig)
+
+type selfConsistencyConfig struct {
+	equivalent    func(a, b string) bool
+	promptOptions []gollm.PromptOption
+	temperature   float64
+	samples       int
+	concurrency   int
+}
+
+// WithSamples sets how many reasoning paths are sampled.
+func WithSamples(n int) SelfConsistencyOption {
+	return func(c *selfConsistencyConfig) {
+		c.samples = n
+	}
+}
+
+// WithSampleTemperature sets the temperature of every sample. It should be
+// high enough for the samples to follow different reasoning paths.
+func WithSampleTemperature(temperature float64) SelfConsistencyOption {
+	return func(c *selfConsistencyConfig) {
+		c.temperature = temperature
+	}
+}
+
+// WithSampleConcurrency limits how many samples are generated at the same
+// time. By default all samples are generated concurrently.
+func WithSampleConcurrency(n int) SelfConsistencyOption {
+	return func(c *selfConsistencyConfig) {
+		c.concurrency = n
+	}
+}
+
+// WithAnswerEquivalence sets how answers are clustered: each answer joins the
+// first cluster whose answer is equivalent to it. By default answers are
+// equivalent when they are equal after normalizing case, whitespace, trailing
+// punctuation and number formatting.
+func WithAnswerEquivalence(equivalent func(a, b string) bool) SelfConsistencyOption {
+	return func(c *selfConsistencyConfig) {
+		c.equivalent = equivalent
+	}
+}
+
+// WithSelfConsistencyPromptOptions sets prompt options, such as
+// gollm.WithContext or gollm.WithExamples, for every sample.
+func WithSelfConsistencyPromptOptions(opts ...gollm.PromptOption) SelfConsistencyOption {
+	return func(c *selfConsistencyConfig) {
+		c.promptOptions = append(c.promptOptions, opts...)
+	}
+}
+
+// selfConsistencyResponse is the structured response requested from the model.
+type selfConsistencyResponse struct {
+	Reasoning string `json:"reasoning" jsonschema:"step-by-step reasoning that leads to the answer"`
+	Answer    string `json:"answer" validate:"required" jsonschema:"the final answer only, as short as possible, without explanation"`
+}
+
+// SelfConsistency answers a question with chain-of-thought reasoning and
+// self-consistency: it samples several reasoning paths at a non-zero
+// temperature, concurrently, extracts the final answer of each with a
+// structured response and returns the answer most paths agree on.
+//
+// Samples that fail are recorded in the result's Errors; SelfConsistency only
+// fails when every sample does.
+//
+// Parameters:
+//   - ctx: Context for cancellation and timeouts
+//   - l: LLM instance to use for sampling
+//   - question: The question to be answered
+//   - opts: Options for the number of samples, temperature and clustering
+//
+// Returns:
+//   - *SelfConsistencyResult: The winning answer, the vote distribution and all reasoning paths
+//   - error: Any error encountered when no sample succeeded
+//
+// Example usage:
+//
+//	result, err := SelfConsistency(ctx, llm,
+//	    "A bat and a ball cost $1.10 in total. The bat costs $1.00 more than the ball. How much does the ball cost?",
+//	    WithSamples(7),
+//	    WithSampleTemperature(0.8),
+//	)
+//	fmt.Printf("%s (%.0f%% agreement)\n", result.Answer, result.Agreement*100)
+//	for _, vote := range result.Votes {
+//	    fmt.Println(vote.Answer, vote.Votes)
+//	}
+func SelfConsistency(
+	ctx context.Context,
+	l gollm.LLM,
+	question string,
+	opts ...SelfConsistencyOption,
+) (*SelfConsistencyResult, error) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	if l == nil {
+		return nil, errors.New("LLM instance cannot be nil")
+	}
+
+	cfg := selfConsistencyConfig{
+		equivalent:  equivalentAnswers,
+		temperature: DefaultSelfConsistencyTemperature,
+		samples:     DefaultSelfConsistencySamples,
+	}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	if cfg.samples <= 0 {
+		return nil, errors.New("number of samples must be positive")
+	}
+	if cfg.concurrency <= 0 {
+		cfg.concurrency = cfg.samples
+	}
+
+	paths := make([]*ReasoningPath, cfg.samples)
+	errs := runConcurrently(ctx, cfg.samples, cfg.concurrency, func(i int) error {
+		var err error
+		paths[i], err = sampleReasoningPath(ctx, l, question, cfg)
+		return err
+	})
+
+	result := &SelfConsistencyResult{}
+	for i, path := range paths {
+		if errs[i] != nil {
+			result.Errors = append(result.Errors, fmt.Errorf("sample %d: %w", i, errs[i]))
+			continue
+		}
+		result.Paths = append(result.Paths, *path)
+		addUsage(&result.Usage, path.Response.Usage)
+	}
+	if len(result.Paths) == 0 {
+		return nil, fmt.Errorf("every sample failed: %w", errors.Join(result.Errors...))
+	}
+
+	result.Votes = voteAnswers(result.Paths, cfg.equivalent)
+	result.Answer = result.Votes[0].Answer
+	result.Agreement = float64(result.Votes[0].Votes) / float64(len(result.Paths))
+	return result, nil
+}
+
+// sampleReasoningPath generates one reasoning path.
+func sampleReasoningPath(
+	ctx context.Context,
+	l gollm.LLM,
+	question string,
+	cfg selfConsistencyConfig,
+) (*ReasoningPath, error) {
+	prompt := gollm.NewPrompt(question,
+		gollm.WithDirectives(
+			"Think through the problem step by step before answering",
+			"Check each step of the reasoning for mistakes",
+			"Give the final answer separately from the reasoning",
+		),
+	)
+	prompt.Apply(cfg.promptOptions...)
+
+	result, response, err := gollm.GenerateTyped[selfConsistencyResponse](ctx, l, prompt,
+		gollm.WithProviderOption("temperature", cfg.temperature))
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate reasoning path: %w", err)
+	}
+	return &ReasoningPath{
+		Response:  response,
+		Reasoning: result.Reasoning,
+		Answer:    strings.TrimSpace(result.Answer),
+	}, nil
+}
+
+// voteAnswers clusters the answers of paths and orders the clusters by votes.
+func voteAnswers(paths []ReasoningPath, equivalent func(a, b string) bool) []AnswerVotes {
+	var votes []AnswerVotes
+	for i, path := range paths {
+		cluster := slices.IndexFunc(votes, func(v AnswerVotes) bool { return equivalent(v.Answer, path.Answer) })
+		if cluster < 0 {
+			votes = append(votes, AnswerVotes{Answer: path.Answer})
+			cluster = len(votes) - 1
+		}
+		votes[cluster].Paths = append(votes[cluster].Paths, i)
+		votes[cluster].Votes++
+	}
+	slices.SortStableFunc(votes, func(a, b AnswerVotes) int { return b.Votes - a.Votes })
+	return votes
+}
+
+// equivalentAnswers reports whether two answers are equal after normalizing.
+func equivalentAnswers(a, b string) bool {
+	return normalizeAnswer(a) == normalizeAnswer(b)
+}
+
+// normalizeAnswer lowercases an answer, collapses its whitespace, trims
+// surrounding punctuation and formats numbers canonically, so that "$0.05",
+// "0.050" and "0.05." vote together.
+func normalizeAnswer(answer string) string {
+	answer = strings.ToLower(strings.Join(strings.Fields(answer), " "))
+	answer = strings.Trim(strings.TrimRight(answer, ".!?;:"), "\"'`*()[] ")
+	answer = strings.TrimLeft(answer, "$€£¥")
+	if n, err := strconv.ParseFloat(strings.ReplaceAll(answer, ",", ""), 64); err == nil {
+		return strconv.FormatFloat(n, 'f', -1, 64)
+	}
+	return answer
+}
@@ -0,0 +1,116 @@
+package presets
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/weave-labs/gollm/gollmtest"
+)
+
+func TestNormalizeAnswer(t *testing.T) {
+	tests := []struct {
+		answer string
+		want   string
+	}{
+		{answer: "$0.05", want: "0.05"},
+		{answer: "0.050", want: "0.05"},
+		{answer: "0.05.", want: "0.05"},
+		{answer: "1,000", want: "1000"},
+		{answer: "**42**", want: "42"},
+		{answer: "  The   Answer is Paris! ", want: "the answer is paris"},
+		{answer: `"yes"`, want: "yes"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.answer, func(t *testing.T) {
+			assert.Equal(t, tt.want, normalizeAnswer(tt.answer))
+		})
+	}
+
+	assert.True(t, equivalentAnswers("$0.05", "0.050"))
+	assert.True(t, equivalentAnswers("0.050", "0.05."))
+	assert.False(t, equivalentAnswers("0.05", "0.5"))
+}
+
+func TestVoteAnswers(t *testing.T) {
+	paths := []ReasoningPath{
+		{Answer: "Paris"},
+		{Answer: "Lyon"},
+		{Answer: "lyon."},
+		{Answer: "paris"},
+		{Answer: "Nice"},
+		{Answer: "LYON"},
+	}
+
+	votes := voteAnswers(paths, equivalentAnswers)
+	assert.Equal(t, []AnswerVotes{
+		{Answer: "Lyon", Paths: []int{1, 2, 5}, Votes: 3},
+		{Answer: "Paris", Paths: []int{0, 3}, Votes: 2},
+		{Answer: "Nice", Paths: []int{4}, Votes: 1},
+	}, votes, "clusters keep the answer of their first sample and are ordered by votes")
+}
+
+func TestVoteAnswersTies(t *testing.T) {
+	paths := []ReasoningPath{{Answer: "b"}, {Answer: "a"}, {Answer: "a"}, {Answer: "b"}, {Answer: "c"}}
+
+	votes := voteAnswers(paths, equivalentAnswers)
+	require.Len(t, votes, 3)
+	assert.Equal(t, "b", votes[0].Answer, "ties are ordered by the first sample that reached them")
+	assert.Equal(t, "a", votes[1].Answer)
+	assert.Equal(t, "c", votes[2].Answer)
+
+	exact := voteAnswers(paths[:2], func(a, b string) bool { return a == b })
+	assert.Equal(t, []AnswerVotes{
+		{Answer: "b", Paths: []int{0}, Votes: 1},
+		{Answer: "a", Paths: []int{1}, Votes: 1},
+	}, exact)
+}
+
+func TestSelfConsistency(t *testing.T) {
+	fake := gollmtest.NewProvider()
+	for _, answer := range []string{"$0.05", "0.050", "0.10", "0.05."} {
+		fake.ReplyJSON(map[string]any{"reasoning": "The ball costs x.", "answer": answer}).WithUsage(50, 20)
+	}
+	fake.ReplyError(errors.New("connection reset"))
+
+	result, err := SelfConsistency(context.Background(), gollmtest.NewLLM(t, fake),
+		"A bat and a ball cost $1.10 in total. The bat costs $1.00 more than the ball. How much does the ball cost?",
+		WithSamples(5),
+		WithSampleTemperature(0.9),
+	)
+	require.NoError(t, err, "a failed sample does not fail the result")
+
+	assert.Equal(t, "0.05", normalizeAnswer(result.Answer))
+	require.Len(t, result.Votes, 2)
+	assert.Equal(t, 3, result.Votes[0].Votes)
+	assert.Equal(t, "0.10", result.Votes[1].Answer)
+	assert.Equal(t, 1, result.Votes[1].Votes)
+	assert.InDelta(t, 0.75, result.Agreement, 1e-9, "agreement is the share of successful samples")
+
+	assert.Len(t, result.Paths, 4)
+	require.Len(t, result.Errors, 1)
+	assert.ErrorContains(t, result.Errors[0], "connection reset")
+	assert.Equal(t, int64(4*50), result.Usage.InputTokens)
+	assert.Equal(t, int64(4*20), result.Usage.OutputTokens)
+
+	require.Len(t, fake.Calls(), 5)
+	for _, call := range fake.Calls() {
+		assert.InDelta(t, 0.9, call.Options["temperature"], 1e-9)
+	}
+}
+
+func TestSelfConsistencyEverySampleFails(t *testing.T) {
+	fake := gollmtest.NewProvider()
+	fake.ReplyError(errors.New("connection reset"))
+	fake.ReplyError(errors.New("connection reset"))
+
+	_, err := SelfConsistency(context.Background(), gollmtest.NewLLM(t, fake), "Question?", WithSamples(2))
+	require.ErrorContains(t, err, "every sample failed")
+	assert.ErrorContains(t, err, "connection reset")
+
+	_, err = SelfConsistency(context.Background(), gollmtest.NewLLM(t, fake), "Question?", WithSamples(0))
+	require.EqualError(t, err, "number of samples must be positive")
+}
@@ -0,0 +1,410 @@
+package presets
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/weave-labs/gollm"
+	"github.com/weave-labs/gollm/providers"
+	"github.com/weave-labs/gollm/textsplit"
+)
+
+// Defaults of SummarizeLong.
+const (
+	DefaultSummaryChunkTokens = 3000
+	DefaultSummaryOverlap     = 100
+	DefaultSummaryConcurrency = 4
+)
+
+// Stages reported in SummaryProgress.
+const (
+	summaryStageMap     = "map"
+	summaryStageCombine = "combine"
+	summaryStageRefine  = "refine"
+	summaryStageFinal   = "final"
+)
+
+const (
+	// maxSummaryCombineLevels bounds the combine rounds of SummaryMapReduce.
+	maxSummaryCombineLevels = 10
+	// summarySeparator separates the summaries combined in one prompt.
+	summarySeparator = "\n\n---\n\n"
+)
+
+// SummaryStrategy selects how SummarizeLong summarizes a text that does not
+// fit in one prompt.
+type SummaryStrategy int
+
+const (
+	// SummaryMapReduce summarizes all chunks independently and concurrently,
+	// then combines the summaries, in rounds if they still do not fit.
+	SummaryMapReduce SummaryStrategy = iota
+	// SummaryRefine summarizes the first chunk and refines that summary
+	// with each following chunk in turn. It is slower but sees the running
+	// summary while reading each chunk.
+	SummaryRefine
+)
+
+// SummaryProgress reports the progress of SummarizeLong after each call to
+// the model.
+type SummaryProgress struct {
+	// Stage is "map", "combine", "refine" or "final".
+	Stage string
+	// Level is the combine round, starting at 1, for the "combine" stage.
+	Level int
+	// Completed is the number of calls finished in the stage or round.
+	Completed int
+	// Total is the number of calls in the stage or round.
+	Total int
+}
+
+// LongSummary is the result of SummarizeLong.
+type LongSummary struct {
+	// Summary is the final summary of the text.
+	Summary string
+	// Usage is the token usage of all calls to the model, where reported.
+	Usage providers.Usage
+	// Chunks is the number of chunks the text was split into.
+	Chunks int
+	// Calls is the number of calls made to the model.
+	Calls int
+}
+
+// SummarizeLongOption configures SummarizeLong.
+type SummarizeLongOption func(*summarizeLongConfig)
+
+type summarizeLongConfig struct {
+	progress      func(SummaryProgress)
+	promptOptions []gollm.PromptOption
+	strategy      SummaryStrategy
+	chunkTokens   int
+	overlap       int
+	concurrency   int
+}
+
+// WithSummaryStrategy selects the summarization strategy. The default is
+// SummaryMapReduce.
+func WithSummaryStrategy(strategy SummaryStrategy) SummarizeLongOption {
+	return func(c *summarizeLongConfig) {
+		c.strategy = strategy
+	}
+}
+
+// WithSummaryChunkTokens sets the token budget of each chunk, and of the
+// summaries combined in one call. It should leave room in the model's context
+// window for the instructions and the response.
+func WithSummaryChunkTokens(tokens int) SummarizeLongOption {
+	return func(c *summarizeLongConfig) {
+		c.chunkTokens = tokens
+	}
+}
+
+// WithSummaryOverlap sets how many tokens of each chunk are repeated at the
+// start of the next one.
+func WithSummaryOverlap(tokens int) SummarizeLongOption {
+	return func(c *summarizeLongConfig) {
+		c.overlap = tokens
+	}
+}
+
+// WithSummaryConcurrency limits how many chunks are summarized at the same
+// time by SummaryMapReduce.
+func WithSummaryConcurrency(n int) SummarizeLongOption {
+	return func(c *summarizeLongConfig) {
+		c.concurrency = n
+	}
+}
+
+// WithSummaryProgress sets a function that is called after each call to the
+// model. Calls to it are not concurrent.
+func WithSummaryProgress(progress func(SummaryProgress)) SummarizeLongOption {
+	return func(c *summarizeLongConfig) {
+		c.progress = progress
+	}
+}
+
+// WithSummaryPromptOptions sets prompt options, such as gollm.WithMaxLength or
+// gollm.WithDirectives, for the calls that write the final summary: the last
+// combine of SummaryMapReduce and every step of SummaryRefine.
+func WithSummaryPromptOptions(opts ...gollm.PromptOption) SummarizeLongOption {
+	return func(c *summarizeLongConfig) {
+		c.promptOptions = append(c.promptOptions, opts...)
+	}
+}
+
+// SummarizeLong summarizes a text of any length. The text is split into
+// chunks by the token budget of the model's tokenizer, and the chunks are
+// summarized with the selected strategy. A text that fits in a single chunk
+// is summarized in one call, like Summarize.
+//
+// Parameters:
+//   - ctx: Context for cancellation and timeouts
+//   - l: LLM instance to use for summarization
+//   - text: The text to be summarized
+//   - opts: Options selecting the strategy, budget, concurrency and progress
+//
+// Returns:
+//   - *LongSummary: The summary with the aggregated usage of all calls
+//   - error: Any error encountered during summarization
+//
+// Example usage:
+//
+//	summary, err := SummarizeLong(ctx, llm, report,
+//	    WithSummaryChunkTokens(4000),
+//	    WithSummaryConcurrency(8),
+//	    WithSummaryProgress(func(p SummaryProgress) {
+//	        fmt.Printf("%s %d/%d\n", p.Stage, p.Completed, p.Total)
+//	    }),
+//	    WithSummaryPromptOptions(gollm.WithMaxLength(300)),
+//	)
+//	fmt.Println(summary.Summary, summary.Usage.TotalTokens)
+func SummarizeLong(ctx context.Context, l gollm.LLM, text string, opts ...SummarizeLongOption) (*LongSummary, error) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	if l == nil {
+		return nil, errors.New("LLM instance cannot be nil")
+	}
+
+	cfg := summarizeLongConfig{
+		strategy:    SummaryMapReduce,
+		chunkTokens: DefaultSummaryChunkTokens,
+		overlap:     DefaultSummaryOverlap,
+		concurrency: DefaultSummaryConcurrency,
+	}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	if cfg.chunkTokens <= 0 {
+		return nil, errors.New("summary chunk tokens must be positive")
+	}
+	cfg.concurrency = max(cfg.concurrency, 1)
+
+	s := &longSummarizer{
+		cfg:       cfg,
+		llm:       l,
+		length:    textsplit.TokenLength(l.GetModel()),
+		completed: make(map[summaryRound]int),
+	}
+	chunks := textsplit.NewTokenSplitter(l.GetModel(),
+		textsplit.WithChunkSize(cfg.chunkTokens),
+		textsplit.WithOverlap(cfg.overlap),
+	).Split(text)
+	if len(chunks) == 0 {
+		return nil, errors.New("text to summarize cannot be empty")
+	}
+	texts := make([]string, len(chunks))
+	for i, chunk := range chunks {
+		texts[i] = chunk.Text
+	}
+
+	var summary string
+	var err error
+	switch {
+	case len(texts) == 1:
+		summary, err = s.generate(ctx, summaryStageFinal, 0, 1, summarizeChunkTemplate,
+			map[string]any{"Text": texts[0]}, cfg.promptOptions)
+	case cfg.strategy == SummaryRefine:
+		summary, err = s.refine(ctx, texts)
+	default:
+		summary, err = s.mapReduce(ctx, texts)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &LongSummary{
+		Summary: summary,
+		Usage:   s.usage,
+		Chunks:  len(texts),
+		Calls:   s.calls,
+	}, nil
+}
+
+var (
+	summarizeChunkTemplate = gollm.NewPromptTemplate(
+		"SummarizeChunk",
+		"Summarize a part of a longer text",
+		"Summarize the following text:\n\n{{.Text}}",
+		gollm.WithPromptOptions(
+			gollm.WithDirectives(
+				"Provide a concise summary",
+				"Capture the main points and key details",
+				"Only use information from the text",
+			),
+			gollm.WithOutput("Summary:"),
+		),
+	)
+	combineSummariesTemplate = gollm.NewPromptTemplate(
+		"CombineSummaries",
+		"Combine summaries of consecutive parts of a text",
+		"The following are summaries of consecutive parts of a longer text, separated by ---. "+
+			"Combine them into a single summary:\n\n{{.Text}}",
+		gollm.WithPromptOptions(
+			gollm.WithDirectives(
+				"Provide a concise, coherent summary",
+				"Keep the order of events and arguments",
+				"Merge points that are repeated across summaries",
+			),
+			gollm.WithOutput("Summary:"),
+		),
+	)
+	refineSummaryTemplate = gollm.NewPromptTemplate(
+		"RefineSummary",
+		"Refine a running summary with the next part of a text",
+		"This is a summary of a text so far:\n\n{{.Summary}}\n\n"+
+			"Refine the summary with the following continuation of the text:\n\n{{.Text}}",
+		gollm.WithPromptOptions(
+			gollm.WithDirectives(
+				"Provide a concise summary of the whole text read so far",
+				"Keep the main points of the existing summary unless the continuation changes them",
+				"Only use information from the summary and the continuation",
+			),
+			gollm.WithOutput("Summary:"),
+		),
+	)
+)
+
+// summaryRound identifies a stage or combine round for progress reports.
+type summaryRound struct {
+	stage string
+	level int
+}
+
+// longSummarizer holds the state of one SummarizeLong call.
+type longSummarizer struct {
+	llm       gollm.LLM
+	length    textsplit.LengthFunc
+	completed map[summaryRound]int
+	cfg       summarizeLongConfig
+	usage     providers.Usage
+	mu        sync.Mutex
+	calls     int
+}
+
+// mapReduce summarizes texts concurrently and combines the summaries until
+// they fit in one call.
+func (s *longSummarizer) mapReduce(ctx context.Context, texts []string) (string, error) {
+	summaries, err := s.generateAll(ctx, summaryStageMap, 0, summarizeChunkTemplate, texts)
+	if err != nil {
+		return "", err
+	}
+
+	for level := 1; len(summaries) > 1 && s.length(strings.Join(summaries, summarySeparator)) > s.cfg.chunkTokens; level++ {
+		if level > maxSummaryCombineLevels {
+			return "", fmt.Errorf("summaries did not fit in %d tokens after %d combine rounds",
+				s.cfg.chunkTokens, maxSummaryCombineLevels)
+		}
+		summaries, err = s.generateAll(ctx, summaryStageCombine, level, combineSummariesTemplate, s.group(summaries))
+		if err != nil {
+			return "", err
+		}
+	}
+
+	return s.generate(ctx, summaryStageFinal, 0, 1, combineSummariesTemplate,
+		map[string]any{"Text": strings.Join(summaries, summarySeparator)}, s.cfg.promptOptions)
+}
+
+// group joins consecutive summaries into groups within the token budget. A
+// group holds at least two summaries so that every round reduces their number.
+func (s *longSummarizer) group(summaries []string) []string {
+	var groups []string
+	var current []string
+	for _, summary := range summaries {
+		if len(current) >= 2 &&
+			s.length(strings.Join(append(current, summary), summarySeparator)) > s.cfg.chunkTokens {
+			groups = append(groups, strings.Join(current, summarySeparator))
+			current = nil
+		}
+		current = append(current, summary)
+	}
+	return append(groups, strings.Join(current, summarySeparator))
+}
+
+// refine summarizes the first text and refines the summary with each
+// following text.
+func (s *longSummarizer) refine(ctx context.Context, texts []string) (string, error) {
+	summary, err := s.generate(ctx, summaryStageRefine, 0, len(texts), summarizeChunkTemplate,
+		map[string]any{"Text": texts[0]}, s.cfg.promptOptions)
+	if err != nil {
+		return "", err
+	}
+	for _, text := range texts[1:] {
+		summary, err = s.generate(ctx, summaryStageRefine, 0, len(texts), refineSummaryTemplate,
+			map[string]any{"Summary": summary, "Text": text}, s.cfg.promptOptions)
+		if err != nil {
+			return "", err
+		}
+	}
+	return summary, nil
+}
+
+// generateAll runs template on each text with at most the configured number
+// of concurrent calls. The first error cancels the remaining calls.
+func (s *longSummarizer) generateAll(
+	ctx context.Context,
+	stage string,
+	level int,
+	template *gollm.PromptTemplate,
+	texts []string,
+) ([]string, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	results := make([]string, len(texts))
+	errs := runConcurrently(ctx, len(texts), s.cfg.concurrency, func(i int) error {
+		var err error
+		results[i], err = s.generate(ctx, stage, level, len(texts), template,
+			map[string]any{"Text": texts[i]}, nil)
+		if err != nil {
+			cancel()
+		}
+		return err
+	})
+
+	for _, err := range errs {
+		if err != nil && !errors.Is(err, context.Canceled) {
+			return nil, err
+		}
+	}
+	if err := errors.Join(errs...); err != nil {
+		return nil, err
+	}
+	return results, nil
+}
+
+// generate executes template with data, generates a response and records its
+// usage and progress.
+func (s *longSummarizer) generate(
+	ctx context.Context,
+	stage string,
+	level, total int,
+	template *gollm.PromptTemplate,
+	data map[string]any,
+	opts []gollm.PromptOption,
+) (string, error) {
+	prompt, err := template.Execute(data)
+	if err != nil {
+		return "", fmt.Errorf("failed to execute %s template: %w", template.Name, err)
+	}
+	prompt.Apply(opts...)
+
+	response, err := s.llm.Generate(ctx, prompt)
+	if err != nil {
+		return "", fmt.Errorf("failed to generate %s summary: %w", stage, err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.calls++
+	addUsage(&s.usage, response.Usage)
+	round := summaryRound{stage: stage, level: level}
+	s.completed[round]++
+	if s.cfg.progress != nil {
+		s.cfg.progress(SummaryProgress{Stage: stage, Level: level, Completed: s.completed[round], Total: total})
+	}
+	return strings.TrimSpace(response.AsText()), nil
+}
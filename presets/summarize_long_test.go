@@ -0,0 +1,136 @@
+package presets
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/weave-labs/gollm/gollmtest"
+)
+
+// words repeats word n times. "cat" and "dog" count as one token each.
+func words(word string, n int) string {
+	return strings.TrimSpace(strings.Repeat(word+" ", n))
+}
+
+func TestSummarizeLongMapReduce(t *testing.T) {
+	fake := gollmtest.NewProvider()
+	for range 4 {
+		fake.Reply(words("dog", 30)).WithUsage(100, 30)
+	}
+	fake.Reply(words("dog", 30)).WithUsage(70, 30)
+	fake.Reply(words("dog", 30)).WithUsage(70, 30)
+	fake.Reply(words("dog", 30)).WithUsage(70, 30)
+	fake.Reply("The final summary.").WithUsage(40, 5)
+
+	paragraph := words("cat", 40)
+	text := strings.Join([]string{paragraph, paragraph, paragraph, paragraph}, "\n\n")
+
+	var progress []SummaryProgress
+	summary, err := SummarizeLong(context.Background(), gollmtest.NewLLM(t, fake), text,
+		WithSummaryChunkTokens(50),
+		WithSummaryOverlap(0),
+		WithSummaryProgress(func(p SummaryProgress) { progress = append(progress, p) }),
+	)
+	require.NoError(t, err)
+
+	assert.Equal(t, "The final summary.", summary.Summary)
+	assert.Equal(t, 4, summary.Chunks)
+	assert.Equal(t, 8, summary.Calls, "4 map calls, combine rounds of 2 and 1 calls, and the final call")
+	assert.Equal(t, int64(4*100+3*70+40), summary.Usage.InputTokens)
+	assert.Equal(t, int64(7*30+5), summary.Usage.OutputTokens)
+	assert.Equal(t, summary.Usage.InputTokens+summary.Usage.OutputTokens, summary.Usage.TotalTokens)
+	assert.Zero(t, fake.Remaining())
+
+	assert.Equal(t, []SummaryProgress{
+		{Stage: "map", Completed: 1, Total: 4},
+		{Stage: "map", Completed: 2, Total: 4},
+		{Stage: "map", Completed: 3, Total: 4},
+		{Stage: "map", Completed: 4, Total: 4},
+		{Stage: "combine", Level: 1, Completed: 1, Total: 2},
+		{Stage: "combine", Level: 1, Completed: 2, Total: 2},
+		{Stage: "combine", Level: 2, Completed: 1, Total: 1},
+		{Stage: "final", Completed: 1, Total: 1},
+	}, progress)
+
+	calls := fake.Calls()
+	assert.Contains(t, calls[4].Request.Messages[0].Content, words("dog", 30)+summarySeparator+words("dog", 30),
+		"the first combine call joins two map summaries")
+}
+
+func TestSummarizeLongSingleChunk(t *testing.T) {
+	fake := gollmtest.NewProvider()
+	fake.Reply("  A short summary.\n")
+
+	summary, err := SummarizeLong(context.Background(), gollmtest.NewLLM(t, fake), "A short text.")
+	require.NoError(t, err)
+	assert.Equal(t, "A short summary.", summary.Summary)
+	assert.Equal(t, 1, summary.Chunks)
+	assert.Equal(t, 1, summary.Calls)
+	assert.Zero(t, summary.Usage, "usage is zero when the provider reports none")
+}
+
+func TestSummaryGroup(t *testing.T) {
+	s := &longSummarizer{
+		cfg:    summarizeLongConfig{chunkTokens: 30},
+		length: func(text string) int { return len(text) },
+	}
+
+	assert.Equal(t, []string{"aa" + summarySeparator + "bb" + summarySeparator + "cc"},
+		s.group([]string{"aa", "bb", "cc"}), "summaries within the budget form one group")
+
+	s.cfg.chunkTokens = 3
+	assert.Equal(t, []string{
+		"aaaa" + summarySeparator + "bbbb",
+		"cccc" + summarySeparator + "dddd",
+		"eeee",
+	}, s.group([]string{"aaaa", "bbbb", "cccc", "dddd", "eeee"}),
+		"groups over the budget still hold at least two summaries")
+}
+
+func TestSummarizeLongCombineLevelsExceeded(t *testing.T) {
+	// Every round halves the summaries, so 1025 of them need 11 rounds.
+	const texts = 1025
+	fake := gollmtest.NewProvider()
+	for range 3 * texts {
+		fake.Reply("summary")
+	}
+
+	s := &longSummarizer{
+		cfg:       summarizeLongConfig{chunkTokens: 10, concurrency: 64},
+		llm:       gollmtest.NewLLM(t, fake),
+		length:    func(string) int { return 11 },
+		completed: make(map[summaryRound]int),
+	}
+	_, err := s.mapReduce(context.Background(), make([]string, texts))
+	require.ErrorContains(t, err, "summaries did not fit in 10 tokens after 10 combine rounds")
+	assert.Zero(t, s.completed[summaryRound{stage: summaryStageFinal}], "no final summary is written")
+}
+
+func TestSummarizeLongCancelsOnFirstError(t *testing.T) {
+	fake := gollmtest.NewProvider()
+	fake.ReplyError(errors.New("connection reset"))
+	for range 3 {
+		fake.Reply("summary").WithLatency(time.Minute)
+	}
+
+	s := &longSummarizer{
+		cfg:       summarizeLongConfig{concurrency: 4},
+		llm:       gollmtest.NewLLM(t, fake),
+		completed: make(map[summaryRound]int),
+	}
+
+	start := time.Now()
+	_, err := s.generateAll(context.Background(), summaryStageMap, 0, summarizeChunkTemplate,
+		[]string{"one", "two", "three", "four"})
+	require.Error(t, err)
+	assert.ErrorContains(t, err, "connection reset", "the first error is returned, not the cancellations")
+	assert.NotErrorIs(t, err, context.Canceled)
+	assert.Less(t, time.Since(start), 10*time.Second, "the pending calls are canceled")
+	assert.Zero(t, s.calls)
+}
@@ -0,0 +1,17 @@
+package presets
+
+import "github.com/weave-labs/gollm/providers"
+
+// addUsage adds usage to total. A nil usage, from a provider that does not
+// report it, adds nothing.
+func addUsage(total, usage *providers.Usage) {
+	if usage == nil {
+		return
+	}
+	total.InputTokens += usage.InputTokens
+	total.CachedInputTokens += usage.CachedInputTokens
+	total.OutputTokens += usage.OutputTokens
+	total.CachedOutputTokens += usage.CachedOutputTokens
+	total.ReasoningTokens += usage.ReasoningTokens
+	total.TotalTokens += usage.TotalTokens
+}
@@ -6,6 +6,8 @@
 package gollm
 
 import (
+	"context"
+
 	"github.com/weave-labs/gollm/llm"
 	"github.com/weave-labs/gollm/providers"
 )
@@ -42,10 +44,109 @@ type (
 	// Templates can include variables that are filled in at runtime.
 	PromptTemplate = llm.PromptTemplate
 
+	// TemplateLibrary is a set of versioned prompt templates loaded from files.
+	TemplateLibrary = llm.TemplateLibrary
+
+	// TemplateVariable declares a variable used by a PromptTemplate.
+	TemplateVariable = llm.TemplateVariable
+
+	// TemplateModel is the model configuration a template is written for.
+	TemplateModel = llm.TemplateModel
+
 	// Response represents the output from an LLM after processing a prompt.
 	Response = providers.Response
+
+	// ResponseMetadata describes how a response was produced: IDs, the resolved
+	// model, the request ID, latency and optionally the raw provider body.
+	ResponseMetadata = providers.ResponseMetadata
+
+	// FinishReason is the normalized reason a model stopped generating.
+	FinishReason = providers.FinishReason
+
+	// StructuredOutputError lists every failed attempt of GenerateTyped.
+	StructuredOutputError = llm.StructuredOutputError
+
+	// StructuredAttempt is one failed attempt recorded in a StructuredOutputError.
+	StructuredAttempt = llm.StructuredAttempt
+
+	// StructuredOutputStrategy describes how a structured response is requested from a model.
+	StructuredOutputStrategy = providers.StructuredOutputStrategy
+
+	// SchemaWarning describes a lossy change made to a response schema for a provider.
+	SchemaWarning = providers.SchemaWarning
+
+	// SchemaTransform rewrites a response schema for a provider.
+	SchemaTransform = providers.SchemaTransform
+
+	// PromptRenderer turns every field of a Prompt into a system prompt and messages.
+	PromptRenderer = llm.PromptRenderer
+
+	// PromptRendererFunc adapts a function to the PromptRenderer interface.
+	PromptRendererFunc = llm.PromptRendererFunc
+
+	// RenderedPrompt is a Prompt reduced to a system prompt and messages.
+	RenderedPrompt = llm.RenderedPrompt
+
+	// DefaultPromptRenderer is the renderer used when none is configured.
+	DefaultPromptRenderer = llm.DefaultPromptRenderer
+
+	// PromptPlacement selects where a rendered part of a Prompt is sent.
+	PromptPlacement = llm.PromptPlacement
+
+	// TokenCounter counts the input tokens of a request.
+	TokenCounter = providers.TokenCounter
+
+	// ModelLimits are the context window and maximum output tokens of a model.
+	ModelLimits = providers.ModelLimits
+
+	// ContextOverflowPolicy selects what happens when a request does not fit the context window.
+	ContextOverflowPolicy = llm.ContextOverflowPolicy
+)
+
+// Context overflow policies for WithContextOverflow.
+const (
+	ContextOverflowError    = llm.ContextOverflowError    // Fail before sending the request
+	ContextOverflowTruncate = llm.ContextOverflowTruncate // Drop the oldest messages
+	ContextOverflowIgnore   = llm.ContextOverflowIgnore   // Send the request unchecked
+)
+
+// ErrContextWindowExceeded is returned when a request does not fit the model's context window.
+var ErrContextWindowExceeded = llm.ErrContextWindowExceeded
+
+// Prompt placements for DefaultPromptRenderer.
+const (
+	PlacementSystem  = llm.PlacementSystem  // Appended to the system prompt
+	PlacementUser    = llm.PlacementUser    // Added to the last user message
+	PlacementFewShot = llm.PlacementFewShot // Example input/output message pairs
+)
+
+// Finish reason constants are the normalized values of Response.FinishReason.
+const (
+	FinishReasonStop          = providers.FinishReasonStop          // Natural stop or stop sequence
+	FinishReasonLength        = providers.FinishReasonLength        // Truncated by the token limit
+	FinishReasonToolCalls     = providers.FinishReasonToolCalls     // Stopped to call tools
+	FinishReasonContentFilter = providers.FinishReasonContentFilter // Blocked by a safety filter
+	FinishReasonError         = providers.FinishReasonError         // Aborted by a provider error
+	FinishReasonOther         = providers.FinishReasonOther         // Any other provider reason
+)
+
+// Structured output strategies, selected per model from its registered capability.
+const (
+	StructuredOutputNative   = providers.StructuredOutputNative   // Provider JSON schema response format
+	StructuredOutputJSONMode = providers.StructuredOutputJSONMode // JSON mode with the schema in the prompt
+	StructuredOutputToolCall = providers.StructuredOutputToolCall // Forced call to a single schema tool
+	StructuredOutputPrompt   = providers.StructuredOutputPrompt   // Prompt instructions and JSON extraction
 )
 
+// ErrTemplateNotFound is returned when a TemplateLibrary has no template with the requested name or version.
+var ErrTemplateNotFound = llm.ErrTemplateNotFound
+
+// ErrSchemaTooDeep is returned when a response schema is nested deeper than the model supports.
+var ErrSchemaTooDeep = providers.ErrSchemaTooDeep
+
+// TransformSchema applies schema transforms to a copy of a response schema.
+var TransformSchema = providers.TransformSchema
+
 // Cache type constants define the available caching strategies.
 const (
 	// CacheTypeEphemeral indicates that cached responses should only persist
@@ -100,6 +201,30 @@ var (
 
 	// WithPromptOptions adds multiple prompt options at once.
 	WithPromptOptions = llm.WithPromptOptions
+
+	// ParsePromptTemplate parses a template file with optional YAML front matter.
+	ParsePromptTemplate = llm.ParsePromptTemplate
+
+	// LoadTemplates loads the templates and partials in a file system, such as an embed.FS.
+	LoadTemplates = llm.LoadTemplates
+
+	// LoadTemplateDir loads the templates and partials in a directory.
+	LoadTemplateDir = llm.LoadTemplateDir
+
+	// WithRepairAttempts sets how often GenerateTyped re-prompts after invalid output.
+	WithRepairAttempts = llm.WithRepairAttempts
+
+	// WithProviderOption sets a provider option, such as "temperature", for a single request.
+	WithProviderOption = llm.WithProviderOption
+
+	// WithPromptRenderer sets the PromptRenderer used for a single request.
+	WithPromptRenderer = llm.WithPromptRenderer
+
+	// WithContextOverflow sets what happens when a request does not fit the context window.
+	WithContextOverflow = llm.WithContextOverflow
+
+	// NewEstimatingTokenCounter creates a calibrated character-based token counter.
+	NewEstimatingTokenCounter = providers.NewEstimatingTokenCounter
 )
 
 // WithStructuredResponseSchema re-exports the llm generic option while preserving the type parameter.
@@ -107,6 +232,35 @@ func WithStructuredResponseSchema[T any]() llm.GenerateOption {
 	return llm.WithStructuredResponse[T]()
 }
 
+// GenerateTyped re-exports the llm generic helper that generates a structured
+// response, decodes it into a T and repairs invalid output by re-prompting.
+func GenerateTyped[T any](
+	ctx context.Context,
+	l LLM,
+	prompt *Prompt,
+	opts ...llm.GenerateOption,
+) (*T, *Response, error) {
+	return llm.GenerateTyped[T](ctx, l, prompt, opts...)
+}
+
+// TypedTemplate is a PromptTemplate rendered from an In struct whose response is decoded into an Out.
+type TypedTemplate[In, Out any] = llm.TypedTemplate[In, Out]
+
+// NewTypedTemplate re-exports the llm generic constructor that checks the
+// template's fields against In.
+func NewTypedTemplate[In, Out any](
+	name, description, tmpl string,
+	opts ...llm.PromptTemplateOption,
+) (*TypedTemplate[In, Out], error) {
+	return llm.NewTypedTemplate[In, Out](name, description, tmpl, opts...)
+}
+
+// BindTypedTemplate re-exports the llm generic helper that binds an existing
+// PromptTemplate, such as one from a TemplateLibrary, to In and Out.
+func BindTypedTemplate[In, Out any](pt *PromptTemplate) (*TypedTemplate[In, Out], error) {
+	return llm.BindTypedTemplate[In, Out](pt)
+}
+
 // WithStructuredResponseJSON Provides a way to specify a JSON schema directly for structured responses.
 func WithStructuredResponseJSON(json []byte) llm.GenerateOption {
 	return llm.WithStructuredResponseJSON(json)
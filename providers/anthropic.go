@@ -92,10 +92,29 @@ func (p *AnthropicProvider) Name() string {
 	return "anthropic"
 }
 
+// anthropicModelLimits are the token limits of known Anthropic models, by model name prefix.
+var anthropicModelLimits = map[string]ModelLimits{
+	"claude-opus-4":      {ContextWindow: 200000, MaxOutputTokens: 32000},
+	"claude-sonnet-4":    {ContextWindow: 200000, MaxOutputTokens: 64000},
+	"claude-3-7-sonnet":  {ContextWindow: 200000, MaxOutputTokens: 64000},
+	"claude-3-5-sonnet":  {ContextWindow: 200000, MaxOutputTokens: 8192},
+	"claude-3-5-haiku":   {ContextWindow: 200000, MaxOutputTokens: 8192},
+	"claude-3-opus":      {ContextWindow: 200000, MaxOutputTokens: 4096},
+	"claude-3-sonnet":    {ContextWindow: 200000, MaxOutputTokens: 4096},
+	"claude-3-haiku":     {ContextWindow: 200000, MaxOutputTokens: 4096},
+	"claude-2.1":         {ContextWindow: 200000, MaxOutputTokens: 4096},
+	"claude-2.0":         {ContextWindow: 100000, MaxOutputTokens: 4096},
+	"claude-instant-1.2": {ContextWindow: 100000, MaxOutputTokens: 4096},
+}
+
 // registerCapabilities registers capabilities for all known Anthropic models
 func (p *AnthropicProvider) registerCapabilities() {
 	registry := GetCapabilityRegistry()
 
+	for model, limits := range anthropicModelLimits {
+		registry.RegisterLimits(ProviderAnthropic, model, limits)
+	}
+
 	// Define all known Anthropic Claude models
 	allModels := []string{
 		// Claude 3.5 models
@@ -280,17 +299,23 @@ func (p *AnthropicProvider) PrepareRequest(req *Request, options map[string]any)
 
 	requestBody := p.initializeRequestBodyWithModel(model)
 
+	strategy, err := resolveStructuredOutput(ProviderAnthropic, model, req, StructuredOutputToolCall)
+	if err != nil {
+		return nil, err
+	}
+
 	systemPrompt := p.extractSystemPromptFromRequest(req, options)
 	systemPrompt = p.handleToolsForRequest(requestBody, systemPrompt, options)
+	systemPrompt, err = structuredOutputSystemPrompt(systemPrompt, req.ResponseSchema, strategy)
+	if err != nil {
+		return nil, fmt.Errorf("failed to add structured response: %w", err)
+	}
 	p.addSystemPromptToRequestBody(requestBody, systemPrompt)
 
 	p.addMessagesToRequestBody(requestBody, req.Messages, options)
 
-	if req.ResponseSchema != nil && p.HasCapability(llmx.CapabilityType_CAPABILITY_TYPE_STRUCTURED_RESPONSE, model) {
-		err := p.addStructuredResponseToRequest(requestBody, req.ResponseSchema)
-		if err != nil {
-			return nil, fmt.Errorf("failed to add structured response: %w", err)
-		}
+	if strategy == StructuredOutputToolCall {
+		p.addStructuredOutputToolToRequest(requestBody, req.ResponseSchema)
 	}
 
 	p.addRemainingOptions(requestBody, options)
@@ -302,6 +327,57 @@ func (p *AnthropicProvider) PrepareRequest(req *Request, options map[string]any)
 	return data, nil
 }
 
+// anthropicTokenCountKeys are the request fields accepted by the count tokens endpoint.
+var anthropicTokenCountKeys = []string{"model", "system", "messages", anthropicKeyTools, anthropicKeyToolChoice, "thinking"}
+
+// PrepareTokenCountRequest implements TokenCountingProvider with the messages
+// count tokens endpoint, which takes the request without generation options.
+func (p *AnthropicProvider) PrepareTokenCountRequest(req *Request, options map[string]any) (string, []byte, error) {
+	data, err := p.PrepareRequest(req, options)
+	if err != nil {
+		return "", nil, err
+	}
+	var requestBody map[string]any
+	if err := json.Unmarshal(data, &requestBody); err != nil {
+		return "", nil, fmt.Errorf("failed to parse request body: %w", err)
+	}
+	countBody := make(map[string]any, len(anthropicTokenCountKeys))
+	for _, key := range anthropicTokenCountKeys {
+		if value, ok := requestBody[key]; ok {
+			countBody[key] = value
+		}
+	}
+	if system, ok := countBody["system"].([]any); ok && len(system) == 0 {
+		delete(countBody, "system")
+	}
+
+	data, err = json.Marshal(countBody)
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to marshal request body: %w", err)
+	}
+	return p.Endpoint() + "/count_tokens", data, nil
+}
+
+// ParseTokenCountResponse implements TokenCountingProvider.
+func (p *AnthropicProvider) ParseTokenCountResponse(body []byte) (int, error) {
+	var response struct {
+		InputTokens *int `json:"input_tokens"`
+	}
+	if err := json.Unmarshal(body, &response); err != nil {
+		return 0, fmt.Errorf("failed to parse token count response: %w", err)
+	}
+	if response.InputTokens == nil {
+		return 0, errors.New("token count response has no input_tokens")
+	}
+	return *response.InputTokens, nil
+}
+
+// MessageRules implements MessageRulesProvider. Claude conversations start with
+// a user turn and alternate between the user and the assistant.
+func (p *AnthropicProvider) MessageRules(_ string) MessageRules {
+	return MessageRules{FirstUser: true, Alternate: true}
+}
+
 // PrepareStreamRequest creates a request body for streaming API calls
 func (p *AnthropicProvider) PrepareStreamRequest(req *Request, options map[string]any) ([]byte, error) {
 	// Determine which model to use
@@ -318,19 +394,22 @@ func (p *AnthropicProvider) PrepareStreamRequest(req *Request, options map[strin
 	requestBody := p.initializeRequestBodyWithModel(model)
 	requestBody[anthropicKeyStream] = true
 
+	// Tool arguments are not streamed as text, so streams describe the schema in the prompt.
+	strategy, err := resolveStructuredOutput(ProviderAnthropic, model, req)
+	if err != nil {
+		return nil, err
+	}
+
 	systemPrompt := p.extractSystemPromptFromRequest(req, options)
 	systemPrompt = p.handleToolsForRequest(requestBody, systemPrompt, options)
+	systemPrompt, err = structuredOutputSystemPrompt(systemPrompt, req.ResponseSchema, strategy)
+	if err != nil {
+		return nil, fmt.Errorf("failed to add structured response: %w", err)
+	}
 	p.addSystemPromptToRequestBody(requestBody, systemPrompt)
 
 	p.addMessagesToRequestBody(requestBody, req.Messages, options)
 
-	if req.ResponseSchema != nil && p.HasCapability(llmx.CapabilityType_CAPABILITY_TYPE_STRUCTURED_RESPONSE, model) {
-		err := p.addStructuredResponseToRequest(requestBody, req.ResponseSchema)
-		if err != nil {
-			return nil, fmt.Errorf("failed to add structured response: %w", err)
-		}
-	}
-
 	p.addRemainingOptions(requestBody, options)
 
 	data, err := json.Marshal(requestBody)
@@ -357,10 +436,19 @@ func (p *AnthropicProvider) ParseResponse(body []byte) (*Response, error) {
 	p.logger.Debug("Number of content blocks: %d", len(anthropicResponse.Content))
 	p.logger.Debug("Stop reason: %s", anthropicResponse.StopReason)
 
-	// Process content blocks
-	result, err := p.processAnthropicContent(anthropicResponse.Content)
-	if err != nil {
-		return nil, err
+	finishReason := NormalizeFinishReason(anthropicResponse.StopReason)
+
+	// Process content blocks. A structured output tool call is the response itself.
+	var result string
+	if input, ok := structuredOutputToolInput(anthropicResponse.Content); ok {
+		result = string(input)
+		finishReason = FinishReasonStop
+	} else {
+		var err error
+		result, err = p.processAnthropicContent(anthropicResponse.Content)
+		if err != nil {
+			return nil, err
+		}
 	}
 
 	p.logger.Debug("Final anthropicResponse: %s", result)
@@ -374,13 +462,16 @@ func (p *AnthropicProvider) ParseResponse(body []byte) (*Response, error) {
 			0,
 			anthropicResponse.Usage.CacheReadInputTokens,
 		),
+		FinishReason: finishReason,
+		Metadata:     newMetadata(anthropicResponse.ID, anthropicResponse.Model, "", anthropicResponse.StopReason),
 	}
 
 	return response, nil
 }
 
 // ParseStreamResponse processes single SSE JSON "data:" payload from Anthropic Messages streaming.
-// It returns either a text Content token, a Usage-only token, io.EOF for message_stop, or "skip token".
+// It returns either a text Content token, a tool-call fragment, a Usage-only token, io.EOF for
+// message_stop, or "skip token".
 func (p *AnthropicProvider) ParseStreamResponse(chunk []byte) (*Response, error) {
 	// Skip empty lines
 	if len(bytes.TrimSpace(chunk)) == 0 {
@@ -397,49 +488,77 @@ func (p *AnthropicProvider) ParseStreamResponse(chunk []byte) (*Response, error)
 	}
 
 	switch ev.Type {
+	case "content_block_start":
+		// A tool_use block starts a tool call; its input follows as input_json_delta
+		if ev.ContentBlock != nil && ev.ContentBlock.Type == "tool_use" {
+			return &Response{ToolCalls: []ToolCall{{
+				ID:       ev.ContentBlock.ID,
+				Type:     "function",
+				Function: FunctionCall{Name: ev.ContentBlock.Name},
+			}}}, nil
+		}
+		return nil, errors.New("skip token")
+
 	case "content_block_delta":
-		// Only emit text deltas as tokens
+		// Emit text deltas as tokens and tool input deltas as tool-call fragments
 		if ev.Delta != nil && ev.Delta.Type == "text_delta" && ev.Delta.Text != "" {
 			return &Response{
 				Content: Text{Value: ev.Delta.Text},
 			}, nil
 		}
+		if ev.Delta != nil && ev.Delta.Type == "input_json_delta" && ev.Delta.PartialJSON != "" {
+			return &Response{ToolCalls: []ToolCall{{
+				Function: FunctionCall{Arguments: json.RawMessage(ev.Delta.PartialJSON)},
+			}}}, nil
+		}
 		return nil, errors.New("skip token")
 
 	case "message_start":
-		// Usage may be present on the embedded message
-		if ev.Message != nil && ev.Message.Usage != nil {
-			return &Response{
-				Usage: NewUsage(
-					ev.Message.Usage.InputTokens,
-					ev.Message.Usage.CacheCreationInputTokens,
-					ev.Message.Usage.OutputTokens,
-					0,
-					ev.Message.Usage.CacheReadInputTokens,
-				),
-			}, nil
+		// The embedded message carries the response ID, model and possibly usage
+		if ev.Message == nil {
+			return nil, errors.New("skip token")
 		}
-		return nil, errors.New("skip token")
+		response := &Response{Metadata: newMetadata(ev.Message.ID, ev.Message.Model, "", "")}
+		if ev.Message.Usage != nil {
+			response.Usage = NewUsage(
+				ev.Message.Usage.InputTokens,
+				ev.Message.Usage.CacheCreationInputTokens,
+				ev.Message.Usage.OutputTokens,
+				0,
+				ev.Message.Usage.CacheReadInputTokens,
+			)
+		}
+		if response.Usage == nil && response.Metadata == nil {
+			return nil, errors.New("skip token")
+		}
+		return response, nil
 
 	case "message_delta":
-		// Usage may be present at the top level; counts are cumulative
+		// Usage may be present at the top level; counts are cumulative.
+		// The delta carries the stop reason.
+		response := &Response{}
 		if ev.Usage != nil {
-			return &Response{
-				Usage: NewUsage(
-					ev.Usage.InputTokens,
-					ev.Usage.CacheCreationInputTokens,
-					ev.Usage.OutputTokens,
-					0,
-					ev.Usage.CacheReadInputTokens,
-				),
-			}, nil
+			response.Usage = NewUsage(
+				ev.Usage.InputTokens,
+				ev.Usage.CacheCreationInputTokens,
+				ev.Usage.OutputTokens,
+				0,
+				ev.Usage.CacheReadInputTokens,
+			)
 		}
-		return nil, errors.New("skip token")
+		if ev.Delta != nil && ev.Delta.StopReason != nil && *ev.Delta.StopReason != "" {
+			response.FinishReason = NormalizeFinishReason(*ev.Delta.StopReason)
+			response.Metadata = newMetadata("", "", "", *ev.Delta.StopReason)
+		}
+		if response.Usage == nil && response.FinishReason == "" {
+			return nil, errors.New("skip token")
+		}
+		return response, nil
 
 	case "message_stop":
 		return nil, io.EOF
 
-	// Ignore pings, starts/stops of blocks, tool JSON partials, thinking/signature, etc.
+	// Ignore pings, stops of blocks, thinking/signature, etc.
 	default:
 		return nil, errors.New("skip token")
 	}
@@ -507,36 +626,19 @@ func (p *AnthropicProvider) addSystemPromptToRequestBody(requestBody map[string]
 	}
 }
 
-// addStructuredResponseToRequest adds structured response schema to the request
-func (p *AnthropicProvider) addStructuredResponseToRequest(requestBody map[string]any, schema any) error {
-	schemaJSON, err := json.MarshalIndent(schema, "", "  ")
-	if err != nil {
-		return fmt.Errorf("failed to marshal schema: %w", err)
-	}
-
-	// For Anthropic, we add the schema to the system prompt
-	systemInstruction := fmt.Sprintf(
-		"You must respond with a JSON object that strictly adheres to this schema:\n%s\nDo not include any explanatory text, only output valid JSON.",
-		string(schemaJSON),
-	)
-
-	// Append to existing system prompt if present
-	if existing, ok := requestBody["system"].([]map[string]any); ok && len(existing) > 0 {
-		existing = append(existing, map[string]any{
-			"type": "text",
-			"text": systemInstruction,
-		})
-		requestBody["system"] = existing
-	} else {
-		requestBody["system"] = []map[string]any{
-			{
-				"type": "text",
-				"text": systemInstruction,
-			},
-		}
+// addStructuredOutputToolToRequest adds a tool whose input is the response schema
+// and forces the model to call it. Any other tools remain declared but unused.
+func (p *AnthropicProvider) addStructuredOutputToolToRequest(requestBody map[string]any, schema any) {
+	tools, _ := requestBody[anthropicKeyTools].([]map[string]any)
+	requestBody[anthropicKeyTools] = append(tools, map[string]any{
+		"name":         StructuredOutputToolName,
+		"description":  structuredOutputToolDescription,
+		"input_schema": schema,
+	})
+	requestBody[anthropicKeyToolChoice] = map[string]any{
+		"type": "tool",
+		"name": StructuredOutputToolName,
 	}
-
-	return nil
 }
 
 // addMessagesToRequestBody converts and adds messages to the request
@@ -749,6 +851,17 @@ func (p *AnthropicProvider) processAnthropicContent(contents []anthropicContent)
 	return finalResponse.String(), nil
 }
 
+// structuredOutputToolInput returns the input of the structured output tool call
+// among contents, if there is one.
+func structuredOutputToolInput(contents []anthropicContent) (json.RawMessage, bool) {
+	for _, content := range contents {
+		if content.Type == "tool_use" && content.Name == StructuredOutputToolName {
+			return content.Input, true
+		}
+	}
+	return nil, false
+}
+
 // processTextContent handles text content blocks
 func (p *AnthropicProvider) processTextContent(pendingText *strings.Builder, text string, lastType string) {
 	// If we have pending text and this is also text, add a space
@@ -810,11 +923,12 @@ type anthropicContent struct {
 }
 
 type anthropicEvent struct {
-	Index   *int              `json:"index,omitempty"`
-	Delta   *anthropicDelta   `json:"delta,omitempty"`
-	Usage   *anthropicUsage   `json:"usage,omitempty"`
-	Message *anthropicMessage `json:"message,omitempty"`
-	Type    string            `json:"type"`
+	Index        *int              `json:"index,omitempty"`
+	Delta        *anthropicDelta   `json:"delta,omitempty"`
+	Usage        *anthropicUsage   `json:"usage,omitempty"`
+	Message      *anthropicMessage `json:"message,omitempty"`
+	ContentBlock *anthropicContent `json:"content_block,omitempty"`
+	Type         string            `json:"type"`
 }
 
 type anthropicMessage struct {
@@ -3,6 +3,7 @@ package providers
 import (
 	"errors"
 	"fmt"
+	"strings"
 	"sync"
 
 	"github.com/puzpuzpuz/xsync/v4"
@@ -18,6 +19,15 @@ var (
 // CapabilityRegistry manages capabilities for all providers and models.
 type CapabilityRegistry struct {
 	models *xsync.Map[string, ModelCapabilities] // key: "provider:model"
+	limits *xsync.Map[string, ModelLimits]       // key: "provider:model"
+}
+
+// ModelLimits are the token limits of a model. Zero means unknown.
+type ModelLimits struct {
+	// ContextWindow is the maximum number of input and output tokens of a request.
+	ContextWindow int
+	// MaxOutputTokens is the maximum number of tokens the model generates.
+	MaxOutputTokens int
 }
 
 // GetCapabilityRegistry returns the singleton global capability registry.
@@ -25,6 +35,7 @@ func GetCapabilityRegistry() *CapabilityRegistry {
 	registryOnce.Do(func() {
 		registry = &CapabilityRegistry{
 			models: xsync.NewMap[string, ModelCapabilities](),
+			limits: xsync.NewMap[string, ModelLimits](),
 		}
 	})
 
@@ -61,9 +72,36 @@ func (r *CapabilityRegistry) GetConfig(provider string, model string, capType ll
 	return modelCaps.GetCapability(capType)
 }
 
-// Clear removes all registered capabilities (mainly for testing).
+// RegisterLimits registers the token limits of a model. The limits also apply
+// to models whose name starts with model, such as dated snapshots, unless a
+// longer registered name matches.
+func (r *CapabilityRegistry) RegisterLimits(provider string, model string, limits ModelLimits) {
+	r.limits.Store(makeSlug(provider, model), limits)
+}
+
+// GetLimits returns the token limits of a model, using the longest registered
+// model name that is a prefix of model when there is no exact match.
+func (r *CapabilityRegistry) GetLimits(provider string, model string) (ModelLimits, bool) {
+	slug := makeSlug(provider, model)
+	if limits, ok := r.limits.Load(slug); ok {
+		return limits, true
+	}
+
+	var best ModelLimits
+	bestLen := 0
+	r.limits.Range(func(key string, limits ModelLimits) bool {
+		if len(key) > bestLen && strings.HasPrefix(slug, key) {
+			best, bestLen = limits, len(key)
+		}
+		return true
+	})
+	return best, bestLen > 0
+}
+
+// Clear removes all registered capabilities and limits (mainly for testing).
 func (r *CapabilityRegistry) Clear() {
 	r.models.Clear()
+	r.limits.Clear()
 }
 
 // makeSlug creates a unique key for provider and model combination.
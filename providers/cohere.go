@@ -19,6 +19,8 @@ const (
 	cohereKeyMessages       = "messages"
 	cohereKeyResponseFormat = "response_format"
 	cohereKeyStream         = "stream"
+	cohereKeyTools          = "tools"
+	cohereKeyToolChoice     = "tool_choice"
 )
 
 // CohereProvider implements the Provider interface for Cohere's API.
@@ -57,10 +59,22 @@ func (p *CohereProvider) Name() string {
 	return "cohere"
 }
 
+// cohereModelLimits are the token limits of known Cohere models, by model name prefix.
+var cohereModelLimits = map[string]ModelLimits{
+	"command-a":      {ContextWindow: 256000, MaxOutputTokens: 8000},
+	"command-r-plus": {ContextWindow: 128000, MaxOutputTokens: 4000},
+	"command-r":      {ContextWindow: 128000, MaxOutputTokens: 4000},
+	"command":        {ContextWindow: 4096, MaxOutputTokens: 4000},
+}
+
 // registerCapabilities registers capabilities for all known Cohere models
 func (p *CohereProvider) registerCapabilities() {
 	registry := GetCapabilityRegistry()
 
+	for model, limits := range cohereModelLimits {
+		registry.RegisterLimits(ProviderCohere, model, limits)
+	}
+
 	// Define all known Cohere models
 	allModels := []string{
 		// Command A models
@@ -222,7 +236,7 @@ func (p *CohereProvider) SetDefaultOptions(cfg *config.Config) {
 func (p *CohereProvider) SetOption(key string, value any) {
 	p.options[key] = value
 	if p.logger != nil {
-		p.logger.Debug("Setting option for Cohere", "key", key, "value", value)
+		p.logger.Debug("Setting option for Cohere", key, value)
 	}
 }
 
@@ -242,22 +256,33 @@ func (p *CohereProvider) PrepareRequest(req *Request, options map[string]any) ([
 		model = m
 	}
 
+	strategy, err := resolveStructuredOutput(ProviderCohere, model, req,
+		StructuredOutputNative, StructuredOutputJSONMode, StructuredOutputToolCall)
+	if err != nil {
+		return nil, err
+	}
+
 	requestBody := p.initializeRequestBodyWithModel(model)
 
 	p.addMessagesToRequestBody(requestBody, req.Messages)
 
-	if req.SystemPrompt != "" {
-		requestBody[cohereKeyPreamble] = req.SystemPrompt
-	} else if systemPrompt, ok := options[cohereKeySystemPrompt].(string); ok && systemPrompt != "" {
-		requestBody[cohereKeyPreamble] = systemPrompt
+	systemPrompt := req.SystemPrompt
+	if systemPrompt == "" {
+		systemPrompt, _ = options[cohereKeySystemPrompt].(string)
 	}
-
-	if req.ResponseSchema != nil && p.HasCapability(llmx.CapabilityType_CAPABILITY_TYPE_STRUCTURED_RESPONSE, model) {
-		p.addStructuredResponseToRequest(requestBody, req.ResponseSchema)
+	systemPrompt, err = structuredOutputSystemPrompt(systemPrompt, req.ResponseSchema, strategy)
+	if err != nil {
+		return nil, err
+	}
+	if systemPrompt != "" {
+		requestBody[cohereKeyPreamble] = systemPrompt
 	}
 
 	p.addRemainingOptions(requestBody, options)
 
+	// Added last so that a forced tool call replaces any tools passed as options.
+	p.addStructuredResponseToRequest(requestBody, req.ResponseSchema, strategy)
+
 	data, err := json.Marshal(requestBody)
 	if err != nil {
 		return nil, fmt.Errorf("failed to marshal request body: %w", err)
@@ -269,7 +294,9 @@ func (p *CohereProvider) PrepareRequest(req *Request, options map[string]any) ([
 // It handles various response formats and error cases
 func (p *CohereProvider) ParseResponse(body []byte) (*Response, error) {
 	var response struct {
-		Message struct {
+		ID           string `json:"id"`
+		FinishReason string `json:"finish_reason"`
+		Message      struct {
 			Role    string `json:"role"`
 			Content []struct {
 				Type string `json:"type"`
@@ -290,6 +317,17 @@ func (p *CohereProvider) ParseResponse(body []byte) (*Response, error) {
 		return nil, fmt.Errorf("error parsing response: %w", err)
 	}
 
+	// A structured output tool call is the response itself.
+	for _, toolCall := range response.Message.ToolCalls {
+		if toolCall.Function.Name == StructuredOutputToolName {
+			return &Response{
+				Content:      Text{Value: toolCall.Function.Arguments},
+				FinishReason: FinishReasonStop,
+				Metadata:     newMetadata(response.ID, "", "", response.FinishReason),
+			}, nil
+		}
+	}
+
 	if len(response.Message.Content) == 0 {
 		return nil, errors.New("empty response from API")
 	}
@@ -320,7 +358,11 @@ func (p *CohereProvider) ParseResponse(body []byte) (*Response, error) {
 	}
 
 	p.logger.Debug("Final response: %s", finalResponse.String())
-	return &Response{Content: Text{Value: finalResponse.String()}}, nil
+	return &Response{
+		Content:      Text{Value: finalResponse.String()},
+		FinishReason: NormalizeFinishReason(response.FinishReason),
+		Metadata:     newMetadata(response.ID, "", "", response.FinishReason),
+	}, nil
 }
 
 // PrepareStreamRequest prepares a request body for streaming
@@ -333,23 +375,34 @@ func (p *CohereProvider) PrepareStreamRequest(req *Request, options map[string]a
 		model = m
 	}
 
+	// Tool arguments are not streamed as text, so streams use JSON mode instead of a tool call.
+	strategy, err := resolveStructuredOutput(ProviderCohere, model, req, StructuredOutputNative, StructuredOutputJSONMode)
+	if err != nil {
+		return nil, err
+	}
+
 	requestBody := p.initializeRequestBodyWithModel(model)
 	requestBody[cohereKeyStream] = true
 
 	p.addMessagesToRequestBody(requestBody, req.Messages)
 
-	if req.SystemPrompt != "" {
-		requestBody[cohereKeyPreamble] = req.SystemPrompt
-	} else if systemPrompt, ok := options[cohereKeySystemPrompt].(string); ok && systemPrompt != "" {
-		requestBody[cohereKeyPreamble] = systemPrompt
+	systemPrompt := req.SystemPrompt
+	if systemPrompt == "" {
+		systemPrompt, _ = options[cohereKeySystemPrompt].(string)
 	}
-
-	if req.ResponseSchema != nil && p.HasCapability(llmx.CapabilityType_CAPABILITY_TYPE_STRUCTURED_RESPONSE, model) {
-		p.addStructuredResponseToRequest(requestBody, req.ResponseSchema)
+	systemPrompt, err = structuredOutputSystemPrompt(systemPrompt, req.ResponseSchema, strategy)
+	if err != nil {
+		return nil, err
+	}
+	if systemPrompt != "" {
+		requestBody[cohereKeyPreamble] = systemPrompt
 	}
 
 	p.addRemainingOptions(requestBody, options)
 
+	// Added last so that a forced tool call replaces any tools passed as options.
+	p.addStructuredResponseToRequest(requestBody, req.ResponseSchema, strategy)
+
 	data, err := json.Marshal(requestBody)
 	if err != nil {
 		return nil, fmt.Errorf("failed to marshal request body: %w", err)
@@ -360,15 +413,39 @@ func (p *CohereProvider) PrepareStreamRequest(req *Request, options map[string]a
 // ParseStreamResponse parses a single chunk from a streaming response
 func (p *CohereProvider) ParseStreamResponse(chunk []byte) (*Response, error) {
 	var response struct {
-		Text string `json:"text"`
+		Text         string `json:"text"`
+		FinishReason string `json:"finish_reason"`
+		Delta        struct {
+			FinishReason string `json:"finish_reason"`
+			Message      struct {
+				Content struct {
+					Text string `json:"text"`
+				} `json:"content"`
+			} `json:"message"`
+		} `json:"delta"`
 	}
 	if err := json.Unmarshal(chunk, &response); err != nil {
 		return nil, fmt.Errorf("malformed response: %w", err)
 	}
-	if response.Text == "" {
+
+	// Text and the finish reason are top-level in v1 events and nested in the
+	// delta of v2 content-delta and message-end events.
+	text := response.Text
+	if text == "" {
+		text = response.Delta.Message.Content.Text
+	}
+	finishReason := response.FinishReason
+	if finishReason == "" {
+		finishReason = response.Delta.FinishReason
+	}
+	if text == "" && finishReason == "" {
 		return nil, errors.New("skip resp")
 	}
-	return &Response{Content: Text{Value: response.Text}}, nil
+	return &Response{
+		Content:      Text{Value: text},
+		FinishReason: NormalizeFinishReason(finishReason),
+		Metadata:     newMetadata("", "", "", finishReason),
+	}, nil
 }
 
 // initializeRequestBodyWithModel creates the base request structure with specified model
@@ -423,11 +500,30 @@ func (p *CohereProvider) convertMessageToCohereFormat(msg *Message) map[string]a
 	return cohereMsg
 }
 
-// addStructuredResponseToRequest adds structured response schema to the request
-func (p *CohereProvider) addStructuredResponseToRequest(requestBody map[string]any, schema any) {
-	requestBody[cohereKeyResponseFormat] = map[string]any{
-		"type":        "json_object",
-		"json_schema": schema,
+// addStructuredResponseToRequest requests a structured response with the given strategy
+func (p *CohereProvider) addStructuredResponseToRequest(
+	requestBody map[string]any,
+	schema any,
+	strategy StructuredOutputStrategy,
+) {
+	switch strategy {
+	case StructuredOutputNative:
+		requestBody[cohereKeyResponseFormat] = map[string]any{
+			"type":        "json_object",
+			"json_schema": schema,
+		}
+	case StructuredOutputJSONMode:
+		requestBody[cohereKeyResponseFormat] = map[string]any{"type": "json_object"}
+	case StructuredOutputToolCall:
+		requestBody[cohereKeyTools] = []map[string]any{{
+			"type": "function",
+			"function": map[string]any{
+				"name":        StructuredOutputToolName,
+				"description": structuredOutputToolDescription,
+				"parameters":  schema,
+			},
+		}}
+		requestBody[cohereKeyToolChoice] = "REQUIRED"
 	}
 }
 
@@ -95,10 +95,20 @@ func (p *DeepSeekProvider) Name() string {
 	return "deepseek"
 }
 
+// deepSeekModelLimits are the token limits of known DeepSeek models, by model name prefix.
+var deepSeekModelLimits = map[string]ModelLimits{
+	"deepseek-chat":     {ContextWindow: 65536, MaxOutputTokens: 8192},
+	"deepseek-reasoner": {ContextWindow: 65536, MaxOutputTokens: 32768},
+}
+
 // registerCapabilities registers capabilities for all known DeepSeek models
 func (p *DeepSeekProvider) registerCapabilities() {
 	registry := GetCapabilityRegistry()
 
+	for model, limits := range deepSeekModelLimits {
+		registry.RegisterLimits(ProviderDeepSeek, model, limits)
+	}
+
 	// Define all known DeepSeek models
 	allModels := []string{
 		// Chat models
@@ -243,20 +253,31 @@ func (p *DeepSeekProvider) PrepareRequest(req *Request, options map[string]any)
 		model = m
 	}
 
+	// DeepSeek only offers JSON mode, so the schema is always described in the prompt
+	strategy, err := resolveStructuredOutput(ProviderDeepSeek, model, req, StructuredOutputJSONMode)
+	if err != nil {
+		return nil, err
+	}
+
 	requestBody := p.initializeRequestBodyWithModel(model)
 
 	// Add messages
 	p.addMessagesToRequestBody(requestBody, req.Messages, options)
 
 	// Handle system prompt
-	systemPrompt := p.extractSystemPromptFromRequest(req, options)
+	systemPrompt, err := structuredOutputSystemPrompt(
+		p.extractSystemPromptFromRequest(req, options), req.ResponseSchema, strategy,
+	)
+	if err != nil {
+		return nil, err
+	}
 	if systemPrompt != "" {
 		p.addSystemPromptToRequestBody(requestBody, systemPrompt)
 	}
 
 	// Add structured response support if schema is provided
-	if req.ResponseSchema != nil {
-		p.addStructuredResponseToRequest(requestBody, req.ResponseSchema)
+	if format := openAIResponseFormat(req.ResponseSchema, strategy, false); format != nil {
+		requestBody[deepSeekKeyResponseFormat] = format
 	}
 
 	// Handle tools
@@ -301,8 +322,10 @@ func (p *DeepSeekProvider) ParseResponse(body []byte) (*Response, error) {
 	content := Text{Value: choice.Message.Content}
 
 	response := &Response{
-		Role:    choice.Message.Role,
-		Content: content,
+		Role:         choice.Message.Role,
+		Content:      content,
+		FinishReason: NormalizeFinishReason(choice.FinishReason),
+		Metadata:     newMetadata(deepSeekResp.ID, deepSeekResp.Model, "", choice.FinishReason),
 	}
 
 	// Add usage information if available
@@ -338,11 +361,11 @@ func (p *DeepSeekProvider) ParseResponse(body []byte) (*Response, error) {
 // ParseStreamResponse parses streaming response chunks from the DeepSeek API.
 // It handles the server-sent events format and extracts delta content.
 func (p *DeepSeekProvider) ParseStreamResponse(chunk []byte) (*Response, error) {
-	// Handle server-sent events format
+	// Handle server-sent events format; the SSE decoder may already have removed the "data: " prefix
 	lines := bytes.Split(chunk, []byte("\n"))
 	for _, line := range lines {
 		line = bytes.TrimSpace(line)
-		if len(line) == 0 || !bytes.HasPrefix(line, []byte("data: ")) {
+		if len(line) == 0 {
 			continue
 		}
 
@@ -365,6 +388,14 @@ func (p *DeepSeekProvider) ParseStreamResponse(chunk []byte) (*Response, error)
 		}
 
 		choice := streamResp.Choices[0]
+		if choice.FinishReason != "" {
+			return &Response{
+				Role:         "assistant",
+				Content:      Text{Value: choice.Delta.Content},
+				FinishReason: NormalizeFinishReason(choice.FinishReason),
+				Metadata:     newMetadata(streamResp.ID, streamResp.Model, "", choice.FinishReason),
+			}, nil
+		}
 		if choice.Delta.Content != "" {
 			return &Response{
 				Role:    "assistant",
@@ -432,21 +463,6 @@ func (p *DeepSeekProvider) addSystemPromptToRequestBody(requestBody map[string]a
 	requestBody[deepSeekKeyMessages] = messagesList
 }
 
-// addStructuredResponseToRequest adds JSON schema for structured responses.
-func (p *DeepSeekProvider) addStructuredResponseToRequest(requestBody map[string]any, schema any) {
-	// DeepSeek supports OpenAI-compatible structured responses
-	responseFormat := map[string]any{
-		"type": "json_object",
-	}
-
-	// If schema is provided, add it to the response format
-	if schema != nil {
-		responseFormat["json_schema"] = schema
-	}
-
-	requestBody[deepSeekKeyResponseFormat] = responseFormat
-}
-
 // addMessagesToRequestBody converts and adds messages to the request body.
 func (p *DeepSeekProvider) addMessagesToRequestBody(
 	requestBody map[string]any,
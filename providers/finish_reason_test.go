@@ -0,0 +1,140 @@
+package providers
+
+import (
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNormalizeFinishReason(t *testing.T) {
+	tests := map[string]FinishReason{
+		"":                   "",
+		"stop":               FinishReasonStop,
+		"end_turn":           FinishReasonStop,
+		"STOP":               FinishReasonStop,
+		"COMPLETE":           FinishReasonStop,
+		"length":             FinishReasonLength,
+		"max_tokens":         FinishReasonLength,
+		"MAX_TOKENS":         FinishReasonLength,
+		"model_length":       FinishReasonLength,
+		"tool_calls":         FinishReasonToolCalls,
+		"tool_use":           FinishReasonToolCalls,
+		"TOOL_CALL":          FinishReasonToolCalls,
+		"content_filter":     FinishReasonContentFilter,
+		"SAFETY":             FinishReasonContentFilter,
+		"refusal":            FinishReasonContentFilter,
+		"MALFORMED_FUNCTION": FinishReasonOther,
+		"pause_turn":         FinishReasonOther,
+	}
+	for raw, want := range tests {
+		assert.Equal(t, want, NormalizeFinishReason(raw), raw)
+	}
+}
+
+func TestParseResponseFinishReasonAndMetadata(t *testing.T) {
+	tests := []struct {
+		provider Provider
+		want     *ResponseMetadata
+		name     string
+		body     string
+		reason   FinishReason
+	}{
+		{
+			name:     "openai",
+			provider: NewOpenAIProvider("fake-key", "gpt-4o", nil),
+			body: `{"id":"chatcmpl-1","model":"gpt-4o-2024-08-06","system_fingerprint":"fp_1",
+				"choices":[{"message":{"content":"Hi"},"finish_reason":"length"}]}`,
+			reason: FinishReasonLength,
+			want: &ResponseMetadata{
+				ID: "chatcmpl-1", Model: "gpt-4o-2024-08-06", SystemFingerprint: "fp_1", RawFinishReason: "length",
+			},
+		},
+		{
+			name:     "anthropic",
+			provider: NewAnthropicProvider("fake-key", "claude-3-5-haiku-latest", nil),
+			body: `{"id":"msg_1","model":"claude-3-5-haiku-20241022","stop_reason":"max_tokens",
+				"content":[{"type":"text","text":"Hi"}],"usage":{"input_tokens":3,"output_tokens":1}}`,
+			reason: FinishReasonLength,
+			want:   &ResponseMetadata{ID: "msg_1", Model: "claude-3-5-haiku-20241022", RawFinishReason: "max_tokens"},
+		},
+		{
+			name:     "gemini",
+			provider: NewGeminiProvider("fake-key", "gemini-2.0-flash", nil),
+			body: `{"responseId":"r1","modelVersion":"gemini-2.0-flash-001",
+				"candidates":[{"finishReason":"SAFETY","content":{"parts":[{"text":"Hi"}]}}]}`,
+			reason: FinishReasonContentFilter,
+			want:   &ResponseMetadata{ID: "r1", Model: "gemini-2.0-flash-001", RawFinishReason: "SAFETY"},
+		},
+		{
+			name:     "ollama",
+			provider: NewOllamaProvider("", "llama3", nil),
+			body:     `{"model":"llama3","response":"Hi","done":true,"done_reason":"stop"}`,
+			reason:   FinishReasonStop,
+			want:     &ResponseMetadata{Model: "llama3", RawFinishReason: "stop"},
+		},
+		{
+			name:     "cohere",
+			provider: NewCohereProvider("fake-key", "command-r", nil),
+			body: `{"id":"c1","finish_reason":"MAX_TOKENS",
+				"message":{"role":"assistant","content":[{"type":"text","text":"Hi"}]}}`,
+			reason: FinishReasonLength,
+			want:   &ResponseMetadata{ID: "c1", RawFinishReason: "MAX_TOKENS"},
+		},
+		{
+			name:     "openrouter",
+			provider: NewOpenRouterProvider("fake-key", "openrouter/auto", nil),
+			body: `{"id":"gen-1","model":"anthropic/claude-3.5-sonnet",
+				"choices":[{"message":{"content":"Hi"},"finish_reason":"tool_calls","native_finish_reason":"tool_use"}]}`,
+			reason: FinishReasonToolCalls,
+			want:   &ResponseMetadata{ID: "gen-1", Model: "anthropic/claude-3.5-sonnet", RawFinishReason: "tool_use"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp, err := tt.provider.ParseResponse([]byte(tt.body))
+			require.NoError(t, err)
+			assert.Equal(t, tt.reason, resp.FinishReason)
+			assert.Equal(t, tt.want, resp.Metadata)
+		})
+	}
+}
+
+func TestOpenAIStreamFinishReason(t *testing.T) {
+	p := NewOpenAIProvider("fake-key", "gpt-4o", nil)
+
+	resp, err := p.ParseStreamResponse([]byte(`{"id":"c1","model":"gpt-4o","choices":[{"delta":{"content":"Hi"}}]}`))
+	require.NoError(t, err)
+	assert.Equal(t, "Hi", resp.AsText())
+	assert.Empty(t, resp.FinishReason)
+
+	resp, err = p.ParseStreamResponse([]byte(`{"id":"c1","model":"gpt-4o","choices":[{"delta":{},"finish_reason":"length"}]}`))
+	require.NoError(t, err, "the finish chunk is reported instead of ending the stream")
+	assert.Equal(t, FinishReasonLength, resp.FinishReason)
+	assert.Equal(t, "gpt-4o", resp.Metadata.Model)
+
+	resp, err = p.ParseStreamResponse([]byte(`{"choices":[],"usage":{"prompt_tokens":5,"completion_tokens":2,` +
+		`"prompt_tokens_details":{"cache_tokens":0}}}`))
+	require.NoError(t, err, "the usage chunk after the finish chunk is reported")
+	assert.Equal(t, int64(2), resp.Usage.OutputTokens)
+
+	_, err = p.ParseStreamResponse([]byte("[DONE]"))
+	require.ErrorIs(t, err, io.EOF)
+}
+
+func TestAnthropicStreamFinishReason(t *testing.T) {
+	p := NewAnthropicProvider("fake-key", "claude-3-5-haiku-latest", nil)
+
+	resp, err := p.ParseStreamResponse([]byte(`{"type":"message_start","message":{"id":"msg_1",` +
+		`"model":"claude-3-5-haiku-20241022","usage":{"input_tokens":3}}}`))
+	require.NoError(t, err)
+	assert.Equal(t, "msg_1", resp.Metadata.ID)
+
+	resp, err = p.ParseStreamResponse([]byte(`{"type":"message_delta","delta":{"stop_reason":"tool_use"},` +
+		`"usage":{"output_tokens":7}}`))
+	require.NoError(t, err)
+	assert.Equal(t, FinishReasonToolCalls, resp.FinishReason)
+	assert.Equal(t, "tool_use", resp.Metadata.RawFinishReason)
+}
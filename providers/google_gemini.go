@@ -5,6 +5,7 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"slices"
 	"strings"
 
 	"github.com/google/jsonschema-go/jsonschema"
@@ -91,10 +92,106 @@ func (p *GeminiProvider) Name() string {
 	return "google"
 }
 
+// geminiUnsupportedSchemaKeywords are JSON Schema keywords outside the OpenAPI
+// subset Gemini accepts in responseSchema.
+var geminiUnsupportedSchemaKeywords = []string{
+	"$schema", "$id", "$comment", "$anchor", "$dynamicAnchor", "$dynamicRef", "$vocabulary",
+	"additionalProperties", "patternProperties", "propertyNames", "unevaluatedProperties",
+	"dependentRequired", "dependentSchemas", "allOf", "not", "if", "then", "else",
+	"exclusiveMinimum", "exclusiveMaximum", "multipleOf", "prefixItems", "additionalItems",
+	"contains", "minContains", "maxContains", "uniqueItems", "unevaluatedItems",
+	"examples", "deprecated", "readOnly", "writeOnly",
+	"contentEncoding", "contentMediaType", "contentSchema",
+}
+
+// SchemaTransforms returns the pipeline that reduces a response schema to the
+// subset Gemini accepts: references are inlined, type lists become nullable
+// types and unsupported keywords are removed.
+func (p *GeminiProvider) SchemaTransforms(_ string) []SchemaTransform {
+	return []SchemaTransform{
+		InlineSchemaRefs,
+		OneOfToAnyOf,
+		geminiNullableTypes,
+		RemoveSchemaKeywords(geminiUnsupportedSchemaKeywords...),
+	}
+}
+
+// MessageRules implements MessageRulesProvider. Gemini conversations start with
+// a user turn and alternate between the user and the model.
+func (p *GeminiProvider) MessageRules(_ string) MessageRules {
+	return MessageRules{FirstUser: true, Alternate: true}
+}
+
+// geminiNullableTypes rewrites type lists and null alternatives, which Gemini
+// does not accept, with its nullable keyword, and const with a single enum value.
+func geminiNullableTypes(schema *jsonschema.Schema) []SchemaWarning {
+	var warnings []SchemaWarning
+	walkSchema(schema, func(s *jsonschema.Schema, path string) {
+		if s.Const != nil {
+			s.Enum, s.Const = []any{*s.Const}, nil
+		}
+
+		nullable := slices.Contains(s.Types, "null")
+		types := slices.DeleteFunc(slices.Clone(s.Types), func(t string) bool { return t == "null" })
+		switch {
+		case len(types) == 1:
+			s.Type, s.Types = types[0], nil
+		case len(types) > 1:
+			warnings = append(warnings, SchemaWarning{path, "type list replaced by anyOf"})
+			for _, t := range types {
+				s.AnyOf = append(s.AnyOf, &jsonschema.Schema{Type: t})
+			}
+			s.Types = nil
+		}
+
+		if i := slices.IndexFunc(s.AnyOf, func(a *jsonschema.Schema) bool { return a.Type == "null" }); i >= 0 {
+			nullable = true
+			s.AnyOf = slices.Delete(s.AnyOf, i, i+1)
+			if len(s.AnyOf) == 1 && s.Type == "" {
+				only := s.AnyOf[0]
+				title, description := s.Title, s.Description
+				*s = *only
+				if title != "" {
+					s.Title = title
+				}
+				if description != "" {
+					s.Description = description
+				}
+			}
+		}
+
+		if nullable {
+			if s.Extra == nil {
+				s.Extra = make(map[string]any)
+			}
+			s.Extra["nullable"] = true
+		}
+	})
+	return warnings
+}
+
+// geminiModelLimits are the token limits of known Gemini models, by model name prefix.
+var geminiModelLimits = map[string]ModelLimits{
+	"gemini-2.5-pro":        {ContextWindow: 1048576, MaxOutputTokens: 65536},
+	"gemini-2.5-flash":      {ContextWindow: 1048576, MaxOutputTokens: 65536},
+	"gemini-2.0-pro":        {ContextWindow: 2097152, MaxOutputTokens: 8192},
+	"gemini-2.0-flash":      {ContextWindow: 1048576, MaxOutputTokens: 8192},
+	"gemini-1.5-pro":        {ContextWindow: 2097152, MaxOutputTokens: 8192},
+	"gemini-1.5-flash":      {ContextWindow: 1048576, MaxOutputTokens: 8192},
+	"gemini-1.0-pro":        {ContextWindow: 32760, MaxOutputTokens: 8192},
+	"gemini-1.0-pro-vision": {ContextWindow: 12288, MaxOutputTokens: 4096},
+	"gemini-pro":            {ContextWindow: 32760, MaxOutputTokens: 8192},
+	"gemini-pro-vision":     {ContextWindow: 12288, MaxOutputTokens: 4096},
+}
+
 // registerCapabilities registers capabilities for all known Google Gemini models
 func (p *GeminiProvider) registerCapabilities() {
 	registry := GetCapabilityRegistry()
 
+	for model, limits := range geminiModelLimits {
+		registry.RegisterLimits(ProviderGemini, model, limits)
+	}
+
 	// Define all known Gemini models
 	allModels := []string{
 		// Gemini 2.5 models
@@ -302,19 +399,25 @@ func (p *GeminiProvider) PrepareRequest(req *Request, options map[string]any) ([
 	p.model = model
 	defer func() { p.model = originalModel }()
 
+	strategy, err := resolveStructuredOutput(ProviderGemini, model, req, StructuredOutputNative, StructuredOutputJSONMode)
+	if err != nil {
+		return nil, err
+	}
+
 	requestBody := p.initializeRequestBody()
 
-	systemPrompt := p.extractSystemPromptFromRequest(req, options)
+	systemPrompt, err := structuredOutputSystemPrompt(
+		p.extractSystemPromptFromRequest(req, options), req.ResponseSchema, strategy,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to add structured response: %w", err)
+	}
 
 	p.addSystemPromptToRequestBody(requestBody, systemPrompt)
 
 	p.handleToolsForRequest(requestBody, options)
 
-	if req.ResponseSchema != nil {
-		if err := p.addStructuredResponseToRequest(requestBody, req.ResponseSchema); err != nil {
-			return nil, fmt.Errorf("failed to add structured response: %w", err)
-		}
-	}
+	p.addStructuredResponseToRequest(requestBody, req.ResponseSchema, strategy)
 
 	p.addMessagesToRequestBody(requestBody, req.Messages)
 
@@ -327,6 +430,50 @@ func (p *GeminiProvider) PrepareRequest(req *Request, options map[string]any) ([
 	return data, nil
 }
 
+// PrepareTokenCountRequest implements TokenCountingProvider with the countTokens
+// method, which takes the full generate request including the model name.
+func (p *GeminiProvider) PrepareTokenCountRequest(req *Request, options map[string]any) (string, []byte, error) {
+	data, err := p.PrepareRequest(req, options)
+	if err != nil {
+		return "", nil, err
+	}
+	var requestBody map[string]any
+	if err := json.Unmarshal(data, &requestBody); err != nil {
+		return "", nil, fmt.Errorf("failed to parse request body: %w", err)
+	}
+
+	modelName := p.model
+	if req.Model != "" {
+		modelName = req.Model
+	} else if m, ok := options["model"].(string); ok && m != "" {
+		modelName = m
+	}
+	if !strings.HasPrefix(modelName, "models/") {
+		modelName = "models/" + modelName
+	}
+	requestBody["model"] = modelName
+
+	data, err = json.Marshal(map[string]any{"generateContentRequest": requestBody})
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to marshal request body: %w", err)
+	}
+	return fmt.Sprintf("https://generativelanguage.googleapis.com/v1beta/%s:countTokens", modelName), data, nil
+}
+
+// ParseTokenCountResponse implements TokenCountingProvider.
+func (p *GeminiProvider) ParseTokenCountResponse(body []byte) (int, error) {
+	var response struct {
+		TotalTokens *int `json:"totalTokens"`
+	}
+	if err := json.Unmarshal(body, &response); err != nil {
+		return 0, fmt.Errorf("failed to parse token count response: %w", err)
+	}
+	if response.TotalTokens == nil {
+		return 0, errors.New("token count response has no totalTokens")
+	}
+	return *response.TotalTokens, nil
+}
+
 // PrepareStreamRequest prepares a streaming request using the new unified Request structure.
 func (p *GeminiProvider) PrepareStreamRequest(req *Request, options map[string]any) ([]byte, error) {
 	// Determine which model to use
@@ -374,8 +521,10 @@ func (p *GeminiProvider) ParseResponse(body []byte) (*Response, error) {
 	}
 
 	response := &Response{
-		Role:    "assistant",
-		Content: Text{Value: finalText.String()},
+		Role:         "assistant",
+		Content:      Text{Value: finalText.String()},
+		FinishReason: NormalizeFinishReason(candidate.FinishReason),
+		Metadata:     newMetadata(geminiResp.ResponseID, geminiResp.ModelVersion, "", candidate.FinishReason),
 	}
 
 	// Add usage information if available
@@ -407,40 +556,43 @@ func (p *GeminiProvider) ParseStreamResponse(chunk []byte) (*Response, error) {
 		return nil, errors.New("skip chunk")
 	}
 
-	// If usage metadata is present (possibly in the final chunk), return a Response with usage info
-	if resp.UsageMetadata != nil {
-		um := resp.UsageMetadata
-		usageResp := &Response{
-			Usage: NewUsage(um.PromptTokenCount, um.CachedContentTokenCount, um.CandidatesTokenCount, 0, 0),
-		}
-		return usageResp, nil
+	// Usage metadata is cumulative, so it is only reported with the final chunk
+	var usage *Usage
+	var rawFinishReason string
+	if len(resp.Candidates) > 0 {
+		rawFinishReason = resp.Candidates[0].FinishReason
 	}
-
-	// If no candidates or parts, skip this chunk
-	if len(resp.Candidates) == 0 || len(resp.Candidates[0].Content.Parts) == 0 {
-		return nil, errors.New("skip chunk")
+	if resp.UsageMetadata != nil && (rawFinishReason != "" || len(resp.Candidates) == 0) {
+		um := resp.UsageMetadata
+		usage = NewUsage(um.PromptTokenCount, um.CachedContentTokenCount, um.CandidatesTokenCount, 0, 0)
 	}
 
-	candidate := resp.Candidates[0]
 	var finalText strings.Builder
-
-	for _, part := range candidate.Content.Parts {
-		if part.Text != "" {
-			finalText.WriteString(part.Text)
-		}
-		if part.FunctionCall != nil {
-			p.processFunctionCall(part.FunctionCall, &finalText)
+	if len(resp.Candidates) > 0 {
+		for _, part := range resp.Candidates[0].Content.Parts {
+			if part.Text != "" {
+				finalText.WriteString(part.Text)
+			}
+			if part.FunctionCall != nil {
+				p.processFunctionCall(part.FunctionCall, &finalText)
+			}
 		}
 	}
 
-	if finalText.Len() == 0 {
+	if finalText.Len() == 0 && usage == nil && rawFinishReason == "" {
 		return nil, errors.New("skip chunk")
 	}
 
-	return &Response{
+	response := &Response{
 		Role:    "assistant",
 		Content: Text{Value: finalText.String()},
-	}, nil
+		Usage:   usage,
+	}
+	if rawFinishReason != "" {
+		response.FinishReason = NormalizeFinishReason(rawFinishReason)
+		response.Metadata = newMetadata(resp.ResponseID, resp.ModelVersion, "", rawFinishReason)
+	}
+	return response, nil
 }
 
 // Private helper methods
@@ -503,27 +655,26 @@ func (p *GeminiProvider) handleToolsForRequest(requestBody map[string]any, optio
 	}
 }
 
-func (p *GeminiProvider) addStructuredResponseToRequest(requestBody map[string]any, schema *jsonschema.Schema) error {
-	if schema == nil {
-		return nil
+// addStructuredResponseToRequest requests a JSON response, constrained to the
+// schema when the strategy is native.
+func (p *GeminiProvider) addStructuredResponseToRequest(
+	requestBody map[string]any,
+	schema *jsonschema.Schema,
+	strategy StructuredOutputStrategy,
+) {
+	if strategy != StructuredOutputNative && strategy != StructuredOutputJSONMode {
+		return
 	}
 
-	// schemaJSON, err := schema.MarshalJSON()
-	// if err != nil {
-	//	return fmt.Errorf("failed to marshal response schema: %w", err)
-	// }
-
-	if genConfig, ok := requestBody["generationConfig"].(map[string]any); ok {
-		genConfig["responseMimeType"] = "application/json"
+	genConfig, ok := requestBody["generationConfig"].(map[string]any)
+	if !ok {
+		genConfig = make(map[string]any)
+		requestBody["generationConfig"] = genConfig
+	}
+	genConfig["responseMimeType"] = "application/json"
+	if strategy == StructuredOutputNative {
 		genConfig["responseSchema"] = schema
-	} else {
-		requestBody["generationConfig"] = map[string]any{
-			"responseMimeType": "application/json",
-			"responseSchema":   schema,
-		}
 	}
-
-	return nil
 }
 
 func (p *GeminiProvider) addMessagesToRequestBody(requestBody map[string]any, messages []Message) {
@@ -683,11 +834,15 @@ func (p *GeminiProvider) modelSupportsStructuredResponse() bool {
 //nolint:tagliatelle // These types are specific to the Gemini API response structure
 type geminiResponse struct {
 	UsageMetadata *geminiUsage      `json:"usageMetadata"`
+	ResponseID    string            `json:"responseId"`
+	ModelVersion  string            `json:"modelVersion"`
 	Candidates    []geminiCandidate `json:"candidates"`
 }
 
+//nolint:tagliatelle // These types are specific to the Gemini API response structure
 type geminiCandidate struct {
-	Content geminiContent `json:"content"`
+	FinishReason string        `json:"finishReason"`
+	Content      geminiContent `json:"content"`
 }
 
 type geminiContent struct {
@@ -67,10 +67,32 @@ func (p *GroqProvider) Name() string {
 	return "groq"
 }
 
+// groqModelLimits are the token limits of known Groq models, by model name prefix.
+var groqModelLimits = map[string]ModelLimits{
+	"llama-3.1-70b-versatile":       {ContextWindow: 131072, MaxOutputTokens: 32768},
+	"llama-3.1-8b-instant":          {ContextWindow: 131072, MaxOutputTokens: 8192},
+	"llama-3.1-405b-reasoning":      {ContextWindow: 131072, MaxOutputTokens: 8192},
+	"llama-3.2":                     {ContextWindow: 8192, MaxOutputTokens: 8192},
+	"llama3-groq":                   {ContextWindow: 8192},
+	"llama3-70b-8192":               {ContextWindow: 8192},
+	"llama3-8b-8192":                {ContextWindow: 8192},
+	"llama-guard-3-8b":              {ContextWindow: 8192},
+	"mixtral-8x7b-32768":            {ContextWindow: 32768},
+	"gemma":                         {ContextWindow: 8192},
+	"deepseek-r1-distill-llama-70b": {ContextWindow: 131072},
+	"openai/gpt-oss":                {ContextWindow: 131072, MaxOutputTokens: 65536},
+	"moonshotai/kimi-k2-instruct":   {ContextWindow: 131072, MaxOutputTokens: 16384},
+	"meta-llama/llama-4":            {ContextWindow: 131072, MaxOutputTokens: 8192},
+}
+
 // registerCapabilities registers capabilities for all known Groq models
 func (p *GroqProvider) registerCapabilities() {
 	registry := GetCapabilityRegistry()
 
+	for model, limits := range groqModelLimits {
+		registry.RegisterLimits(ProviderGroq, model, limits)
+	}
+
 	// Define all known Groq models
 	allModels := []string{
 		// Llama models
@@ -227,16 +249,31 @@ func (p *GroqProvider) PrepareRequest(req *Request, options map[string]any) ([]b
 		model = m
 	}
 
+	// Groq does not support response formats when streaming, so streams describe
+	// the schema in the prompt.
+	supported := []StructuredOutputStrategy{StructuredOutputNative, StructuredOutputJSONMode}
+	if options[groqKeyStream] == true {
+		supported = nil
+	}
+	strategy, err := resolveStructuredOutput(ProviderGroq, model, req, supported...)
+	if err != nil {
+		return nil, err
+	}
+
 	requestBody := p.initializeRequestBodyWithModel(model)
 
 	p.addMessagesToRequestBody(requestBody, req.Messages, options)
 
-	if req.SystemPrompt != "" {
-		p.addSystemPromptToRequestBody(requestBody, req.SystemPrompt)
+	systemPrompt, err := structuredOutputSystemPrompt(req.SystemPrompt, req.ResponseSchema, strategy)
+	if err != nil {
+		return nil, err
+	}
+	if systemPrompt != "" {
+		p.addSystemPromptToRequestBody(requestBody, systemPrompt)
 	}
 
-	if req.ResponseSchema != nil && options[groqKeyStream] != true {
-		p.addStructuredResponseToRequest(requestBody, req.ResponseSchema)
+	if format := openAIResponseFormat(req.ResponseSchema, strategy, false); format != nil {
+		requestBody["response_format"] = format
 	}
 
 	p.addRemainingOptions(requestBody, options)
@@ -280,10 +317,14 @@ func (p *GroqProvider) ParseResponse(body []byte) (*Response, error) {
 			CompletionTokens int64 `json:"completion_tokens"`
 			TotalTokens      int64 `json:"total_tokens"`
 		} `json:"usage"`
-		Choices []struct {
+		ID                string `json:"id"`
+		Model             string `json:"model"`
+		SystemFingerprint string `json:"system_fingerprint"`
+		Choices           []struct {
 			Message struct {
 				Content string `json:"content"`
 			} `json:"message"`
+			FinishReason string `json:"finish_reason"`
 		} `json:"choices"`
 	}
 
@@ -296,7 +337,12 @@ func (p *GroqProvider) ParseResponse(body []byte) (*Response, error) {
 		return nil, errors.New("empty response from API")
 	}
 
-	resp := &Response{Content: Text{Value: response.Choices[0].Message.Content}}
+	choice := response.Choices[0]
+	resp := &Response{
+		Content:      Text{Value: choice.Message.Content},
+		FinishReason: NormalizeFinishReason(choice.FinishReason),
+		Metadata:     newMetadata(response.ID, response.Model, response.SystemFingerprint, choice.FinishReason),
+	}
 	if response.Usage != nil {
 		resp.Usage = NewUsage(response.Usage.PromptTokens, 0, response.Usage.CompletionTokens, 0, 0)
 	}
@@ -306,19 +352,34 @@ func (p *GroqProvider) ParseResponse(body []byte) (*Response, error) {
 // ParseStreamResponse parses a single chunk from a streaming response
 func (p *GroqProvider) ParseStreamResponse(chunk []byte) (*Response, error) {
 	var response struct {
-		Choices []struct {
+		ID                string `json:"id"`
+		Model             string `json:"model"`
+		SystemFingerprint string `json:"system_fingerprint"`
+		Choices           []struct {
 			Delta struct {
 				Content string `json:"content"`
 			} `json:"delta"`
+			FinishReason string `json:"finish_reason"`
 		} `json:"choices"`
 	}
 	if err := json.Unmarshal(chunk, &response); err != nil {
 		return nil, fmt.Errorf("malformed response: %w", err)
 	}
-	if len(response.Choices) == 0 || response.Choices[0].Delta.Content == "" {
+	if len(response.Choices) == 0 {
+		return nil, errors.New("skip resp")
+	}
+	choice := response.Choices[0]
+	if choice.FinishReason != "" {
+		return &Response{
+			Content:      Text{Value: choice.Delta.Content},
+			FinishReason: NormalizeFinishReason(choice.FinishReason),
+			Metadata:     newMetadata(response.ID, response.Model, response.SystemFingerprint, choice.FinishReason),
+		}, nil
+	}
+	if choice.Delta.Content == "" {
 		return nil, errors.New("skip resp")
 	}
-	return &Response{Content: Text{Value: response.Choices[0].Delta.Content}}, nil
+	return &Response{Content: Text{Value: choice.Delta.Content}}, nil
 }
 
 // Private helper methods
@@ -375,14 +436,6 @@ func (p *GroqProvider) addSystemPromptToRequestBody(requestBody map[string]any,
 	}
 }
 
-// addStructuredResponseToRequest adds structured response schema to the request
-func (p *GroqProvider) addStructuredResponseToRequest(requestBody map[string]any, schema any) {
-	requestBody["response_format"] = map[string]any{
-		"type":   "json_schema",
-		"schema": schema,
-	}
-}
-
 // addRemainingOptions adds provider options and request options to the request body
 func (p *GroqProvider) addRemainingOptions(requestBody map[string]any, options map[string]any) {
 	// Add provider options first
@@ -0,0 +1,28 @@
+package providers
+
+// MessageRules are the constraints a provider puts on the order of the
+// messages of a conversation. Tool call pairs are always kept together,
+// whatever the rules.
+type MessageRules struct {
+	// FirstUser requires the first message after the system prompt to be a
+	// user message.
+	FirstUser bool
+	// Alternate requires user and assistant turns to alternate, so consecutive
+	// messages of the same role must be merged.
+	Alternate bool
+}
+
+// MessageRulesProvider is implemented by providers that constrain the order of
+// conversation messages.
+type MessageRulesProvider interface {
+	MessageRules(model string) MessageRules
+}
+
+// GetMessageRules returns the message rules of a provider for model. Providers
+// that do not implement MessageRulesProvider have no rules.
+func GetMessageRules(provider Provider, model string) MessageRules {
+	if ruled, ok := provider.(MessageRulesProvider); ok {
+		return ruled.MessageRules(model)
+	}
+	return MessageRules{}
+}
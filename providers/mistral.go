@@ -99,10 +99,33 @@ func (p *MistralProvider) Name() string {
 	return "mistral"
 }
 
+// mistralModelLimits are the token limits of known Mistral models, by model name prefix.
+var mistralModelLimits = map[string]ModelLimits{
+	"mistral-large":         {ContextWindow: 131072},
+	"mistral-medium-latest": {ContextWindow: 131072},
+	"mistral-medium":        {ContextWindow: 32768},
+	"mistral-small-latest":  {ContextWindow: 131072},
+	"mistral-small":         {ContextWindow: 32768},
+	"devstral-small":        {ContextWindow: 131072},
+	"codestral-latest":      {ContextWindow: 256000},
+	"codestral-2405":        {ContextWindow: 32768},
+	"codestral-mamba":       {ContextWindow: 262144},
+	"ministral":             {ContextWindow: 131072},
+	"pixtral":               {ContextWindow: 131072},
+	"open-mistral-nemo":     {ContextWindow: 131072},
+	"open-mistral-7b":       {ContextWindow: 32768},
+	"open-mixtral-8x7b":     {ContextWindow: 32768},
+	"open-mixtral-8x22b":    {ContextWindow: 65536},
+}
+
 // registerCapabilities registers capabilities for all known Mistral models
 func (p *MistralProvider) registerCapabilities() {
 	registry := GetCapabilityRegistry()
 
+	for model, limits := range mistralModelLimits {
+		registry.RegisterLimits(ProviderMistral, model, limits)
+	}
+
 	// Define all known Mistral models
 	allModels := []string{
 		// Current latest models
@@ -143,7 +166,7 @@ func (p *MistralProvider) registerCapabilities() {
 				llmx.CapabilityType_CAPABILITY_TYPE_STRUCTURED_RESPONSE, &llmx.StructuredResponse{
 					MaxSchemaDepth:   10,
 					SupportedFormats: []llmx.DataFormat{llmx.DataFormat_DATA_FORMAT_JSON},
-					RequiresJsonMode: true,
+					RequiresJsonMode: false,
 					SupportedTypes: []llmx.JsonSchemaType{
 						llmx.JsonSchemaType_JSON_SCHEMA_TYPE_OBJECT,
 						llmx.JsonSchemaType_JSON_SCHEMA_TYPE_ARRAY,
@@ -270,10 +293,20 @@ func (p *MistralProvider) PrepareRequest(req *Request, options map[string]any) (
 		model = m
 	}
 
+	strategy, err := resolveStructuredOutput(ProviderMistral, model, req, StructuredOutputNative, StructuredOutputJSONMode)
+	if err != nil {
+		return nil, err
+	}
+
 	requestBody := p.initializeRequestBodyWithModel(model)
 
 	// Add system prompt if present
-	systemPrompt := p.extractSystemPromptFromRequest(req, options)
+	systemPrompt, err := structuredOutputSystemPrompt(
+		p.extractSystemPromptFromRequest(req, options), req.ResponseSchema, strategy,
+	)
+	if err != nil {
+		return nil, err
+	}
 	if systemPrompt != "" {
 		p.addSystemPromptToRequestBody(requestBody, systemPrompt)
 	}
@@ -281,9 +314,9 @@ func (p *MistralProvider) PrepareRequest(req *Request, options map[string]any) (
 	// Add messages
 	p.addMessagesToRequestBody(requestBody, req.Messages)
 
-	// Add structured response if supported
-	if req.ResponseSchema != nil && p.HasCapability(llmx.CapabilityType_CAPABILITY_TYPE_STRUCTURED_RESPONSE, model) {
-		p.addStructuredResponseToRequest(requestBody, req.ResponseSchema)
+	// Add structured response format
+	if format := openAIResponseFormat(req.ResponseSchema, strategy, false); format != nil {
+		requestBody[mistralKeyResponseFormat] = format
 	}
 
 	// Add remaining options
@@ -307,8 +340,11 @@ func (p *MistralProvider) PrepareRequest(req *Request, options map[string]any) (
 //   - Any error encountered during parsing
 func (p *MistralProvider) ParseResponse(body []byte) (*Response, error) {
 	var response struct {
+		ID      string `json:"id"`
+		Model   string `json:"model"`
 		Choices []struct {
-			Message struct {
+			FinishReason string `json:"finish_reason"`
+			Message      struct {
 				Content   string `json:"content"`
 				ToolCalls []struct {
 					Function struct {
@@ -350,7 +386,12 @@ func (p *MistralProvider) ParseResponse(body []byte) (*Response, error) {
 		finalResponse.WriteString(functionCall)
 	}
 
-	return &Response{Content: Text{Value: finalResponse.String()}}, nil
+	finishReason := response.Choices[0].FinishReason
+	return &Response{
+		Content:      Text{Value: finalResponse.String()},
+		FinishReason: NormalizeFinishReason(finishReason),
+		Metadata:     newMetadata(response.ID, response.Model, "", finishReason),
+	}, nil
 }
 
 // SetExtraHeaders configures additional HTTP headers for API requests.
@@ -369,11 +410,21 @@ func (p *MistralProvider) PrepareStreamRequest(req *Request, options map[string]
 		model = m
 	}
 
+	strategy, err := resolveStructuredOutput(ProviderMistral, model, req, StructuredOutputNative, StructuredOutputJSONMode)
+	if err != nil {
+		return nil, err
+	}
+
 	requestBody := p.initializeRequestBodyWithModel(model)
 	requestBody[mistralKeyStream] = true
 
 	// Add system prompt if present
-	systemPrompt := p.extractSystemPromptFromRequest(req, options)
+	systemPrompt, err := structuredOutputSystemPrompt(
+		p.extractSystemPromptFromRequest(req, options), req.ResponseSchema, strategy,
+	)
+	if err != nil {
+		return nil, err
+	}
 	if systemPrompt != "" {
 		p.addSystemPromptToRequestBody(requestBody, systemPrompt)
 	}
@@ -381,9 +432,9 @@ func (p *MistralProvider) PrepareStreamRequest(req *Request, options map[string]
 	// Add messages
 	p.addMessagesToRequestBody(requestBody, req.Messages)
 
-	// Add structured response if supported
-	if req.ResponseSchema != nil && p.HasCapability(llmx.CapabilityType_CAPABILITY_TYPE_STRUCTURED_RESPONSE, model) {
-		p.addStructuredResponseToRequest(requestBody, req.ResponseSchema)
+	// Add structured response format
+	if format := openAIResponseFormat(req.ResponseSchema, strategy, false); format != nil {
+		requestBody[mistralKeyResponseFormat] = format
 	}
 
 	// Add remaining options
@@ -408,10 +459,13 @@ func (p *MistralProvider) ParseStreamResponse(chunk []byte) (*Response, error) {
 	}
 
 	var response struct {
+		ID      string `json:"id"`
+		Model   string `json:"model"`
 		Choices []struct {
 			Delta struct {
 				Content string `json:"content"`
 			} `json:"delta"`
+			FinishReason string `json:"finish_reason"`
 		} `json:"choices"`
 	}
 
@@ -419,11 +473,24 @@ func (p *MistralProvider) ParseStreamResponse(chunk []byte) (*Response, error) {
 		return nil, fmt.Errorf("malformed response: %w", err)
 	}
 
-	if len(response.Choices) == 0 || response.Choices[0].Delta.Content == "" {
+	if len(response.Choices) == 0 {
 		return nil, errors.New("skip token")
 	}
 
-	return &Response{Content: Text{Value: response.Choices[0].Delta.Content}}, nil
+	choice := response.Choices[0]
+	if choice.FinishReason != "" {
+		return &Response{
+			Content:      Text{Value: choice.Delta.Content},
+			FinishReason: NormalizeFinishReason(choice.FinishReason),
+			Metadata:     newMetadata(response.ID, response.Model, "", choice.FinishReason),
+		}, nil
+	}
+
+	if choice.Delta.Content == "" {
+		return nil, errors.New("skip token")
+	}
+
+	return &Response{Content: Text{Value: choice.Delta.Content}}, nil
 }
 
 // initializeRequestBodyWithModel creates the base request structure with specified model
@@ -485,14 +552,6 @@ func (p *MistralProvider) addMessagesToRequestBody(requestBody map[string]any, m
 	}
 }
 
-// addStructuredResponseToRequest adds structured response schema to the request
-func (p *MistralProvider) addStructuredResponseToRequest(requestBody map[string]any, schema any) {
-	requestBody[mistralKeyResponseFormat] = map[string]any{
-		"type":   "json_schema",
-		"schema": schema,
-	}
-}
-
 // addRemainingOptions adds provider options and additional options to the request
 func (p *MistralProvider) addRemainingOptions(requestBody map[string]any, options map[string]any) {
 	// Add provider options first
@@ -235,7 +235,7 @@ func (p *OllamaProvider) SetDefaultOptions(cfg *config.Config) {
 func (p *OllamaProvider) SetOption(key string, value any) {
 	p.options[key] = value
 	if p.logger != nil {
-		p.logger.Debug("Setting option for Ollama", "key", key, "value", value)
+		p.logger.Debug("Setting option for Ollama", key, value)
 	}
 }
 
@@ -256,6 +256,16 @@ func (p *OllamaProvider) PrepareRequest(req *Request, options map[string]any) ([
 		model = m
 	}
 
+	// Structured responses are described in the system prompt
+	strategy, err := resolveStructuredOutput(ProviderOllama, model, req)
+	if err != nil {
+		return nil, err
+	}
+	systemPrompt, err := structuredOutputSystemPrompt(req.SystemPrompt, req.ResponseSchema, strategy)
+	if err != nil {
+		return nil, err
+	}
+
 	requestBody := map[string]any{
 		ollamaKeyModel: model,
 	}
@@ -265,9 +275,9 @@ func (p *OllamaProvider) PrepareRequest(req *Request, options map[string]any) ([
 		var prompt strings.Builder
 
 		// Add system prompt if present
-		if req.SystemPrompt != "" {
+		if systemPrompt != "" {
 			prompt.WriteString("System: ")
-			prompt.WriteString(req.SystemPrompt)
+			prompt.WriteString(systemPrompt)
 			prompt.WriteString("\n\n")
 		}
 
@@ -296,7 +306,6 @@ func (p *OllamaProvider) PrepareRequest(req *Request, options map[string]any) ([
 
 // PrepareStreamRequest prepares a request body for streaming
 func (p *OllamaProvider) PrepareStreamRequest(req *Request, options map[string]any) ([]byte, error) {
-	// Ollama doesn't support structured response natively; proceed with standard streaming
 	options[ollamaKeyStream] = true
 	return p.PrepareRequest(req, options)
 }
@@ -314,6 +323,7 @@ func (p *OllamaProvider) ParseResponse(body []byte) (*Response, error) {
 	var fullText strings.Builder
 	var promptEvalCount int64
 	var evalCount int64
+	var model, doneReason string
 
 	decoder := json.NewDecoder(bytes.NewReader(body))
 
@@ -321,6 +331,7 @@ func (p *OllamaProvider) ParseResponse(body []byte) (*Response, error) {
 		var response struct {
 			Model           string `json:"model"`
 			Response        string `json:"response"`
+			DoneReason      string `json:"done_reason"`
 			Done            bool   `json:"done"`
 			PromptEvalCount int64  `json:"prompt_eval_count"`
 			EvalCount       int64  `json:"eval_count"`
@@ -338,12 +349,20 @@ func (p *OllamaProvider) ParseResponse(body []byte) (*Response, error) {
 		if response.EvalCount > 0 {
 			evalCount = response.EvalCount
 		}
+		if response.Model != "" {
+			model = response.Model
+		}
 		if response.Done {
+			doneReason = response.DoneReason
 			break
 		}
 	}
 
-	resp := &Response{Content: Text{Value: fullText.String()}}
+	resp := &Response{
+		Content:      Text{Value: fullText.String()},
+		FinishReason: NormalizeFinishReason(doneReason),
+		Metadata:     newMetadata("", model, "", doneReason),
+	}
 	// Attach usage if we captured any token counts
 	if promptEvalCount > 0 || evalCount > 0 {
 		resp.Usage = NewUsage(promptEvalCount, 0, evalCount, 0, 0)
@@ -354,7 +373,9 @@ func (p *OllamaProvider) ParseResponse(body []byte) (*Response, error) {
 // ParseStreamResponse parses a single chunk from a streaming response
 func (p *OllamaProvider) ParseStreamResponse(chunk []byte) (*Response, error) {
 	var response struct {
+		Model           string `json:"model"`
 		Response        string `json:"response"`
+		DoneReason      string `json:"done_reason"`
 		Done            bool   `json:"done"`
 		PromptEvalCount int64  `json:"prompt_eval_count"`
 		EvalCount       int64  `json:"eval_count"`
@@ -368,7 +389,11 @@ func (p *OllamaProvider) ParseStreamResponse(chunk []byte) (*Response, error) {
 		if response.PromptEvalCount > 0 || response.EvalCount > 0 {
 			usage = NewUsage(response.PromptEvalCount, 0, response.EvalCount, 0, 0)
 		}
-		return &Response{Usage: usage}, nil
+		return &Response{
+			Usage:        usage,
+			FinishReason: NormalizeFinishReason(response.DoneReason),
+			Metadata:     newMetadata("", response.Model, "", response.DoneReason),
+		}, nil
 	}
 	if strings.TrimSpace(response.Response) == "" {
 		return nil, errors.New("skip resp")
@@ -8,8 +8,6 @@ import (
 	"io"
 	"strings"
 
-	"github.com/google/jsonschema-go/jsonschema"
-
 	"github.com/weave-labs/gollm/config"
 	"github.com/weave-labs/gollm/internal/logging"
 	"github.com/weave-labs/gollm/internal/models"
@@ -90,7 +88,7 @@ func (p *OpenAIProvider) SetOption(key string, value any) {
 	}
 
 	p.options[key] = value
-	p.logger.Debug("Option set", "key", key, "value", value)
+	p.logger.Debug("Option set", key, value)
 }
 
 // SetDefaultOptions configures standard options from the global configuration.
@@ -117,10 +115,57 @@ func (p *OpenAIProvider) Name() string {
 	return "openai"
 }
 
+// openAIStrictUnsupportedSchemaKeywords are JSON Schema keywords rejected in
+// strict mode.
+var openAIStrictUnsupportedSchemaKeywords = []string{
+	"allOf", "not", "if", "then", "else", "dependentRequired", "dependentSchemas",
+	"patternProperties", "propertyNames", "unevaluatedProperties", "minProperties", "maxProperties",
+	"prefixItems", "additionalItems", "contains", "minContains", "maxContains", "uniqueItems",
+	"unevaluatedItems", "contentEncoding", "contentMediaType", "contentSchema", "default", "examples",
+}
+
+// SchemaTransforms returns the strict mode pipeline for models that take the
+// schema natively: every object is closed and every property required, with
+// optional properties made nullable. Models that only see the schema in the
+// prompt receive it unchanged.
+func (p *OpenAIProvider) SchemaTransforms(model string) []SchemaTransform {
+	if SelectStructuredOutputStrategy(ProviderOpenAI, model, nil) != StructuredOutputNative {
+		return nil
+	}
+	return []SchemaTransform{
+		OneOfToAnyOf,
+		RemoveSchemaKeywords(openAIStrictUnsupportedSchemaKeywords...),
+		NullableOptionalProperties,
+		CloseObjects,
+	}
+}
+
+// openAIModelLimits are the token limits of known OpenAI models, by model name prefix.
+var openAIModelLimits = map[string]ModelLimits{
+	"gpt-4.1":              {ContextWindow: 1047576, MaxOutputTokens: 32768},
+	"gpt-4o":               {ContextWindow: 128000, MaxOutputTokens: 16384},
+	"gpt-4o-2024-05-13":    {ContextWindow: 128000, MaxOutputTokens: 4096},
+	"gpt-4-turbo":          {ContextWindow: 128000, MaxOutputTokens: 4096},
+	"gpt-4-0125-preview":   {ContextWindow: 128000, MaxOutputTokens: 4096},
+	"gpt-4-1106-preview":   {ContextWindow: 128000, MaxOutputTokens: 4096},
+	"gpt-4-vision-preview": {ContextWindow: 128000, MaxOutputTokens: 4096},
+	"gpt-4":                {ContextWindow: 8192, MaxOutputTokens: 8192},
+	"gpt-3.5-turbo":        {ContextWindow: 16385, MaxOutputTokens: 4096},
+	"gpt-3.5-turbo-0613":   {ContextWindow: 4096, MaxOutputTokens: 4096},
+	"o1":                   {ContextWindow: 200000, MaxOutputTokens: 100000},
+	"o1-preview":           {ContextWindow: 128000, MaxOutputTokens: 32768},
+	"o1-mini":              {ContextWindow: 128000, MaxOutputTokens: 65536},
+	"o3-mini":              {ContextWindow: 200000, MaxOutputTokens: 100000},
+}
+
 // registerCapabilities registers capabilities for all known OpenAI models
 func (p *OpenAIProvider) registerCapabilities() {
 	registry := GetCapabilityRegistry()
 
+	for model, limits := range openAIModelLimits {
+		registry.RegisterLimits(ProviderOpenAI, model, limits)
+	}
+
 	// Define all known OpenAI models
 	allModels := []string{
 		// GPT-4.1 models
@@ -154,17 +199,17 @@ func (p *OpenAIProvider) registerCapabilities() {
 			continue
 		}
 
-		// GPT-4o models - advanced structured response
-		if strings.HasPrefix(model, "gpt-4o") || strings.HasPrefix(model, "gpt-4-turbo") {
+		// GPT-4o and GPT-4.1 models - native JSON schema structured response
+		if strings.HasPrefix(model, "gpt-4o") || strings.HasPrefix(model, "gpt-4.1") {
 			registry.RegisterCapability(ProviderOpenAI, model,
 				llmx.CapabilityType_CAPABILITY_TYPE_STRUCTURED_RESPONSE, &llmx.StructuredResponse{
 					RequiresToolUse:  false,
 					MaxSchemaDepth:   15,
 					SupportedFormats: []llmx.DataFormat{llmx.DataFormat_DATA_FORMAT_JSON},
-					RequiresJsonMode: true,
+					RequiresJsonMode: false,
 				})
 		} else if strings.HasPrefix(model, "gpt-4") {
-			// Regular GPT-4 models
+			// GPT-4 and GPT-4 Turbo models - JSON mode only
 			registry.RegisterCapability(ProviderOpenAI, model,
 				llmx.CapabilityType_CAPABILITY_TYPE_STRUCTURED_RESPONSE, &llmx.StructuredResponse{
 					RequiresToolUse:  false,
@@ -301,8 +346,18 @@ func (p *OpenAIProvider) PrepareRequest(req *Request, options map[string]any) ([
 
 	requestBody := p.initializeOpenAIRequestWithModel(model)
 
+	strategy, err := resolveStructuredOutput(ProviderOpenAI, model, req, StructuredOutputNative, StructuredOutputJSONMode)
+	if err != nil {
+		return nil, err
+	}
+
 	// Handle system prompt from Request or options
-	systemPrompt := p.extractSystemPromptFromRequest(req, options)
+	systemPrompt, err := structuredOutputSystemPrompt(
+		p.extractSystemPromptFromRequest(req, options), req.ResponseSchema, strategy,
+	)
+	if err != nil {
+		return nil, err
+	}
 	if systemPrompt != "" {
 		p.addSystemPromptToRequestBody(requestBody, systemPrompt)
 	}
@@ -322,11 +377,8 @@ func (p *OpenAIProvider) PrepareRequest(req *Request, options map[string]any) ([
 	}
 
 	// Handle structured response schema
-	if req.ResponseSchema != nil && p.HasCapability(llmx.CapabilityType_CAPABILITY_TYPE_STRUCTURED_RESPONSE, model) {
-		err := p.addStructuredResponseToRequest(requestBody, req.ResponseSchema)
-		if err != nil {
-			return nil, err
-		}
+	if format := openAIResponseFormat(req.ResponseSchema, strategy, isStrictSchema(req.ResponseSchema)); format != nil {
+		requestBody["response_format"] = format
 	}
 
 	// Add remaining options
@@ -364,11 +416,17 @@ func (p *OpenAIProvider) ParseResponse(body []byte) (*Response, error) {
 		)
 	}
 
-	message := response.Choices[0].Message
+	choice := response.Choices[0]
+	metadata := newMetadata(response.ID, response.Model, response.SystemFingerprint, choice.FinishReason)
+	finishReason := NormalizeFinishReason(choice.FinishReason)
+
+	message := choice.Message
 	if message.Content != "" {
 		return &Response{
-			Content: Text{message.Content},
-			Usage:   usage,
+			Content:      Text{message.Content},
+			Usage:        usage,
+			FinishReason: finishReason,
+			Metadata:     metadata,
 		}, nil
 	}
 
@@ -389,8 +447,10 @@ func (p *OpenAIProvider) ParseResponse(body []byte) (*Response, error) {
 		}
 
 		return &Response{
-			Content: Text{strings.Join(functionCalls, "\n")},
-			Usage:   usage,
+			Content:      Text{strings.Join(functionCalls, "\n")},
+			Usage:        usage,
+			FinishReason: finishReason,
+			Metadata:     metadata,
 		}, nil
 	}
 
@@ -422,8 +482,18 @@ func (p *OpenAIProvider) PrepareStreamRequest(req *Request, options map[string]a
 	requestBody[openAIKeyStream] = true
 	requestBody["stream_options"] = map[string]bool{"include_usage": true}
 
+	strategy, err := resolveStructuredOutput(ProviderOpenAI, model, req, StructuredOutputNative, StructuredOutputJSONMode)
+	if err != nil {
+		return nil, err
+	}
+
 	// Handle system prompt from Request or options
-	systemPrompt := p.extractSystemPromptFromRequest(req, options)
+	systemPrompt, err := structuredOutputSystemPrompt(
+		p.extractSystemPromptFromRequest(req, options), req.ResponseSchema, strategy,
+	)
+	if err != nil {
+		return nil, err
+	}
 	if systemPrompt != "" {
 		p.addSystemPromptToRequestBody(requestBody, systemPrompt)
 	}
@@ -443,11 +513,8 @@ func (p *OpenAIProvider) PrepareStreamRequest(req *Request, options map[string]a
 	}
 
 	// Handle structured response schema
-	if req.ResponseSchema != nil && p.HasCapability(llmx.CapabilityType_CAPABILITY_TYPE_STRUCTURED_RESPONSE, model) {
-		err := p.addStructuredResponseToRequest(requestBody, req.ResponseSchema)
-		if err != nil {
-			return nil, err
-		}
+	if format := openAIResponseFormat(req.ResponseSchema, strategy, isStrictSchema(req.ResponseSchema)); format != nil {
+		requestBody["response_format"] = format
 	}
 
 	// Add remaining options
@@ -479,20 +546,6 @@ func (p *OpenAIProvider) ParseStreamResponse(chunk []byte) (*Response, error) {
 		return nil, fmt.Errorf("malformed response: %w", err)
 	}
 
-	if len(response.Choices) == 0 {
-		return nil, errors.New("no choices in response")
-	}
-
-	// Handle finish reason
-	if response.Choices[0].FinishReason != "" {
-		return nil, io.EOF
-	}
-
-	// Skip role-only messages
-	if response.Choices[0].Delta.Role != "" && response.Choices[0].Delta.Content == "" {
-		return nil, errors.New("skip token")
-	}
-
 	usage := &Usage{}
 
 	if response.Usage != nil && response.Usage.PromptTokensDetails != nil {
@@ -505,11 +558,38 @@ func (p *OpenAIProvider) ParseStreamResponse(chunk []byte) (*Response, error) {
 		)
 	}
 
+	// The final usage chunk carries no choices
+	if len(response.Choices) == 0 {
+		if response.Usage == nil {
+			return nil, errors.New("no choices in response")
+		}
+		return &Response{Usage: usage}, nil
+	}
+
+	choice := response.Choices[0]
+
+	// The finish chunk carries the finish reason and usually no content
+	if choice.FinishReason != "" {
+		return &Response{
+			Content:      Text{choice.Delta.Content},
+			Usage:        usage,
+			ToolCalls:    choice.Delta.toolCalls(),
+			FinishReason: NormalizeFinishReason(choice.FinishReason),
+			Metadata:     newMetadata(response.ID, response.Model, response.SystemFingerprint, choice.FinishReason),
+		}, nil
+	}
+
+	// Skip role-only messages
+	if choice.Delta.Role != "" && choice.Delta.Content == "" && len(choice.Delta.ToolCalls) == 0 {
+		return nil, errors.New("skip token")
+	}
+
 	return &Response{
 		Content: Text{
-			response.Choices[0].Delta.Content,
+			choice.Delta.Content,
 		},
-		Usage: usage,
+		Usage:     usage,
+		ToolCalls: choice.Delta.toolCalls(),
 	}, nil
 }
 
@@ -649,36 +729,7 @@ func (p *OpenAIProvider) handleToolsForRequest(requestBody map[string]any, optio
 	}
 }
 
-// addStructuredResponseToRequest adds a structured response schema to the request
-func (p *OpenAIProvider) addStructuredResponseToRequest(
-	requestBody map[string]any,
-	responseJSONSchema *jsonschema.Schema,
-) error {
-	responseJSON, err := responseJSONSchema.MarshalJSON()
-	if err != nil {
-		return fmt.Errorf("failed to marshal response schema: %w", err)
-	}
-
-	responseMap := make(map[string]any)
-
-	err = json.Unmarshal(responseJSON, &responseMap)
-	if err != nil {
-		return fmt.Errorf("failed to unmarshal response schema: %w", err)
-	}
-
-	requestBody["response_format"] = map[string]any{
-		"type": "json_schema",
-		"json_schema": map[string]any{
-			"name":   "response",
-			"schema": responseMap,
-			"strict": false,
-		},
-	}
-
-	return nil
-}
-
-// addStructuredResponseToRequest adds a structured response schema to the request
+// addStructuredResponseJSONToRequest adds a raw JSON response schema to the request
 func (p *OpenAIProvider) addStructuredResponseJSONToRequest(
 	requestBody map[string]any,
 	responseJSON []byte,
@@ -759,12 +810,16 @@ func (p *OpenAIProvider) handleTokenParameters(mergedOptions map[string]any) {
 }
 
 type openAIResponse struct {
-	Usage   *openAIUsage   `json:"usage"`
-	Choices []openAIChoice `json:"choices"`
+	Usage             *openAIUsage   `json:"usage"`
+	ID                string         `json:"id"`
+	Model             string         `json:"model"`
+	SystemFingerprint string         `json:"system_fingerprint"`
+	Choices           []openAIChoice `json:"choices"`
 }
 
 type openAIChoice struct {
-	Message *openAIMessage `json:"message"`
+	Message      *openAIMessage `json:"message"`
+	FinishReason string         `json:"finish_reason"`
 }
 
 type openAIMessage struct {
@@ -803,8 +858,11 @@ type openAICompletionTokensDetails struct {
 }
 
 type openAIStreamResponse struct {
-	Usage   *openAIUsage         `json:"usage,omitempty"`
-	Choices []openAIStreamChoice `json:"choices"`
+	Usage             *openAIUsage         `json:"usage,omitempty"`
+	ID                string               `json:"id"`
+	Model             string               `json:"model"`
+	SystemFingerprint string               `json:"system_fingerprint"`
+	Choices           []openAIStreamChoice `json:"choices"`
 }
 
 type openAIStreamChoice struct {
@@ -813,6 +871,39 @@ type openAIStreamChoice struct {
 }
 
 type openAIStreamDelta struct {
-	Role    string `json:"role"`
-	Content string `json:"content"`
+	Role      string                 `json:"role"`
+	Content   string                 `json:"content"`
+	ToolCalls []openAIStreamToolCall `json:"tool_calls"`
+}
+
+// openAIStreamToolCall is a tool-call fragment. The first fragment of a call
+// carries its ID and name; later ones append to its arguments.
+type openAIStreamToolCall struct {
+	Function openAIStreamFunction `json:"function"`
+	ID       string               `json:"id"`
+	Type     string               `json:"type"`
+}
+
+type openAIStreamFunction struct {
+	Name      string `json:"name"`
+	Arguments string `json:"arguments"`
+}
+
+// toolCalls converts the tool-call fragments of the delta.
+func (d openAIStreamDelta) toolCalls() []ToolCall {
+	if len(d.ToolCalls) == 0 {
+		return nil
+	}
+	calls := make([]ToolCall, len(d.ToolCalls))
+	for i, call := range d.ToolCalls {
+		calls[i] = ToolCall{
+			ID:   call.ID,
+			Type: call.Type,
+			Function: FunctionCall{
+				Name:      call.Function.Name,
+				Arguments: json.RawMessage(call.Function.Arguments),
+			},
+		}
+	}
+	return calls
 }
@@ -9,6 +9,7 @@ import (
 	"io"
 	"slices"
 
+	"github.com/google/jsonschema-go/jsonschema"
 	"github.com/weave-labs/weave-go/weaveapi/llmx/v1"
 
 	"github.com/weave-labs/gollm/config"
@@ -555,11 +556,21 @@ func (p *OpenRouterProvider) PrepareRequest(req *Request, options map[string]any
 		model = m
 	}
 
+	strategy, err := resolveStructuredOutput(ProviderOpenRouter, model, req,
+		StructuredOutputNative, StructuredOutputJSONMode)
+	if err != nil {
+		return nil, err
+	}
+	systemPrompt, err := structuredOutputSystemPrompt(req.SystemPrompt, req.ResponseSchema, strategy)
+	if err != nil {
+		return nil, err
+	}
+
 	requestBody := p.initializeRequestBodyWithModel(model, options)
 	p.handleModelRouting(requestBody)
 	p.handleProviderPreferences(requestBody)
-	p.addMessages(requestBody, req)
-	p.handleStructuredResponse(requestBody, req, model)
+	p.addMessages(requestBody, systemPrompt, req.Messages)
+	p.handleStructuredResponse(requestBody, req.ResponseSchema, strategy)
 	p.handleToolsAndOptions(requestBody)
 
 	data, err := json.Marshal(requestBody)
@@ -607,19 +618,19 @@ func (p *OpenRouterProvider) handleProviderPreferences(requestBody map[string]an
 }
 
 // addMessages adds system prompt and messages to the request body
-func (p *OpenRouterProvider) addMessages(requestBody map[string]any, req *Request) {
-	messages := make([]map[string]any, 0, len(req.Messages)+1)
+func (p *OpenRouterProvider) addMessages(requestBody map[string]any, systemPrompt string, reqMessages []Message) {
+	messages := make([]map[string]any, 0, len(reqMessages)+1)
 
 	// Add system prompt if provided
-	if req.SystemPrompt != "" {
+	if systemPrompt != "" {
 		messages = append(messages, map[string]any{
 			"role":    "system",
-			"content": req.SystemPrompt,
+			"content": systemPrompt,
 		})
 	}
 
 	// Add messages from the Request
-	for _, msg := range req.Messages {
+	for _, msg := range reqMessages {
 		openRouterMsg := p.convertMessage(&msg)
 		messages = append(messages, openRouterMsg)
 	}
@@ -645,13 +656,14 @@ func (p *OpenRouterProvider) convertMessage(msg *Message) map[string]any {
 	return openRouterMsg
 }
 
-// handleStructuredResponse adds structured response schema if supported
-func (p *OpenRouterProvider) handleStructuredResponse(requestBody map[string]any, req *Request, model string) {
-	if req.ResponseSchema != nil && p.HasCapability(llmx.CapabilityType_CAPABILITY_TYPE_STRUCTURED_RESPONSE, model) {
-		requestBody["response_format"] = map[string]any{
-			"type":   "json_object",
-			"schema": req.ResponseSchema,
-		}
+// handleStructuredResponse adds the response format for the structured output strategy
+func (p *OpenRouterProvider) handleStructuredResponse(
+	requestBody map[string]any,
+	schema *jsonschema.Schema,
+	strategy StructuredOutputStrategy,
+) {
+	if format := openAIResponseFormat(schema, strategy, false); format != nil {
+		requestBody["response_format"] = format
 	}
 }
 
@@ -786,7 +798,13 @@ func (p *OpenRouterProvider) parseChatCompletion(body []byte) (*Response, error)
 		p.logger.Info("Model used", "requested", p.model, "actual", chatResp.Model)
 	}
 
-	resp := &Response{Content: Text{Value: chatResp.Choices[0].Message.Content}}
+	choice := chatResp.Choices[0]
+	resp := &Response{
+		Content:      Text{Value: choice.Message.Content},
+		FinishReason: NormalizeFinishReason(choice.FinishReason),
+		Metadata: newMetadata(chatResp.ID, chatResp.Model, "",
+			openRouterRawFinishReason(choice.FinishReason, choice.NativeFinishReason)),
+	}
 	if chatResp.Usage != nil {
 		resp.Usage = NewUsage(
 			chatResp.Usage.PromptTokens,
@@ -803,7 +821,8 @@ func (p *OpenRouterProvider) parseChatCompletion(body []byte) (*Response, error)
 func (p *OpenRouterProvider) parseTextCompletion(body []byte, chatErr error) (*Response, error) {
 	var textResp struct {
 		Choices []struct {
-			Text string `json:"text"`
+			Text         string `json:"text"`
+			FinishReason string `json:"finish_reason"`
 		} `json:"choices"`
 		Error struct {
 			Message string `json:"message"`
@@ -844,7 +863,12 @@ func (p *OpenRouterProvider) parseTextCompletion(body []byte, chatErr error) (*R
 
 	p.logger.Debug("Parsed text completion", "text", textResp.Choices[0].Text)
 
-	resp := &Response{Content: Text{Value: textResp.Choices[0].Text}}
+	choice := textResp.Choices[0]
+	resp := &Response{
+		Content:      Text{Value: choice.Text},
+		FinishReason: NormalizeFinishReason(choice.FinishReason),
+		Metadata:     newMetadata(textResp.ID, textResp.Model, "", choice.FinishReason),
+	}
 	if textResp.Usage != nil {
 		resp.Usage = NewUsage(
 			textResp.Usage.PromptTokens,
@@ -913,7 +937,8 @@ func (p *OpenRouterProvider) ParseStreamResponse(chunk []byte) (*Response, error
 					} `json:"function"`
 				} `json:"tool_calls"`
 			} `json:"delta"`
-			FinishReason string `json:"finish_reason"`
+			FinishReason       string `json:"finish_reason"`
+			NativeFinishReason string `json:"native_finish_reason"`
 		} `json:"choices"`
 		Error struct {
 			Message string `json:"message"`
@@ -954,6 +979,21 @@ func (p *OpenRouterProvider) ParseStreamResponse(chunk []byte) (*Response, error
 		usage = NewUsage(int64(resp.Usage.PromptTokens), 0, int64(resp.Usage.CompletionTokens), 0, 0)
 	}
 
+	// The final chunks carry the finish reason and usage, usually without content
+	if len(resp.Choices) > 0 && resp.Choices[0].FinishReason != "" {
+		choice := resp.Choices[0]
+		return &Response{
+			Content:      Text{Value: choice.Delta.Content},
+			Usage:        usage,
+			FinishReason: NormalizeFinishReason(choice.FinishReason),
+			Metadata: newMetadata(resp.ID, resp.Model, "",
+				openRouterRawFinishReason(choice.FinishReason, choice.NativeFinishReason)),
+		}, nil
+	}
+	if len(resp.Choices) == 0 && usage != nil {
+		return &Response{Usage: usage}, nil
+	}
+
 	// Check if we have at least one choice with content
 	if len(resp.Choices) == 0 || resp.Choices[0].Delta.Content == "" {
 		return nil, errors.New("skip token")
@@ -971,3 +1011,12 @@ func (p *OpenRouterProvider) ParseStreamResponse(chunk []byte) (*Response, error
 		Usage:   usage,
 	}, nil
 }
+
+// openRouterRawFinishReason prefers the upstream provider's native finish reason
+// over OpenRouter's normalized one.
+func openRouterRawFinishReason(finishReason, nativeFinishReason string) string {
+	if nativeFinishReason != "" {
+		return nativeFinishReason
+	}
+	return finishReason
+}
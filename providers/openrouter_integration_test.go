@@ -6,28 +6,27 @@ import (
 	"fmt"
 	"io"
 	"net/http"
-	"os"
 	"testing"
 	"time"
 
-	"github.com/invopop/jsonschema"
+	"github.com/google/jsonschema-go/jsonschema"
 	"github.com/stretchr/testify/require"
 
+	"github.com/weave-labs/gollm/cassette"
 	"github.com/weave-labs/gollm/internal/logging"
 )
 
-// TestOpenRouterIntegration performs integration tests against the actual OpenRouter API.
-// These tests require a valid API key set in the OPENROUTER_API_KEY environment variable.
+// TestOpenRouterIntegration performs integration tests against the OpenRouter API.
+// Subtests replay their cassette from testdata/cassettes when it has been recorded
+// and are skipped otherwise. Set GOLLM_CASSETTE_MODE=record and OPENROUTER_API_KEY
+// to record them against the actual API.
 func TestOpenRouterIntegration(t *testing.T) {
-	apiKey := os.Getenv("OPENROUTER_API_KEY")
-	if apiKey == "" {
-		t.Skip("Skipping OpenRouter integration tests. Set OPENROUTER_API_KEY to run them.")
-	}
-
 	// Create a logger to see detailed output
 	logger := logging.NewLogger(logging.LogLevelDebug)
 
 	t.Run("Basic Chat Completion", func(t *testing.T) {
+		rec, apiKey := openRouterCassette(t, "openrouter_basic_chat")
+
 		// Create provider with Claude model
 		provider := NewOpenRouterProvider(apiKey, "anthropic/claude-3-haiku", nil)
 		provider.SetLogger(logger)
@@ -50,7 +49,7 @@ func TestOpenRouterIntegration(t *testing.T) {
 		require.NoError(t, err)
 
 		// Make the actual API call
-		respBody, err := makeAPICall(t, provider.Endpoint(), provider.Headers(), requestBody)
+		respBody, err := makeAPICall(t, rec, provider.Endpoint(), provider.Headers(), requestBody)
 		require.NoError(t, err)
 		t.Logf("Response body: %s", string(respBody))
 
@@ -64,6 +63,8 @@ func TestOpenRouterIntegration(t *testing.T) {
 	})
 
 	t.Run("Model Fallback", func(t *testing.T) {
+		rec, apiKey := openRouterCassette(t, "openrouter_model_fallback")
+
 		// Create provider with an intentionally invalid model and fallbacks
 		provider := NewOpenRouterProvider(apiKey, "invalid-model", nil)
 		provider.SetLogger(logger)
@@ -87,7 +88,7 @@ func TestOpenRouterIntegration(t *testing.T) {
 		require.NoError(t, err)
 
 		// Make the actual API call
-		respBody, err := makeAPICall(t, provider.Endpoint(), provider.Headers(), requestBody)
+		respBody, err := makeAPICall(t, rec, provider.Endpoint(), provider.Headers(), requestBody)
 		require.NoError(t, err)
 		t.Logf("Response body: %s", string(respBody))
 
@@ -112,6 +113,8 @@ func TestOpenRouterIntegration(t *testing.T) {
 	})
 
 	t.Run("JSON Schema Validation", func(t *testing.T) {
+		rec, apiKey := openRouterCassette(t, "openrouter_json_schema")
+
 		// Create provider with Claude model (supports JSON schema)
 		provider := NewOpenRouterProvider(apiKey, "anthropic/claude-3-haiku", nil)
 		provider.SetLogger(logger)
@@ -154,7 +157,7 @@ func TestOpenRouterIntegration(t *testing.T) {
 		require.NoError(t, err)
 
 		// Make the actual API call
-		respBody, err := makeAPICall(t, provider.Endpoint(), provider.Headers(), requestBody)
+		respBody, err := makeAPICall(t, rec, provider.Endpoint(), provider.Headers(), requestBody)
 		require.NoError(t, err)
 		t.Logf("Response body: %s", string(respBody))
 
@@ -177,6 +180,8 @@ func TestOpenRouterIntegration(t *testing.T) {
 	})
 
 	t.Run("Message History with Reasoning", func(t *testing.T) {
+		rec, apiKey := openRouterCassette(t, "openrouter_message_history")
+
 		// Create provider with Claude model
 		provider := NewOpenRouterProvider(apiKey, "anthropic/claude-3-haiku", nil)
 		provider.SetLogger(logger)
@@ -204,7 +209,7 @@ func TestOpenRouterIntegration(t *testing.T) {
 		require.NoError(t, err)
 
 		// Make the actual API call
-		respBody, err := makeAPICall(t, provider.Endpoint(), provider.Headers(), requestBody)
+		respBody, err := makeAPICall(t, rec, provider.Endpoint(), provider.Headers(), requestBody)
 		require.NoError(t, err)
 		t.Logf("Response body: %s", string(respBody))
 
@@ -220,6 +225,8 @@ func TestOpenRouterIntegration(t *testing.T) {
 	})
 
 	t.Run("Tool Calling", func(t *testing.T) {
+		rec, apiKey := openRouterCassette(t, "openrouter_tool_calling")
+
 		// Use a model that supports tool calling
 		provider := NewOpenRouterProvider(apiKey, "openai/gpt-4o", nil)
 		provider.SetLogger(logger)
@@ -265,7 +272,7 @@ func TestOpenRouterIntegration(t *testing.T) {
 		require.NoError(t, err)
 
 		// Make the actual API call
-		respBody, err := makeAPICall(t, provider.Endpoint(), provider.Headers(), requestBody)
+		respBody, err := makeAPICall(t, rec, provider.Endpoint(), provider.Headers(), requestBody)
 		require.NoError(t, err)
 		t.Logf("Response body: %s", string(respBody))
 
@@ -313,13 +320,33 @@ func TestOpenRouterIntegration(t *testing.T) {
 	})
 }
 
-// makeAPICall makes an actual HTTP request to the OpenRouter API
-func makeAPICall(t *testing.T, endpoint string, headers map[string]string, requestBody []byte) ([]byte, error) {
+// openRouterCassette returns the recorder for a subtest's cassette and the API
+// key to send through it.
+func openRouterCassette(t *testing.T, name string) (*cassette.Recorder, string) {
+	t.Helper()
+
+	rec := cassette.ForTest(t, name)
+	apiKey := rec.APIKey("OPENROUTER_API_KEY")
+	if apiKey == "" {
+		t.Skip("Skipping OpenRouter integration tests. Set OPENROUTER_API_KEY to run them.")
+	}
+	return rec, apiKey
+}
+
+// makeAPICall makes an HTTP request to the OpenRouter API through transport
+func makeAPICall(
+	t *testing.T,
+	transport http.RoundTripper,
+	endpoint string,
+	headers map[string]string,
+	requestBody []byte,
+) ([]byte, error) {
 	t.Helper()
 
 	// Create an HTTP client with a reasonable timeout
 	httpClient := &http.Client{
-		Timeout: 30 * time.Second,
+		Transport: transport,
+		Timeout:   30 * time.Second,
 	}
 
 	// Create the request
@@ -4,7 +4,7 @@ import (
 	"encoding/json"
 	"testing"
 
-	"github.com/invopop/jsonschema"
+	"github.com/google/jsonschema-go/jsonschema"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 
@@ -292,9 +292,11 @@ func TestOpenRouterProvider(t *testing.T) {
 		responseFormat, ok := req["response_format"].(map[string]any)
 		assert.True(t, ok, "response_format should be present")
 		if ok {
-			assert.Equal(t, "json_object", responseFormat["type"])
+			assert.Equal(t, "json_schema", responseFormat["type"])
+			jsonSchema, ok := responseFormat["json_schema"].(map[string]any)
+			require.True(t, ok, "json_schema should be present in response_format")
 			// Compare the schema as map[string]any since unmarshaling loses the type
-			schemaFromReq, ok := responseFormat["schema"].(map[string]any)
+			schemaFromReq, ok := jsonSchema["schema"].(map[string]any)
 			assert.True(t, ok, "schema should be present in response_format")
 			assert.NotNil(t, schemaFromReq)
 			// Just check that schema has expected structure
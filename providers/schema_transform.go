@@ -0,0 +1,384 @@
+package providers
+
+import (
+	"fmt"
+	"maps"
+	"reflect"
+	"slices"
+	"strings"
+
+	"github.com/google/jsonschema-go/jsonschema"
+)
+
+// SchemaWarning describes a lossy change made to a response schema while
+// transforming it for a provider.
+type SchemaWarning struct {
+	// Path is the JSON pointer of the affected subschema; empty for the root.
+	Path    string
+	Message string
+}
+
+// String returns the warning prefixed with its path.
+func (w SchemaWarning) String() string {
+	if w.Path == "" {
+		return "/: " + w.Message
+	}
+	return w.Path + ": " + w.Message
+}
+
+// SchemaTransform rewrites a response schema in place and reports any change
+// that loosens or drops constraints.
+type SchemaTransform func(schema *jsonschema.Schema) []SchemaWarning
+
+// SchemaTransformer is implemented by providers that accept only part of JSON
+// Schema. SchemaTransforms returns the pipeline applied to a response schema
+// before a request for the model is prepared.
+type SchemaTransformer interface {
+	SchemaTransforms(model string) []SchemaTransform
+}
+
+// TransformSchema applies transforms in order to a copy of schema and returns
+// the copy with the warnings of every transform. schema is not modified.
+func TransformSchema(schema *jsonschema.Schema, transforms ...SchemaTransform) (*jsonschema.Schema, []SchemaWarning) {
+	if schema == nil {
+		return nil, nil
+	}
+	transformed := schema.CloneSchemas()
+	var warnings []SchemaWarning
+	for _, transform := range transforms {
+		warnings = append(warnings, transform(transformed)...)
+	}
+	return transformed, warnings
+}
+
+// TransformResponseSchema applies the provider's schema pipeline for the model.
+// Providers that do not implement SchemaTransformer get InlineSchemaRefs, since
+// most reject $ref and $defs.
+func TransformResponseSchema(
+	provider Provider,
+	model string,
+	schema *jsonschema.Schema,
+) (*jsonschema.Schema, []SchemaWarning) {
+	transforms := []SchemaTransform{InlineSchemaRefs}
+	if transformer, ok := provider.(SchemaTransformer); ok {
+		transforms = transformer.SchemaTransforms(model)
+	}
+	return TransformSchema(schema, transforms...)
+}
+
+// InlineSchemaRefs replaces every reference to $defs or definitions with a copy
+// of its target and removes the definitions. A recursive reference cannot be
+// inlined and is replaced by a schema that accepts any value.
+func InlineSchemaRefs(schema *jsonschema.Schema) []SchemaWarning {
+	var warnings []SchemaWarning
+	var inline func(s *jsonschema.Schema, path string, expanding []string)
+	inline = func(s *jsonschema.Schema, path string, expanding []string) {
+		if ref := s.Ref; ref != "" {
+			target := lookupDef(schema, ref)
+			switch {
+			case target == nil:
+				warnings = append(warnings, SchemaWarning{path, fmt.Sprintf(
+					"unresolved reference %q replaced by a schema that accepts any value", ref)})
+				*s = jsonschema.Schema{Title: s.Title, Description: s.Description}
+			case slices.Contains(expanding, ref):
+				warnings = append(warnings, SchemaWarning{path, fmt.Sprintf(
+					"recursive reference %q replaced by a schema that accepts any value", ref)})
+				*s = jsonschema.Schema{Title: s.Title, Description: s.Description}
+			default:
+				expanded := target.CloneSchemas()
+				if s.Title != "" {
+					expanded.Title = s.Title
+				}
+				if s.Description != "" {
+					expanded.Description = s.Description
+				}
+				*s = *expanded
+				expanding = append(slices.Clip(expanding), ref)
+			}
+		}
+		eachSubschema(s, path, false, func(child *jsonschema.Schema, childPath string) {
+			inline(child, childPath, expanding)
+		})
+	}
+	inline(schema, "", nil)
+	schema.Defs, schema.Definitions = nil, nil
+	return warnings
+}
+
+// NullableOptionalProperties marks every property of every object as required
+// and lets the properties that were optional be null instead, as OpenAI strict
+// mode requires. StripOptionalNulls reverses it on a response.
+func NullableOptionalProperties(schema *jsonschema.Schema) []SchemaWarning {
+	walkSchema(schema, func(s *jsonschema.Schema, _ string) {
+		for _, name := range slices.Sorted(maps.Keys(s.Properties)) {
+			if !slices.Contains(s.Required, name) {
+				makeNullable(s.Properties[name])
+				s.Required = append(s.Required, name)
+			}
+		}
+	})
+	return nil
+}
+
+// CloseObjects sets additionalProperties to false on every object. An object
+// whose additionalProperties allowed values, such as a map, loses them.
+func CloseObjects(schema *jsonschema.Schema) []SchemaWarning {
+	var warnings []SchemaWarning
+	walkSchema(schema, func(s *jsonschema.Schema, path string) {
+		if !isObjectSchema(s) || isFalseSchema(s.AdditionalProperties) {
+			return
+		}
+		if s.AdditionalProperties != nil {
+			warnings = append(warnings, SchemaWarning{path, "additional properties are no longer allowed"})
+		}
+		s.AdditionalProperties = &jsonschema.Schema{Not: &jsonschema.Schema{}}
+	})
+	return warnings
+}
+
+// OneOfToAnyOf replaces oneOf with anyOf, which no longer requires exactly one
+// alternative to match.
+func OneOfToAnyOf(schema *jsonschema.Schema) []SchemaWarning {
+	var warnings []SchemaWarning
+	walkSchema(schema, func(s *jsonschema.Schema, path string) {
+		if s.OneOf == nil {
+			return
+		}
+		warnings = append(warnings, SchemaWarning{path, "oneOf replaced by anyOf"})
+		s.AnyOf = append(s.AnyOf, s.OneOf...)
+		s.OneOf = nil
+	})
+	return warnings
+}
+
+// RemoveSchemaKeywords returns a transform that removes the keywords wherever
+// they appear. Removing a keyword that constrains values is reported; removing
+// an annotation such as title or default is not.
+func RemoveSchemaKeywords(keywords ...string) SchemaTransform {
+	return func(schema *jsonschema.Schema) []SchemaWarning {
+		var warnings []SchemaWarning
+		walkSchema(schema, func(s *jsonschema.Schema, path string) {
+			for _, keyword := range keywords {
+				if removeKeyword(s, keyword) && !annotationKeywords[keyword] {
+					warnings = append(warnings, SchemaWarning{path, fmt.Sprintf("unsupported keyword %q removed", keyword)})
+				}
+			}
+		})
+		return warnings
+	}
+}
+
+// StripOptionalNulls removes null object members from instance, a decoded JSON
+// value, where schema does not require the property and does not allow null.
+// It undoes NullableOptionalProperties so the response validates against the
+// original schema.
+func StripOptionalNulls(schema *jsonschema.Schema, instance any) {
+	stripOptionalNulls(schema, schema, instance, map[*jsonschema.Schema]bool{})
+}
+
+func stripOptionalNulls(root, s *jsonschema.Schema, instance any, visiting map[*jsonschema.Schema]bool) {
+	if s == nil || visiting[s] {
+		return
+	}
+	if s.Ref != "" {
+		visiting[s] = true
+		defer delete(visiting, s)
+		stripOptionalNulls(root, lookupDef(root, s.Ref), instance, visiting)
+		return
+	}
+
+	switch value := instance.(type) {
+	case map[string]any:
+		for name, member := range value {
+			property, ok := s.Properties[name]
+			if !ok {
+				continue
+			}
+			if member == nil && !slices.Contains(s.Required, name) && !allowsNull(root, property) {
+				delete(value, name)
+				continue
+			}
+			stripOptionalNulls(root, property, member, visiting)
+		}
+	case []any:
+		for i, item := range value {
+			if i < len(s.PrefixItems) {
+				stripOptionalNulls(root, s.PrefixItems[i], item, visiting)
+			} else {
+				stripOptionalNulls(root, s.Items, item, visiting)
+			}
+		}
+	}
+}
+
+// annotationKeywords do not constrain the values a schema accepts.
+var annotationKeywords = map[string]bool{
+	"$schema": true, "$id": true, "$comment": true, "$anchor": true, "$dynamicAnchor": true,
+	"$vocabulary": true, "$defs": true, "definitions": true, "title": true, "description": true,
+	"default": true, "examples": true, "deprecated": true, "readOnly": true, "writeOnly": true,
+}
+
+// removeKeyword clears keyword from s and reports whether it was set.
+func removeKeyword(s *jsonschema.Schema, keyword string) bool {
+	if _, ok := s.Extra[keyword]; ok {
+		delete(s.Extra, keyword)
+		return true
+	}
+	if keyword == "type" {
+		set := s.Type != "" || s.Types != nil
+		s.Type, s.Types = "", nil
+		return set
+	}
+
+	v := reflect.ValueOf(s).Elem()
+	for i := range v.NumField() {
+		name, _, _ := strings.Cut(v.Type().Field(i).Tag.Get("json"), ",")
+		if name != keyword {
+			continue
+		}
+		field := v.Field(i)
+		if field.IsZero() {
+			return false
+		}
+		field.SetZero()
+		return true
+	}
+	return false
+}
+
+// makeNullable lets s also accept null.
+func makeNullable(s *jsonschema.Schema) {
+	switch {
+	case allowsNull(nil, s):
+		return
+	case s.Type != "" && s.Const == nil:
+		s.Types = []string{s.Type, "null"}
+		s.Type = ""
+	case s.Types != nil && s.Const == nil:
+		s.Types = append(s.Types, "null")
+	default:
+		inner := s.CloneSchemas()
+		inner.Title, inner.Description = "", ""
+		*s = jsonschema.Schema{
+			Title:       s.Title,
+			Description: s.Description,
+			AnyOf:       []*jsonschema.Schema{inner, {Type: "null"}},
+		}
+		return
+	}
+	if s.Enum != nil && !slices.ContainsFunc(s.Enum, func(v any) bool { return v == nil }) {
+		s.Enum = append(s.Enum, nil)
+	}
+}
+
+// allowsNull reports whether s explicitly accepts null. References are followed
+// when root is not nil.
+func allowsNull(root, s *jsonschema.Schema) bool {
+	if s.Ref != "" && root != nil {
+		if target := lookupDef(root, s.Ref); target != nil && target != s {
+			return allowsNull(nil, target)
+		}
+	}
+	if s.Type == "null" || slices.Contains(s.Types, "null") {
+		return true
+	}
+	return slices.ContainsFunc(s.AnyOf, func(alternative *jsonschema.Schema) bool {
+		return alternative.Type == "null"
+	})
+}
+
+// isFalseSchema reports whether s is the schema false, which accepts no value.
+func isFalseSchema(s *jsonschema.Schema) bool {
+	if s == nil || s.Not == nil || !reflect.ValueOf(*s.Not).IsZero() {
+		return false
+	}
+	rest := *s
+	rest.Not = nil
+	return reflect.ValueOf(rest).IsZero()
+}
+
+// isStrictSchema reports whether schema meets OpenAI strict mode: an object
+// whose objects all forbid additional properties and require every property.
+func isStrictSchema(schema *jsonschema.Schema) bool {
+	if schema == nil || !isObjectSchema(schema) {
+		return false
+	}
+	strict := true
+	walkSchema(schema, func(s *jsonschema.Schema, _ string) {
+		if !isObjectSchema(s) {
+			return
+		}
+		if !isFalseSchema(s.AdditionalProperties) {
+			strict = false
+		}
+		for name := range s.Properties {
+			if !slices.Contains(s.Required, name) {
+				strict = false
+			}
+		}
+	})
+	return strict
+}
+
+// walkSchema calls f for schema and every subschema, including definitions,
+// with the JSON pointer of each. The schema false is skipped.
+func walkSchema(schema *jsonschema.Schema, f func(s *jsonschema.Schema, path string)) {
+	var walk func(s *jsonschema.Schema, path string)
+	walk = func(s *jsonschema.Schema, path string) {
+		if isFalseSchema(s) {
+			return
+		}
+		f(s, path)
+		eachSubschema(s, path, true, walk)
+	}
+	walk(schema, "")
+}
+
+// eachSubschema calls f for every immediate subschema of s in a stable order.
+// Definitions are included only when defs is true.
+func eachSubschema(s *jsonschema.Schema, path string, defs bool, f func(child *jsonschema.Schema, path string)) {
+	one := func(keyword string, child *jsonschema.Schema) {
+		if child != nil {
+			f(child, path+"/"+keyword)
+		}
+	}
+	list := func(keyword string, children []*jsonschema.Schema) {
+		for i, child := range children {
+			one(fmt.Sprintf("%s/%d", keyword, i), child)
+		}
+	}
+	named := func(keyword string, children map[string]*jsonschema.Schema) {
+		for _, name := range slices.Sorted(maps.Keys(children)) {
+			one(keyword+"/"+escapePointer(name), children[name])
+		}
+	}
+
+	if defs {
+		named("$defs", s.Defs)
+		named("definitions", s.Definitions)
+	}
+	named("properties", s.Properties)
+	named("patternProperties", s.PatternProperties)
+	named("dependentSchemas", s.DependentSchemas)
+	one("additionalProperties", s.AdditionalProperties)
+	one("propertyNames", s.PropertyNames)
+	one("unevaluatedProperties", s.UnevaluatedProperties)
+	list("prefixItems", s.PrefixItems)
+	one("items", s.Items)
+	one("additionalItems", s.AdditionalItems)
+	one("contains", s.Contains)
+	one("unevaluatedItems", s.UnevaluatedItems)
+	list("allOf", s.AllOf)
+	list("anyOf", s.AnyOf)
+	list("oneOf", s.OneOf)
+	one("not", s.Not)
+	one("if", s.If)
+	one("then", s.Then)
+	one("else", s.Else)
+	one("contentSchema", s.ContentSchema)
+}
+
+// escapePointer escapes a property name for use in a JSON pointer.
+func escapePointer(name string) string {
+	return strings.NewReplacer("~", "~0", "/", "~1").Replace(name)
+}
@@ -0,0 +1,196 @@
+package providers
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/google/jsonschema-go/jsonschema"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// schemaJSON unmarshals a schema literal.
+func schemaJSON(t *testing.T, data string) *jsonschema.Schema {
+	t.Helper()
+	var schema jsonschema.Schema
+	require.NoError(t, json.Unmarshal([]byte(data), &schema))
+	return &schema
+}
+
+func assertSchemaJSON(t *testing.T, expected string, schema *jsonschema.Schema) {
+	t.Helper()
+	data, err := json.Marshal(schema)
+	require.NoError(t, err)
+	assert.JSONEq(t, expected, string(data))
+}
+
+func TestTransformSchemaCopies(t *testing.T) {
+	schema := schemaJSON(t, `{"type":"object","properties":{"a":{"type":"string"}}}`)
+	transformed, warnings := TransformSchema(schema, CloseObjects)
+	assert.Empty(t, warnings)
+	assert.Nil(t, schema.AdditionalProperties, "the original schema is unchanged")
+	assertSchemaJSON(t, `{"type":"object","properties":{"a":{"type":"string"}},"additionalProperties":false}`,
+		transformed)
+}
+
+func TestInlineSchemaRefs(t *testing.T) {
+	schema := schemaJSON(t, `{
+		"type": "object",
+		"properties": {
+			"home": {"$ref": "#/$defs/address", "description": "Where they live"},
+			"tree": {"$ref": "#/$defs/node"}
+		},
+		"$defs": {
+			"address": {"type": "object", "properties": {"city": {"type": "string"}}},
+			"node": {"type": "object", "properties": {"children": {"type": "array", "items": {"$ref": "#/$defs/node"}}}}
+		}
+	}`)
+
+	transformed, warnings := TransformSchema(schema, InlineSchemaRefs)
+	assertSchemaJSON(t, `{
+		"type": "object",
+		"properties": {
+			"home": {"type": "object", "description": "Where they live", "properties": {"city": {"type": "string"}}},
+			"tree": {"type": "object", "properties": {"children": {"type": "array", "items": true}}}
+		}
+	}`, transformed)
+	require.Len(t, warnings, 1)
+	assert.Equal(t, "/properties/tree/properties/children/items", warnings[0].Path)
+	assert.Contains(t, warnings[0].Message, "recursive reference")
+}
+
+func TestNullableOptionalProperties(t *testing.T) {
+	schema := schemaJSON(t, `{
+		"type": "object",
+		"required": ["name"],
+		"properties": {
+			"name": {"type": "string"},
+			"size": {"type": "string", "enum": ["S", "M"]},
+			"tags": {"type": ["array", "null"], "items": {"type": "string"}},
+			"kind": {"const": "shirt"}
+		}
+	}`)
+
+	transformed, warnings := TransformSchema(schema, NullableOptionalProperties, CloseObjects)
+	assert.Empty(t, warnings)
+	assertSchemaJSON(t, `{
+		"type": "object",
+		"required": ["name", "kind", "size", "tags"],
+		"additionalProperties": false,
+		"properties": {
+			"name": {"type": "string"},
+			"size": {"type": ["string", "null"], "enum": ["S", "M", null]},
+			"tags": {"type": ["array", "null"], "items": {"type": "string"}},
+			"kind": {"anyOf": [{"const": "shirt"}, {"type": "null"}]}
+		}
+	}`, transformed)
+	assert.True(t, isStrictSchema(transformed))
+	assert.False(t, isStrictSchema(schema))
+}
+
+func TestStripOptionalNulls(t *testing.T) {
+	schema := schemaJSON(t, `{
+		"type": "object",
+		"required": ["name"],
+		"properties": {
+			"name": {"type": ["string", "null"]},
+			"size": {"type": "string"},
+			"items": {"type": "array", "items": {"type": "object", "properties": {"note": {"type": "string"}}}}
+		}
+	}`)
+
+	var instance any
+	require.NoError(t, json.Unmarshal([]byte(`{"name":null,"size":null,"items":[{"note":null}]}`), &instance))
+	StripOptionalNulls(schema, instance)
+	assert.Equal(t, map[string]any{"name": nil, "items": []any{map[string]any{}}}, instance)
+}
+
+func TestCloseObjectsWarnsForMaps(t *testing.T) {
+	schema := schemaJSON(t, `{"type":"object","additionalProperties":{"type":"integer"}}`)
+	transformed, warnings := TransformSchema(schema, CloseObjects)
+	assert.True(t, isFalseSchema(transformed.AdditionalProperties))
+	require.Len(t, warnings, 1)
+	assert.Equal(t, "/: additional properties are no longer allowed", warnings[0].String())
+}
+
+func TestRemoveSchemaKeywords(t *testing.T) {
+	schema := schemaJSON(t, `{
+		"$schema": "https://json-schema.org/draft/2020-12/schema",
+		"type": "object",
+		"properties": {"code": {"type": "string", "pattern": "^[A-Z]+$", "title": "Code"}}
+	}`)
+
+	transformed, warnings := TransformSchema(schema, RemoveSchemaKeywords("$schema", "pattern", "title"))
+	assertSchemaJSON(t, `{"type":"object","properties":{"code":{"type":"string"}}}`, transformed)
+	require.Len(t, warnings, 1, "annotations are removed silently")
+	assert.Equal(t, SchemaWarning{Path: "/properties/code", Message: `unsupported keyword "pattern" removed`},
+		warnings[0])
+}
+
+func TestGeminiSchemaTransforms(t *testing.T) {
+	schema := schemaJSON(t, `{
+		"$schema": "https://json-schema.org/draft/2020-12/schema",
+		"type": "object",
+		"additionalProperties": false,
+		"properties": {
+			"nickname": {"type": ["string", "null"]},
+			"status": {"const": "active"},
+			"score": {"anyOf": [{"type": "number", "exclusiveMinimum": 0}, {"type": "null"}]},
+			"pet": {"$ref": "#/$defs/pet"}
+		},
+		"$defs": {"pet": {"oneOf": [{"type": "string"}, {"type": "integer"}]}}
+	}`)
+
+	provider := NewGeminiProvider("fake-key", "gemini-2.0-flash", nil)
+	transformed, warnings := TransformResponseSchema(provider, "gemini-2.0-flash", schema)
+	assertSchemaJSON(t, `{
+		"type": "object",
+		"properties": {
+			"nickname": {"type": "string", "nullable": true},
+			"status": {"enum": ["active"]},
+			"score": {"type": "number", "nullable": true},
+			"pet": {"anyOf": [{"type": "string"}, {"type": "integer"}]}
+		}
+	}`, transformed)
+
+	messages := make([]string, 0, len(warnings))
+	for _, warning := range warnings {
+		messages = append(messages, warning.String())
+	}
+	assert.ElementsMatch(t, []string{
+		"/properties/pet: oneOf replaced by anyOf",
+		`/: unsupported keyword "additionalProperties" removed`,
+		`/properties/score: unsupported keyword "exclusiveMinimum" removed`,
+	}, messages)
+}
+
+func TestOpenAISchemaTransforms(t *testing.T) {
+	schema := citySchema(t)
+	schema.Required = []string{"name"}
+
+	provider := NewOpenAIProvider("fake-key", "gpt-4o", nil)
+	transformed, warnings := TransformResponseSchema(provider, "gpt-4o", schema)
+	assert.Empty(t, warnings)
+	require.True(t, isStrictSchema(transformed))
+
+	data, err := provider.PrepareRequest(&Request{
+		Messages:       []Message{{Role: "user", Content: "Describe Paris"}},
+		ResponseSchema: transformed,
+	}, nil)
+	require.NoError(t, err)
+	var body map[string]any
+	require.NoError(t, json.Unmarshal(data, &body))
+	format := body["response_format"].(map[string]any)["json_schema"].(map[string]any)
+	assert.Equal(t, true, format["strict"])
+
+	unchanged, _ := TransformResponseSchema(provider, "gpt-4-turbo", schema)
+	assert.Equal(t, schema, unchanged, "JSON mode models see the schema in the prompt unchanged")
+}
+
+func TestTransformResponseSchemaDefault(t *testing.T) {
+	schema := schemaJSON(t, `{"$ref":"#/$defs/a","$defs":{"a":{"type":"string"}}}`)
+	transformed, warnings := TransformResponseSchema(NewAnthropicProvider("fake-key", "claude-3-5-haiku", nil),
+		"claude-3-5-haiku", schema)
+	assert.Empty(t, warnings)
+	assertSchemaJSON(t, `{"type":"string"}`, transformed)
+}
@@ -0,0 +1,230 @@
+package providers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/google/jsonschema-go/jsonschema"
+
+	"github.com/weave-labs/weave-go/weaveapi/llmx/v1"
+)
+
+// StructuredOutputStrategy describes how a structured response is requested from a model.
+type StructuredOutputStrategy string
+
+const (
+	// StructuredOutputNative passes the schema in the provider's JSON schema response format.
+	StructuredOutputNative StructuredOutputStrategy = "native"
+	// StructuredOutputJSONMode enables the provider's JSON mode, which guarantees
+	// valid JSON but not its shape, and describes the schema in the system prompt.
+	StructuredOutputJSONMode StructuredOutputStrategy = "json_mode"
+	// StructuredOutputToolCall forces a call to a single tool whose parameters are
+	// the schema, and returns the tool arguments as the response text.
+	StructuredOutputToolCall StructuredOutputStrategy = "tool_call"
+	// StructuredOutputPrompt only describes the schema in the system prompt. The
+	// JSON value is extracted from the response text.
+	StructuredOutputPrompt StructuredOutputStrategy = "prompt"
+)
+
+// StructuredOutputToolName is the name of the tool used by StructuredOutputToolCall.
+const StructuredOutputToolName = "structured_response"
+
+// structuredOutputToolDescription describes the structured output tool to the model.
+const structuredOutputToolDescription = "Respond to the user with a value that matches the input schema."
+
+// ErrSchemaTooDeep is returned when a response schema nests objects and arrays
+// deeper than the model's registered MaxSchemaDepth.
+var ErrSchemaTooDeep = errors.New("response schema is nested too deeply for the model")
+
+// SelectStructuredOutputStrategy picks how a response matching schema is requested
+// from a model, based on its registered structured response capability:
+//
+//   - no capability: StructuredOutputPrompt
+//   - RequiresToolUse: StructuredOutputToolCall, or StructuredOutputPrompt when the
+//     schema does not describe an object, since tool parameters must be objects
+//   - RequiresJsonMode: StructuredOutputJSONMode
+//   - otherwise: StructuredOutputNative
+//
+// schema may be nil, in which case only the capability is considered.
+func SelectStructuredOutputStrategy(provider, model string, schema *jsonschema.Schema) StructuredOutputStrategy {
+	capability := structuredResponseCapability(provider, model)
+	switch {
+	case capability == nil:
+		return StructuredOutputPrompt
+	case capability.GetRequiresToolUse():
+		if schema != nil && !isObjectSchema(schema) {
+			return StructuredOutputPrompt
+		}
+		return StructuredOutputToolCall
+	case capability.GetRequiresJsonMode():
+		return StructuredOutputJSONMode
+	default:
+		return StructuredOutputNative
+	}
+}
+
+// CheckSchemaDepth returns an error wrapping ErrSchemaTooDeep when schema is
+// deeper than the MaxSchemaDepth registered for the model. Models without a
+// registered limit accept any depth.
+func CheckSchemaDepth(provider, model string, schema *jsonschema.Schema) error {
+	limit := int(structuredResponseCapability(provider, model).GetMaxSchemaDepth())
+	if schema == nil || limit <= 0 {
+		return nil
+	}
+	if depth := SchemaDepth(schema); depth > limit {
+		return fmt.Errorf("%w: depth %d exceeds the limit of %d for %s", ErrSchemaTooDeep, depth, limit,
+			makeSlug(provider, model))
+	}
+	return nil
+}
+
+// SchemaDepth returns the number of nested object and array levels in schema.
+// A flat object has depth 1 and a scalar has depth 0. References to $defs are
+// followed; a recursive reference is counted once.
+func SchemaDepth(schema *jsonschema.Schema) int {
+	return schemaDepth(schema, schema, map[*jsonschema.Schema]bool{})
+}
+
+func schemaDepth(root, s *jsonschema.Schema, visiting map[*jsonschema.Schema]bool) int {
+	if s == nil || visiting[s] {
+		return 0
+	}
+	visiting[s] = true
+	defer delete(visiting, s)
+
+	children := 0
+	descend := func(child *jsonschema.Schema) {
+		children = max(children, schemaDepth(root, child, visiting))
+	}
+	for _, child := range s.Properties {
+		descend(child)
+	}
+	for _, child := range s.PrefixItems {
+		descend(child)
+	}
+	descend(s.Items)
+	descend(s.AdditionalProperties)
+
+	// Alternatives and references describe the same level as s.
+	depth := 0
+	for _, alternatives := range [][]*jsonschema.Schema{s.AllOf, s.AnyOf, s.OneOf} {
+		for _, alternative := range alternatives {
+			depth = max(depth, schemaDepth(root, alternative, visiting))
+		}
+	}
+	if s.Ref != "" {
+		depth = max(depth, schemaDepth(root, lookupDef(root, s.Ref), visiting))
+	}
+
+	if isObjectSchema(s) || isArraySchema(s) {
+		return max(depth, children+1)
+	}
+	return max(depth, children)
+}
+
+// lookupDef returns the schema in root's $defs or definitions that ref points to.
+func lookupDef(root *jsonschema.Schema, ref string) *jsonschema.Schema {
+	if name, ok := strings.CutPrefix(ref, "#/$defs/"); ok {
+		return root.Defs[name]
+	}
+	if name, ok := strings.CutPrefix(ref, "#/definitions/"); ok {
+		return root.Definitions[name]
+	}
+	return nil
+}
+
+func isObjectSchema(s *jsonschema.Schema) bool {
+	return s.Type == "object" || slices.Contains(s.Types, "object") || s.Properties != nil
+}
+
+func isArraySchema(s *jsonschema.Schema) bool {
+	return s.Type == "array" || slices.Contains(s.Types, "array") || s.Items != nil || s.PrefixItems != nil
+}
+
+// structuredResponseCapability returns the structured response capability
+// registered for the model, or nil.
+func structuredResponseCapability(provider, model string) *llmx.StructuredResponse {
+	capability, _ := GetCapabilityRegistry().GetConfig(
+		provider, model, llmx.CapabilityType_CAPABILITY_TYPE_STRUCTURED_RESPONSE,
+	).(*llmx.StructuredResponse)
+	return capability
+}
+
+// resolveStructuredOutput checks that the request schema fits the model and
+// selects the strategy used to request it. supported lists the strategies the
+// calling provider implements besides StructuredOutputPrompt; any other strategy
+// falls back to JSON mode when supported and to prompt instructions otherwise.
+// The strategy is empty when the request has no schema.
+func resolveStructuredOutput(
+	provider, model string,
+	req *Request,
+	supported ...StructuredOutputStrategy,
+) (StructuredOutputStrategy, error) {
+	if req.ResponseSchema == nil {
+		return "", nil
+	}
+	if err := CheckSchemaDepth(provider, model, req.ResponseSchema); err != nil {
+		return "", err
+	}
+
+	strategy := SelectStructuredOutputStrategy(provider, model, req.ResponseSchema)
+	switch {
+	case strategy == StructuredOutputPrompt || slices.Contains(supported, strategy):
+		return strategy, nil
+	case slices.Contains(supported, StructuredOutputJSONMode):
+		return StructuredOutputJSONMode, nil
+	default:
+		return StructuredOutputPrompt, nil
+	}
+}
+
+// structuredOutputSystemPrompt appends instructions describing the schema to
+// systemPrompt for the strategies that rely on the prompt to convey it.
+func structuredOutputSystemPrompt(
+	systemPrompt string,
+	schema *jsonschema.Schema,
+	strategy StructuredOutputStrategy,
+) (string, error) {
+	if schema == nil || (strategy != StructuredOutputJSONMode && strategy != StructuredOutputPrompt) {
+		return systemPrompt, nil
+	}
+
+	schemaJSON, err := json.MarshalIndent(schema, "", "  ")
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal response schema: %w", err)
+	}
+	instructions := fmt.Sprintf(
+		"You must respond with a JSON value that strictly adheres to this schema:\n%s\n"+
+			"Do not include any explanatory text or Markdown, only output valid JSON.",
+		schemaJSON,
+	)
+
+	if systemPrompt == "" {
+		return instructions, nil
+	}
+	return systemPrompt + "\n\n" + instructions, nil
+}
+
+// openAIResponseFormat returns the OpenAI-compatible response_format for the
+// strategy, or nil when the strategy does not use one. strict enables strict
+// schema adherence, which needs a schema that passes isStrictSchema.
+func openAIResponseFormat(schema *jsonschema.Schema, strategy StructuredOutputStrategy, strict bool) map[string]any {
+	switch strategy {
+	case StructuredOutputNative:
+		return map[string]any{
+			"type": "json_schema",
+			"json_schema": map[string]any{
+				"name":   "response",
+				"schema": schema,
+				"strict": strict,
+			},
+		}
+	case StructuredOutputJSONMode:
+		return map[string]any{"type": "json_object"}
+	default:
+		return nil
+	}
+}
@@ -0,0 +1,210 @@
+package providers
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/google/jsonschema-go/jsonschema"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/weave-labs/weave-go/weaveapi/llmx/v1"
+)
+
+type structuredCity struct {
+	Name    string `json:"name"`
+	Country struct {
+		Name string `json:"name"`
+	} `json:"country"`
+	Landmarks []struct {
+		Name string `json:"name"`
+	} `json:"landmarks"`
+}
+
+func citySchema(t *testing.T) *jsonschema.Schema {
+	t.Helper()
+	schema, err := jsonschema.For[structuredCity](nil)
+	require.NoError(t, err)
+	return schema
+}
+
+func TestSelectStructuredOutputStrategy(t *testing.T) {
+	const provider = "structured-output-test"
+	registry := GetCapabilityRegistry()
+	structured := llmx.CapabilityType_CAPABILITY_TYPE_STRUCTURED_RESPONSE
+	registry.RegisterCapability(provider, "native", structured, &llmx.StructuredResponse{})
+	registry.RegisterCapability(provider, "json", structured, &llmx.StructuredResponse{RequiresJsonMode: true})
+	registry.RegisterCapability(provider, "tool", structured, &llmx.StructuredResponse{RequiresToolUse: true})
+
+	schema := citySchema(t)
+	assert.Equal(t, StructuredOutputNative, SelectStructuredOutputStrategy(provider, "native", schema))
+	assert.Equal(t, StructuredOutputJSONMode, SelectStructuredOutputStrategy(provider, "json", schema))
+	assert.Equal(t, StructuredOutputToolCall, SelectStructuredOutputStrategy(provider, "tool", schema))
+	assert.Equal(t, StructuredOutputPrompt, SelectStructuredOutputStrategy(provider, "unregistered", schema))
+
+	list := &jsonschema.Schema{Type: "array", Items: &jsonschema.Schema{Type: "string"}}
+	assert.Equal(t, StructuredOutputPrompt, SelectStructuredOutputStrategy(provider, "tool", list),
+		"tool parameters must be objects")
+}
+
+func TestSchemaDepth(t *testing.T) {
+	assert.Equal(t, 0, SchemaDepth(&jsonschema.Schema{Type: "string"}))
+	assert.Equal(t, 1, SchemaDepth(&jsonschema.Schema{
+		Type:       "object",
+		Properties: map[string]*jsonschema.Schema{"name": {Type: "string"}},
+	}))
+	assert.Equal(t, 3, SchemaDepth(citySchema(t)), "object, landmarks array, landmark object")
+
+	recursive := &jsonschema.Schema{
+		Ref: "#/$defs/node",
+		Defs: map[string]*jsonschema.Schema{"node": {
+			Type: "object",
+			Properties: map[string]*jsonschema.Schema{
+				"children": {Type: "array", Items: &jsonschema.Schema{Ref: "#/$defs/node"}},
+			},
+		}},
+	}
+	assert.Equal(t, 2, SchemaDepth(recursive), "a recursive reference is counted once")
+}
+
+func TestCheckSchemaDepth(t *testing.T) {
+	const provider = "structured-output-test"
+	GetCapabilityRegistry().RegisterCapability(provider, "shallow",
+		llmx.CapabilityType_CAPABILITY_TYPE_STRUCTURED_RESPONSE, &llmx.StructuredResponse{MaxSchemaDepth: 1})
+
+	err := CheckSchemaDepth(provider, "shallow", citySchema(t))
+	require.ErrorIs(t, err, ErrSchemaTooDeep)
+	assert.Contains(t, err.Error(), "depth 3 exceeds the limit of 1")
+
+	require.NoError(t, CheckSchemaDepth(provider, "unregistered", citySchema(t)))
+
+	_, err = NewAnthropicProvider("fake-key", "claude-3-5-haiku", nil).PrepareRequest(&Request{
+		Messages:       []Message{{Role: "user", Content: "Paris"}},
+		ResponseSchema: &jsonschema.Schema{Type: "object", Properties: map[string]*jsonschema.Schema{"a": nest(10)}},
+	}, nil)
+	require.ErrorIs(t, err, ErrSchemaTooDeep, "the request is rejected before it is sent")
+}
+
+// nest returns an object schema of the given depth.
+func nest(depth int) *jsonschema.Schema {
+	if depth == 1 {
+		return &jsonschema.Schema{Type: "object"}
+	}
+	return &jsonschema.Schema{Type: "object", Properties: map[string]*jsonschema.Schema{"a": nest(depth - 1)}}
+}
+
+func TestStructuredOutputRequests(t *testing.T) {
+	tests := []struct {
+		prepare func(*Request, map[string]any) ([]byte, error)
+		check   func(t *testing.T, body map[string]any)
+		name    string
+		// prompt reports whether the schema is described in the system prompt.
+		prompt bool
+	}{
+		{
+			name:    "openai native",
+			prepare: NewOpenAIProvider("fake-key", "gpt-4o", nil).PrepareRequest,
+			check: func(t *testing.T, body map[string]any) {
+				format := body["response_format"].(map[string]any)
+				assert.Equal(t, "json_schema", format["type"])
+				assert.NotNil(t, format["json_schema"].(map[string]any)["schema"])
+			},
+		},
+		{
+			name:    "openai json mode",
+			prepare: NewOpenAIProvider("fake-key", "gpt-4-turbo", nil).PrepareRequest,
+			prompt:  true,
+			check: func(t *testing.T, body map[string]any) {
+				assert.Equal(t, map[string]any{"type": "json_object"}, body["response_format"])
+			},
+		},
+		{
+			name:    "openai without capability",
+			prepare: NewOpenAIProvider("fake-key", "o1-mini", nil).PrepareRequest,
+			prompt:  true,
+			check: func(t *testing.T, body map[string]any) {
+				assert.NotContains(t, body, "response_format")
+			},
+		},
+		{
+			name:    "anthropic tool call",
+			prepare: NewAnthropicProvider("fake-key", "claude-3-5-haiku", nil).PrepareRequest,
+			check: func(t *testing.T, body map[string]any) {
+				tools := body["tools"].([]any)
+				require.Len(t, tools, 1)
+				assert.Equal(t, StructuredOutputToolName, tools[0].(map[string]any)["name"])
+				assert.Equal(t, map[string]any{"type": "tool", "name": StructuredOutputToolName}, body["tool_choice"])
+			},
+		},
+		{
+			name:    "anthropic stream",
+			prepare: NewAnthropicProvider("fake-key", "claude-3-5-haiku", nil).PrepareStreamRequest,
+			prompt:  true,
+			check: func(t *testing.T, body map[string]any) {
+				assert.NotContains(t, body, "tools")
+			},
+		},
+		{
+			name:    "cohere tool call",
+			prepare: NewCohereProvider("fake-key", "command-r", nil).PrepareRequest,
+			check: func(t *testing.T, body map[string]any) {
+				tools := body["tools"].([]any)
+				require.Len(t, tools, 1)
+				assert.Equal(t, "REQUIRED", body["tool_choice"])
+			},
+		},
+		{
+			name:    "deepseek json mode",
+			prepare: NewDeepSeekProvider("fake-key", "deepseek-chat", nil).PrepareRequest,
+			prompt:  true,
+			check: func(t *testing.T, body map[string]any) {
+				assert.Equal(t, map[string]any{"type": "json_object"}, body["response_format"])
+			},
+		},
+		{
+			name:    "gemini native",
+			prepare: NewGeminiProvider("fake-key", "gemini-2.0-flash", nil).PrepareRequest,
+			check: func(t *testing.T, body map[string]any) {
+				genConfig := body["generationConfig"].(map[string]any)
+				assert.Equal(t, "application/json", genConfig["responseMimeType"])
+				assert.NotNil(t, genConfig["responseSchema"])
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, err := tt.prepare(&Request{
+				Messages:       []Message{{Role: "user", Content: "Describe Paris"}},
+				SystemPrompt:   "You are a travel guide.",
+				ResponseSchema: citySchema(t),
+			}, map[string]any{})
+			require.NoError(t, err)
+
+			var body map[string]any
+			require.NoError(t, json.Unmarshal(data, &body))
+			if tt.prompt {
+				assert.Contains(t, string(data), "You must respond with a JSON value")
+			} else {
+				assert.NotContains(t, string(data), "You must respond with a JSON value")
+			}
+			tt.check(t, body)
+		})
+	}
+}
+
+func TestStructuredOutputToolResponse(t *testing.T) {
+	anthropic := NewAnthropicProvider("fake-key", "claude-3-5-haiku", nil)
+	resp, err := anthropic.ParseResponse([]byte(`{"stop_reason":"tool_use","content":[` +
+		`{"type":"tool_use","id":"t1","name":"structured_response","input":{"name":"Paris"}}]}`))
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"name":"Paris"}`, resp.AsText())
+	assert.Equal(t, FinishReasonStop, resp.FinishReason)
+
+	cohere := NewCohereProvider("fake-key", "command-r", nil)
+	resp, err = cohere.ParseResponse([]byte(`{"finish_reason":"TOOL_CALL","message":{"tool_calls":[` +
+		`{"id":"t1","type":"function","function":{"name":"structured_response","arguments":"{\"name\":\"Paris\"}"}}]}}`))
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"name":"Paris"}`, resp.AsText())
+	assert.Equal(t, FinishReasonStop, resp.FinishReason)
+}
@@ -0,0 +1,217 @@
+package providers
+
+import (
+	"context"
+	"encoding/json"
+	"math"
+	"sync"
+
+	"github.com/pkoukk/tiktoken-go"
+)
+
+const (
+	// DefaultCharsPerToken is the initial ratio of EstimatingTokenCounter for
+	// providers without a known ratio.
+	DefaultCharsPerToken = 4.0
+
+	// tokensPerMessage is the formatting overhead of each message, and
+	// tokensPerRequest that of the request, as counted for OpenAI chat models.
+	tokensPerMessage = 4
+	tokensPerRequest = 3
+
+	// calibrationWeight is the weight of each observation in the calibrated ratio.
+	calibrationWeight = 0.2
+)
+
+// providerCharsPerToken are the typical characters per token of English text
+// for providers whose tokenizers differ noticeably from the default.
+var providerCharsPerToken = map[string]float64{
+	ProviderAnthropic: 3.5,
+	ProviderCohere:    4.2,
+	ProviderMistral:   3.7,
+}
+
+// TokenCounter counts the input tokens of a request.
+type TokenCounter interface {
+	CountTokens(ctx context.Context, req *Request) (int, error)
+}
+
+// TokenCountingProvider is implemented by providers whose API counts the input
+// tokens of a request exactly. The client sends the prepared request to the
+// returned endpoint with the provider's headers.
+type TokenCountingProvider interface {
+	PrepareTokenCountRequest(req *Request, options map[string]any) (endpoint string, body []byte, err error)
+	ParseTokenCountResponse(body []byte) (int, error)
+}
+
+// TextTokenCounter counts the tokens of a text on its own, without the
+// formatting overhead of a message.
+type TextTokenCounter interface {
+	CountText(text string) int
+}
+
+// TokenCountCalibrator is implemented by counters that improve their estimates
+// from the input tokens a provider reported for a request.
+type TokenCountCalibrator interface {
+	Calibrate(req *Request, inputTokens int)
+}
+
+// DefaultTokenCounter returns the local token counter for a provider: tiktoken
+// for OpenAI and an EstimatingTokenCounter for every other provider.
+func DefaultTokenCounter(provider string, model string) TokenCounter {
+	if provider == ProviderOpenAI {
+		return NewTiktokenCounter(model)
+	}
+	return NewEstimatingTokenCounter(providerCharsPerToken[provider])
+}
+
+// EstimatingTokenCounter estimates token counts from the number of characters.
+// The ratio starts at a per-provider value and is calibrated from the token
+// counts providers report.
+type EstimatingTokenCounter struct {
+	mu            sync.Mutex
+	charsPerToken float64
+}
+
+// NewEstimatingTokenCounter creates an estimating counter with an initial ratio
+// of characters per token. A ratio of zero uses DefaultCharsPerToken.
+func NewEstimatingTokenCounter(charsPerToken float64) *EstimatingTokenCounter {
+	if charsPerToken <= 0 {
+		charsPerToken = DefaultCharsPerToken
+	}
+	return &EstimatingTokenCounter{charsPerToken: charsPerToken}
+}
+
+// CountTokens implements TokenCounter.
+func (e *EstimatingTokenCounter) CountTokens(_ context.Context, req *Request) (int, error) {
+	e.mu.Lock()
+	ratio := e.charsPerToken
+	e.mu.Unlock()
+	return int(math.Ceil(float64(requestChars(req))/ratio)) + requestOverhead(req), nil
+}
+
+// CountText implements TextTokenCounter.
+func (e *EstimatingTokenCounter) CountText(text string) int {
+	e.mu.Lock()
+	ratio := e.charsPerToken
+	e.mu.Unlock()
+	return int(math.Ceil(float64(len([]rune(text))) / ratio))
+}
+
+// Calibrate implements TokenCountCalibrator by moving the ratio towards the one
+// observed for req.
+func (e *EstimatingTokenCounter) Calibrate(req *Request, inputTokens int) {
+	tokens := inputTokens - requestOverhead(req)
+	chars := requestChars(req)
+	if tokens <= 0 || chars == 0 {
+		return
+	}
+	observed := min(max(float64(chars)/float64(tokens), 1), 10)
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.charsPerToken += calibrationWeight * (observed - e.charsPerToken)
+}
+
+// CharsPerToken returns the current ratio of characters per token.
+func (e *EstimatingTokenCounter) CharsPerToken() float64 {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.charsPerToken
+}
+
+// TiktokenCounter counts tokens with the tiktoken encoding of an OpenAI model.
+// The encoding is loaded on first use; if it cannot be loaded, for example
+// without network access to fetch it, tokens are estimated instead.
+type TiktokenCounter struct {
+	model    string
+	once     sync.Once
+	encoding *tiktoken.Tiktoken
+	fallback *EstimatingTokenCounter
+}
+
+// NewTiktokenCounter creates a tiktoken counter for model. Models unknown to
+// tiktoken use the gpt-4o encoding.
+func NewTiktokenCounter(model string) *TiktokenCounter {
+	return &TiktokenCounter{model: model, fallback: NewEstimatingTokenCounter(DefaultCharsPerToken)}
+}
+
+// load loads the encoding of the model once.
+func (t *TiktokenCounter) load() *tiktoken.Tiktoken {
+	t.once.Do(func() {
+		encoding, err := tiktoken.EncodingForModel(t.model)
+		if err != nil {
+			encoding, err = tiktoken.EncodingForModel("gpt-4o")
+		}
+		if err == nil {
+			t.encoding = encoding
+		}
+	})
+	return t.encoding
+}
+
+// CountTokens implements TokenCounter.
+func (t *TiktokenCounter) CountTokens(ctx context.Context, req *Request) (int, error) {
+	if t.load() == nil {
+		return t.fallback.CountTokens(ctx, req)
+	}
+
+	count := requestOverhead(req)
+	for _, text := range requestTexts(req) {
+		count += len(t.encoding.Encode(text, nil, nil))
+	}
+	return count, nil
+}
+
+// CountText implements TextTokenCounter.
+func (t *TiktokenCounter) CountText(text string) int {
+	encoding := t.load()
+	if encoding == nil {
+		return t.fallback.CountText(text)
+	}
+	return len(encoding.Encode(text, nil, nil))
+}
+
+// Calibrate implements TokenCountCalibrator for the estimate used when the
+// encoding is not available.
+func (t *TiktokenCounter) Calibrate(req *Request, inputTokens int) {
+	t.fallback.Calibrate(req, inputTokens)
+}
+
+// requestTexts returns the texts of a request that count as input tokens.
+func requestTexts(req *Request) []string {
+	texts := make([]string, 0, len(req.Messages)+2)
+	if req.SystemPrompt != "" {
+		texts = append(texts, req.SystemPrompt)
+	}
+	for _, msg := range req.Messages {
+		texts = append(texts, msg.Content)
+		for _, call := range msg.ToolCalls {
+			texts = append(texts, call.Function.Name, string(call.Function.Arguments))
+		}
+	}
+	if req.ResponseSchema != nil {
+		if data, err := json.Marshal(req.ResponseSchema); err == nil {
+			texts = append(texts, string(data))
+		}
+	} else if len(req.ResponseJSON) > 0 {
+		texts = append(texts, string(req.ResponseJSON))
+	}
+	return texts
+}
+
+func requestChars(req *Request) int {
+	chars := 0
+	for _, text := range requestTexts(req) {
+		chars += len([]rune(text))
+	}
+	return chars
+}
+
+func requestOverhead(req *Request) int {
+	messages := len(req.Messages)
+	if req.SystemPrompt != "" {
+		messages++
+	}
+	return messages*tokensPerMessage + tokensPerRequest
+}
@@ -0,0 +1,104 @@
+package providers
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEstimatingTokenCounter(t *testing.T) {
+	req := &Request{
+		SystemPrompt: "Be brief.",                                       // 9 characters
+		Messages:     []Message{{Role: "user", Content: "Hello there"}}, // 11 characters
+	}
+
+	counter := NewEstimatingTokenCounter(0)
+	count, err := counter.CountTokens(context.Background(), req)
+	require.NoError(t, err)
+	assert.Equal(t, 5+2*tokensPerMessage+tokensPerRequest, count)
+
+	// The provider counted 10 tokens of text, 2 characters per token.
+	counter.Calibrate(req, 10+2*tokensPerMessage+tokensPerRequest)
+	assert.InDelta(t, 3.6, counter.CharsPerToken(), 0.001)
+
+	counter.Calibrate(req, 0)
+	assert.InDelta(t, 3.6, counter.CharsPerToken(), 0.001, "missing usage is ignored")
+}
+
+func TestDefaultTokenCounter(t *testing.T) {
+	assert.IsType(t, &TiktokenCounter{}, DefaultTokenCounter(ProviderOpenAI, "gpt-4o"))
+	anthropic, ok := DefaultTokenCounter(ProviderAnthropic, "claude-3-5-haiku").(*EstimatingTokenCounter)
+	require.True(t, ok)
+	assert.InDelta(t, 3.5, anthropic.CharsPerToken(), 0.001)
+}
+
+func TestModelLimits(t *testing.T) {
+	NewAnthropicProvider("fake-key", "claude-3-5-haiku", nil)
+	registry := GetCapabilityRegistry()
+
+	limits, ok := registry.GetLimits(ProviderAnthropic, "claude-3-5-haiku-20241022")
+	require.True(t, ok, "dated models use the limits of their base name")
+	assert.Equal(t, ModelLimits{ContextWindow: 200000, MaxOutputTokens: 8192}, limits)
+
+	registry.RegisterLimits(ProviderAnthropic, "claude-3-5-haiku-test", ModelLimits{ContextWindow: 10})
+	limits, _ = registry.GetLimits(ProviderAnthropic, "claude-3-5-haiku-test-1")
+	assert.Equal(t, 10, limits.ContextWindow, "the longest prefix wins")
+
+	_, ok = registry.GetLimits(ProviderAnthropic, "unknown-model")
+	assert.False(t, ok)
+}
+
+func TestAnthropicTokenCountRequest(t *testing.T) {
+	provider := NewAnthropicProvider("fake-key", "claude-3-5-haiku", nil)
+	provider.SetOption(anthropicKeyMaxTokens, 1024)
+	provider.SetOption("temperature", 0.2)
+
+	endpoint, body, err := provider.PrepareTokenCountRequest(&Request{
+		SystemPrompt: "Be brief.",
+		Messages:     []Message{{Role: "user", Content: "Hello"}},
+	}, nil)
+	require.NoError(t, err)
+	assert.Equal(t, "https://api.anthropic.com/v1/messages/count_tokens", endpoint)
+
+	var got map[string]any
+	require.NoError(t, json.Unmarshal(body, &got))
+	assert.ElementsMatch(t, []string{"model", "system", "messages"}, keys(got), "generation options are not sent")
+
+	count, err := provider.ParseTokenCountResponse([]byte(`{"input_tokens": 14}`))
+	require.NoError(t, err)
+	assert.Equal(t, 14, count)
+	_, err = provider.ParseTokenCountResponse([]byte(`{}`))
+	require.Error(t, err)
+}
+
+func TestGeminiTokenCountRequest(t *testing.T) {
+	provider := NewGeminiProvider("fake-key", "gemini-2.0-flash", nil)
+
+	endpoint, body, err := provider.PrepareTokenCountRequest(&Request{
+		Messages: []Message{{Role: "user", Content: "Hello"}},
+	}, nil)
+	require.NoError(t, err)
+	assert.Equal(t, "https://generativelanguage.googleapis.com/v1beta/models/gemini-2.0-flash:countTokens", endpoint)
+
+	var got struct {
+		GenerateContentRequest map[string]any `json:"generateContentRequest"`
+	}
+	require.NoError(t, json.Unmarshal(body, &got))
+	assert.Equal(t, "models/gemini-2.0-flash", got.GenerateContentRequest["model"])
+	assert.Contains(t, got.GenerateContentRequest, "contents")
+
+	count, err := provider.ParseTokenCountResponse([]byte(`{"totalTokens": 7}`))
+	require.NoError(t, err)
+	assert.Equal(t, 7, count)
+}
+
+func keys(m map[string]any) []string {
+	result := make([]string, 0, len(m))
+	for key := range m {
+		result = append(result, key)
+	}
+	return result
+}
@@ -2,6 +2,8 @@ package providers
 
 import (
 	"encoding/json"
+	"strings"
+	"time"
 
 	"github.com/google/jsonschema-go/jsonschema"
 )
@@ -28,13 +30,115 @@ type Message struct {
 // Response represents the response from an LLM model.
 // It contains the content of the response and optional usage information.
 type Response struct {
-	Content    Content
-	Usage      *Usage     `json:"usage,omitempty"`
-	Role       string     `json:"role"`
-	CacheType  CacheType  `json:"cache_type,omitempty"`
-	Name       string     `json:"name,omitempty"`
-	ToolCallID string     `json:"tool_call_id,omitempty"`
-	ToolCalls  []ToolCall `json:"tool_calls,omitempty"`
+	Content      Content
+	Usage        *Usage            `json:"usage,omitempty"`
+	Metadata     *ResponseMetadata `json:"metadata,omitempty"`
+	Role         string            `json:"role"`
+	FinishReason FinishReason      `json:"finish_reason,omitempty"`
+	CacheType    CacheType         `json:"cache_type,omitempty"`
+	Name         string            `json:"name,omitempty"`
+	ToolCallID   string            `json:"tool_call_id,omitempty"`
+	ToolCalls    []ToolCall        `json:"tool_calls,omitempty"`
+}
+
+// ResponseMetadata describes how a response was produced. Providers fill in the
+// fields their API reports; the client adds the request ID, latency and, when
+// enabled, the raw response body.
+type ResponseMetadata struct {
+	// ID is the provider's identifier for the response or generation.
+	ID string `json:"id,omitempty"`
+	// Model is the model that actually served the request, which can differ from
+	// the requested one, for example after OpenRouter routing or alias resolution.
+	Model string `json:"model,omitempty"`
+	// SystemFingerprint identifies the backend configuration, where reported.
+	SystemFingerprint string `json:"system_fingerprint,omitempty"`
+	// RequestID is the provider request ID taken from the response headers.
+	RequestID string `json:"request_id,omitempty"`
+	// RawFinishReason is the finish reason exactly as reported by the provider.
+	RawFinishReason string `json:"raw_finish_reason,omitempty"`
+	// RawBody is the unparsed response body. It is only kept when requested.
+	RawBody json.RawMessage `json:"raw_body,omitempty"`
+	// Latency is the time from sending the request to receiving the full response.
+	Latency time.Duration `json:"latency,omitempty"`
+}
+
+// FinishReason is the normalized reason a model stopped generating.
+type FinishReason string
+
+const (
+	// FinishReasonStop indicates a natural stop or a stop sequence.
+	FinishReasonStop FinishReason = "stop"
+	// FinishReasonLength indicates the output was truncated by the token limit.
+	FinishReasonLength FinishReason = "length"
+	// FinishReasonToolCalls indicates the model stopped to call one or more tools.
+	FinishReasonToolCalls FinishReason = "tool_calls"
+	// FinishReasonContentFilter indicates the output was blocked or cut by a safety filter.
+	FinishReasonContentFilter FinishReason = "content_filter"
+	// FinishReasonError indicates the provider aborted generation with an error.
+	FinishReasonError FinishReason = "error"
+	// FinishReasonOther indicates a reason without a normalized equivalent.
+	// The provider's value is kept in ResponseMetadata.RawFinishReason.
+	FinishReasonOther FinishReason = "other"
+)
+
+// finishReasons maps provider-specific finish and stop reasons, lower-cased, to
+// their normalized values.
+var finishReasons = map[string]FinishReason{
+	// OpenAI and compatible APIs
+	"stop":           FinishReasonStop,
+	"length":         FinishReasonLength,
+	"tool_calls":     FinishReasonToolCalls,
+	"function_call":  FinishReasonToolCalls,
+	"content_filter": FinishReasonContentFilter,
+	"error":          FinishReasonError,
+	// Anthropic
+	"end_turn":      FinishReasonStop,
+	"stop_sequence": FinishReasonStop,
+	"max_tokens":    FinishReasonLength,
+	"tool_use":      FinishReasonToolCalls,
+	"refusal":       FinishReasonContentFilter,
+	// Google Gemini
+	"safety":                  FinishReasonContentFilter,
+	"recitation":              FinishReasonContentFilter,
+	"blocklist":               FinishReasonContentFilter,
+	"prohibited_content":      FinishReasonContentFilter,
+	"spii":                    FinishReasonContentFilter,
+	"image_safety":            FinishReasonContentFilter,
+	"malformed_function_call": FinishReasonError,
+	// Cohere
+	"complete":    FinishReasonStop,
+	"tool_call":   FinishReasonToolCalls,
+	"error_toxic": FinishReasonContentFilter,
+	"error_limit": FinishReasonLength,
+	// Mistral
+	"model_length": FinishReasonLength,
+}
+
+// NormalizeFinishReason maps a provider's finish or stop reason to a FinishReason.
+// Matching is case-insensitive. An empty reason stays empty and an unknown one
+// becomes FinishReasonOther.
+func NormalizeFinishReason(raw string) FinishReason {
+	if raw == "" {
+		return ""
+	}
+	if reason, ok := finishReasons[strings.ToLower(raw)]; ok {
+		return reason
+	}
+	return FinishReasonOther
+}
+
+// newMetadata returns metadata with the given identifiers and raw finish reason,
+// or nil when all of them are empty.
+func newMetadata(id, model, fingerprint, rawFinishReason string) *ResponseMetadata {
+	if id == "" && model == "" && fingerprint == "" && rawFinishReason == "" {
+		return nil
+	}
+	return &ResponseMetadata{
+		ID:                id,
+		Model:             model,
+		SystemFingerprint: fingerprint,
+		RawFinishReason:   rawFinishReason,
+	}
 }
 
 // Content is a sealed interface for different types of content in a response. Currently, text content only.
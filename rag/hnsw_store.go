@@ -0,0 +1,313 @@
+package rag
+
+import (
+	"container/heap"
+	"context"
+	"fmt"
+	"math"
+	"math/rand/v2"
+	"sync"
+)
+
+// Default parameters of an HNSWStore.
+const (
+	DefaultHNSWConnections    = 16
+	DefaultHNSWEfConstruction = 200
+	DefaultHNSWEfSearch       = 64
+)
+
+// HNSWStore is a VectorStore that finds approximate nearest neighbors in a
+// hierarchical navigable small world graph. Searches visit a small part of the
+// collection, so they stay fast for hundreds of thousands of chunks, at the
+// cost of occasionally missing a close chunk.
+//
+// Deleted and replaced chunks are no longer returned but stay in the graph to
+// keep it connected, so a store with many deletions should be rebuilt.
+type HNSWStore struct {
+	rng            *rand.Rand
+	ids            map[string]int
+	nodes          []*hnswNode
+	levelFactor    float64
+	connections    int
+	efConstruction int
+	efSearch       int
+	dimension      int
+	entry          int
+	maxLevel       int
+	mu             sync.RWMutex
+}
+
+// hnswNode is a chunk in the graph with its links on each of its levels.
+type hnswNode struct {
+	vector  []float32
+	links   [][]int
+	chunk   Chunk
+	deleted bool
+}
+
+// HNSWOption configures an HNSWStore.
+type HNSWOption func(*HNSWStore)
+
+// WithHNSWConnections sets how many neighbors each chunk links to on each
+// level, M in the HNSW paper. More connections improve recall and use more
+// memory. The default is DefaultHNSWConnections.
+func WithHNSWConnections(m int) HNSWOption {
+	return func(s *HNSWStore) {
+		s.connections = m
+	}
+}
+
+// WithHNSWEfConstruction sets how many candidates are considered when a chunk
+// is linked into the graph. The default is DefaultHNSWEfConstruction.
+func WithHNSWEfConstruction(ef int) HNSWOption {
+	return func(s *HNSWStore) {
+		s.efConstruction = ef
+	}
+}
+
+// WithHNSWEfSearch sets how many candidates a search considers, at least k.
+// Larger values improve recall and slow searches down. The default is
+// DefaultHNSWEfSearch.
+func WithHNSWEfSearch(ef int) HNSWOption {
+	return func(s *HNSWStore) {
+		s.efSearch = ef
+	}
+}
+
+// WithHNSWSeed seeds the random levels of the graph, making it reproducible.
+func WithHNSWSeed(seed uint64) HNSWOption {
+	return func(s *HNSWStore) {
+		s.rng = rand.New(rand.NewPCG(seed, seed))
+	}
+}
+
+// NewHNSWStore creates an empty HNSW store.
+func NewHNSWStore(opts ...HNSWOption) *HNSWStore {
+	s := &HNSWStore{
+		ids:            make(map[string]int),
+		connections:    DefaultHNSWConnections,
+		efConstruction: DefaultHNSWEfConstruction,
+		efSearch:       DefaultHNSWEfSearch,
+		entry:          -1,
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	if s.rng == nil {
+		s.rng = rand.New(rand.NewPCG(rand.Uint64(), rand.Uint64()))
+	}
+	s.connections = max(s.connections, 2)
+	s.efConstruction = max(s.efConstruction, s.connections)
+	s.levelFactor = 1 / math.Log(float64(s.connections))
+	return s
+}
+
+// Add implements VectorStore.
+func (s *HNSWStore) Add(_ context.Context, chunks []Chunk) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	dimension := s.dimension
+	if len(s.ids) == 0 {
+		dimension = 0
+	}
+	for _, chunk := range chunks {
+		if err := checkEmbedding(chunk, &dimension); err != nil {
+			return err
+		}
+	}
+
+	if len(s.ids) == 0 {
+		// Start over, dropping the nodes of deleted chunks.
+		s.nodes, s.entry, s.maxLevel = nil, -1, 0
+	}
+	s.dimension = dimension
+	for _, chunk := range chunks {
+		if old, ok := s.ids[chunk.ID]; ok {
+			s.nodes[old].deleted = true
+		}
+		s.ids[chunk.ID] = s.insert(chunk)
+	}
+	return nil
+}
+
+// Search implements VectorStore.
+func (s *HNSWStore) Search(_ context.Context, query []float32, k int) ([]ScoredChunk, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if len(s.ids) == 0 || k <= 0 {
+		return nil, nil
+	}
+	if len(query) != s.dimension {
+		return nil, fmt.Errorf("%w: query has %d dimensions, store has %d", ErrDimensionMismatch, len(query), s.dimension)
+	}
+
+	query = normalize(query)
+	entry := s.entry
+	for level := s.maxLevel; level > 0; level-- {
+		entry = s.searchLevel(query, entry, 1, level)[0].id
+	}
+
+	var results []ScoredChunk
+	for _, found := range s.searchLevel(query, entry, max(s.efSearch, k), 0) {
+		if node := s.nodes[found.id]; !node.deleted {
+			results = append(results, ScoredChunk{Chunk: node.chunk, Score: found.score})
+		}
+	}
+	sortScored(results)
+	return results[:min(k, len(results))], nil
+}
+
+// Delete implements VectorStore.
+func (s *HNSWStore) Delete(_ context.Context, ids ...string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, id := range ids {
+		if node, ok := s.ids[id]; ok {
+			s.nodes[node].deleted = true
+			delete(s.ids, id)
+		}
+	}
+	return nil
+}
+
+// Len implements VectorStore.
+func (s *HNSWStore) Len() int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return len(s.ids)
+}
+
+// insert links a chunk into the graph and returns its node.
+func (s *HNSWStore) insert(chunk Chunk) int {
+	level := int(-math.Log(1-s.rng.Float64()) * s.levelFactor)
+	node := &hnswNode{vector: normalize(chunk.Embedding), links: make([][]int, level+1), chunk: chunk}
+	id := len(s.nodes)
+	s.nodes = append(s.nodes, node)
+
+	if s.entry < 0 {
+		s.entry, s.maxLevel = id, level
+		return id
+	}
+
+	entry := s.entry
+	for l := s.maxLevel; l > level; l-- {
+		entry = s.searchLevel(node.vector, entry, 1, l)[0].id
+	}
+	for l := min(level, s.maxLevel); l >= 0; l-- {
+		found := s.searchLevel(node.vector, entry, s.efConstruction, l)
+		neighbors := closest(found, s.connections)
+		node.links[l] = neighbors
+		for _, neighbor := range neighbors {
+			s.link(neighbor, id, l)
+		}
+		entry = found[0].id
+	}
+
+	if level > s.maxLevel {
+		s.entry, s.maxLevel = id, level
+	}
+	return id
+}
+
+// link adds a link from node to target on level, keeping only the closest
+// links when the node has too many.
+func (s *HNSWStore) link(node, target, level int) {
+	n := s.nodes[node]
+	n.links[level] = append(n.links[level], target)
+
+	limit := s.connections
+	if level == 0 {
+		limit *= 2
+	}
+	if len(n.links[level]) <= limit {
+		return
+	}
+	candidates := make([]candidate, 0, len(n.links[level]))
+	for _, linked := range n.links[level] {
+		candidates = append(candidates, candidate{id: linked, score: dot(n.vector, s.nodes[linked].vector)})
+	}
+	n.links[level] = closest(candidates, limit)
+}
+
+// searchLevel returns the up to ef nodes closest to query that a greedy
+// search from entry finds on level, closest first.
+func (s *HNSWStore) searchLevel(query []float32, entry, ef, level int) []candidate {
+	start := candidate{id: entry, score: dot(query, s.nodes[entry].vector)}
+	visited := map[int]bool{entry: true}
+	candidates := &candidateHeap{items: []candidate{start}}
+	results := &candidateHeap{items: []candidate{start}, worstFirst: true}
+
+	for candidates.Len() > 0 {
+		current := heap.Pop(candidates).(candidate)
+		if results.Len() >= ef && current.score < results.items[0].score {
+			break
+		}
+		for _, neighbor := range s.nodes[current.id].links[level] {
+			if visited[neighbor] {
+				continue
+			}
+			visited[neighbor] = true
+			next := candidate{id: neighbor, score: dot(query, s.nodes[neighbor].vector)}
+			if results.Len() < ef || next.score > results.items[0].score {
+				heap.Push(candidates, next)
+				heap.Push(results, next)
+				if results.Len() > ef {
+					heap.Pop(results)
+				}
+			}
+		}
+	}
+
+	found := make([]candidate, results.Len())
+	for i := len(found) - 1; i >= 0; i-- {
+		found[i] = heap.Pop(results).(candidate)
+	}
+	return found
+}
+
+// candidate is a node with its similarity to a query.
+type candidate struct {
+	id    int
+	score float64
+}
+
+// closest returns the IDs of the n candidates with the highest scores.
+func closest(candidates []candidate, n int) []int {
+	h := &candidateHeap{items: append([]candidate(nil), candidates...)}
+	heap.Init(h)
+	ids := make([]int, 0, min(n, h.Len()))
+	for h.Len() > 0 && len(ids) < n {
+		ids = append(ids, heap.Pop(h).(candidate).id)
+	}
+	return ids
+}
+
+// candidateHeap is a heap of candidates with the highest score on top, or the
+// lowest when worstFirst is set.
+type candidateHeap struct {
+	items      []candidate
+	worstFirst bool
+}
+
+func (h *candidateHeap) Len() int { return len(h.items) }
+
+func (h *candidateHeap) Less(i, j int) bool {
+	if h.worstFirst {
+		return h.items[i].score < h.items[j].score
+	}
+	return h.items[i].score > h.items[j].score
+}
+
+func (h *candidateHeap) Swap(i, j int) { h.items[i], h.items[j] = h.items[j], h.items[i] }
+
+func (h *candidateHeap) Push(x any) { h.items = append(h.items, x.(candidate)) }
+
+func (h *candidateHeap) Pop() any {
+	last := h.items[len(h.items)-1]
+	h.items = h.items[:len(h.items)-1]
+	return last
+}
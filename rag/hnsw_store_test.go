@@ -0,0 +1,104 @@
+package rag
+
+import (
+	"context"
+	"fmt"
+	"math/rand/v2"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func randomChunks(rng *rand.Rand, n, dimension int) []Chunk {
+	chunks := make([]Chunk, n)
+	for i := range chunks {
+		vector := make([]float32, dimension)
+		for j := range vector {
+			vector[j] = float32(rng.NormFloat64())
+		}
+		chunks[i] = Chunk{ID: fmt.Sprintf("chunk-%d", i), Text: fmt.Sprintf("Text %d", i), Embedding: vector}
+	}
+	return chunks
+}
+
+func TestInMemoryStore(t *testing.T) {
+	ctx := context.Background()
+	store := NewInMemoryStore()
+	require.NoError(t, store.Add(ctx, []Chunk{
+		{ID: "north", Embedding: []float32{0, 1}},
+		{ID: "east", Embedding: []float32{2, 0}},
+		{ID: "north-east", Embedding: []float32{1, 1}},
+	}))
+
+	results, err := store.Search(ctx, []float32{0, 3}, 2)
+	require.NoError(t, err)
+	require.Len(t, results, 2)
+	assert.Equal(t, "north", results[0].ID)
+	assert.InDelta(t, 1, results[0].Score, 1e-6)
+	assert.Equal(t, "north-east", results[1].ID)
+	assert.InDelta(t, 0.7071, results[1].Score, 1e-4)
+
+	require.NoError(t, store.Delete(ctx, "north"))
+	assert.Equal(t, 2, store.Len())
+
+	_, err = store.Search(ctx, []float32{1, 2, 3}, 1)
+	require.ErrorIs(t, err, ErrDimensionMismatch)
+	require.ErrorIs(t, store.Add(ctx, []Chunk{{ID: "up", Embedding: []float32{0, 0, 1}}}), ErrDimensionMismatch)
+}
+
+func TestHNSWStoreRecall(t *testing.T) {
+	ctx := context.Background()
+	rng := rand.New(rand.NewPCG(1, 2))
+	chunks := randomChunks(rng, 2000, 16)
+
+	exact := NewInMemoryStore()
+	require.NoError(t, exact.Add(ctx, chunks))
+	approximate := NewHNSWStore(WithHNSWSeed(3))
+	require.NoError(t, approximate.Add(ctx, chunks))
+	require.Equal(t, len(chunks), approximate.Len())
+
+	const k, queries = 10, 50
+	found := 0
+	for _, query := range randomChunks(rng, queries, 16) {
+		want, err := exact.Search(ctx, query.Embedding, k)
+		require.NoError(t, err)
+		got, err := approximate.Search(ctx, query.Embedding, k)
+		require.NoError(t, err)
+		require.Len(t, got, k)
+
+		ids := make(map[string]bool, k)
+		for _, chunk := range want {
+			ids[chunk.ID] = true
+		}
+		for _, chunk := range got {
+			if ids[chunk.ID] {
+				found++
+			}
+		}
+	}
+	assert.GreaterOrEqual(t, float64(found)/(k*queries), 0.9, "recall")
+}
+
+func TestHNSWStoreDeleteAndReplace(t *testing.T) {
+	ctx := context.Background()
+	store := NewHNSWStore(WithHNSWSeed(1))
+	require.NoError(t, store.Add(ctx, randomChunks(rand.New(rand.NewPCG(4, 5)), 100, 8)))
+
+	target := []float32{1, 0, 0, 0, 0, 0, 0, 0}
+	require.NoError(t, store.Add(ctx, []Chunk{{ID: "chunk-7", Text: "Replaced", Embedding: target}}))
+	assert.Equal(t, 100, store.Len())
+
+	results, err := store.Search(ctx, target, 1)
+	require.NoError(t, err)
+	require.Len(t, results, 1)
+	assert.Equal(t, "Replaced", results[0].Text)
+
+	require.NoError(t, store.Delete(ctx, "chunk-7"))
+	results, err = store.Search(ctx, target, 100)
+	require.NoError(t, err)
+	for _, chunk := range results {
+		assert.NotEqual(t, "chunk-7", chunk.ID)
+	}
+	assert.Equal(t, 99, store.Len())
+}
@@ -0,0 +1,121 @@
+package rag
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"slices"
+	"sync"
+)
+
+// InMemoryStore is a VectorStore that compares the query with every stored
+// chunk. Its results are exact, and it is fast enough for up to tens of
+// thousands of chunks; use an HNSWStore for larger collections.
+type InMemoryStore struct {
+	chunks    map[string]storedChunk
+	dimension int
+	mu        sync.RWMutex
+}
+
+// storedChunk is a chunk with its normalized embedding.
+type storedChunk struct {
+	vector []float32
+	chunk  Chunk
+}
+
+// NewInMemoryStore creates an empty in-memory store.
+func NewInMemoryStore() *InMemoryStore {
+	return &InMemoryStore{chunks: make(map[string]storedChunk)}
+}
+
+// Add implements VectorStore.
+func (s *InMemoryStore) Add(_ context.Context, chunks []Chunk) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	dimension := s.dimension
+	if len(s.chunks) == 0 {
+		dimension = 0
+	}
+	for _, chunk := range chunks {
+		if err := checkEmbedding(chunk, &dimension); err != nil {
+			return err
+		}
+	}
+
+	s.dimension = dimension
+	for _, chunk := range chunks {
+		s.chunks[chunk.ID] = storedChunk{vector: normalize(chunk.Embedding), chunk: chunk}
+	}
+	return nil
+}
+
+// Search implements VectorStore.
+func (s *InMemoryStore) Search(_ context.Context, query []float32, k int) ([]ScoredChunk, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if len(s.chunks) == 0 || k <= 0 {
+		return nil, nil
+	}
+	if len(query) != s.dimension {
+		return nil, fmt.Errorf("%w: query has %d dimensions, store has %d", ErrDimensionMismatch, len(query), s.dimension)
+	}
+
+	query = normalize(query)
+	results := make([]ScoredChunk, 0, len(s.chunks))
+	for _, stored := range s.chunks {
+		results = append(results, ScoredChunk{Chunk: stored.chunk, Score: dot(query, stored.vector)})
+	}
+	sortScored(results)
+	return results[:min(k, len(results))], nil
+}
+
+// Delete implements VectorStore.
+func (s *InMemoryStore) Delete(_ context.Context, ids ...string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, id := range ids {
+		delete(s.chunks, id)
+	}
+	return nil
+}
+
+// Len implements VectorStore.
+func (s *InMemoryStore) Len() int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return len(s.chunks)
+}
+
+// checkEmbedding checks that chunk has an ID and an embedding of dimension,
+// setting dimension from the chunk when it is zero.
+func checkEmbedding(chunk Chunk, dimension *int) error {
+	if chunk.ID == "" {
+		return fmt.Errorf("chunk without ID: %.40q", chunk.Text)
+	}
+	if len(chunk.Embedding) == 0 {
+		return fmt.Errorf("chunk %q has no embedding", chunk.ID)
+	}
+	if *dimension == 0 {
+		*dimension = len(chunk.Embedding)
+	}
+	if len(chunk.Embedding) != *dimension {
+		return fmt.Errorf("%w: chunk %q has %d dimensions, want %d",
+			ErrDimensionMismatch, chunk.ID, len(chunk.Embedding), *dimension)
+	}
+	return nil
+}
+
+// sortScored orders results by descending score, and by ID among equal scores
+// so that results are deterministic.
+func sortScored(results []ScoredChunk) {
+	slices.SortFunc(results, func(a, b ScoredChunk) int {
+		if c := cmp.Compare(b.Score, a.Score); c != 0 {
+			return c
+		}
+		return cmp.Compare(a.ID, b.ID)
+	})
+}
@@ -0,0 +1,126 @@
+package rag
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+// DefaultOpenAIEmbeddingEndpoint is the embeddings endpoint of the OpenAI API.
+const DefaultOpenAIEmbeddingEndpoint = "https://api.openai.com/v1/embeddings"
+
+// OpenAIEmbedder embeds texts with the OpenAI embeddings API. Other services
+// with an OpenAI-compatible API, such as Ollama, Mistral or a local server,
+// can be used through WithEmbeddingEndpoint.
+type OpenAIEmbedder struct {
+	client     *http.Client
+	apiKey     string
+	model      string
+	endpoint   string
+	dimensions int
+}
+
+// OpenAIEmbedderOption configures an OpenAIEmbedder.
+type OpenAIEmbedderOption func(*OpenAIEmbedder)
+
+// WithEmbeddingEndpoint sets the URL of the embeddings endpoint.
+func WithEmbeddingEndpoint(endpoint string) OpenAIEmbedderOption {
+	return func(e *OpenAIEmbedder) {
+		e.endpoint = endpoint
+	}
+}
+
+// WithEmbeddingHTTPClient sets the HTTP client that sends requests.
+func WithEmbeddingHTTPClient(client *http.Client) OpenAIEmbedderOption {
+	return func(e *OpenAIEmbedder) {
+		e.client = client
+	}
+}
+
+// WithEmbeddingDimensions asks models that support it for shorter vectors.
+func WithEmbeddingDimensions(dimensions int) OpenAIEmbedderOption {
+	return func(e *OpenAIEmbedder) {
+		e.dimensions = dimensions
+	}
+}
+
+// NewOpenAIEmbedder creates an embedder for model, such as
+// "text-embedding-3-small".
+func NewOpenAIEmbedder(apiKey, model string, opts ...OpenAIEmbedderOption) *OpenAIEmbedder {
+	e := &OpenAIEmbedder{
+		client:   http.DefaultClient,
+		apiKey:   apiKey,
+		model:    model,
+		endpoint: DefaultOpenAIEmbeddingEndpoint,
+	}
+	for _, opt := range opts {
+		opt(e)
+	}
+	return e
+}
+
+type openAIEmbeddingRequest struct {
+	Model      string   `json:"model"`
+	Input      []string `json:"input"`
+	Dimensions int      `json:"dimensions,omitempty"`
+}
+
+type openAIEmbeddingResponse struct {
+	Data []struct {
+		Embedding []float32 `json:"embedding"`
+		Index     int       `json:"index"`
+	} `json:"data"`
+}
+
+// Embed implements Embedder.
+func (e *OpenAIEmbedder) Embed(ctx context.Context, texts []string) ([][]float32, error) {
+	if len(texts) == 0 {
+		return nil, nil
+	}
+
+	body, err := json.Marshal(openAIEmbeddingRequest{Model: e.model, Input: texts, Dimensions: e.dimensions})
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode embedding request: %w", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, e.endpoint, bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create embedding request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if e.apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+e.apiKey)
+	}
+
+	resp, err := e.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to send embedding request: %w", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read embedding response: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("embedding request failed with status %d: %s", resp.StatusCode, data)
+	}
+
+	var parsed openAIEmbeddingResponse
+	if err := json.Unmarshal(data, &parsed); err != nil {
+		return nil, fmt.Errorf("failed to decode embedding response: %w", err)
+	}
+	if len(parsed.Data) != len(texts) {
+		return nil, fmt.Errorf("embedding response has %d vectors for %d texts", len(parsed.Data), len(texts))
+	}
+	vectors := make([][]float32, len(texts))
+	for _, item := range parsed.Data {
+		if item.Index < 0 || item.Index >= len(texts) {
+			return nil, fmt.Errorf("embedding response has invalid index %d", item.Index)
+		}
+		vectors[item.Index] = item.Embedding
+	}
+	return vectors, nil
+}
@@ -0,0 +1,43 @@
+package rag
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOpenAIEmbedder(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "Bearer test-key", r.Header.Get("Authorization"))
+		var req openAIEmbeddingRequest
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+		assert.Equal(t, "text-embedding-3-small", req.Model)
+		assert.Equal(t, []string{"first", "second"}, req.Input)
+		assert.Equal(t, 2, req.Dimensions)
+
+		// Vectors may be listed out of order.
+		_, _ = w.Write([]byte(`{"data":[{"index":1,"embedding":[0,1]},{"index":0,"embedding":[1,0]}]}`))
+	}))
+	defer server.Close()
+
+	embedder := NewOpenAIEmbedder("test-key", "text-embedding-3-small",
+		WithEmbeddingEndpoint(server.URL), WithEmbeddingDimensions(2))
+	vectors, err := embedder.Embed(context.Background(), []string{"first", "second"})
+	require.NoError(t, err)
+	assert.Equal(t, [][]float32{{1, 0}, {0, 1}}, vectors)
+}
+
+func TestOpenAIEmbedderError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		http.Error(w, `{"error":{"message":"invalid model"}}`, http.StatusBadRequest)
+	}))
+	defer server.Close()
+
+	_, err := NewOpenAIEmbedder("", "unknown", WithEmbeddingEndpoint(server.URL)).Embed(context.Background(), []string{"x"})
+	require.ErrorContains(t, err, "status 400")
+}
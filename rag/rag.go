@@ -0,0 +1,110 @@
+// Package rag provides retrieval-augmented generation for gollm.
+//
+// Documents are split into chunks, embedded with an Embedder and kept in a
+// VectorStore. A Retriever embeds a question, fetches the most similar chunks
+// and injects them into a prompt, labeled with their chunk IDs so that answers
+// can cite the chunks they are based on.
+//
+// Example usage:
+//
+//	store := rag.NewHNSWStore()
+//	retriever := rag.NewRetriever(rag.NewOpenAIEmbedder(apiKey, "text-embedding-3-small"), store,
+//	    rag.WithTopK(4),
+//	)
+//	err := retriever.Index(ctx, []rag.Chunk{
+//	    {ID: "handbook#1", SourceID: "handbook.md", Text: "Employees get 25 vacation days."},
+//	})
+//
+//	prompt := gollm.NewPrompt("How many vacation days do I get?")
+//	chunks, err := retriever.Augment(ctx, prompt)
+//	resp, err := client.Generate(ctx, prompt)
+package rag
+
+import (
+	"context"
+	"errors"
+	"math"
+)
+
+// ErrDimensionMismatch is returned when an embedding does not have the
+// dimension of the vectors already in a store.
+var ErrDimensionMismatch = errors.New("embedding dimension mismatch")
+
+// Chunk is a piece of a document that is retrieved as a whole.
+type Chunk struct {
+	// Metadata holds any further information about the chunk, such as its
+	// title or position in the source.
+	Metadata map[string]any `json:"metadata,omitempty"`
+	// ID identifies the chunk and is what answers cite.
+	ID string `json:"id"`
+	// SourceID identifies the document the chunk was taken from.
+	SourceID string `json:"source_id,omitempty"`
+	// Text is the content of the chunk.
+	Text string `json:"text"`
+	// Embedding is the vector of Text. Retriever.Index fills it in when it
+	// is empty.
+	Embedding []float32 `json:"embedding,omitempty"`
+}
+
+// ScoredChunk is a chunk found by a search, with its cosine similarity to the
+// query.
+type ScoredChunk struct {
+	Chunk
+	Score float64 `json:"score"`
+}
+
+// Embedder turns texts into embedding vectors.
+type Embedder interface {
+	// Embed returns one vector per text, in the order of texts.
+	Embed(ctx context.Context, texts []string) ([][]float32, error)
+}
+
+// EmbedderFunc adapts a function to the Embedder interface.
+type EmbedderFunc func(ctx context.Context, texts []string) ([][]float32, error)
+
+// Embed implements Embedder.
+func (f EmbedderFunc) Embed(ctx context.Context, texts []string) ([][]float32, error) {
+	return f(ctx, texts)
+}
+
+// VectorStore keeps embedded chunks and finds those most similar to a query.
+// Implementations must be safe for concurrent use.
+type VectorStore interface {
+	// Add stores chunks, replacing stored chunks with the same IDs. Every
+	// chunk must have an embedding.
+	Add(ctx context.Context, chunks []Chunk) error
+	// Search returns up to k chunks ordered by descending similarity to query.
+	Search(ctx context.Context, query []float32, k int) ([]ScoredChunk, error)
+	// Delete removes the chunks with ids. Unknown IDs are ignored.
+	Delete(ctx context.Context, ids ...string) error
+	// Len returns the number of stored chunks.
+	Len() int
+}
+
+// normalize returns v scaled to unit length, so that the dot product of
+// normalized vectors is their cosine similarity. A zero vector is returned
+// unchanged.
+func normalize(v []float32) []float32 {
+	var sum float64
+	for _, x := range v {
+		sum += float64(x) * float64(x)
+	}
+	normalized := make([]float32, len(v))
+	if sum == 0 {
+		return normalized
+	}
+	norm := math.Sqrt(sum)
+	for i, x := range v {
+		normalized[i] = float32(float64(x) / norm)
+	}
+	return normalized
+}
+
+// dot returns the dot product of a and b, which have the same length.
+func dot(a, b []float32) float64 {
+	var sum float64
+	for i := range a {
+		sum += float64(a[i]) * float64(b[i])
+	}
+	return sum
+}
@@ -0,0 +1,204 @@
+package rag
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/weave-labs/gollm/llm"
+)
+
+// Default settings of a Retriever.
+const (
+	DefaultTopK               = 4
+	DefaultEmbeddingBatchSize = 64
+)
+
+// Placement is where a Retriever puts the retrieved chunks in a prompt.
+type Placement int
+
+const (
+	// PlaceInContext appends the chunks to Prompt.Context.
+	PlaceInContext Placement = iota
+	// PlaceInMessages sends the chunks as a user message before the last
+	// user message of the prompt.
+	PlaceInMessages
+)
+
+// Retriever finds the chunks relevant to a question and adds them to prompts.
+type Retriever struct {
+	embedder  Embedder
+	store     VectorStore
+	minScore  float64
+	topK      int
+	batchSize int
+	placement Placement
+}
+
+// RetrieverOption configures a Retriever.
+type RetrieverOption func(*Retriever)
+
+// WithTopK sets how many chunks are retrieved. The default is DefaultTopK.
+func WithTopK(k int) RetrieverOption {
+	return func(r *Retriever) {
+		r.topK = k
+	}
+}
+
+// WithMinScore leaves out chunks whose cosine similarity to the question is
+// below score.
+func WithMinScore(score float64) RetrieverOption {
+	return func(r *Retriever) {
+		r.minScore = score
+	}
+}
+
+// WithEmbeddingBatchSize sets how many chunks Index embeds per request. The
+// default is DefaultEmbeddingBatchSize.
+func WithEmbeddingBatchSize(size int) RetrieverOption {
+	return func(r *Retriever) {
+		r.batchSize = size
+	}
+}
+
+// WithPlacement sets where Augment puts the chunks. The default is
+// PlaceInContext.
+func WithPlacement(placement Placement) RetrieverOption {
+	return func(r *Retriever) {
+		r.placement = placement
+	}
+}
+
+// NewRetriever creates a retriever that embeds with embedder and searches
+// store.
+func NewRetriever(embedder Embedder, store VectorStore, opts ...RetrieverOption) *Retriever {
+	r := &Retriever{
+		embedder:  embedder,
+		store:     store,
+		topK:      DefaultTopK,
+		batchSize: DefaultEmbeddingBatchSize,
+	}
+	for _, opt := range opts {
+		opt(r)
+	}
+	r.batchSize = max(r.batchSize, 1)
+	return r
+}
+
+// Index embeds the chunks that have no embedding yet and adds all chunks to
+// the store.
+func (r *Retriever) Index(ctx context.Context, chunks []Chunk) error {
+	chunks = append([]Chunk(nil), chunks...)
+
+	var pending []int
+	for i, chunk := range chunks {
+		if len(chunk.Embedding) == 0 {
+			pending = append(pending, i)
+		}
+	}
+	for start := 0; start < len(pending); start += r.batchSize {
+		batch := pending[start:min(start+r.batchSize, len(pending))]
+		texts := make([]string, len(batch))
+		for i, index := range batch {
+			texts[i] = chunks[index].Text
+		}
+		vectors, err := r.embedder.Embed(ctx, texts)
+		if err != nil {
+			return fmt.Errorf("failed to embed chunks: %w", err)
+		}
+		if len(vectors) != len(batch) {
+			return fmt.Errorf("embedder returned %d vectors for %d chunks", len(vectors), len(batch))
+		}
+		for i, index := range batch {
+			chunks[index].Embedding = vectors[i]
+		}
+	}
+
+	if err := r.store.Add(ctx, chunks); err != nil {
+		return fmt.Errorf("failed to store chunks: %w", err)
+	}
+	return nil
+}
+
+// Retrieve returns the chunks most similar to query, most similar first.
+func (r *Retriever) Retrieve(ctx context.Context, query string) ([]ScoredChunk, error) {
+	vectors, err := r.embedder.Embed(ctx, []string{query})
+	if err != nil {
+		return nil, fmt.Errorf("failed to embed query: %w", err)
+	}
+	if len(vectors) != 1 {
+		return nil, fmt.Errorf("embedder returned %d vectors for the query", len(vectors))
+	}
+
+	chunks, err := r.store.Search(ctx, vectors[0], r.topK)
+	if err != nil {
+		return nil, fmt.Errorf("failed to search chunks: %w", err)
+	}
+	relevant := chunks[:0]
+	for _, chunk := range chunks {
+		if chunk.Score >= r.minScore {
+			relevant = append(relevant, chunk)
+		}
+	}
+	return relevant, nil
+}
+
+// Augment retrieves the chunks relevant to the prompt's input, or to its last
+// user message, and adds them to the prompt, labeled with their IDs. It
+// returns the chunks added; the prompt is unchanged when none are found.
+func (r *Retriever) Augment(ctx context.Context, prompt *llm.Prompt) ([]ScoredChunk, error) {
+	query := prompt.Input
+	last := lastUserMessage(prompt.Messages)
+	if last >= 0 {
+		query = prompt.Messages[last].Content
+	}
+
+	chunks, err := r.Retrieve(ctx, query)
+	if err != nil || len(chunks) == 0 {
+		return nil, err
+	}
+
+	sources := FormatChunks(chunks)
+	if r.placement == PlaceInMessages {
+		messages := prompt.Messages
+		if last < 0 {
+			messages = []llm.PromptMessage{{Role: "user", Content: prompt.Input}}
+			last = 0
+		}
+		prompt.Messages = append(messages[:last:last], llm.PromptMessage{Role: "user", Content: sources})
+		prompt.Messages = append(prompt.Messages, messages[last:]...)
+		return chunks, nil
+	}
+
+	if prompt.Context != "" {
+		sources = prompt.Context + "\n\n" + sources
+	}
+	prompt.Context = sources
+	return chunks, nil
+}
+
+// FormatChunks formats chunks as sources for a prompt, each headed by its ID
+// in brackets and its source.
+func FormatChunks(chunks []ScoredChunk) string {
+	var b strings.Builder
+	b.WriteString("Sources:")
+	for _, chunk := range chunks {
+		fmt.Fprintf(&b, "\n\n[%s]", chunk.ID)
+		if chunk.SourceID != "" {
+			fmt.Fprintf(&b, " (source: %s)", chunk.SourceID)
+		}
+		b.WriteString("\n")
+		b.WriteString(strings.TrimSpace(chunk.Text))
+	}
+	return b.String()
+}
+
+// lastUserMessage returns the index of the last user message, or -1.
+func lastUserMessage(messages []llm.PromptMessage) int {
+	for i := len(messages) - 1; i >= 0; i-- {
+		if messages[i].Role == "user" {
+			return i
+		}
+	}
+	return -1
+}
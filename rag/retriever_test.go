@@ -0,0 +1,78 @@
+package rag
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/weave-labs/gollm/llm"
+)
+
+// keywordEmbedder embeds texts by counting the keywords they contain.
+type keywordEmbedder struct {
+	keywords []string
+	calls    [][]string
+}
+
+func (e *keywordEmbedder) Embed(_ context.Context, texts []string) ([][]float32, error) {
+	e.calls = append(e.calls, texts)
+	vectors := make([][]float32, len(texts))
+	for i, text := range texts {
+		vectors[i] = make([]float32, len(e.keywords))
+		for j, keyword := range e.keywords {
+			vectors[i][j] = float32(strings.Count(strings.ToLower(text), keyword))
+		}
+	}
+	return vectors, nil
+}
+
+func newTestRetriever(t *testing.T, opts ...RetrieverOption) (*Retriever, *keywordEmbedder) {
+	t.Helper()
+	embedder := &keywordEmbedder{keywords: []string{"vacation", "salary", "office"}}
+	retriever := NewRetriever(embedder, NewInMemoryStore(), opts...)
+	require.NoError(t, retriever.Index(context.Background(), []Chunk{
+		{ID: "handbook#1", SourceID: "handbook.md", Text: "Employees get 25 vacation days."},
+		{ID: "handbook#2", SourceID: "handbook.md", Text: "Salary is paid monthly."},
+		{ID: "handbook#3", SourceID: "handbook.md", Text: "The office opens at 8."},
+	}))
+	return retriever, embedder
+}
+
+func TestRetrieverIndexBatches(t *testing.T) {
+	_, embedder := newTestRetriever(t, WithEmbeddingBatchSize(2))
+	require.Len(t, embedder.calls, 2)
+	assert.Len(t, embedder.calls[0], 2)
+	assert.Len(t, embedder.calls[1], 1)
+}
+
+func TestRetrieverAugmentContext(t *testing.T) {
+	retriever, _ := newTestRetriever(t, WithTopK(2), WithMinScore(0.5))
+
+	prompt := llm.NewPrompt("How many vacation days do I get?", llm.WithContext("The user works in Berlin."))
+	chunks, err := retriever.Augment(context.Background(), prompt)
+	require.NoError(t, err)
+	require.Len(t, chunks, 1, "chunks below the minimum score are left out")
+	assert.Equal(t, "handbook#1", chunks[0].ID)
+	assert.Equal(t, "The user works in Berlin.\n\nSources:\n\n[handbook#1] (source: handbook.md)\n"+
+		"Employees get 25 vacation days.", prompt.Context)
+}
+
+func TestRetrieverAugmentMessages(t *testing.T) {
+	retriever, _ := newTestRetriever(t, WithTopK(1), WithPlacement(PlaceInMessages))
+
+	prompt := &llm.Prompt{Messages: []llm.PromptMessage{
+		{Role: "user", Content: "Hi"},
+		{Role: "assistant", Content: "Hello!"},
+		{Role: "user", Content: "When does the office open?"},
+	}}
+	chunks, err := retriever.Augment(context.Background(), prompt)
+	require.NoError(t, err)
+	require.Len(t, chunks, 1)
+
+	require.Len(t, prompt.Messages, 4)
+	assert.Equal(t, "Sources:\n\n[handbook#3] (source: handbook.md)\nThe office opens at 8.", prompt.Messages[2].Content)
+	assert.Equal(t, "When does the office open?", prompt.Messages[3].Content)
+}
@@ -0,0 +1,32 @@
+package rag
+
+import (
+	"maps"
+
+	"github.com/weave-labs/gollm/textsplit"
+)
+
+// ChunksFromText converts the chunks of a textsplit splitter or loader to
+// chunks for a retriever. Each chunk is identified by its source and index,
+// and its offsets and headings are added to its metadata.
+func ChunksFromText(chunks []textsplit.Chunk) []Chunk {
+	converted := make([]Chunk, len(chunks))
+	for i, c := range chunks {
+		metadata := maps.Clone(c.Metadata)
+		if metadata == nil {
+			metadata = make(map[string]any, 3)
+		}
+		metadata["start"] = c.Start
+		metadata["end"] = c.End
+		if len(c.Headings) > 0 {
+			metadata["headings"] = c.Headings
+		}
+		converted[i] = Chunk{
+			Metadata: metadata,
+			ID:       c.ID(),
+			SourceID: c.Source,
+			Text:     c.Text,
+		}
+	}
+	return converted
+}
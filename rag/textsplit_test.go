@@ -0,0 +1,24 @@
+package rag
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/weave-labs/gollm/textsplit"
+)
+
+func TestChunksFromText(t *testing.T) {
+	split := textsplit.NewMarkdownSplitter().Split("# Setup\n\nInstall it.\n")
+	for i := range split {
+		split[i].Source = "guide.md"
+	}
+
+	chunks := ChunksFromText(split)
+	require.Len(t, chunks, 1)
+	assert.Equal(t, "guide.md#0", chunks[0].ID)
+	assert.Equal(t, "guide.md", chunks[0].SourceID)
+	assert.Equal(t, "# Setup\n\nInstall it.", chunks[0].Text)
+	assert.Equal(t, map[string]any{"start": 0, "end": 20, "headings": []string{"Setup"}}, chunks[0].Metadata)
+}
@@ -3,6 +3,9 @@
 package gollm
 
 import (
+	"context"
+	"iter"
+
 	"github.com/weave-labs/gollm/llm"
 )
 
@@ -17,4 +20,52 @@ type (
 
 	// RetryStrategy defines the interface for handling stream interruptions.
 	RetryStrategy = llm.RetryStrategy
+
+	// StreamAccumulator assembles streamed tokens into a complete Response.
+	StreamAccumulator = llm.StreamAccumulator
+
+	// PartialJSONParser incrementally parses a JSON document that arrives in fragments.
+	PartialJSONParser = llm.PartialJSONParser
+)
+
+// StructuredSnapshot is one update of a structured response that is being streamed.
+type StructuredSnapshot[T any] = llm.StructuredSnapshot[T]
+
+// Re-export streaming options and errors from the llm package
+var (
+	WithFirstTokenTimeout = llm.WithFirstTokenTimeout // Fails a stream when the first token is late
+	WithIdleTimeout       = llm.WithIdleTimeout       // Fails a stream when tokens stop arriving
+	WithStreamReconnect   = llm.WithStreamReconnect   // Re-issues the request on transient stream failures
+
+	ErrFirstTokenTimeout = llm.ErrFirstTokenTimeout
+	ErrStreamIdleTimeout = llm.ErrStreamIdleTimeout
+	ErrStreamDiverged    = llm.ErrStreamDiverged
+	ErrStreamClosed      = llm.ErrStreamClosed
+
+	ErrInvalidPartialJSON           = llm.ErrInvalidPartialJSON
+	ErrIncompleteStructuredResponse = llm.ErrIncompleteStructuredResponse
+)
+
+// Re-export stream consumption helpers from the llm package
+var (
+	CollectStream   = llm.CollectStream   // Consumes a stream into a complete Response
+	CollectStreamTo = llm.CollectStreamTo // Consumes a stream, writing text to an io.Writer
+	StreamTokens    = llm.StreamTokens    // Adapts a stream for range-over-func loops
 )
+
+// StreamStructured re-exports the llm generic helper that yields progressively
+// populated snapshots of T from a structured token stream.
+func StreamStructured[T any](ctx context.Context, stream TokenStream) iter.Seq2[*StructuredSnapshot[T], error] {
+	return llm.StreamStructured[T](ctx, stream)
+}
+
+// GenerateStructuredStream re-exports the llm generic helper that streams a
+// structured response of type T from l.
+func GenerateStructuredStream[T any](
+	ctx context.Context,
+	l LLM,
+	prompt *Prompt,
+	opts ...llm.GenerateOption,
+) iter.Seq2[*StructuredSnapshot[T], error] {
+	return llm.GenerateStructuredStream[T](ctx, l, prompt, opts...)
+}
@@ -0,0 +1,81 @@
+package textsplit
+
+import (
+	"path/filepath"
+	"strings"
+)
+
+// Language is a programming language a code splitter knows the top-level
+// declarations of.
+type Language string
+
+// Languages supported by NewCodeSplitter.
+const (
+	LanguageGo         Language = "go"
+	LanguagePython     Language = "python"
+	LanguageJavaScript Language = "javascript"
+	LanguageTypeScript Language = "typescript"
+	LanguageJava       Language = "java"
+	LanguageRust       Language = "rust"
+)
+
+// codeSeparators are the boundaries code is cut at for each language: its
+// declarations, then blank lines, lines and words.
+var codeSeparators = map[Language][]string{
+	LanguageGo: {
+		"\nfunc ", "\ntype ", "\nvar ", "\nconst ",
+		"\n\tif ", "\n\tfor ", "\n\tswitch ", "\n\tselect ",
+	},
+	LanguagePython: {
+		"\nclass ", "\ndef ", "\nasync def ", "\n\tdef ", "\n    def ", "\n    async def ",
+		"\n\tif ", "\n    if ", "\n    for ", "\n    while ", "\n    with ", "\n    try:",
+	},
+	LanguageJavaScript: {
+		"\nexport ", "\nfunction ", "\nclass ", "\nconst ", "\nlet ",
+		"\n  if ", "\n  for ", "\n  while ", "\n  switch ", "\n  return ",
+	},
+	LanguageTypeScript: {
+		"\nexport ", "\ninterface ", "\ntype ", "\nenum ", "\nfunction ", "\nclass ", "\nconst ", "\nlet ",
+		"\n  if ", "\n  for ", "\n  while ", "\n  switch ", "\n  return ",
+	},
+	LanguageJava: {
+		"\npublic ", "\nprotected ", "\nprivate ", "\nclass ", "\ninterface ", "\nenum ",
+		"\n    public ", "\n    protected ", "\n    private ", "\n    static ",
+		"\n        if ", "\n        for ", "\n        while ", "\n        switch ",
+	},
+	LanguageRust: {
+		"\npub fn ", "\nfn ", "\npub struct ", "\nstruct ", "\npub enum ", "\nenum ",
+		"\nimpl ", "\ntrait ", "\nmod ", "\nconst ", "\nstatic ",
+		"\n    pub fn ", "\n    fn ", "\n    if ", "\n    for ", "\n    while ", "\n    match ",
+	},
+}
+
+// languageExtensions maps file extensions to their languages.
+var languageExtensions = map[string]Language{
+	".go":   LanguageGo,
+	".py":   LanguagePython,
+	".js":   LanguageJavaScript,
+	".jsx":  LanguageJavaScript,
+	".mjs":  LanguageJavaScript,
+	".ts":   LanguageTypeScript,
+	".tsx":  LanguageTypeScript,
+	".java": LanguageJava,
+	".rs":   LanguageRust,
+}
+
+// LanguageForFile returns the language of a source file from its extension.
+func LanguageForFile(path string) (Language, bool) {
+	language, ok := languageExtensions[strings.ToLower(filepath.Ext(path))]
+	return language, ok
+}
+
+// NewCodeSplitter creates a recursive splitter that keeps the declarations of
+// language together, cutting between functions and types before it cuts
+// inside them. Unknown languages are cut at blank lines, lines and words.
+func NewCodeSplitter(language Language, opts ...Option) *RecursiveSplitter {
+	separators := append(append([]string(nil), codeSeparators[language]...), "\n\n", "\n", " ", "")
+	return &RecursiveSplitter{opts: newOptions(options{
+		separators: separators,
+		overlap:    DefaultOverlap,
+	}, opts)}
+}
@@ -0,0 +1,144 @@
+package textsplit
+
+import (
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// blankLines matches runs of lines that are empty or hold only spaces.
+var blankLines = regexp.MustCompile(`\n[ \t]*(?:\n[ \t]*)+`)
+
+// hiddenElements are the elements whose content is not visible text.
+var hiddenElements = map[atom.Atom]bool{
+	atom.Head:     true,
+	atom.Script:   true,
+	atom.Style:    true,
+	atom.Noscript: true,
+	atom.Template: true,
+	atom.Svg:      true,
+	atom.Iframe:   true,
+}
+
+// blockElements are the elements that start a new paragraph.
+var blockElements = map[atom.Atom]bool{
+	atom.P: true, atom.Div: true, atom.Section: true, atom.Article: true, atom.Main: true,
+	atom.Header: true, atom.Footer: true, atom.Aside: true, atom.Nav: true,
+	atom.Blockquote: true, atom.Table: true, atom.Ul: true, atom.Ol: true, atom.Dl: true,
+	atom.Figure: true, atom.Form: true, atom.Hr: true,
+}
+
+// headingLevels are the levels of the heading elements.
+var headingLevels = map[atom.Atom]int{
+	atom.H1: 1, atom.H2: 2, atom.H3: 3, atom.H4: 4, atom.H5: 5, atom.H6: 6,
+}
+
+// htmlText extracts the visible text of an HTML page as Markdown, and its title.
+func htmlText(r io.Reader) (text, title string, err error) {
+	doc, err := html.Parse(r)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to parse HTML: %w", err)
+	}
+
+	var b strings.Builder
+	var walk func(n *html.Node, pre bool)
+	walk = func(n *html.Node, pre bool) {
+		switch n.Type {
+		case html.TextNode:
+			if pre {
+				b.WriteString(n.Data)
+			} else if fields := strings.Fields(n.Data); len(fields) > 0 {
+				if strings.TrimLeft(n.Data, " \t\r\n") != n.Data && !endsWithSpace(&b) {
+					b.WriteString(" ")
+				}
+				b.WriteString(strings.Join(fields, " "))
+				if strings.TrimRight(n.Data, " \t\r\n") != n.Data {
+					b.WriteString(" ")
+				}
+			}
+			return
+		case html.ElementNode:
+			if hiddenElements[n.DataAtom] {
+				return
+			}
+			switch {
+			case headingLevels[n.DataAtom] > 0:
+				fmt.Fprintf(&b, "\n\n%s %s\n\n", strings.Repeat("#", headingLevels[n.DataAtom]),
+					strings.Join(strings.Fields(nodeText(n)), " "))
+				return
+			case n.DataAtom == atom.Pre:
+				b.WriteString("\n\n```\n")
+				for c := n.FirstChild; c != nil; c = c.NextSibling {
+					walk(c, true)
+				}
+				b.WriteString("\n```\n\n")
+				return
+			case n.DataAtom == atom.Li:
+				b.WriteString("\n- ")
+			case n.DataAtom == atom.Br || n.DataAtom == atom.Tr:
+				b.WriteString("\n")
+			case n.DataAtom == atom.Td || n.DataAtom == atom.Th:
+				b.WriteString(" ")
+			case blockElements[n.DataAtom]:
+				b.WriteString("\n\n")
+			}
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c, pre)
+		}
+		if n.Type == html.ElementNode && blockElements[n.DataAtom] {
+			b.WriteString("\n\n")
+		}
+	}
+	walk(doc, false)
+	if n := findElement(doc, atom.Title); n != nil {
+		title = strings.Join(strings.Fields(nodeText(n)), " ")
+	}
+
+	lines := strings.Split(b.String(), "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimRight(line, " \t")
+	}
+	text = blankLines.ReplaceAllString(strings.Join(lines, "\n"), "\n\n")
+	return strings.TrimSpace(text), title, nil
+}
+
+// nodeText returns the text inside n.
+func nodeText(n *html.Node) string {
+	var b strings.Builder
+	var walk func(n *html.Node)
+	walk = func(n *html.Node) {
+		if n.Type == html.TextNode {
+			b.WriteString(n.Data)
+			b.WriteString(" ")
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c)
+		}
+	}
+	walk(n)
+	return b.String()
+}
+
+// findElement returns the first element of kind a under n, or nil.
+func findElement(n *html.Node, a atom.Atom) *html.Node {
+	if n.Type == html.ElementNode && n.DataAtom == a {
+		return n
+	}
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		if found := findElement(c, a); found != nil {
+			return found
+		}
+	}
+	return nil
+}
+
+// endsWithSpace reports whether b is empty or ends with a space or newline.
+func endsWithSpace(b *strings.Builder) bool {
+	s := b.String()
+	return s == "" || strings.HasSuffix(s, " ") || strings.HasSuffix(s, "\n")
+}
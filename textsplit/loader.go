@@ -0,0 +1,164 @@
+package textsplit
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// maxJSONLLine is the longest JSONL record a loader reads.
+const maxJSONLLine = 64 << 20
+
+// Loader reads a document and splits it into chunks.
+type Loader interface {
+	// Load reads the document from r. Its chunks record source as their
+	// Source.
+	Load(r io.Reader, source string) ([]Chunk, error)
+}
+
+// TextLoader reads a document as text and splits it with its splitter. It
+// loads plain text, Markdown and source code.
+type TextLoader struct {
+	splitter Splitter
+}
+
+// NewTextLoader creates a loader for plain text.
+func NewTextLoader(opts ...Option) *TextLoader {
+	return &TextLoader{splitter: NewRecursiveSplitter(opts...)}
+}
+
+// Load implements Loader.
+func (l *TextLoader) Load(r io.Reader, source string) ([]Chunk, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", source, err)
+	}
+	return numberChunks(l.splitter.Split(string(data)), source, nil), nil
+}
+
+// NewMarkdownLoader creates a loader for Markdown whose chunks record their
+// headings.
+func NewMarkdownLoader(opts ...Option) *TextLoader {
+	return &TextLoader{splitter: NewMarkdownSplitter(opts...)}
+}
+
+// NewCodeLoader creates a loader for source code in language.
+func NewCodeLoader(language Language, opts ...Option) *TextLoader {
+	return &TextLoader{splitter: NewCodeSplitter(language, opts...)}
+}
+
+// HTMLLoader loads the visible text of HTML pages. Headings, paragraphs, list
+// items and code blocks are kept as Markdown, which is split like
+// NewMarkdownLoader. Offsets refer to the extracted text, and the page title
+// is recorded as the "title" metadata.
+type HTMLLoader struct {
+	splitter *MarkdownSplitter
+}
+
+// NewHTMLLoader creates a loader for HTML.
+func NewHTMLLoader(opts ...Option) *HTMLLoader {
+	return &HTMLLoader{splitter: NewMarkdownSplitter(opts...)}
+}
+
+// Load implements Loader.
+func (l *HTMLLoader) Load(r io.Reader, source string) ([]Chunk, error) {
+	text, title, err := htmlText(r)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", source, err)
+	}
+	var metadata map[string]any
+	if title != "" {
+		metadata = map[string]any{"title": title}
+	}
+	return numberChunks(l.splitter.Split(text), source, metadata), nil
+}
+
+// JSONLLoader loads JSON Lines, one record per line. The text of each record
+// is split like plain text, and its other fields and its line number are
+// recorded as metadata. Offsets refer to the text of the record.
+type JSONLLoader struct {
+	splitter  Splitter
+	textField string
+}
+
+// NewJSONLLoader creates a loader for JSON Lines whose records hold their text
+// in textField, "text" if it is empty.
+func NewJSONLLoader(textField string, opts ...Option) *JSONLLoader {
+	if textField == "" {
+		textField = "text"
+	}
+	return &JSONLLoader{splitter: NewRecursiveSplitter(opts...), textField: textField}
+}
+
+// Load implements Loader.
+func (l *JSONLLoader) Load(r io.Reader, source string) ([]Chunk, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(nil, maxJSONLLine)
+
+	var chunks []Chunk
+	for line := 1; scanner.Scan(); line++ {
+		data := bytes.TrimSpace(scanner.Bytes())
+		if len(data) == 0 {
+			continue
+		}
+
+		var record map[string]any
+		if err := json.Unmarshal(data, &record); err != nil {
+			return nil, fmt.Errorf("failed to decode %s line %d: %w", source, line, err)
+		}
+		text, ok := record[l.textField].(string)
+		if !ok {
+			return nil, fmt.Errorf("%s line %d has no string field %q", source, line, l.textField)
+		}
+		delete(record, l.textField)
+		record["line"] = line
+
+		for _, chunk := range l.splitter.Split(text) {
+			chunk.Metadata = record
+			chunks = append(chunks, chunk)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", source, err)
+	}
+
+	for i := range chunks {
+		chunks[i].Source = source
+		chunks[i].Index = i
+	}
+	return chunks, nil
+}
+
+// LoaderForFile returns the loader for a file from its extension: Markdown,
+// HTML, JSONL, source code of a known language, or plain text.
+func LoaderForFile(path string, opts ...Option) Loader {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".md", ".markdown":
+		return NewMarkdownLoader(opts...)
+	case ".html", ".htm":
+		return NewHTMLLoader(opts...)
+	case ".jsonl", ".ndjson":
+		return NewJSONLLoader("", opts...)
+	}
+	if language, ok := LanguageForFile(path); ok {
+		return NewCodeLoader(language, opts...)
+	}
+	return NewTextLoader(opts...)
+}
+
+// LoadFile reads a file with the loader for its extension and splits it into
+// chunks whose source is path.
+func LoadFile(path string, opts ...Option) ([]Chunk, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open %s: %w", path, err)
+	}
+	defer func() { _ = f.Close() }()
+
+	return LoaderForFile(path, opts...).Load(f, path)
+}
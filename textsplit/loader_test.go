@@ -0,0 +1,71 @@
+package textsplit
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHTMLLoader(t *testing.T) {
+	page := `<html><head><title>Release  notes</title><style>p { color: red }</style></head>
+<body>
+<h1>Version 2</h1>
+<p>Adds   <b>streaming</b> support.</p>
+<script>track()</script>
+<ul><li>Faster</li><li>Smaller</li></ul>
+<pre>go get example.com/x
+go test ./...</pre>
+</body></html>`
+
+	chunks, err := NewHTMLLoader().Load(strings.NewReader(page), "notes.html")
+	require.NoError(t, err)
+	require.Len(t, chunks, 1)
+
+	chunk := chunks[0]
+	assert.Equal(t, "notes.html", chunk.Source)
+	assert.Equal(t, map[string]any{"title": "Release notes"}, chunk.Metadata)
+	assert.Equal(t, []string{"Version 2"}, chunk.Headings)
+	assert.Equal(t, "# Version 2\n\nAdds streaming support.\n\n- Faster\n- Smaller\n\n```\ngo get example.com/x\ngo test ./...\n```", chunk.Text)
+}
+
+func TestJSONLLoader(t *testing.T) {
+	input := `{"id": "a", "body": "First record."}
+
+{"id": "b", "body": "Second record."}
+`
+	chunks, err := NewJSONLLoader("body").Load(strings.NewReader(input), "records.jsonl")
+	require.NoError(t, err)
+	require.Len(t, chunks, 2)
+
+	assert.Equal(t, "First record.", chunks[0].Text)
+	assert.Equal(t, map[string]any{"id": "a", "line": 1}, chunks[0].Metadata)
+	assert.Equal(t, "Second record.", chunks[1].Text)
+	assert.Equal(t, map[string]any{"id": "b", "line": 3}, chunks[1].Metadata)
+	assert.Equal(t, "records.jsonl#1", chunks[1].ID())
+
+	_, err = NewJSONLLoader("body").Load(strings.NewReader(`{"id": "c"}`), "records.jsonl")
+	assert.ErrorContains(t, err, `line 1 has no string field "body"`)
+}
+
+func TestLoadFile(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "guide.md")
+	require.NoError(t, os.WriteFile(path, []byte("# Guide\n\nRead me.\n"), 0o600))
+
+	chunks, err := LoadFile(path)
+	require.NoError(t, err)
+	require.Len(t, chunks, 1)
+	assert.Equal(t, path, chunks[0].Source)
+	assert.Equal(t, []string{"Guide"}, chunks[0].Headings)
+
+	assert.IsType(t, &HTMLLoader{}, LoaderForFile("page.HTM"))
+	assert.IsType(t, &JSONLLoader{}, LoaderForFile("data.ndjson"))
+	assert.IsType(t, &TextLoader{}, LoaderForFile("notes.txt"))
+
+	_, err = LoadFile(filepath.Join(dir, "missing.txt"))
+	assert.Error(t, err)
+}
@@ -0,0 +1,95 @@
+package textsplit
+
+import (
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// MarkdownSeparators are the boundaries a MarkdownSplitter cuts sections at:
+// code fences, paragraphs, lines, sentences, words and characters.
+var MarkdownSeparators = []string{"\n```", "\n~~~", "\n\n", "\n", ". ", " ", ""}
+
+// markdownHeading matches an ATX heading line and captures its level and title.
+var markdownHeading = regexp.MustCompile(`^(#{1,6})[ \t]+(.*?)(?:[ \t]+#+)?[ \t]*$`)
+
+// MarkdownSplitter splits Markdown into the sections under its headings and
+// records the headings of each chunk. Sections larger than the chunk size are
+// split further like a RecursiveSplitter. Headings inside code blocks are
+// ignored.
+type MarkdownSplitter struct {
+	recursive *RecursiveSplitter
+}
+
+// NewMarkdownSplitter creates a Markdown splitter. Its chunks are measured in
+// characters unless WithLengthFunc is given.
+func NewMarkdownSplitter(opts ...Option) *MarkdownSplitter {
+	return &MarkdownSplitter{recursive: &RecursiveSplitter{opts: newOptions(options{
+		separators: MarkdownSeparators,
+		overlap:    DefaultOverlap,
+	}, opts)}}
+}
+
+// Split implements Splitter.
+func (s *MarkdownSplitter) Split(text string) []Chunk {
+	var chunks []Chunk
+	for _, section := range markdownSections(text) {
+		chunks = append(chunks, s.recursive.splitSpan(text, section.span, section.headings)...)
+	}
+	return numberChunks(chunks, "", nil)
+}
+
+// markdownSection is the text under a heading, including the heading line.
+type markdownSection struct {
+	headings []string
+	span
+}
+
+// markdownSections cuts text before every heading outside code blocks.
+func markdownSections(text string) []markdownSection {
+	var sections []markdownSection
+	var path []string
+	var levels []int
+	current := markdownSection{}
+	fence := ""
+
+	for offset := 0; offset < len(text); {
+		end := strings.IndexByte(text[offset:], '\n')
+		if end < 0 {
+			end = len(text)
+		} else {
+			end += offset + 1
+		}
+		line := strings.TrimRight(text[offset:end], "\r\n")
+		trimmed := strings.TrimLeft(line, " ")
+
+		switch {
+		case fence != "":
+			if strings.HasPrefix(trimmed, fence) {
+				fence = ""
+			}
+		case strings.HasPrefix(trimmed, "```") || strings.HasPrefix(trimmed, "~~~"):
+			fence = trimmed[:3]
+		default:
+			if match := markdownHeading.FindStringSubmatch(line); match != nil {
+				if offset > current.start {
+					current.end = offset
+					sections = append(sections, current)
+				}
+				level := len(match[1])
+				for len(levels) > 0 && levels[len(levels)-1] >= level {
+					levels, path = levels[:len(levels)-1], path[:len(path)-1]
+				}
+				levels, path = append(levels, level), append(path, match[2])
+				current = markdownSection{headings: slices.Clone(path), span: span{start: offset}}
+			}
+		}
+		offset = end
+	}
+
+	current.end = len(text)
+	if current.end > current.start {
+		sections = append(sections, current)
+	}
+	return sections
+}
@@ -0,0 +1,38 @@
+package textsplit
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMarkdownSplitterRecordsHeadings(t *testing.T) {
+	text := "Intro text.\n\n# Guide\n\nOverview.\n\n## Install\n\nRun the installer.\n\n```sh\n# not a heading\n```\n\n## Usage\n\nCall it.\n\n# Appendix\n\nMore.\n"
+	chunks := NewMarkdownSplitter().Split(text)
+
+	require.Len(t, chunks, 5)
+	assert.Empty(t, chunks[0].Headings)
+	assert.Equal(t, "Intro text.", chunks[0].Text)
+	assert.Equal(t, []string{"Guide"}, chunks[1].Headings)
+	assert.Equal(t, []string{"Guide", "Install"}, chunks[2].Headings)
+	assert.Contains(t, chunks[2].Text, "# not a heading")
+	assert.Equal(t, []string{"Guide", "Usage"}, chunks[3].Headings)
+	assert.Equal(t, []string{"Appendix"}, chunks[4].Headings)
+	for i, chunk := range chunks {
+		assert.Equal(t, i, chunk.Index)
+		assert.Equal(t, chunk.Text, text[chunk.Start:chunk.End])
+	}
+}
+
+func TestMarkdownSplitterSplitsLongSections(t *testing.T) {
+	text := "# Title\n\nFirst paragraph of the section.\n\nSecond paragraph of the section."
+	chunks := NewMarkdownSplitter(WithChunkSize(40), WithOverlap(0)).Split(text)
+
+	require.Len(t, chunks, 2)
+	assert.Equal(t, "# Title\n\nFirst paragraph of the section.", chunks[0].Text)
+	assert.Equal(t, "Second paragraph of the section.", chunks[1].Text)
+	for _, chunk := range chunks {
+		assert.Equal(t, []string{"Title"}, chunk.Headings)
+	}
+}
@@ -0,0 +1,105 @@
+package textsplit
+
+import "strings"
+
+// DefaultSeparators are the boundaries a RecursiveSplitter cuts text at:
+// paragraphs, lines, sentences, words and finally characters.
+var DefaultSeparators = []string{"\n\n", "\n", ". ", " ", ""}
+
+// RecursiveSplitter cuts text at the first of its separators that occurs in
+// it and merges the parts into chunks up to the chunk size. Parts that are
+// still too large are cut again at the next separator.
+type RecursiveSplitter struct {
+	opts options
+}
+
+// NewRecursiveSplitter creates a splitter that measures chunks in characters
+// unless WithLengthFunc is given.
+func NewRecursiveSplitter(opts ...Option) *RecursiveSplitter {
+	return &RecursiveSplitter{opts: newOptions(options{
+		separators: DefaultSeparators,
+		overlap:    DefaultOverlap,
+	}, opts)}
+}
+
+// NewTokenSplitter creates a recursive splitter that measures chunks in the
+// tokens of model. Its default size is DefaultTokenChunkSize tokens with
+// DefaultTokenOverlap tokens of overlap.
+func NewTokenSplitter(model string, opts ...Option) *RecursiveSplitter {
+	return &RecursiveSplitter{opts: newOptions(options{
+		length:     TokenLength(model),
+		separators: DefaultSeparators,
+		size:       DefaultTokenChunkSize,
+		overlap:    DefaultTokenOverlap,
+	}, opts)}
+}
+
+// Split implements Splitter.
+func (s *RecursiveSplitter) Split(text string) []Chunk {
+	return numberChunks(s.splitSpan(text, span{0, len(text)}, nil), "", nil)
+}
+
+// splitSpan splits text[within] into chunks under headings.
+func (s *RecursiveSplitter) splitSpan(text string, within span, headings []string) []Chunk {
+	return toChunks(text, s.split(text, within, s.opts.separators), headings)
+}
+
+// split cuts text[within] at the first separator that occurs in it and merges
+// the parts into spans up to the chunk size.
+func (s *RecursiveSplitter) split(text string, within span, separators []string) []span {
+	segment := text[within.start:within.end]
+	if s.opts.length(segment) <= s.opts.size {
+		return []span{within}
+	}
+
+	separator, found := "", false
+	for i, candidate := range separators {
+		if candidate == "" || strings.Contains(segment, candidate) {
+			separator, separators, found = candidate, separators[i+1:], true
+			break
+		}
+	}
+	if !found {
+		return []span{within}
+	}
+
+	var spans []span
+	var fitting []span
+	for _, part := range splitKeep(text, within.start, within.end, separator) {
+		if s.opts.length(text[part.start:part.end]) <= s.opts.size {
+			fitting = append(fitting, part)
+			continue
+		}
+		spans = append(spans, s.merge(text, fitting)...)
+		fitting = nil
+		spans = append(spans, s.split(text, part, separators)...)
+	}
+	return append(spans, s.merge(text, fitting)...)
+}
+
+// merge joins consecutive parts into spans up to the chunk size. Each span
+// after the first starts with the last parts of the previous span, up to the
+// overlap.
+func (s *RecursiveSplitter) merge(text string, parts []span) []span {
+	var spans []span
+	var window []span
+	var lengths []int
+	total := 0
+	for _, part := range parts {
+		length := s.opts.length(text[part.start:part.end])
+		if total+length > s.opts.size && len(window) > 0 {
+			spans = append(spans, span{window[0].start, window[len(window)-1].end})
+			for len(window) > 0 && (total > s.opts.overlap || total+length > s.opts.size) {
+				total -= lengths[0]
+				window, lengths = window[1:], lengths[1:]
+			}
+		}
+		window = append(window, part)
+		lengths = append(lengths, length)
+		total += length
+	}
+	if len(window) > 0 {
+		spans = append(spans, span{window[0].start, window[len(window)-1].end})
+	}
+	return spans
+}
@@ -0,0 +1,90 @@
+package textsplit
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRecursiveSplitterKeepsShortText(t *testing.T) {
+	chunks := NewRecursiveSplitter().Split("  A short note.\n")
+
+	require.Len(t, chunks, 1)
+	assert.Equal(t, "A short note.", chunks[0].Text)
+	assert.Equal(t, 2, chunks[0].Start)
+	assert.Equal(t, 15, chunks[0].End)
+}
+
+func TestRecursiveSplitterCutsAtParagraphs(t *testing.T) {
+	text := "First paragraph here.\n\nSecond paragraph here.\n\nThird paragraph here."
+	chunks := NewRecursiveSplitter(WithChunkSize(30), WithOverlap(0)).Split(text)
+
+	require.Len(t, chunks, 3)
+	for i, chunk := range chunks {
+		assert.Equal(t, i, chunk.Index)
+		assert.Equal(t, chunk.Text, text[chunk.Start:chunk.End])
+	}
+	assert.Equal(t, "First paragraph here.", chunks[0].Text)
+	assert.Equal(t, "Third paragraph here.", chunks[2].Text)
+}
+
+func TestRecursiveSplitterOverlap(t *testing.T) {
+	text := strings.Repeat("word ", 40)
+	chunks := NewRecursiveSplitter(WithChunkSize(50), WithOverlap(10)).Split(text)
+
+	require.Greater(t, len(chunks), 1)
+	for i, chunk := range chunks {
+		assert.LessOrEqual(t, CharacterLength(chunk.Text), 50)
+		assert.Equal(t, chunk.Text, text[chunk.Start:chunk.End])
+		if i > 0 {
+			assert.Less(t, chunk.Start, chunks[i-1].End, "chunk %d should overlap the previous one", i)
+		}
+	}
+	assert.Equal(t, strings.TrimSpace(text)[len(strings.TrimSpace(text))-4:], chunks[len(chunks)-1].Text[len(chunks[len(chunks)-1].Text)-4:])
+}
+
+func TestRecursiveSplitterCutsLongWords(t *testing.T) {
+	chunks := NewRecursiveSplitter(WithChunkSize(10), WithOverlap(0)).Split(strings.Repeat("x", 25))
+
+	require.Len(t, chunks, 3)
+	assert.Equal(t, strings.Repeat("x", 5), chunks[2].Text)
+}
+
+func TestRecursiveSplitterLengthFunc(t *testing.T) {
+	words := func(text string) int { return len(strings.Fields(text)) }
+	chunks := NewRecursiveSplitter(WithLengthFunc(words), WithChunkSize(3), WithOverlap(0)).Split("a b c d e f g")
+
+	require.Len(t, chunks, 3)
+	assert.Equal(t, "a b c", chunks[0].Text)
+	assert.Equal(t, "d e f", chunks[1].Text)
+	assert.Equal(t, "g", chunks[2].Text)
+}
+
+func TestTokenSplitter(t *testing.T) {
+	text := strings.Repeat("The quick brown fox jumps over the lazy dog. ", 50)
+	length := TokenLength("gpt-4o")
+	chunks := NewTokenSplitter("gpt-4o", WithChunkSize(40), WithOverlap(5)).Split(text)
+
+	require.Greater(t, len(chunks), 1)
+	for _, chunk := range chunks {
+		assert.LessOrEqual(t, length(chunk.Text), 40)
+	}
+}
+
+func TestCodeSplitterKeepsFunctions(t *testing.T) {
+	source := "package main\n\nfunc a() {\n\treturn\n}\n\nfunc b() {\n\treturn\n}\n"
+	chunks := NewCodeSplitter(LanguageGo, WithChunkSize(30), WithOverlap(0)).Split(source)
+
+	require.Len(t, chunks, 3)
+	assert.Equal(t, "package main", chunks[0].Text)
+	assert.Equal(t, "func a() {\n\treturn\n}", chunks[1].Text)
+	assert.Equal(t, "func b() {\n\treturn\n}", chunks[2].Text)
+
+	language, ok := LanguageForFile("cmd/main.GO")
+	assert.True(t, ok)
+	assert.Equal(t, LanguageGo, language)
+	_, ok = LanguageForFile("notes.txt")
+	assert.False(t, ok)
+}
@@ -0,0 +1,218 @@
+// Package textsplit splits long documents into chunks that fit a model's
+// context window, for summarization, extraction and retrieval.
+//
+// Splitters cut text at the most meaningful boundary that keeps chunks within
+// a size, such as paragraphs before lines and lines before words, and repeat
+// the end of each chunk at the start of the next to keep context across the
+// cut. Sizes are measured in characters or, with NewTokenSplitter, in tokens.
+// Loaders read plain text, Markdown, HTML, JSONL and source files and split
+// them into chunks that record their source, offsets and headings.
+//
+// Example usage:
+//
+//	splitter := textsplit.NewTokenSplitter("gpt-4o", textsplit.WithChunkSize(500), textsplit.WithOverlap(50))
+//	for _, chunk := range splitter.Split(report) {
+//	    fmt.Println(chunk.Start, chunk.End, chunk.Text)
+//	}
+//
+//	chunks, err := textsplit.LoadFile("docs/handbook.md")
+package textsplit
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/weave-labs/gollm/providers"
+)
+
+// Default sizes of splitters, in characters, or in tokens for token splitters.
+const (
+	DefaultChunkSize      = 2000
+	DefaultOverlap        = 200
+	DefaultTokenChunkSize = 500
+	DefaultTokenOverlap   = 50
+)
+
+// Chunk is a piece of a document.
+type Chunk struct {
+	// Metadata holds further information about the chunk's document, such
+	// as the fields of a JSONL record.
+	Metadata map[string]any `json:"metadata,omitempty"`
+	// Source identifies the document, for example by its path.
+	Source string `json:"source,omitempty"`
+	// Text is the content of the chunk, without leading and trailing space.
+	Text string `json:"text"`
+	// Headings are the titles of the sections the chunk is in, outermost
+	// first.
+	Headings []string `json:"headings,omitempty"`
+	// Start and End are the byte offsets of Text in the split text.
+	Start int `json:"start"`
+	End   int `json:"end"`
+	// Index is the position of the chunk among the chunks of its document.
+	Index int `json:"index"`
+}
+
+// ID returns an identifier of the chunk made of its source and index, such as
+// "handbook.md#3".
+func (c Chunk) ID() string {
+	return fmt.Sprintf("%s#%d", c.Source, c.Index)
+}
+
+// Splitter splits a text into chunks.
+type Splitter interface {
+	Split(text string) []Chunk
+}
+
+// LengthFunc measures the size of a text, for example in characters or tokens.
+type LengthFunc func(text string) int
+
+// CharacterLength measures texts in characters.
+func CharacterLength(text string) int {
+	return utf8.RuneCountInString(text)
+}
+
+// TokenLength measures texts in the tokens of model, with the tiktoken
+// encoding that Memory counts with. When the encoding is not available,
+// tokens are estimated from the number of characters.
+func TokenLength(model string) LengthFunc {
+	return CounterLength(providers.NewTiktokenCounter(model))
+}
+
+// CounterLength measures texts with a token counter, for example a calibrated
+// providers.EstimatingTokenCounter for models without a tiktoken encoding.
+func CounterLength(counter providers.TextTokenCounter) LengthFunc {
+	return counter.CountText
+}
+
+// Option configures a splitter or loader.
+type Option func(*options)
+
+type options struct {
+	length     LengthFunc
+	separators []string
+	size       int
+	overlap    int
+}
+
+// WithChunkSize sets the largest size of a chunk. A single word or line that
+// cannot be cut at any separator may exceed it.
+func WithChunkSize(size int) Option {
+	return func(o *options) {
+		o.size = size
+	}
+}
+
+// WithOverlap sets how much of the end of each chunk is repeated at the start
+// of the next one.
+func WithOverlap(overlap int) Option {
+	return func(o *options) {
+		o.overlap = overlap
+	}
+}
+
+// WithLengthFunc sets how the size of chunks is measured. The default is
+// CharacterLength.
+func WithLengthFunc(length LengthFunc) Option {
+	return func(o *options) {
+		o.length = length
+	}
+}
+
+// WithSeparators sets the boundaries text is cut at, most meaningful first.
+// An empty separator cuts between characters.
+func WithSeparators(separators ...string) Option {
+	return func(o *options) {
+		o.separators = separators
+	}
+}
+
+// newOptions applies opts over the given defaults.
+func newOptions(defaults options, opts []Option) options {
+	o := defaults
+	for _, opt := range opts {
+		opt(&o)
+	}
+	if o.length == nil {
+		o.length = CharacterLength
+	}
+	if o.size <= 0 {
+		o.size = DefaultChunkSize
+	}
+	o.overlap = min(max(o.overlap, 0), o.size/2)
+	return o
+}
+
+// span is a byte range of a text.
+type span struct {
+	start, end int
+}
+
+// toChunks converts spans of text to chunks, trimming space and dropping
+// empty chunks.
+func toChunks(text string, spans []span, headings []string) []Chunk {
+	chunks := make([]Chunk, 0, len(spans))
+	for _, s := range spans {
+		start, end := s.start, s.end
+		for start < end {
+			r, size := utf8.DecodeRuneInString(text[start:end])
+			if !unicode.IsSpace(r) {
+				break
+			}
+			start += size
+		}
+		for end > start {
+			r, size := utf8.DecodeLastRuneInString(text[start:end])
+			if !unicode.IsSpace(r) {
+				break
+			}
+			end -= size
+		}
+		if start == end {
+			continue
+		}
+		chunks = append(chunks, Chunk{Text: text[start:end], Headings: headings, Start: start, End: end})
+	}
+	return chunks
+}
+
+// numberChunks sets the source, metadata and index of chunks.
+func numberChunks(chunks []Chunk, source string, metadata map[string]any) []Chunk {
+	for i := range chunks {
+		chunks[i].Source = source
+		chunks[i].Metadata = metadata
+		chunks[i].Index = i
+	}
+	return chunks
+}
+
+// splitKeep cuts text[start:end] at every separator, keeping each separator
+// at the start of the part that follows it, so that the parts are contiguous.
+func splitKeep(text string, start, end int, separator string) []span {
+	if separator == "" {
+		parts := make([]span, 0, end-start)
+		for i := start; i < end; {
+			_, size := utf8.DecodeRuneInString(text[i:end])
+			parts = append(parts, span{i, i + size})
+			i += size
+		}
+		return parts
+	}
+
+	var parts []span
+	partStart := start
+	for i := start; i < end; {
+		next := strings.Index(text[i:end], separator)
+		if next < 0 {
+			break
+		}
+		cut := i + next
+		if cut > partStart {
+			parts = append(parts, span{partStart, cut})
+			partStart = cut
+		}
+		i = cut + len(separator)
+	}
+	return append(parts, span{partStart, end})
+}
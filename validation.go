@@ -97,3 +97,25 @@ func GenerateJSONSchema(v any) ([]byte, error) {
 	}
 	return schema, nil
 }
+
+// SchemaViolation is one way a JSON value fails to match a schema, located by a JSON pointer.
+type SchemaViolation = llm.SchemaViolation
+
+// SchemaValidationError lists every violation found when validating a structured response.
+type SchemaValidationError = llm.SchemaValidationError
+
+// ValidateAgainstSchema validates a JSON response against a JSON schema under draft 2020-12.
+// A mismatch is reported as a *SchemaValidationError whose violations carry JSON-pointer paths.
+//
+// Example usage:
+//
+//	err := ValidateAgainstSchema(`{"text": "Hello"}`, `{"type": "object", "required": ["text"]}`)
+//	var schemaErr *SchemaValidationError
+//	if errors.As(err, &schemaErr) {
+//	    for _, v := range schemaErr.Violations {
+//	        fmt.Println(v.Path, v.Message)
+//	    }
+//	}
+func ValidateAgainstSchema(response string, schema any) error {
+	return llm.ValidateAgainstSchema(response, schema)
+}